@@ -0,0 +1,226 @@
+// Command oidc-stub is a minimal OpenID Connect issuer for local testing.
+// It approves every login as one configured user and can mint bearer tokens
+// directly. Never expose it outside a development machine.
+//
+//	go run ./cmd/oidc-stub -addr :9000 -groups fw-admins
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=fwmg \
+//	OIDC_REDIRECT_URL=http://localhost:8080/api/auth/oidc/callback \
+//	OIDC_GROUP_ROLES=fw-admins=admin go run ./cmd/server
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type stub struct {
+	issuer   string
+	key      *rsa.PrivateKey
+	kid      string
+	sub      string
+	username string
+	groups   []string
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+type authCode struct {
+	clientID  string
+	nonce     string
+	challenge string
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9000", "listen address")
+	issuer := flag.String("issuer", "", "issuer URL (default http://<addr>)")
+	sub := flag.String("sub", "stub-user-1", "subject of the logged-in user")
+	username := flag.String("username", "alice", "preferred_username of the logged-in user")
+	groups := flag.String("groups", "fw-admins", "comma-separated groups of the logged-in user")
+	jwksOut := flag.String("jwks-out", "", "also write the JWKS to this file")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := &stub{
+		issuer:   *issuer,
+		key:      key,
+		kid:      "stub-1",
+		sub:      *sub,
+		username: *username,
+		groups:   strings.Split(*groups, ","),
+		codes:    make(map[string]authCode),
+	}
+	if s.issuer == "" {
+		s.issuer = "http://" + *addr
+	}
+	if *jwksOut != "" {
+		data, _ := json.Marshal(s.jwks())
+		if err := os.WriteFile(*jwksOut, data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) { writeJSON(w, s.jwks()) })
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/issue", s.issue)
+
+	log.Printf("oidc stub issuer %s listening on %s", s.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *stub) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (s *stub) jwks() map[string]any {
+	return map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": s.kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   b64(s.key.N.Bytes()),
+		"e":   b64(big.NewInt(int64(s.key.E)).Bytes()),
+	}}}
+}
+
+// authorize approves immediately and redirects back with a code.
+func (s *stub) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	code := randomID()
+	s.mu.Lock()
+	s.codes[code] = authCode{clientID: q.Get("client_id"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	s.mu.Unlock()
+
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *stub) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, "invalid_grant")
+		return
+	}
+	if code.challenge != "" {
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if b64(sum[:]) != code.challenge {
+			writeError(w, "invalid_grant")
+			return
+		}
+	}
+
+	idToken := s.sign(map[string]any{"aud": code.clientID, "nonce": code.nonce})
+	writeJSON(w, map[string]any{
+		"access_token": idToken,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+// issue mints a bearer token: /issue?aud=fwmg&groups=a,b&sub=x&ttl=1h
+func (s *stub) issue(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	claims := map[string]any{"aud": q.Get("aud")}
+	if v := q.Get("sub"); v != "" {
+		claims["sub"] = v
+		claims["preferred_username"] = v
+	}
+	if v := q.Get("groups"); v != "" {
+		claims["groups"] = strings.Split(v, ",")
+	}
+	if ttl, err := time.ParseDuration(q.Get("ttl")); err == nil {
+		claims["exp"] = time.Now().Add(ttl).Unix()
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(s.sign(claims)))
+}
+
+// sign returns an RS256 JWT for the stub user with extra claims merged in.
+func (s *stub) sign(extra map[string]any) string {
+	now := time.Now()
+	claims := map[string]any{
+		"iss":                s.issuer,
+		"sub":                s.sub,
+		"preferred_username": s.username,
+		"groups":             s.groups,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return b64(b)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -2,16 +2,99 @@ package main
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
 	Port           string
 	Env            string
 	DBPath         string
-	APIKey         string
+	APIKey         string // legacy shared admin credential; empty disables it
 	AllowedOrigins []string
 	FrontendPath   string
+	// TrustedProxies may set X-Forwarded-For. Empty trusts none.
+	TrustedProxies []string
+
+	// ScheduleInterval is how often rule schedules are checked for transitions.
+	ScheduleInterval time.Duration
+
+	// CounterCacheTTL is how long collected rule and interface counters are
+	// served before they are read again.
+	CounterCacheTTL time.Duration
+	// CounterSampleInterval is how often counters are recorded into the
+	// time series; 0 disables it. Samples older than CounterRetention are
+	// deleted; 0 keeps them forever.
+	CounterSampleInterval time.Duration
+	CounterRetention      time.Duration
+
+	// DriftCheckInterval is how often the live ruleset is compared with the
+	// last apply to publish drift events; 0 disables it.
+	DriftCheckInterval time.Duration
+
+	// LogSources are where packets logged by rules are read from: kmsg,
+	// journald, file:<path> or nflog:<group>. Empty disables collection.
+	// The newest LogMaxEntries are kept, none older than LogRetention;
+	// 0 disables either limit.
+	LogSources    []string
+	LogMaxEntries int
+	LogRetention  time.Duration
+
+	// ConntrackFlushOnApply deletes the tracked connections a new ruleset
+	// denies after every apply, unless the apply request says otherwise.
+	ConntrackFlushOnApply bool
+
+	// TrafficSampleInterval is how often conntrack counters are attributed
+	// to hosts and peers; 0 disables it. Addresses in TrafficLocalNets
+	// (default the private ranges) and this host's own are local hosts, the
+	// others remote peers.
+	TrafficSampleInterval time.Duration
+	TrafficLocalNets      []string
+
+	// AlertEvalInterval is how often threshold alert rules are evaluated;
+	// 0 disables the alert engine.
+	AlertEvalInterval time.Duration
+
+	// SessionTTL is how long a login session stays valid.
+	SessionTTL time.Duration
+	// AdminUsername and AdminPassword bootstrap the first admin account
+	// when the user table is empty.
+	AdminUsername string
+	AdminPassword string
+
+	// OIDC settings. An empty OIDCIssuer disables OIDC.
+	OIDCIssuer        string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCAudience      string
+	OIDCJWKSFile      string
+	OIDCScopes        []string
+	OIDCUsernameClaim string
+	OIDCGroupsClaim   string
+	OIDCGroupRoles    string // "group=role[:zone=x|:tag=y],..."
+	// OIDCPostLoginURL is where the browser lands after login, with the
+	// session token in the URL fragment.
+	OIDCPostLoginURL string
+
+	// TLS settings. An empty TLSCertFile serves plain HTTP.
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string
+	TLSClientAuth     string // none, optional, require
+	TLSClientRoles    string // "cn:name=role,dns:host=role[:zone=x|:tag=y],..."
+	TLSReloadInterval time.Duration
+
+	// MgmtAllowlist lists source CIDRs always accepted on MgmtPorts at the
+	// top of INPUT. MgmtPorts defaults to Port.
+	MgmtAllowlist []string
+	MgmtPorts     []string
+
+	// MetricsToken and MetricsAllowlist guard /metrics, which is only
+	// served if at least one is set.
+	MetricsToken     string
+	MetricsAllowlist []string
 }
 
 func loadConfig() Config {
@@ -20,11 +103,6 @@ func loadConfig() Config {
 		origins = "http://localhost:5173"
 	}
 
-	apiKey := os.Getenv("API_KEY")
-	if apiKey == "" {
-		apiKey = "dev-insecure-key-change-in-production"
-	}
-
 	dbPath := os.Getenv("DB_PATH")
 	if dbPath == "" {
 		dbPath = "./firewall.db"
@@ -45,12 +123,152 @@ func loadConfig() Config {
 		frontendPath = "../frontend/dist"
 	}
 
+	scheduleInterval, err := time.ParseDuration(os.Getenv("SCHEDULE_INTERVAL"))
+	if err != nil || scheduleInterval <= 0 {
+		scheduleInterval = 30 * time.Second
+	}
+
+	counterCacheTTL, err := time.ParseDuration(os.Getenv("COUNTER_CACHE_TTL"))
+	if err != nil || counterCacheTTL < 0 {
+		counterCacheTTL = 2 * time.Second
+	}
+
+	counterSampleInterval, err := time.ParseDuration(os.Getenv("COUNTER_SAMPLE_INTERVAL"))
+	if err != nil || counterSampleInterval < 0 {
+		counterSampleInterval = 30 * time.Second
+	}
+
+	counterRetention, err := time.ParseDuration(os.Getenv("COUNTER_RETENTION"))
+	if err != nil || counterRetention < 0 {
+		counterRetention = 30 * 24 * time.Hour
+	}
+
+	driftCheckInterval, err := time.ParseDuration(os.Getenv("DRIFT_CHECK_INTERVAL"))
+	if err != nil || driftCheckInterval < 0 {
+		driftCheckInterval = time.Minute
+	}
+
+	logMaxEntries, err := strconv.Atoi(os.Getenv("LOG_MAX_ENTRIES"))
+	if err != nil || logMaxEntries < 0 {
+		logMaxEntries = 100000
+	}
+
+	logRetention, err := time.ParseDuration(os.Getenv("LOG_RETENTION"))
+	if err != nil || logRetention < 0 {
+		logRetention = 7 * 24 * time.Hour
+	}
+
+	trafficSampleInterval, err := time.ParseDuration(os.Getenv("TRAFFIC_SAMPLE_INTERVAL"))
+	if err != nil || trafficSampleInterval < 0 {
+		trafficSampleInterval = 30 * time.Second
+	}
+
+	alertEvalInterval, err := time.ParseDuration(os.Getenv("ALERT_EVAL_INTERVAL"))
+	if err != nil || alertEvalInterval < 0 {
+		alertEvalInterval = 30 * time.Second
+	}
+
+	sessionTTL, err := time.ParseDuration(os.Getenv("SESSION_TTL"))
+	if err != nil || sessionTTL <= 0 {
+		sessionTTL = 12 * time.Hour
+	}
+
+	adminUsername := os.Getenv("ADMIN_USERNAME")
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+
+	var oidcScopes []string
+	if v := os.Getenv("OIDC_SCOPES"); v != "" {
+		oidcScopes = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+
+	oidcPostLoginURL := os.Getenv("OIDC_POST_LOGIN_URL")
+	if oidcPostLoginURL == "" {
+		oidcPostLoginURL = "/"
+	}
+
+	tlsClientAuth := os.Getenv("TLS_CLIENT_AUTH")
+	if tlsClientAuth == "" && os.Getenv("TLS_CLIENT_CA_FILE") != "" {
+		tlsClientAuth = "optional"
+	}
+
+	tlsReloadInterval, err := time.ParseDuration(os.Getenv("TLS_RELOAD_INTERVAL"))
+	if err != nil || tlsReloadInterval <= 0 {
+		tlsReloadInterval = 10 * time.Second
+	}
+
+	mgmtPorts := splitList(os.Getenv("MGMT_PORTS"))
+	if len(mgmtPorts) == 0 {
+		mgmtPorts = []string{port}
+	}
+
 	return Config{
 		Port:           port,
 		Env:            env,
 		DBPath:         dbPath,
-		APIKey:         apiKey,
+		APIKey:         os.Getenv("API_KEY"),
 		AllowedOrigins: strings.Split(origins, ","),
 		FrontendPath:   frontendPath,
+		TrustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+
+		ScheduleInterval: scheduleInterval,
+		CounterCacheTTL:  counterCacheTTL,
+
+		CounterSampleInterval: counterSampleInterval,
+		CounterRetention:      counterRetention,
+
+		DriftCheckInterval: driftCheckInterval,
+
+		LogSources:    splitList(os.Getenv("LOG_SOURCES")),
+		LogMaxEntries: logMaxEntries,
+		LogRetention:  logRetention,
+
+		ConntrackFlushOnApply: os.Getenv("CONNTRACK_FLUSH_ON_APPLY") == "true",
+
+		TrafficSampleInterval: trafficSampleInterval,
+		TrafficLocalNets:      splitList(os.Getenv("TRAFFIC_LOCAL_NETS")),
+
+		AlertEvalInterval: alertEvalInterval,
+
+		SessionTTL:    sessionTTL,
+		AdminUsername: adminUsername,
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
+
+		OIDCIssuer:        os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCAudience:      os.Getenv("OIDC_AUDIENCE"),
+		OIDCJWKSFile:      os.Getenv("OIDC_JWKS_FILE"),
+		OIDCScopes:        oidcScopes,
+		OIDCUsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+		OIDCGroupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+		OIDCGroupRoles:    os.Getenv("OIDC_GROUP_ROLES"),
+		OIDCPostLoginURL:  oidcPostLoginURL,
+
+		TLSCertFile:       os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:        os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile:   os.Getenv("TLS_CLIENT_CA_FILE"),
+		TLSClientAuth:     tlsClientAuth,
+		TLSClientRoles:    os.Getenv("TLS_CLIENT_ROLES"),
+		TLSReloadInterval: tlsReloadInterval,
+
+		MgmtAllowlist: splitList(os.Getenv("MGMT_ALLOWLIST")),
+		MgmtPorts:     mgmtPorts,
+
+		MetricsToken:     os.Getenv("METRICS_TOKEN"),
+		MetricsAllowlist: splitList(os.Getenv("METRICS_ALLOWLIST")),
+	}
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
@@ -9,13 +9,19 @@ import (
 	"path/filepath"
 	"syscall"
 	"time"
+	_ "time/tzdata" // rule schedules may name any IANA timezone
 
 	"github.com/firewall-manager/backend/internal/api/handlers"
 	"github.com/firewall-manager/backend/internal/api/middleware"
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/conntrack"
 	"github.com/firewall-manager/backend/internal/firewall"
+	"github.com/firewall-manager/backend/internal/fwlog"
 	"github.com/firewall-manager/backend/internal/network"
+	"github.com/firewall-manager/backend/internal/oidc"
 	"github.com/firewall-manager/backend/internal/repository"
 	"github.com/firewall-manager/backend/internal/service"
+	"github.com/firewall-manager/backend/internal/tlsutil"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -46,12 +52,15 @@ func main() {
 	}
 
 	var netDriver network.Driver
+	var conntrackDriver conntrack.Driver
 	if os.Getenv("USE_MOCK_DRIVER") == "true" {
 		log.Info("using mock network driver for development")
 		netDriver = network.NewMockDriver(log)
+		conntrackDriver = conntrack.NewMockDriver(log)
 	} else {
 		log.Info("using netlink network driver")
 		netDriver = network.NewNetlinkDriver(log)
+		conntrackDriver = conntrack.NewNetlinkDriver(log)
 	}
 
 	ruleRepo := repository.NewRuleRepository(db)
@@ -60,14 +69,97 @@ func main() {
 	ifaceRepo := repository.NewInterfaceRepository(db)
 	zoneRepo := repository.NewZoneRepository(db)
 	natRuleRepo := repository.NewNATRuleRepository(db)
+	scheduledJobRepo := repository.NewScheduledJobRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	panicRepo := repository.NewPanicRepository(db)
+	counterSampleRepo := repository.NewCounterSampleRepository(db)
+	logEntryRepo := repository.NewLogEntryRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
 
 	driver := firewall.NewIptablesDriver(log)
 
-	fwService := service.NewFirewallServiceWithConfig(ruleRepo, historyRepo, configRepo, natRuleRepo, driver, log)
-	configService := service.NewConfigService(configRepo, driver, log)
-	interfaceService := service.NewInterfaceService(ifaceRepo, netDriver, log)
-	zoneService := service.NewZoneService(zoneRepo, log)
-	natRuleService := service.NewNATRuleService(natRuleRepo, log)
+	auditService := service.NewAuditService(auditRepo, log)
+	// Audited changes to the ruleset and its resources are also published
+	// to the event stream.
+	eventBus := service.NewEventBus(log)
+	auditor := service.NewPublishingAuditor(auditService, eventBus)
+	lockoutGuard, err := service.NewLockoutGuard(netDriver, cfg.MgmtAllowlist, cfg.MgmtPorts, auditor, log)
+	if err != nil {
+		log.WithError(err).Fatal("invalid management allowlist")
+	}
+	counterCollector := service.NewCounterCollector(driver, cfg.CounterCacheTTL)
+	counterTracker := service.NewCounterTracker(counterCollector, log)
+	conntrackService := service.NewConntrackService(conntrackDriver, cfg.ConntrackFlushOnApply, auditor, log)
+	fwService := service.NewFirewallServiceWithConfig(ruleRepo, ifaceRepo, historyRepo, configRepo, natRuleRepo, panicRepo, driver, lockoutGuard, counterCollector, counterTracker, conntrackService, auditor, log)
+	configService := service.NewConfigService(configRepo, driver, auditor, log)
+	interfaceService := service.NewInterfaceService(ifaceRepo, netDriver, lockoutGuard, auditor, log)
+	zoneService := service.NewZoneService(zoneRepo, auditor, log)
+	natRuleService := service.NewNATRuleService(natRuleRepo, ifaceRepo, counterTracker, auditor, log)
+	counterHistoryService := service.NewCounterHistoryService(counterSampleRepo, ruleRepo, natRuleRepo, counterCollector, eventBus, cfg.CounterSampleInterval, cfg.CounterRetention, log)
+	trafficService, err := service.NewTrafficService(conntrackDriver, counterSampleRepo, counterHistoryService, cfg.TrafficLocalNets, cfg.TrafficSampleInterval, log)
+	if err != nil {
+		log.WithError(err).Fatal("invalid TRAFFIC_LOCAL_NETS")
+	}
+	alertService := service.NewAlertService(alertRepo, counterSampleRepo, ruleRepo, natRuleRepo, ifaceRepo, eventBus, auditor, cfg.AlertEvalInterval, log)
+	notificationService := service.NewNotificationService(notificationRepo, eventBus, auditor, log)
+	metricsService := service.NewMetricsService(fwService, ruleRepo, natRuleRepo, historyRepo, counterCollector, log)
+	var logSources []fwlog.Source
+	for _, spec := range cfg.LogSources {
+		src, err := fwlog.ParseSource(spec)
+		if err != nil {
+			log.WithError(err).Fatal("invalid LOG_SOURCES")
+		}
+		logSources = append(logSources, src)
+	}
+	firewallLogService := service.NewFirewallLogService(logEntryRepo, ruleRepo, logSources, cfg.LogMaxEntries, cfg.LogRetention, log)
+	applyJobService := service.NewApplyJobService(fwService, eventBus, log)
+	authConfig := service.AuthConfig{APIKey: cfg.APIKey, SessionTTL: cfg.SessionTTL}
+	if cfg.APIKey != "" {
+		log.Warn("legacy API_KEY is enabled and grants global admin; prefer user accounts and scoped API tokens")
+	}
+	if cfg.OIDCIssuer != "" {
+		provider, err := oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Audience:     cfg.OIDCAudience,
+			JWKSFile:     cfg.OIDCJWKSFile,
+			Scopes:       cfg.OIDCScopes,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("invalid oidc configuration")
+		}
+		groupRoles, err := auth.ParseRoleMapping(cfg.OIDCGroupRoles)
+		if err != nil {
+			log.WithError(err).Fatal("invalid OIDC_GROUP_ROLES")
+		}
+		authConfig.OIDC = provider
+		authConfig.UsernameClaim = cfg.OIDCUsernameClaim
+		authConfig.GroupsClaim = cfg.OIDCGroupsClaim
+		authConfig.GroupRoles = groupRoles
+		log.WithField("issuer", cfg.OIDCIssuer).Info("oidc authentication enabled")
+	}
+	certRoles, err := auth.ParseRoleMapping(cfg.TLSClientRoles)
+	if err != nil {
+		log.WithError(err).Fatal("invalid TLS_CLIENT_ROLES")
+	}
+	authConfig.CertRoles = certRoles
+
+	authService := service.NewAuthService(userRepo, sessionRepo, apiTokenRepo, authConfig, log)
+	scheduledJobService := service.NewScheduledJobService(scheduledJobRepo, historyRepo, applyJobService, authService, auditor, log)
+	userService := service.NewUserService(userRepo, auditService, log)
+	apiTokenService := service.NewAPITokenService(apiTokenRepo, auditService, log)
+
+	if cfg.AdminPassword != "" {
+		if err := userService.EnsureAdmin(context.Background(), cfg.AdminUsername, cfg.AdminPassword); err != nil {
+			log.WithError(err).Fatal("failed to bootstrap admin user")
+		}
+	}
 
 	ruleHandler := handlers.NewRuleHandler(fwService, log)
 	firewallHandler := handlers.NewFirewallHandler(fwService, log)
@@ -75,8 +167,45 @@ func main() {
 	interfaceHandler := handlers.NewInterfaceHandler(interfaceService, log)
 	zoneHandler := handlers.NewZoneHandler(zoneService, log)
 	natRuleHandler := handlers.NewNATRuleHandler(natRuleService, log)
+	scheduledJobHandler := handlers.NewScheduledJobHandler(scheduledJobService, log)
+	applyJobHandler := handlers.NewApplyJobHandler(applyJobService, log)
+	authHandler := handlers.NewAuthHandler(authService, cfg.OIDCPostLoginURL, log)
+	userHandler := handlers.NewUserHandler(userService, log)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService, log)
+	auditHandler := handlers.NewAuditHandler(auditService, log)
+	counterHistoryHandler := handlers.NewCounterHistoryHandler(counterHistoryService, log)
+	metricsHandler := handlers.NewMetricsHandler(metricsService, log)
+	eventHandler := handlers.NewEventHandler(eventBus, log)
+	firewallLogHandler := handlers.NewFirewallLogHandler(firewallLogService, log)
+	conntrackHandler := handlers.NewConntrackHandler(conntrackService, log)
+	trafficHandler := handlers.NewTrafficHandler(trafficService, log)
+	alertHandler := handlers.NewAlertHandler(alertService, log)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, log)
+
+	if err := fwService.RestorePanic(context.Background()); err != nil {
+		log.WithError(err).Error("failed to re-apply panic lockdown")
+	}
+
+	// Background workers run until shutdown.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go service.NewRuleScheduler(fwService, cfg.ScheduleInterval, log).Run(workerCtx)
+	go scheduledJobService.Run(workerCtx)
+	go applyJobService.Run(workerCtx)
+	go counterHistoryService.Run(workerCtx)
+	go trafficService.Run(workerCtx)
+	go alertService.Run(workerCtx)
+	go notificationService.Run(workerCtx)
+	go firewallLogService.Run(workerCtx)
+	go service.NewDriftMonitor(fwService, eventBus, cfg.DriftCheckInterval, log).Run(workerCtx)
 
 	router := gin.New()
+	// Client addresses end up in the audit log, so X-Forwarded-For is only
+	// honoured from configured proxies.
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.WithError(err).Fatal("invalid TRUSTED_PROXIES")
+	}
 	router.Use(gin.Recovery())
 	router.Use(middleware.RequestLogger(log))
 	router.Use(cors.New(cors.Config{
@@ -89,59 +218,187 @@ func main() {
 	}))
 
 	// Public health endpoint (no auth) so UI and load-checkers can probe status.
-	router.GET("/api/health", handlers.HealthHandler)
+	router.GET("/api/health", firewallHandler.Health)
+	router.POST("/api/auth/login", authHandler.Login)
+	router.GET("/api/auth/oidc/login", authHandler.OIDCLogin)
+	router.GET("/api/auth/oidc/callback", authHandler.OIDCCallback)
+
+	// Prometheus scrapes with its own token or address allowlist, not an
+	// API credential. Without either the endpoint is not served.
+	if cfg.MetricsToken != "" || len(cfg.MetricsAllowlist) > 0 {
+		metricsAccess, err := middleware.MetricsAccess(cfg.MetricsToken, cfg.MetricsAllowlist)
+		if err != nil {
+			log.WithError(err).Fatal("invalid METRICS_ALLOWLIST")
+		}
+		router.GET("/metrics", metricsAccess, metricsHandler.Metrics)
+	} else {
+		log.Info("metrics endpoint disabled; set METRICS_TOKEN or METRICS_ALLOWLIST to enable it")
+	}
+
+	read := middleware.Require(auth.PermRead)
+	edit := middleware.Require(auth.PermEdit)
+	apply := middleware.Require(auth.PermApply)
+	admin := middleware.Require(auth.PermAdmin)
+	scope := middleware.Scope
 
 	api := router.Group("/api")
-	api.Use(middleware.Auth(cfg.APIKey))
+	api.Use(middleware.Auth(authService))
 	{
-		rules := api.Group("/rules")
+		authRoutes := api.Group("/auth")
+		{
+			authRoutes.GET("/me", authHandler.Me)
+			authRoutes.POST("/logout", authHandler.Logout)
+		}
+
+		users := api.Group("/users", admin, scope("users"))
+		{
+			users.GET("", userHandler.List)
+			users.POST("", userHandler.Create)
+			users.PUT("/:id", userHandler.Update)
+			users.DELETE("/:id", userHandler.Delete)
+		}
+
+		// Any user may manage their own tokens; admins see all of them.
+		tokens := api.Group("/tokens", scope("tokens"))
+		{
+			tokens.GET("", apiTokenHandler.List)
+			tokens.POST("", apiTokenHandler.Create)
+			tokens.DELETE("/:id", apiTokenHandler.Revoke)
+		}
+
+		rules := api.Group("/rules", scope("rules"))
+		{
+			rules.GET("", read, ruleHandler.List)
+			rules.POST("", edit, ruleHandler.Create)
+			rules.PUT("/:id", edit, ruleHandler.Update)
+			rules.DELETE("/:id", edit, ruleHandler.Delete)
+			rules.GET("/:id/counters", read, ruleHandler.Counters)
+		}
+
+		// Config changes are pushed to the kernel immediately.
+		config := api.Group("/config", scope("config"))
+		{
+			config.GET("", read, configHandler.GetConfig)
+			config.POST("", apply, configHandler.UpdateConfig)
+		}
+
+		interfaces := api.Group("/interfaces", scope("interfaces"))
+		{
+			interfaces.GET("", read, interfaceHandler.List)
+			interfaces.POST("", edit, interfaceHandler.Create)
+			interfaces.PUT("/:id", edit, interfaceHandler.Update)
+			interfaces.DELETE("/:id", edit, interfaceHandler.Delete)
+			interfaces.POST("/:id/addresses", edit, interfaceHandler.AddAddress)
+			interfaces.DELETE("/:id/addresses", edit, interfaceHandler.RemoveAddress)
+		}
+
+		zones := api.Group("/zones", scope("zones"))
 		{
-			rules.GET("", ruleHandler.List)
-			rules.POST("", ruleHandler.Create)
-			rules.PUT("/:id", ruleHandler.Update)
-			rules.DELETE("/:id", ruleHandler.Delete)
+			zones.GET("", read, zoneHandler.List)
+			zones.POST("", edit, zoneHandler.Create)
+			zones.PUT("/:id", edit, zoneHandler.Update)
+			zones.DELETE("/:id", edit, zoneHandler.Delete)
 		}
 
-		config := api.Group("/config")
+		natRules := api.Group("/nat-rules", scope("nat"))
 		{
-			config.GET("", configHandler.GetConfig)
-			config.POST("", configHandler.UpdateConfig)
+			natRules.GET("", read, natRuleHandler.List)
+			natRules.POST("", edit, natRuleHandler.Create)
+			natRules.PUT("/:id", edit, natRuleHandler.Update)
+			natRules.DELETE("/:id", edit, natRuleHandler.Delete)
+			natRules.GET("/:id/counters", read, natRuleHandler.Counters)
 		}
 
-		interfaces := api.Group("/interfaces")
+		api.POST("/apply", apply, scope("apply"), applyJobHandler.Apply)
+		api.POST("/rollback", apply, scope("apply"), applyJobHandler.Rollback)
+
+		jobs := api.Group("/jobs", read, scope("apply"))
 		{
-			interfaces.GET("", interfaceHandler.List)
-			interfaces.POST("", interfaceHandler.Create)
-			interfaces.PUT("/:id", interfaceHandler.Update)
-			interfaces.DELETE("/:id", interfaceHandler.Delete)
+			jobs.GET("", applyJobHandler.List)
+			jobs.GET("/:id", applyJobHandler.Get)
+			jobs.GET("/:id/stream", applyJobHandler.Stream)
 		}
+		api.GET("/history", read, scope("history"), firewallHandler.History)
 
-		zones := api.Group("/zones")
+		panicMode := api.Group("/panic", scope("apply"))
 		{
-			zones.GET("", zoneHandler.List)
-			zones.POST("", zoneHandler.Create)
-			zones.PUT("/:id", zoneHandler.Update)
-			zones.DELETE("/:id", zoneHandler.Delete)
+			panicMode.GET("", read, firewallHandler.PanicState)
+			panicMode.POST("", apply, firewallHandler.Panic)
+			panicMode.POST("/release", apply, firewallHandler.ReleasePanic)
 		}
 
-		natRules := api.Group("/nat-rules")
+		audit := api.Group("/audit", admin, scope("audit"))
 		{
-			natRules.GET("", natRuleHandler.List)
-			natRules.POST("", natRuleHandler.Create)
-			natRules.PUT("/:id", natRuleHandler.Update)
-			natRules.DELETE("/:id", natRuleHandler.Delete)
+			audit.GET("", auditHandler.List)
+			audit.GET("/verify", auditHandler.Verify)
 		}
 
-		api.POST("/apply", firewallHandler.Apply)
-		api.POST("/rollback", firewallHandler.Rollback)
+		scheduledJobs := api.Group("/scheduled-jobs", scope("jobs"))
+		{
+			scheduledJobs.GET("", read, scheduledJobHandler.List)
+			scheduledJobs.POST("", apply, scheduledJobHandler.Create)
+			scheduledJobs.GET("/:id", read, scheduledJobHandler.Get)
+			scheduledJobs.DELETE("/:id", apply, scheduledJobHandler.Cancel)
+		}
 
-		counters := api.Group("/counters")
+		counters := api.Group("/counters", read, scope("counters"))
 		{
 			counters.GET("", firewallHandler.Counters)
 			counters.GET("/interfaces", firewallHandler.GetInterfaces)
 			counters.GET("/aggregate", firewallHandler.GetAggregatedCounters)
 			counters.GET("/interfaces/:interface", firewallHandler.GetInterfaceCounters)
+			counters.GET("/series", counterHistoryHandler.Series)
+			counters.GET("/history", counterHistoryHandler.Query)
+		}
+
+		logs := api.Group("/logs", read, scope("logs"))
+		{
+			logs.GET("", firewallLogHandler.List)
+			logs.GET("/tail", firewallLogHandler.Tail)
+		}
+
+		// Deleting entries cuts live connections, like an apply.
+		conntrackRoutes := api.Group("/conntrack", scope("conntrack"))
+		{
+			conntrackRoutes.GET("", read, conntrackHandler.List)
+			conntrackRoutes.DELETE("", apply, conntrackHandler.Delete)
+			conntrackRoutes.DELETE("/:id", apply, conntrackHandler.DeleteOne)
+		}
+
+		traffic := api.Group("/traffic", read, scope("traffic"))
+		{
+			traffic.GET("/top", trafficHandler.Top)
+			traffic.GET("/hosts/:address", trafficHandler.HostHistory)
+			traffic.GET("/peers/:address", trafficHandler.PeerHistory)
 		}
+
+		alerts := api.Group("/alerts", scope("alerts"))
+		{
+			alerts.GET("", read, alertHandler.List)
+			alerts.GET("/rules", read, alertHandler.ListRules)
+			alerts.POST("/rules", edit, alertHandler.CreateRule)
+			alerts.GET("/rules/:id", read, alertHandler.GetRule)
+			alerts.PUT("/rules/:id", edit, alertHandler.UpdateRule)
+			alerts.DELETE("/rules/:id", edit, alertHandler.DeleteRule)
+			alerts.GET("/silences", read, alertHandler.ListSilences)
+			alerts.POST("/silences", edit, alertHandler.CreateSilence)
+			alerts.DELETE("/silences/:id", edit, alertHandler.DeleteSilence)
+		}
+
+		// Sinks hold credentials and decide where event data goes.
+		notifications := api.Group("/notifications", scope("notifications"))
+		{
+			notifications.GET("/sinks", admin, notificationHandler.ListSinks)
+			notifications.POST("/sinks", admin, notificationHandler.CreateSink)
+			notifications.GET("/sinks/:id", admin, notificationHandler.GetSink)
+			notifications.PUT("/sinks/:id", admin, notificationHandler.UpdateSink)
+			notifications.DELETE("/sinks/:id", admin, notificationHandler.DeleteSink)
+			notifications.POST("/sinks/:id/test", admin, notificationHandler.TestSink)
+			notifications.GET("/deliveries", read, notificationHandler.ListDeliveries)
+		}
+
+		// Each subscriber only receives the events its token scopes cover.
+		api.GET("/events", read, eventHandler.Stream)
 	}
 
 	// If a built frontend exists, serve it from / so visiting the server shows the UI.
@@ -168,18 +425,54 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	var certs *tlsutil.Reloader
+	if cfg.TLSCertFile != "" {
+		clientAuth, err := tlsutil.ParseClientAuth(cfg.TLSClientAuth)
+		if err != nil {
+			log.WithError(err).Fatal("invalid TLS_CLIENT_AUTH")
+		}
+		certs, err = tlsutil.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, clientAuth, log)
+		if err != nil {
+			log.WithError(err).Fatal("failed to load tls certificate")
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		go certs.Watch(workerCtx, cfg.TLSReloadInterval)
+	}
+
 	go func() {
-		log.WithField("port", cfg.Port).Info("server starting")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.WithFields(logrus.Fields{"port": cfg.Port, "tls": certs != nil}).Info("server starting")
+		var err error
+		if certs != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.WithError(err).Fatal("server failed")
 		}
 	}()
 
+	// SIGHUP reloads the TLS certificate, e.g. after renewal.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if certs == nil {
+				continue
+			}
+			if err := certs.Reload(); err != nil {
+				log.WithError(err).Error("tls reload failed, keeping previous certificate")
+			}
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info("shutting down server...")
+	stopWorkers()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
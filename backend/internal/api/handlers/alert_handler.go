@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertHandler handles alert rules, silences and the alert history
+type AlertHandler struct {
+	svc service.AlertService
+	log *logrus.Logger
+}
+
+func NewAlertHandler(svc service.AlertService, log *logrus.Logger) *AlertHandler {
+	return &AlertHandler{svc: svc, log: log}
+}
+
+// alertStatus maps permission errors to 403, validation errors to 400 and
+// unknown rules or silences to 404.
+func alertStatus(err error) int {
+	if errors.Is(err, auth.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, service.ErrInvalidAlertRule) {
+		return http.StatusBadRequest
+	}
+	if strings.Contains(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *AlertHandler) ListRules(c *gin.Context) {
+	rules, err := h.svc.ListRules(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list alert rules failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *AlertHandler) GetRule(c *gin.Context) {
+	rule, err := h.svc.GetRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(alertStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+func (h *AlertHandler) CreateRule(c *gin.Context) {
+	var dto service.AlertRuleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.svc.CreateRule(c.Request.Context(), dto)
+	if err != nil {
+		h.log.WithError(err).Error("create alert rule failed")
+		c.JSON(alertStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+func (h *AlertHandler) UpdateRule(c *gin.Context) {
+	var dto service.AlertRuleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.svc.UpdateRule(c.Request.Context(), c.Param("id"), dto)
+	if err != nil {
+		h.log.WithError(err).Error("update alert rule failed")
+		c.JSON(alertStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+func (h *AlertHandler) DeleteRule(c *gin.Context) {
+	if err := h.svc.DeleteRule(c.Request.Context(), c.Param("id")); err != nil {
+		h.log.WithError(err).Error("delete alert rule failed")
+		c.JSON(alertStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// List returns the alert history, most recent first. Filters: ruleId,
+// state (pending, firing, resolved), from and to (RFC 3339, on the start
+// time) and limit (default 100, at most 1000).
+func (h *AlertHandler) List(c *gin.Context) {
+	filter := models.AlertFilter{
+		RuleID: c.Query("ruleId"),
+		State:  models.AlertState(c.Query("state")),
+	}
+	switch filter.State {
+	case "", models.AlertPending, models.AlertFiring, models.AlertResolved:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state"})
+		return
+	}
+	var err error
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	alerts, err := h.svc.ListAlerts(c.Request.Context(), filter)
+	if err != nil {
+		h.log.WithError(err).Error("list alerts failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+func (h *AlertHandler) ListSilences(c *gin.Context) {
+	silences, err := h.svc.ListSilences(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list alert silences failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"silences": silences})
+}
+
+func (h *AlertHandler) CreateSilence(c *gin.Context) {
+	var dto service.CreateAlertSilenceDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	silence, err := h.svc.CreateSilence(c.Request.Context(), dto)
+	if err != nil {
+		h.log.WithError(err).Error("create alert silence failed")
+		c.JSON(alertStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"silence": silence})
+}
+
+func (h *AlertHandler) DeleteSilence(c *gin.Context) {
+	if err := h.svc.DeleteSilence(c.Request.Context(), c.Param("id")); err != nil {
+		h.log.WithError(err).Error("delete alert silence failed")
+		c.JSON(alertStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ApplyJobHandler queues applies and rollbacks and reports their progress
+type ApplyJobHandler struct {
+	svc service.ApplyJobService
+	log *logrus.Logger
+}
+
+func NewApplyJobHandler(svc service.ApplyJobService, log *logrus.Logger) *ApplyJobHandler {
+	return &ApplyJobHandler{svc: svc, log: log}
+}
+
+// Apply queues an apply and answers 202 with the job to follow.
+// ?flushConntrack=true or false overrides whether the connections the new
+// ruleset denies are deleted from the conntrack table.
+func (h *ApplyJobHandler) Apply(c *gin.Context) {
+	ctx := clientConnContext(c)
+	if v := c.Query("flushConntrack"); v != "" {
+		ctx = service.WithConntrackFlush(ctx, v == "true")
+	}
+	job, err := h.svc.SubmitApply(ctx)
+	if err != nil {
+		h.log.WithError(err).Error("queue apply failed")
+		c.JSON(jobStatusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Location", "/api/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+// rollbackRequest optionally selects the snapshot to restore.
+type rollbackRequest struct {
+	HistoryID string `json:"historyId"`
+}
+
+// Rollback queues a rollback and answers 202 with the job to follow.
+func (h *ApplyJobHandler) Rollback(c *gin.Context) {
+	var req rollbackRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	job, err := h.svc.SubmitRollback(clientConnContext(c), req.HistoryID)
+	if err != nil {
+		h.log.WithError(err).Error("queue rollback failed")
+		c.JSON(jobStatusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Location", "/api/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (h *ApplyJobHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": h.svc.ListJobs(c.Request.Context())})
+}
+
+func (h *ApplyJobHandler) Get(c *gin.Context) {
+	job, err := h.svc.GetJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(jobStatusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// Stream sends the job as server-sent "job" events whenever it changes and
+// ends once the job has finished.
+func (h *ApplyJobHandler) Stream(c *gin.Context) {
+	updates, err := h.svc.Watch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(jobStatusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// A job may run longer than the server's WriteTimeout.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.WithError(err).Warn("could not lift write deadline for job stream")
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		job, ok := <-updates
+		if !ok {
+			return false
+		}
+		c.SSEvent("job", job)
+		return !job.Finished()
+	})
+}
+
+func jobStatusFor(err error) int {
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrJobQueueFull):
+		return http.StatusServiceUnavailable
+	}
+	return statusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditHandler struct {
+	svc service.AuditService
+	log *logrus.Logger
+}
+
+func NewAuditHandler(svc service.AuditService, log *logrus.Logger) *AuditHandler {
+	return &AuditHandler{svc: svc, log: log}
+}
+
+// List returns audit entries, newest first. Filters: actor, action,
+// resource, resourceId, from and to (RFC 3339), limit, and before (a
+// sequence number, for paging).
+func (h *AuditHandler) List(c *gin.Context) {
+	filter := models.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		Resource:   c.Query("resource"),
+		ResourceID: c.Query("resourceId"),
+	}
+	var err error
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	if v := c.Query("before"); v != "" {
+		if filter.BeforeSeq, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before"})
+			return
+		}
+	}
+
+	entries, err := h.svc.Query(c.Request.Context(), filter)
+	if err != nil {
+		h.log.WithError(err).Error("query audit log failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (h *AuditHandler) Verify(c *gin.Context) {
+	result, err := h.svc.Verify(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("verify audit log failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !result.Valid {
+		h.log.WithFields(logrus.Fields{
+			"broken_at": result.BrokenAt,
+			"problem":   result.Problem,
+		}).Error("audit log hash chain is broken")
+	}
+	c.JSON(http.StatusOK, gin.H{"verification": result})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// statusFor maps permission errors to 403, refused lockouts and panic mode
+// conflicts to 409 and everything else to fallback.
+func statusFor(err error, fallback int) int {
+	if errors.Is(err, auth.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, service.ErrLockout) || errors.Is(err, service.ErrPanicActive) || errors.Is(err, service.ErrPanicInactive) {
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+// AuthHandler handles login, logout and identity lookups
+type AuthHandler struct {
+	svc          service.AuthService
+	postLoginURL string
+	log          *logrus.Logger
+}
+
+func NewAuthHandler(svc service.AuthService, postLoginURL string, log *logrus.Logger) *AuthHandler {
+	return &AuthHandler{svc: svc, postLoginURL: postLoginURL, log: log}
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var dto service.LoginDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.svc.Login(c.Request.Context(), dto)
+	if err != nil {
+		h.log.WithField("username", dto.Username).Warn("login failed")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// OIDCLogin redirects the browser to the identity provider.
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	target, err := h.svc.BeginOIDCLogin(c.Request.Context())
+	if errors.Is(err, service.ErrOIDCDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("oidc login failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// OIDCCallback completes the login and hands the session token to the UI in
+// the URL fragment, which browsers do not send to servers.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if msg := c.Query("error"); msg != "" {
+		h.log.WithField("error", msg).Warn("oidc provider returned an error")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": msg + ": " + c.Query("error_description")})
+		return
+	}
+
+	result, err := h.svc.CompleteOIDCLogin(c.Request.Context(), c.Query("state"), c.Query("code"))
+	if err != nil {
+		h.log.WithError(err).Warn("oidc login failed")
+		c.JSON(statusFor(err, http.StatusUnauthorized), gin.H{"error": err.Error()})
+		return
+	}
+
+	fragment := url.Values{
+		"session":   {result.Token},
+		"expiresAt": {result.ExpiresAt.Format(time.RFC3339)},
+	}
+	c.Redirect(http.StatusFound, h.postLoginURL+"#"+fragment.Encode())
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer"))
+	if err := h.svc.Logout(c.Request.Context(), token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "not a session token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "logged out"})
+}
+
+func (h *AuthHandler) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"principal": auth.FromContext(c.Request.Context())})
+}
+
+// UserHandler handles user account management
+type UserHandler struct {
+	svc service.UserService
+	log *logrus.Logger
+}
+
+func NewUserHandler(svc service.UserService, log *logrus.Logger) *UserHandler {
+	return &UserHandler{svc: svc, log: log}
+}
+
+func (h *UserHandler) List(c *gin.Context) {
+	users, err := h.svc.ListUsers(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list users failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func (h *UserHandler) Create(c *gin.Context) {
+	var dto service.CreateUserDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.svc.CreateUser(c.Request.Context(), dto)
+	if err != nil {
+		h.log.WithError(err).Error("create user failed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"user": user})
+}
+
+func (h *UserHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	var dto service.UpdateUserDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.svc.UpdateUser(c.Request.Context(), id, dto)
+	if err != nil {
+		h.log.WithError(err).WithField("id", id).Error("update user failed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (h *UserHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.DeleteUser(c.Request.Context(), id); err != nil {
+		h.log.WithError(err).WithField("id", id).Error("delete user failed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// APITokenHandler handles API token management
+type APITokenHandler struct {
+	svc service.APITokenService
+	log *logrus.Logger
+}
+
+func NewAPITokenHandler(svc service.APITokenService, log *logrus.Logger) *APITokenHandler {
+	return &APITokenHandler{svc: svc, log: log}
+}
+
+func (h *APITokenHandler) List(c *gin.Context) {
+	tokens, err := h.svc.ListTokens(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list api tokens failed")
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *APITokenHandler) Create(c *gin.Context) {
+	var dto service.CreateAPITokenDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.svc.CreateToken(c.Request.Context(), dto)
+	if err != nil {
+		h.log.WithError(err).Error("create api token failed")
+		c.JSON(statusFor(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *APITokenHandler) Revoke(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.RevokeToken(c.Request.Context(), id); err != nil {
+		h.log.WithError(err).WithField("id", id).Error("revoke api token failed")
+		c.JSON(statusFor(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/firewall-manager/backend/internal/network"
 	"github.com/firewall-manager/backend/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -73,7 +76,7 @@ func (h *InterfaceHandler) Create(c *gin.Context) {
 	iface, err := h.svc.CreateInterface(c.Request.Context(), dto)
 	if err != nil {
 		h.log.WithError(err).Error("create interface failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"interface": iface})
@@ -87,10 +90,10 @@ func (h *InterfaceHandler) Update(c *gin.Context) {
 		return
 	}
 
-	iface, err := h.svc.UpdateInterface(c.Request.Context(), id, dto)
+	iface, err := h.svc.UpdateInterface(clientConnContext(c), id, dto)
 	if err != nil {
 		h.log.WithError(err).Error("update interface failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"interface": iface})
@@ -100,12 +103,60 @@ func (h *InterfaceHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.svc.DeleteInterface(c.Request.Context(), id); err != nil {
 		h.log.WithError(err).Error("delete interface failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 }
 
+// interfaceAddressStatus maps invalid addresses to 400, unknown interfaces
+// and addresses to 404 and duplicate addresses to 409.
+func interfaceAddressStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidInterfaceAddress):
+		return http.StatusBadRequest
+	case errors.Is(err, network.ErrAddrExists):
+		return http.StatusConflict
+	case errors.Is(err, network.ErrAddrNotFound), strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return statusFor(err, http.StatusInternalServerError)
+}
+
+// AddAddress adds one IPv4 or IPv6 address, leaving the others in place.
+func (h *InterfaceHandler) AddAddress(c *gin.Context) {
+	var dto service.InterfaceAddressDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addresses, err := h.svc.AddAddress(c.Request.Context(), c.Param("id"), dto)
+	if err != nil {
+		h.log.WithError(err).Error("add interface address failed")
+		c.JSON(interfaceAddressStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"addresses": addresses})
+}
+
+// RemoveAddress removes the address given as ?address=ip[/prefix].
+func (h *InterfaceHandler) RemoveAddress(c *gin.Context) {
+	address := c.Query("address")
+	if address == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+		return
+	}
+
+	addresses, err := h.svc.RemoveAddress(clientConnContext(c), c.Param("id"), address)
+	if err != nil {
+		h.log.WithError(err).Error("remove interface address failed")
+		c.JSON(interfaceAddressStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
 // ZoneHandler handles zone operations
 type ZoneHandler struct {
 	svc service.ZoneService
@@ -136,7 +187,7 @@ func (h *ZoneHandler) Create(c *gin.Context) {
 	zone, err := h.svc.CreateZone(c.Request.Context(), dto)
 	if err != nil {
 		h.log.WithError(err).Error("create zone failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"zone": zone})
@@ -153,7 +204,7 @@ func (h *ZoneHandler) Update(c *gin.Context) {
 	zone, err := h.svc.UpdateZone(c.Request.Context(), id, dto)
 	if err != nil {
 		h.log.WithError(err).Error("update zone failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"zone": zone})
@@ -163,7 +214,7 @@ func (h *ZoneHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.svc.DeleteZone(c.Request.Context(), id); err != nil {
 		h.log.WithError(err).Error("delete zone failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
@@ -189,6 +240,17 @@ func (h *NATRuleHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"natRules": rules})
 }
 
+// Counters returns the live packet and byte counters of one NAT rule.
+func (h *NATRuleHandler) Counters(c *gin.Context) {
+	counters, err := h.svc.GetNATRuleCounters(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.log.WithError(err).WithField("id", c.Param("id")).Error("get nat rule counters failed")
+		c.JSON(counterStatusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"counters": counters})
+}
+
 func (h *NATRuleHandler) Create(c *gin.Context) {
 	var dto service.CreateNATRuleDTO
 	if err := c.BindJSON(&dto); err != nil {
@@ -199,7 +261,7 @@ func (h *NATRuleHandler) Create(c *gin.Context) {
 	rule, err := h.svc.CreateNATRule(c.Request.Context(), dto)
 	if err != nil {
 		h.log.WithError(err).Error("create nat rule failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"natRule": rule})
@@ -216,7 +278,7 @@ func (h *NATRuleHandler) Update(c *gin.Context) {
 	rule, err := h.svc.UpdateNATRule(c.Request.Context(), id, dto)
 	if err != nil {
 		h.log.WithError(err).Error("update nat rule failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"natRule": rule})
@@ -226,7 +288,7 @@ func (h *NATRuleHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.svc.DeleteNATRule(c.Request.Context(), id); err != nil {
 		h.log.WithError(err).Error("delete nat rule failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ConntrackHandler lists and deletes tracked connections
+type ConntrackHandler struct {
+	svc service.ConntrackService
+	log *logrus.Logger
+}
+
+func NewConntrackHandler(svc service.ConntrackService, log *logrus.Logger) *ConntrackHandler {
+	return &ConntrackHandler{svc: svc, log: log}
+}
+
+// List returns tracked connections. Filters: family (ipv4, ipv6), proto,
+// state (TCP state or flag such as UNREPLIED), addr (any address of either
+// direction), src and dst (original direction; address or CIDR), port (any
+// port), and limit.
+func (h *ConntrackHandler) List(c *gin.Context) {
+	filter, err := connectionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	conns, total, err := h.svc.List(c.Request.Context(), filter, limit)
+	if err != nil {
+		h.log.WithError(err).Error("list connections failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"connections": conns, "total": total})
+}
+
+// Delete removes the connections matching the filters of List. Without
+// filters it refuses unless ?all=true.
+func (h *ConntrackHandler) Delete(c *gin.Context) {
+	filter, err := connectionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Empty() && c.Query("all") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no filter given; add all=true to delete every connection"})
+		return
+	}
+	h.delete(c, filter)
+}
+
+// DeleteOne removes the connection with the given ID.
+func (h *ConntrackHandler) DeleteOne(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid connection id"})
+		return
+	}
+	h.delete(c, models.ConnectionFilter{ID: uint32(id)})
+}
+
+func (h *ConntrackHandler) delete(c *gin.Context, filter models.ConnectionFilter) {
+	n, err := h.svc.Delete(c.Request.Context(), filter)
+	if err != nil {
+		h.log.WithError(err).Error("delete connections failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "deleted": n})
+		return
+	}
+	if n == 0 && filter.ID != 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "connection not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": n})
+}
+
+func connectionFilterFromQuery(c *gin.Context) (models.ConnectionFilter, error) {
+	filter := models.ConnectionFilter{
+		Family:   c.Query("family"),
+		Protocol: c.Query("proto"),
+		State:    c.Query("state"),
+		Addr:     c.Query("addr"),
+		Src:      c.Query("src"),
+		Dst:      c.Query("dst"),
+	}
+	if filter.Family != "" && filter.Family != "ipv4" && filter.Family != "ipv6" {
+		return filter, fmt.Errorf("invalid family: %s", filter.Family)
+	}
+	for name, v := range map[string]string{"addr": filter.Addr, "src": filter.Src, "dst": filter.Dst} {
+		if v == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(v); err != nil && net.ParseIP(v) == nil {
+			return filter, fmt.Errorf("invalid %s: %s", name, v)
+		}
+	}
+	if v := c.Query("port"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port < 1 || port > 65535 {
+			return filter, fmt.Errorf("invalid port: %s", v)
+		}
+		filter.Port = port
+	}
+	return filter, nil
+}
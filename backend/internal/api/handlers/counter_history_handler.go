@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type CounterHistoryHandler struct {
+	svc service.CounterHistoryService
+	log *logrus.Logger
+}
+
+func NewCounterHistoryHandler(svc service.CounterHistoryService, log *logrus.Logger) *CounterHistoryHandler {
+	return &CounterHistoryHandler{svc: svc, log: log}
+}
+
+func (h *CounterHistoryHandler) Series(c *gin.Context) {
+	series, err := h.svc.Series(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list counter series failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+// Query returns the traffic and rates of one or more series (repeated
+// series parameter) between from and to (RFC 3339, default the last hour)
+// in steps of step (a duration or seconds).
+func (h *CounterHistoryHandler) Query(c *gin.Context) {
+	now := time.Now().UTC()
+	q := models.CounterQuery{
+		Series: c.QueryArray("series"),
+		From:   now.Add(-time.Hour),
+		To:     now,
+	}
+	var err error
+	if v := c.Query("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("step"); v != "" {
+		if q.Step, err = parseStep(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step"})
+			return
+		}
+	}
+
+	series, err := h.svc.Query(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCounterQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.WithError(err).Error("query counter history failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+// parseStep accepts a Go duration ("5m") or a number of seconds.
+func parseStep(v string) (time.Duration, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, errors.New("invalid step")
+	}
+	return d, nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// eventKeepalive is how often an idle stream sends a comment so proxies
+// do not close it.
+const eventKeepalive = 30 * time.Second
+
+// EventHandler streams the event bus to clients as server-sent events
+type EventHandler struct {
+	bus *service.EventBus
+	log *logrus.Logger
+}
+
+func NewEventHandler(bus *service.EventBus, log *logrus.Logger) *EventHandler {
+	return &EventHandler{bus: bus, log: log}
+}
+
+// Stream sends matching events until the client disconnects. Query
+// parameters: type (repeated or comma-separated; "rule" matches every rule
+// event), resourceId, and after to replay recent events; a reconnecting
+// EventSource sends Last-Event-ID instead.
+func (h *EventHandler) Stream(c *gin.Context) {
+	var filter models.EventFilter
+	for _, v := range c.QueryArray("type") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+	filter.ResourceID = c.Query("resourceId")
+
+	after := c.GetHeader("Last-Event-ID")
+	if after == "" {
+		after = c.Query("after")
+	}
+	var afterID uint64
+	if after != "" {
+		id, err := strconv.ParseUint(after, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id: " + after})
+			return
+		}
+		afterID = id
+	}
+
+	events := h.bus.Subscribe(c.Request.Context(), filter, afterID)
+
+	// The stream stays open far longer than the server's WriteTimeout.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.WithError(err).Warn("could not lift write deadline for event stream")
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				h.log.WithError(err).WithField("event_id", e.ID).Error("encode event failed")
+				return true
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			return err == nil
+		case <-keepalive.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/firewall-manager/backend/internal/service"
 	"github.com/gin-gonic/gin"
@@ -17,22 +20,38 @@ func NewFirewallHandler(svc service.FirewallService, log *logrus.Logger) *Firewa
 	return &FirewallHandler{svc: svc, log: log}
 }
 
-func (h *FirewallHandler) Apply(c *gin.Context) {
-	if err := h.svc.ApplyRules(c.Request.Context()); err != nil {
-		h.log.WithError(err).Error("apply rules failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// clientConnContext returns the request context carrying the caller's TCP
+// connection for the lockout check. ?allowLockout=true overrides the check.
+func clientConnContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	conn := service.ClientConn{Override: c.Query("allowLockout") == "true"}
+	if host, port, err := net.SplitHostPort(c.Request.RemoteAddr); err == nil {
+		conn.ClientIP = host
+		conn.ClientPort, _ = strconv.Atoi(port)
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "applied"})
+	if addr, ok := ctx.Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if host, port, err := net.SplitHostPort(addr.String()); err == nil {
+			conn.LocalIP = host
+			conn.LocalPort, _ = strconv.Atoi(port)
+		}
+	}
+	return service.WithClientConn(ctx, conn)
 }
 
-func (h *FirewallHandler) Rollback(c *gin.Context) {
-	if err := h.svc.Rollback(c.Request.Context()); err != nil {
-		h.log.WithError(err).Error("rollback failed")
+func (h *FirewallHandler) History(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	entries, err := h.svc.ListHistory(c.Request.Context(), limit)
+	if err != nil {
+		h.log.WithError(err).Error("list history failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "rolled back"})
+	c.JSON(http.StatusOK, gin.H{"history": entries})
 }
 
 func (h *FirewallHandler) Counters(c *gin.Context) {
@@ -60,7 +79,7 @@ func (h *FirewallHandler) GetInterfaceCounters(c *gin.Context) {
 	counters, err := h.svc.GetInterfaceCounters(c.Request.Context(), iface)
 	if err != nil {
 		h.log.WithError(err).Error("get interface counters failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(counterStatusFor(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"counters": counters})
@@ -76,9 +95,65 @@ func (h *FirewallHandler) GetAggregatedCounters(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"counters": counters})
 }
 
-func HealthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+// panicRequest optionally records why panic mode was entered.
+type panicRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *FirewallHandler) Panic(c *gin.Context) {
+	var req panicRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	state, err := h.svc.Panic(clientConnContext(c), req.Reason)
+	if err != nil {
+		h.log.WithError(err).Error("panic failed")
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"panic": state})
+}
+
+func (h *FirewallHandler) ReleasePanic(c *gin.Context) {
+	state, err := h.svc.ReleasePanic(clientConnContext(c))
+	if err != nil {
+		h.log.WithError(err).Error("panic release failed")
+		c.JSON(statusFor(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"panic": state})
+}
+
+func (h *FirewallHandler) PanicState(c *gin.Context) {
+	state, err := h.svc.GetPanicState(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"panic": state})
+}
+
+// Health is public, so it only reports whether panic mode is on and since
+// when, not who triggered it or why.
+func (h *FirewallHandler) Health(c *gin.Context) {
+	resp := gin.H{
 		"status":  "ok",
 		"service": "firewall-manager",
-	})
-}
\ No newline at end of file
+	}
+	if state, err := h.svc.GetPanicState(c.Request.Context()); err != nil {
+		h.log.WithError(err).Warn("could not read panic state for health")
+	} else {
+		panicInfo := gin.H{"active": state.Active}
+		if state.Active {
+			panicInfo["activatedAt"] = state.ActivatedAt
+		}
+		resp["panic"] = panicInfo
+	}
+	// Lets fleet dashboards tell which policy version each host runs.
+	resp["payloadHash"] = h.svc.LoadedPayloadHash(c.Request.Context())
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// FirewallLogHandler searches and tails the packets logged by rules
+type FirewallLogHandler struct {
+	svc service.FirewallLogService
+	log *logrus.Logger
+}
+
+func NewFirewallLogHandler(svc service.FirewallLogService, log *logrus.Logger) *FirewallLogHandler {
+	return &FirewallLogHandler{svc: svc, log: log}
+}
+
+// List returns log entries, newest first. Filters: ruleId, prefix, in, out,
+// src, dst, proto, port (source or destination), q (text in the message),
+// from and to (RFC 3339), limit, and before (an entry ID, for paging).
+func (h *FirewallLogHandler) List(c *gin.Context) {
+	filter, err := logFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	if v := c.Query("before"); v != "" {
+		if filter.Before, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before"})
+			return
+		}
+	}
+
+	entries, err := h.svc.Query(c.Request.Context(), filter)
+	if err != nil {
+		h.log.WithError(err).Error("query firewall log failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// Tail sends matching entries as server-sent "log" events as they are
+// stored, until the client disconnects. It takes the filters of List.
+func (h *FirewallLogHandler) Tail(c *gin.Context) {
+	filter, err := logFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries := h.svc.Tail(c.Request.Context(), filter)
+
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.WithError(err).Warn("could not lift write deadline for log tail")
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				return false
+			}
+			c.SSEvent("log", e)
+			return true
+		case <-keepalive.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
+	})
+}
+
+func logFilterFromQuery(c *gin.Context) (models.LogFilter, error) {
+	filter := models.LogFilter{
+		RuleID: c.Query("ruleId"),
+		Prefix: c.Query("prefix"),
+		In:     c.Query("in"),
+		Out:    c.Query("out"),
+		Src:    c.Query("src"),
+		Dst:    c.Query("dst"),
+		Proto:  c.Query("proto"),
+		Text:   c.Query("q"),
+	}
+	var err error
+	if v := c.Query("port"); v != "" {
+		if filter.Port, err = strconv.Atoi(v); err != nil || filter.Port < 1 || filter.Port > 65535 {
+			return filter, fmt.Errorf("invalid port: %s", v)
+		}
+	}
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	return filter, nil
+}
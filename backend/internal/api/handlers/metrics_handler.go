@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MetricsHandler struct {
+	svc service.MetricsService
+	log *logrus.Logger
+}
+
+func NewMetricsHandler(svc service.MetricsService, log *logrus.Logger) *MetricsHandler {
+	return &MetricsHandler{svc: svc, log: log}
+}
+
+// Metrics serves the Prometheus text exposition format.
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := h.svc.Write(c.Request.Context(), &buf); err != nil {
+		h.log.WithError(err).Error("render metrics failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
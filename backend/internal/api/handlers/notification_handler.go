@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationHandler handles notification sinks and their delivery log
+type NotificationHandler struct {
+	svc service.NotificationService
+	log *logrus.Logger
+}
+
+func NewNotificationHandler(svc service.NotificationService, log *logrus.Logger) *NotificationHandler {
+	return &NotificationHandler{svc: svc, log: log}
+}
+
+// notificationStatus maps validation errors to 400 and unknown sinks to 404.
+func notificationStatus(err error) int {
+	if errors.Is(err, service.ErrInvalidNotificationSink) {
+		return http.StatusBadRequest
+	}
+	if strings.Contains(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *NotificationHandler) ListSinks(c *gin.Context) {
+	sinks, err := h.svc.ListSinks(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list notification sinks failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sinks": sinks})
+}
+
+func (h *NotificationHandler) GetSink(c *gin.Context) {
+	sink, err := h.svc.GetSink(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(notificationStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sink": sink})
+}
+
+func (h *NotificationHandler) CreateSink(c *gin.Context) {
+	var dto service.NotificationSinkDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sink, err := h.svc.CreateSink(c.Request.Context(), dto)
+	if err != nil {
+		h.log.WithError(err).Error("create notification sink failed")
+		c.JSON(notificationStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"sink": sink})
+}
+
+func (h *NotificationHandler) UpdateSink(c *gin.Context) {
+	var dto service.NotificationSinkDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sink, err := h.svc.UpdateSink(c.Request.Context(), c.Param("id"), dto)
+	if err != nil {
+		h.log.WithError(err).Error("update notification sink failed")
+		c.JSON(notificationStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sink": sink})
+}
+
+func (h *NotificationHandler) DeleteSink(c *gin.Context) {
+	if err := h.svc.DeleteSink(c.Request.Context(), c.Param("id")); err != nil {
+		h.log.WithError(err).Error("delete notification sink failed")
+		c.JSON(notificationStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// TestSink sends a test notification and waits for the result. A failed
+// delivery is reported as 502 with the delivery and its error.
+func (h *NotificationHandler) TestSink(c *gin.Context) {
+	delivery, err := h.svc.TestSink(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.log.WithError(err).Error("test notification sink failed")
+		c.JSON(notificationStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if delivery.Status != models.DeliveryDelivered {
+		c.JSON(http.StatusBadGateway, gin.H{"error": delivery.Error, "delivery": delivery})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+// ListDeliveries returns the delivery log, newest first. Filters: sinkId,
+// status (pending, delivered, failed), eventType (matches the types below
+// it too) and limit (default 100, at most 1000).
+func (h *NotificationHandler) ListDeliveries(c *gin.Context) {
+	filter := models.NotificationDeliveryFilter{
+		SinkID:    c.Query("sinkId"),
+		Status:    models.NotificationDeliveryStatus(c.Query("status")),
+		EventType: c.Query("eventType"),
+	}
+	switch filter.Status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := h.svc.ListDeliveries(c.Request.Context(), filter)
+	if err != nil {
+		h.log.WithError(err).Error("list notification deliveries failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
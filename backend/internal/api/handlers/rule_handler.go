@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/firewall-manager/backend/internal/service"
@@ -37,7 +38,7 @@ func (h *RuleHandler) Create(c *gin.Context) {
 	rule, err := h.svc.CreateRule(c.Request.Context(), dto)
 	if err != nil {
 		h.log.WithError(err).Error("create rule failed")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -60,7 +61,7 @@ func (h *RuleHandler) Update(c *gin.Context) {
 	rule, err := h.svc.UpdateRule(c.Request.Context(), id, dto)
 	if err != nil {
 		h.log.WithError(err).WithField("id", id).Error("update rule failed")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -76,9 +77,27 @@ func (h *RuleHandler) Delete(c *gin.Context) {
 
 	if err := h.svc.DeleteRule(c.Request.Context(), id); err != nil {
 		h.log.WithError(err).WithField("id", id).Error("delete rule failed")
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err, http.StatusNotFound), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusNoContent, nil)
-}
\ No newline at end of file
+}
+
+// Counters returns the live packet and byte counters of one rule.
+func (h *RuleHandler) Counters(c *gin.Context) {
+	counters, err := h.svc.GetRuleCounters(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.log.WithError(err).WithField("id", c.Param("id")).Error("get rule counters failed")
+		c.JSON(counterStatusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"counters": counters})
+}
+
+func counterStatusFor(err error) int {
+	if errors.Is(err, service.ErrRuleNotFound) || errors.Is(err, service.ErrInterfaceNotFound) {
+		return http.StatusNotFound
+	}
+	return statusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduledJobHandler handles scheduled apply/rollback jobs
+type ScheduledJobHandler struct {
+	svc service.ScheduledJobService
+	log *logrus.Logger
+}
+
+func NewScheduledJobHandler(svc service.ScheduledJobService, log *logrus.Logger) *ScheduledJobHandler {
+	return &ScheduledJobHandler{svc: svc, log: log}
+}
+
+func (h *ScheduledJobHandler) List(c *gin.Context) {
+	jobs, err := h.svc.ListJobs(c.Request.Context())
+	if err != nil {
+		h.log.WithError(err).Error("list scheduled jobs failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func (h *ScheduledJobHandler) Get(c *gin.Context) {
+	id := c.Param("id")
+	job, err := h.svc.GetJob(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func (h *ScheduledJobHandler) Create(c *gin.Context) {
+	var dto service.CreateScheduledJobDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The job runs later with this connection's lockout check and override.
+	job, err := h.svc.CreateJob(clientConnContext(c), dto)
+	if err != nil {
+		h.log.WithError(err).Error("create scheduled job failed")
+		c.JSON(statusFor(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"job": job})
+}
+
+func (h *ScheduledJobHandler) Cancel(c *gin.Context) {
+	id := c.Param("id")
+	job, err := h.svc.CancelJob(c.Request.Context(), id)
+	if err != nil {
+		h.log.WithError(err).WithField("id", id).Error("cancel scheduled job failed")
+		c.JSON(statusFor(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
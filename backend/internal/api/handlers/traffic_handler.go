@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// TrafficHandler serves the traffic of local hosts and remote peers
+type TrafficHandler struct {
+	svc service.TrafficService
+	log *logrus.Logger
+}
+
+func NewTrafficHandler(svc service.TrafficService, log *logrus.Logger) *TrafficHandler {
+	return &TrafficHandler{svc: svc, log: log}
+}
+
+// Top ranks talkers between from and to (RFC 3339, default the last hour).
+// Parameters: by (host or peer, default host), direction (rx, tx or total,
+// default total) and limit.
+func (h *TrafficHandler) Top(c *gin.Context) {
+	from, to, ok := trafficWindow(c)
+	if !ok {
+		return
+	}
+	q := models.TrafficQuery{
+		By:        c.DefaultQuery("by", "host"),
+		Direction: c.DefaultQuery("direction", "total"),
+		From:      from,
+		To:        to,
+	}
+	if v := c.Query("limit"); v != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	talkers, err := h.svc.Top(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTrafficQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.WithError(err).Error("query top talkers failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"talkers": talkers, "from": from, "to": to})
+}
+
+// HostHistory returns the rx and tx series of a local host.
+func (h *TrafficHandler) HostHistory(c *gin.Context) {
+	h.history(c, "host")
+}
+
+// PeerHistory returns the rx and tx series of a remote peer.
+func (h *TrafficHandler) PeerHistory(c *gin.Context) {
+	h.history(c, "peer")
+}
+
+// history takes the window of Top and a step (a duration or seconds).
+func (h *TrafficHandler) history(c *gin.Context, kind string) {
+	addr := c.Param("address")
+	if ip := net.ParseIP(addr); ip != nil {
+		addr = ip.String()
+	} else if addr != "other" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+		return
+	}
+	from, to, ok := trafficWindow(c)
+	if !ok {
+		return
+	}
+	var step time.Duration
+	if v := c.Query("step"); v != "" {
+		var err error
+		if step, err = parseStep(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step"})
+			return
+		}
+	}
+
+	series, err := h.svc.History(c.Request.Context(), kind, addr, from, to, step)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCounterQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.WithError(err).Error("query traffic history failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": addr, "series": series})
+}
+
+// trafficWindow parses from and to, writing a 400 response if either is
+// invalid.
+func trafficWindow(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	from := to.Add(-time.Hour)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+			return from, to, false
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
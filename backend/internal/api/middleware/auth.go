@@ -1,24 +1,42 @@
 package middleware
 
 import (
+	"context"
+	"crypto/x509"
 	"net/http"
 	"strings"
 
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/tlsutil"
 	"github.com/gin-gonic/gin"
 )
 
-// Auth is a bearer token middleware placeholder.
-// In production, replace with JWT or mTLS validation.
-func Auth(apiKey string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if apiKey == "" {
-			c.Next()
-			return
-		}
+// Authenticator resolves a bearer credential or client certificate to the
+// principal it belongs to.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*auth.Principal, error)
+	AuthenticateCertificate(ctx context.Context, cert *x509.Certificate) (*auth.Principal, error)
+}
 
+// Auth requires a valid bearer credential, or a verified client certificate
+// when no Authorization header is sent, and stores the resolved principal in
+// the request context for handlers and services.
+func Auth(authn Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
+			cert := tlsutil.ClientCertificate(c.Request)
+			if cert == nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
+				return
+			}
+			principal, err := authn.AuthenticateCertificate(c.Request.Context(), cert)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "client certificate is not mapped to a role"})
+				return
+			}
+			setPrincipal(c, principal)
+			c.Next()
 			return
 		}
 
@@ -28,11 +46,46 @@ func Auth(apiKey string) gin.HandlerFunc {
 			return
 		}
 
-		if parts[1] != apiKey {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid api key"})
+		principal, err := authn.Authenticate(c.Request.Context(), parts[1])
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid credentials"})
 			return
 		}
 
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+func setPrincipal(c *gin.Context, principal *auth.Principal) {
+	c.Set("principal", principal)
+	ctx := auth.WithPrincipal(c.Request.Context(), principal)
+	c.Request = c.Request.WithContext(auth.WithSourceIP(ctx, c.ClientIP()))
+}
+
+// Require rejects requests whose principal holds no binding granting perm.
+// Scoped bindings pass here; services check the object's zone or tags.
+func Require(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.FromContext(c.Request.Context())
+		if principal == nil || !principal.Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Scope rejects requests from tokens not scoped to resource. Safe methods
+// need read access, everything else write access.
+func Scope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead
+		principal := auth.FromContext(c.Request.Context())
+		if principal == nil || !principal.HasScope(resource, write) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token scope does not allow this request"})
+			return
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
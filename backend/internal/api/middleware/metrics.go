@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsAccess guards the metrics endpoint independently of the API
+// credentials. With a token, scrapers must send it as a bearer token; with
+// an allowlist of addresses or CIDRs, they must connect from one of them.
+// When both are set, both apply.
+func MetricsAccess(token string, allow []string) (gin.HandlerFunc, error) {
+	var nets []*net.IPNet
+	for _, spec := range allow {
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid metrics allowlist entry: %s", spec)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics allowlist entry: %s", spec)
+		}
+		nets = append(nets, n)
+	}
+
+	return func(c *gin.Context) {
+		if len(nets) > 0 && !containsIP(nets, net.ParseIP(c.ClientIP())) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "address not allowed to scrape metrics"})
+			return
+		}
+		if token != "" {
+			got := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+				return
+			}
+		}
+		c.Next()
+	}, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+// Package auth defines authenticated principals and the permission model
+// shared by the HTTP middleware and the service layer.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// ErrForbidden is returned when a principal lacks a required permission.
+var ErrForbidden = errors.New("forbidden")
+
+// Permission is a single capability checked by routes and services
+type Permission string
+
+const (
+	PermRead  Permission = "read"
+	PermEdit  Permission = "edit"
+	PermApply Permission = "apply"
+	PermAdmin Permission = "admin"
+)
+
+var rolePermissions = map[models.Role][]Permission{
+	models.RoleViewer:     {PermRead},
+	models.RoleRuleEditor: {PermRead, PermEdit},
+	models.RoleApplier:    {PermRead, PermApply},
+	models.RoleAdmin:      {PermRead, PermEdit, PermApply, PermAdmin},
+}
+
+// ValidateBinding checks that a role exists and that only roles limited to
+// individual objects are scoped. Apply and admin act on the whole ruleset,
+// so they can only be granted globally.
+func ValidateBinding(b models.RoleBinding) error {
+	if _, ok := rolePermissions[b.Role]; !ok {
+		return fmt.Errorf("invalid role: %s", b.Role)
+	}
+	if b.Zone != "" && b.Tag != "" {
+		return fmt.Errorf("role binding may be scoped to a zone or a tag, not both")
+	}
+	if (b.Zone != "" || b.Tag != "") && b.Role != models.RoleViewer && b.Role != models.RoleRuleEditor {
+		return fmt.Errorf("role %s can only be granted globally", b.Role)
+	}
+	return nil
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	ID       string               `json:"id"`
+	Name     string               `json:"name"`
+	Kind     string               `json:"kind"` // "user", "api-key", "token", "oidc", "certificate"
+	Bindings []models.RoleBinding `json:"roles"`
+	// Scopes limits an API token to parts of the API. Nil means unrestricted.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// ScopeResources are the API areas a token can be scoped to. A scope is a
+// resource ("rules"), a resource with an access level ("rules:read",
+// "rules:write") or "*" for everything.
+var ScopeResources = []string{
+	"rules", "nat", "zones", "interfaces", "config", "apply",
+	"history", "jobs", "counters", "logs", "conntrack", "traffic", "alerts", "notifications",
+	"users", "tokens", "audit",
+}
+
+// ValidateScope checks that scope names a known resource and access level.
+func ValidateScope(scope string) error {
+	if scope == "*" {
+		return nil
+	}
+	resource, level, hasLevel := strings.Cut(scope, ":")
+	if hasLevel && level != "read" && level != "write" {
+		return fmt.Errorf("invalid scope: %s", scope)
+	}
+	for _, r := range ScopeResources {
+		if r == resource {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid scope: %s", scope)
+}
+
+// HasScope reports whether the principal's scopes allow read or write access
+// to resource. A write scope implies read.
+func (p *Principal) HasScope(resource string, write bool) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, s := range p.Scopes {
+		switch s {
+		case "*", resource, resource + ":write":
+			return true
+		case resource + ":read":
+			if !write {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Can reports whether any of the principal's bindings, scoped or not,
+// grants perm. Object-level checks use CanOn.
+func (p *Principal) Can(perm Permission) bool {
+	for _, b := range p.Bindings {
+		if grants(b.Role, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanOn reports whether the principal holds perm for an object in the given
+// zone or carrying any of the given tags.
+func (p *Principal) CanOn(perm Permission, zone string, tags []string) bool {
+	for _, b := range p.Bindings {
+		if !grants(b.Role, perm) {
+			continue
+		}
+		switch {
+		case b.Zone == "" && b.Tag == "":
+			return true
+		case b.Zone != "" && b.Zone == zone:
+			return true
+		case b.Tag != "":
+			for _, t := range tags {
+				if t == b.Tag {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func grants(role models.Role, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or nil.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authorize checks that the caller in ctx holds perm on an object in zone
+// with the given tags. Contexts without a principal belong to the server
+// itself (scheduler, job worker) and are always allowed.
+func Authorize(ctx context.Context, perm Permission, zone string, tags []string) error {
+	p := FromContext(ctx)
+	if p == nil || p.CanOn(perm, zone, tags) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s lacks %s permission on this object", ErrForbidden, p.Name, perm)
+}
+
+// ParseRoleMapping parses a mapping of external identities (OIDC groups,
+// client certificate names) to role bindings in the form
+// "key=role[:zone=name|:tag=name],...". A key may appear several times to
+// receive several bindings.
+func ParseRoleMapping(s string) (map[string][]models.RoleBinding, error) {
+	mapping := make(map[string][]models.RoleBinding)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, spec, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", entry)
+		}
+		role, scope, _ := strings.Cut(spec, ":")
+		b := models.RoleBinding{Role: models.Role(role)}
+		if scope != "" {
+			kind, name, _ := strings.Cut(scope, "=")
+			switch {
+			case kind == "zone" && name != "":
+				b.Zone = name
+			case kind == "tag" && name != "":
+				b.Tag = name
+			default:
+				return nil, fmt.Errorf("invalid scope in role mapping %q", entry)
+			}
+		}
+		if err := ValidateBinding(b); err != nil {
+			return nil, fmt.Errorf("role mapping %q: %w", entry, err)
+		}
+		mapping[key] = append(mapping[key], b)
+	}
+	return mapping, nil
+}
+
+// MappedBindings returns the bindings granted to any of the keys.
+func MappedBindings(mapping map[string][]models.RoleBinding, keys []string) []models.RoleBinding {
+	bindings := []models.RoleBinding{}
+	seen := make(map[models.RoleBinding]bool)
+	for _, k := range keys {
+		for _, b := range mapping[k] {
+			if !seen[b] {
+				seen[b] = true
+				bindings = append(bindings, b)
+			}
+		}
+	}
+	return bindings
+}
+
+type sourceIPKey struct{}
+
+// WithSourceIP returns a copy of ctx carrying the client address of the
+// request, for the audit log.
+func WithSourceIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, sourceIPKey{}, ip)
+}
+
+// SourceIP returns the client address stored in ctx, or "".
+func SourceIP(ctx context.Context) string {
+	ip, _ := ctx.Value(sourceIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,107 @@
+// Package conntrack reads and deletes entries of the kernel's connection
+// tracking table over ctnetlink.
+package conntrack
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Driver defines the operations on the connection tracking table.
+type Driver interface {
+	// List returns every tracked connection.
+	List() ([]*models.Connection, error)
+	// Delete removes the tracked connections match selects and returns how
+	// many were removed. Entries that expire in the meantime count as
+	// removed.
+	Delete(match func(*models.Connection) bool) (int, error)
+}
+
+// NetlinkDriver talks to the kernel over ctnetlink.
+type NetlinkDriver struct {
+	log *logrus.Logger
+}
+
+// NewNetlinkDriver creates a new NetlinkDriver.
+func NewNetlinkDriver(log *logrus.Logger) *NetlinkDriver {
+	return &NetlinkDriver{log: log}
+}
+
+// entry is a dumped connection with the message it was parsed from, which
+// identifies it for deletion.
+type entry struct {
+	conn *models.Connection
+	raw  []byte
+}
+
+func (d *NetlinkDriver) List() ([]*models.Connection, error) {
+	entries, err := d.dump()
+	if err != nil {
+		return nil, err
+	}
+	conns := make([]*models.Connection, 0, len(entries))
+	for _, e := range entries {
+		conns = append(conns, e.conn)
+	}
+	return conns, nil
+}
+
+func (d *NetlinkDriver) Delete(match func(*models.Connection) bool) (int, error) {
+	entries, err := d.dump()
+	if err != nil {
+		return 0, err
+	}
+	deleted, failed := 0, 0
+	var lastErr error
+	for _, e := range entries {
+		if !match(e.conn) {
+			continue
+		}
+		// The dumped attributes carry the original tuple, zone and ID, which
+		// is what the kernel looks the entry up by; the ID keeps a new
+		// connection that reuses the tuple from being deleted instead.
+		req := nl.NewNetlinkRequest(unix.NFNL_SUBSYS_CTNETLINK<<8|nl.IPCTNL_MSG_CT_DELETE, unix.NLM_F_ACK)
+		req.AddData(&nl.Nfgenmsg{NfgenFamily: e.raw[0], Version: unix.NFNETLINK_V0})
+		req.AddRawData(e.raw[nl.SizeofNfgenmsg:])
+		if _, err := req.Execute(unix.NETLINK_NETFILTER, 0); err != nil && !errors.Is(err, unix.ENOENT) {
+			failed++
+			lastErr = err
+			continue
+		}
+		deleted++
+	}
+	if failed > 0 {
+		return deleted, fmt.Errorf("delete %d conntrack entries: %w", failed, lastErr)
+	}
+	return deleted, nil
+}
+
+// dump reads the whole table, both address families.
+func (d *NetlinkDriver) dump() ([]entry, error) {
+	req := nl.NewNetlinkRequest(unix.NFNL_SUBSYS_CTNETLINK<<8|nl.IPCTNL_MSG_CT_GET, unix.NLM_F_DUMP)
+	req.AddData(&nl.Nfgenmsg{NfgenFamily: unix.AF_UNSPEC, Version: unix.NFNETLINK_V0})
+	msgs, err := req.Execute(unix.NETLINK_NETFILTER, 0)
+	if err != nil {
+		if !errors.Is(err, nl.ErrDumpInterrupted) {
+			return nil, fmt.Errorf("dump conntrack table: %w", err)
+		}
+		// The table changed during the dump; what was read is still
+		// usable, if possibly incomplete.
+		d.log.Debug("conntrack dump interrupted, results may be incomplete")
+	}
+
+	entries := make([]entry, 0, len(msgs))
+	for _, m := range msgs {
+		conn, ok := parseConnection(m)
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry{conn: conn, raw: m})
+	}
+	return entries, nil
+}
@@ -0,0 +1,74 @@
+package conntrack
+
+import (
+	"sync"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// MockDriver is a mock conntrack driver for testing. It serves a fixed table
+// from which deleted entries disappear.
+type MockDriver struct {
+	log *logrus.Logger
+
+	mu    sync.Mutex
+	conns []*models.Connection
+}
+
+// NewMockDriver creates a new MockDriver.
+func NewMockDriver(log *logrus.Logger) *MockDriver {
+	return &MockDriver{log: log, conns: []*models.Connection{
+		{
+			ID: 1, Family: "ipv4", Protocol: "tcp", State: "ESTABLISHED", Flags: []string{"ASSURED"},
+			Original: models.ConnectionTuple{Src: "192.168.1.50", Dst: "192.168.1.100", SrcPort: 52044, DstPort: 22, Packets: 1204, Bytes: 98312},
+			Reply:    models.ConnectionTuple{Src: "192.168.1.100", Dst: "192.168.1.50", SrcPort: 22, DstPort: 52044, Packets: 988, Bytes: 402114},
+			Timeout:  431999,
+		},
+		{
+			ID: 2, Family: "ipv4", Protocol: "tcp", State: "ESTABLISHED", Flags: []string{"ASSURED", "DNAT"},
+			Original: models.ConnectionTuple{Src: "203.0.113.7", Dst: "192.168.1.100", SrcPort: 40112, DstPort: 8443, Packets: 310, Bytes: 41200},
+			Reply:    models.ConnectionTuple{Src: "10.0.0.20", Dst: "203.0.113.7", SrcPort: 443, DstPort: 40112, Packets: 295, Bytes: 1804331},
+			DNAT:     "10.0.0.20:443",
+			Timeout:  431880,
+		},
+		{
+			ID: 3, Family: "ipv4", Protocol: "udp", Flags: []string{"ASSURED", "SNAT"},
+			Original: models.ConnectionTuple{Src: "10.0.0.20", Dst: "1.1.1.1", SrcPort: 51000, DstPort: 53, Packets: 2, Bytes: 140},
+			Reply:    models.ConnectionTuple{Src: "1.1.1.1", Dst: "192.168.1.100", SrcPort: 53, DstPort: 51000, Packets: 2, Bytes: 312},
+			SNAT:     "192.168.1.100:51000",
+			Timeout:  118,
+		},
+		{
+			ID: 4, Family: "ipv6", Protocol: "icmpv6", Flags: []string{"UNREPLIED"},
+			Original: models.ConnectionTuple{Src: "2001:db8::10", Dst: "2001:db8::1", Packets: 1, Bytes: 104},
+			Reply:    models.ConnectionTuple{Src: "2001:db8::1", Dst: "2001:db8::10"},
+			Timeout:  27,
+		},
+	}}
+}
+
+// List returns the remaining entries of the fixed table.
+func (d *MockDriver) List() ([]*models.Connection, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*models.Connection{}, d.conns...), nil
+}
+
+// Delete removes matching entries from the fixed table.
+func (d *MockDriver) Delete(match func(*models.Connection) bool) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kept := d.conns[:0]
+	deleted := 0
+	for _, c := range d.conns {
+		if match(c) {
+			deleted++
+			continue
+		}
+		kept = append(kept, c)
+	}
+	d.conns = kept
+	d.log.WithField("entries", deleted).Info("MOCK: Deleting conntrack entries")
+	return deleted, nil
+}
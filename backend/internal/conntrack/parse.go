@@ -0,0 +1,229 @@
+package conntrack
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Connection status bits, from linux/netfilter/nf_conntrack_common.h.
+const (
+	ipsSeenReply = 1 << 1
+	ipsAssured   = 1 << 2
+	ipsSrcNAT    = 1 << 4
+	ipsDstNAT    = 1 << 5
+	ipsOffload   = 1 << 14
+)
+
+// tcpStates names the TCP conntrack states by number.
+var tcpStates = []string{
+	"NONE", "SYN_SENT", "SYN_RECV", "ESTABLISHED", "FIN_WAIT",
+	"CLOSE_WAIT", "LAST_ACK", "TIME_WAIT", "CLOSE", "SYN_SENT2",
+}
+
+// parseConnection parses an IPCTNL_MSG_CT_NEW message of a table dump.
+func parseConnection(data []byte) (*models.Connection, bool) {
+	if len(data) < nl.SizeofNfgenmsg {
+		return nil, false
+	}
+	attrs, err := nl.ParseRouteAttr(data[nl.SizeofNfgenmsg:])
+	if err != nil {
+		return nil, false
+	}
+
+	c := &models.Connection{Family: "ipv4"}
+	if data[0] == unix.AF_INET6 {
+		c.Family = "ipv6"
+	}
+	var proto uint8
+	var status uint32
+	for _, a := range attrs {
+		switch a.Attr.Type & nl.NLA_TYPE_MASK {
+		case nl.CTA_TUPLE_ORIG:
+			proto = parseTuple(a.Value, &c.Original)
+		case nl.CTA_TUPLE_REPLY:
+			parseTuple(a.Value, &c.Reply)
+		case nl.CTA_COUNTERS_ORIG:
+			parseCounters(a.Value, &c.Original)
+		case nl.CTA_COUNTERS_REPLY:
+			parseCounters(a.Value, &c.Reply)
+		case nl.CTA_STATUS:
+			status = be32(a.Value)
+		case nl.CTA_TIMEOUT:
+			c.Timeout = be32(a.Value)
+		case nl.CTA_MARK:
+			c.Mark = be32(a.Value)
+		case nl.CTA_ID:
+			c.ID = be32(a.Value)
+		case nl.CTA_ZONE:
+			if len(a.Value) >= 2 {
+				c.Zone = binary.BigEndian.Uint16(a.Value)
+			}
+		case nl.CTA_PROTOINFO:
+			c.State = parseTCPState(a.Value)
+		}
+	}
+	if c.Original.Src == "" {
+		return nil, false
+	}
+	c.Protocol = protoName(proto)
+
+	if status&ipsSeenReply == 0 {
+		c.Flags = append(c.Flags, "UNREPLIED")
+	}
+	if status&ipsAssured != 0 {
+		c.Flags = append(c.Flags, "ASSURED")
+	}
+	// The reply is addressed to the translated source and comes from the
+	// translated destination.
+	if status&ipsSrcNAT != 0 {
+		c.Flags = append(c.Flags, "SNAT")
+		c.SNAT = hostPort(c.Reply.Dst, c.Reply.DstPort)
+	}
+	if status&ipsDstNAT != 0 {
+		c.Flags = append(c.Flags, "DNAT")
+		c.DNAT = hostPort(c.Reply.Src, c.Reply.SrcPort)
+	}
+	if status&ipsOffload != 0 {
+		c.Flags = append(c.Flags, "OFFLOAD")
+	}
+	return c, true
+}
+
+// parseTuple fills in the addresses and ports of a CTA_TUPLE_* attribute
+// and returns the protocol number.
+func parseTuple(data []byte, t *models.ConnectionTuple) uint8 {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return 0
+	}
+	var proto uint8
+	for _, a := range attrs {
+		switch a.Attr.Type & nl.NLA_TYPE_MASK {
+		case nl.CTA_TUPLE_IP:
+			ips, err := nl.ParseRouteAttr(a.Value)
+			if err != nil {
+				continue
+			}
+			for _, ip := range ips {
+				switch ip.Attr.Type & nl.NLA_TYPE_MASK {
+				case nl.CTA_IP_V4_SRC, nl.CTA_IP_V6_SRC:
+					t.Src = net.IP(ip.Value).String()
+				case nl.CTA_IP_V4_DST, nl.CTA_IP_V6_DST:
+					t.Dst = net.IP(ip.Value).String()
+				}
+			}
+		case nl.CTA_TUPLE_PROTO:
+			ps, err := nl.ParseRouteAttr(a.Value)
+			if err != nil {
+				continue
+			}
+			for _, p := range ps {
+				switch p.Attr.Type & nl.NLA_TYPE_MASK {
+				case nl.CTA_PROTO_NUM:
+					if len(p.Value) >= 1 {
+						proto = p.Value[0]
+					}
+				case nl.CTA_PROTO_SRC_PORT:
+					t.SrcPort = int(be16(p.Value))
+				case nl.CTA_PROTO_DST_PORT:
+					t.DstPort = int(be16(p.Value))
+				}
+			}
+		}
+	}
+	return proto
+}
+
+func parseCounters(data []byte, t *models.ConnectionTuple) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return
+	}
+	for _, a := range attrs {
+		if len(a.Value) < 8 {
+			continue
+		}
+		switch a.Attr.Type & nl.NLA_TYPE_MASK {
+		case nl.CTA_COUNTERS_PACKETS:
+			t.Packets = binary.BigEndian.Uint64(a.Value)
+		case nl.CTA_COUNTERS_BYTES:
+			t.Bytes = binary.BigEndian.Uint64(a.Value)
+		}
+	}
+}
+
+// parseTCPState returns the state in a CTA_PROTOINFO attribute, or "" for
+// protocols other than TCP.
+func parseTCPState(data []byte) string {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return ""
+	}
+	for _, a := range attrs {
+		if a.Attr.Type&nl.NLA_TYPE_MASK != nl.CTA_PROTOINFO_TCP {
+			continue
+		}
+		tcp, err := nl.ParseRouteAttr(a.Value)
+		if err != nil {
+			return ""
+		}
+		for _, t := range tcp {
+			if t.Attr.Type&nl.NLA_TYPE_MASK == nl.CTA_PROTOINFO_TCP_STATE && len(t.Value) >= 1 {
+				if int(t.Value[0]) < len(tcpStates) {
+					return tcpStates[t.Value[0]]
+				}
+				return strconv.Itoa(int(t.Value[0]))
+			}
+		}
+	}
+	return ""
+}
+
+// protoName names a protocol the way conntrack(8) does.
+func protoName(proto uint8) string {
+	switch proto {
+	case unix.IPPROTO_TCP:
+		return "tcp"
+	case unix.IPPROTO_UDP:
+		return "udp"
+	case unix.IPPROTO_UDPLITE:
+		return "udplite"
+	case unix.IPPROTO_SCTP:
+		return "sctp"
+	case unix.IPPROTO_DCCP:
+		return "dccp"
+	case unix.IPPROTO_ICMP:
+		return "icmp"
+	case unix.IPPROTO_ICMPV6:
+		return "icmpv6"
+	case unix.IPPROTO_GRE:
+		return "gre"
+	}
+	return strconv.Itoa(int(proto))
+}
+
+func hostPort(host string, port int) string {
+	if port == 0 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func be16(b []byte) uint16 {
+	if len(b) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func be32(b []byte) uint32 {
+	if len(b) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
@@ -0,0 +1,167 @@
+package firewall
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// ruleTagPrefix starts the comment of every rule the driver renders. The tag
+// that follows identifies the models.Rule across applies.
+const ruleTagPrefix = "fwmg:"
+
+// RuleTag returns the short, stable tag embedded in the comment of the
+// kernel rule rendered from the rule with the given ID.
+func RuleTag(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return ruleTagPrefix + hex.EncodeToString(sum[:6])
+}
+
+// ruleComment prefixes a user comment with the rule's tag.
+func ruleComment(id, comment string) string {
+	if c := sanitizeComment(comment); c != "" {
+		return RuleTag(id) + " " + c
+	}
+	return RuleTag(id)
+}
+
+// logTagSuffix marks the tag of the log line rendered in front of a verdict
+// rule, so its counters are not added to the rule's.
+const logTagSuffix = "/log"
+
+// LogPrefix returns the prefix the LOG or NFLOG target writes before every
+// packet the rule logs. Unless the rule sets its own, it is the rule's tag,
+// so log lines can be traced back to the rule.
+func LogPrefix(r *models.Rule) string {
+	if r.Log != nil {
+		if p := strings.TrimSpace(r.Log.Prefix); p != "" {
+			return p + " "
+		}
+	}
+	return RuleTag(r.ID) + " "
+}
+
+// lineTag returns the rule tag in a rendered or saved rule line, or "".
+func lineTag(args []string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--comment" && strings.HasPrefix(args[i+1], ruleTagPrefix) {
+			return strings.Fields(args[i+1])[0]
+		}
+	}
+	return ""
+}
+
+// savedCounters are the counters of one iptables-save -c output, keyed by
+// table and chain and, for rules, by tag and occurrence of the tag.
+type savedCounters struct {
+	policies map[string]savedPolicy
+	rules    map[string]savedRule
+}
+
+type savedPolicy struct {
+	policy   string
+	counters string // "[packets:bytes]"
+}
+
+type savedRule struct {
+	canonical string
+	counters  string
+}
+
+func parseSavedCounters(raw string) *savedCounters {
+	saved := &savedCounters{policies: make(map[string]savedPolicy), rules: make(map[string]savedRule)}
+	walkRuleset(raw, func(table, line string, seen map[string]int) string {
+		switch {
+		case strings.HasPrefix(line, ":"):
+			if fields := strings.Fields(line[1:]); len(fields) >= 3 {
+				saved.policies[table+"/"+fields[0]] = savedPolicy{policy: fields[1], counters: fields[2]}
+			}
+		case strings.HasPrefix(line, "["):
+			end := strings.Index(line, "]")
+			if end < 0 {
+				break
+			}
+			rest := strings.TrimSpace(line[end+1:])
+			if key, canonical, ok := ruleKey(table, rest, seen); ok {
+				saved.rules[key] = savedRule{canonical: canonical, counters: line[:end+1]}
+			}
+		}
+		return line
+	})
+	return saved
+}
+
+// carryCounters copies into a rendered ruleset the counters of the rules and
+// chain policies that are unchanged since live, the iptables-save -c output
+// taken before the apply. A rule is unchanged if the live table has a rule
+// with the same tag in the same chain that matches it in canonical form;
+// new and modified rules, and untagged ones, start from zero. It returns the
+// ruleset for iptables-restore --counters and the number of rules carried.
+func carryCounters(ruleset, live string) (string, int) {
+	saved := parseSavedCounters(live)
+	carried := 0
+	out := walkRuleset(ruleset, func(table, line string, seen map[string]int) string {
+		switch {
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(line[1:])
+			if len(fields) < 2 {
+				break
+			}
+			if p, ok := saved.policies[table+"/"+fields[0]]; ok && p.policy == fields[1] {
+				return ":" + fields[0] + " " + fields[1] + " " + p.counters
+			}
+		case strings.HasPrefix(line, "-A"):
+			key, canonical, ok := ruleKey(table, line, seen)
+			if !ok {
+				break
+			}
+			if r, ok := saved.rules[key]; ok && r.canonical == canonical {
+				carried++
+				return r.counters + " " + line
+			}
+		}
+		return line
+	})
+	return out, carried
+}
+
+// walkRuleset calls fn for every line inside a table and replaces the line
+// with its result. seen counts tag occurrences per chain, for ruleKey.
+func walkRuleset(raw string, fn func(table, line string, seen map[string]int) string) string {
+	lines := strings.Split(raw, "\n")
+	table := ""
+	var seen map[string]int
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "*"):
+			table = trimmed[1:]
+			seen = make(map[string]int)
+		case trimmed == "COMMIT":
+			table = ""
+		case table != "" && trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			lines[i] = fn(table, trimmed, seen)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ruleKey identifies a tagged rule line by table, chain, tag and how often
+// the tag has occurred in the chain so far.
+func ruleKey(table, line string, seen map[string]int) (key, canonical string, ok bool) {
+	tag := lineTag(splitRuleArgs(line))
+	if tag == "" {
+		return "", "", false
+	}
+	chain, canonical, ok := canonicalRule(line)
+	if !ok {
+		return "", "", false
+	}
+	base := table + "/" + chain + "/" + tag
+	n := seen[base]
+	seen[base] = n + 1
+	return base + "/" + strconv.Itoa(n), canonical, true
+}
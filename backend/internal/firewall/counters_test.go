@@ -0,0 +1,197 @@
+package firewall
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+func TestRuleTag(t *testing.T) {
+	if got := RuleTag("r1"); got != "fwmg:82f3e9c695dc" {
+		t.Errorf("RuleTag(r1) = %s", got)
+	}
+	r := &models.Rule{ID: "r1"}
+	if got := LogPrefix(r); got != "fwmg:82f3e9c695dc " {
+		t.Errorf("LogPrefix = %q", got)
+	}
+	r.Log = &models.LogOptions{Prefix: "  ssh-deny "}
+	if got := LogPrefix(r); got != "ssh-deny " {
+		t.Errorf("LogPrefix with prefix = %q", got)
+	}
+}
+
+func TestRuleKey(t *testing.T) {
+	seen := make(map[string]int)
+	tests := []struct {
+		table string
+		line  string
+		key   string // "" if the line has no key
+	}{
+		{"filter", `-A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`, "filter/INPUT/fwmg:82f3e9c695dc/0"},
+		{"filter", `-A INPUT -p tcp -m tcp --dport 80 -m comment --comment "fwmg:82f3e9c695dc web" -j ACCEPT`, "filter/INPUT/fwmg:82f3e9c695dc/1"},
+		{"filter", `-A OUTPUT -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`, "filter/OUTPUT/fwmg:82f3e9c695dc/0"},
+		{"nat", `-A POSTROUTING -o eth0 -m comment --comment "fwmg:82f3e9c695dc" -j MASQUERADE`, "nat/POSTROUTING/fwmg:82f3e9c695dc/0"},
+		{"filter", `-A INPUT -m comment --comment "fwmg:dbb7b294e78f/log" -j LOG --log-prefix "fwmg:dbb7b294e78f "`, "filter/INPUT/fwmg:dbb7b294e78f/log/0"},
+		{"filter", `-A INPUT -m comment --comment "fwmg:dbb7b294e78f" -j DROP`, "filter/INPUT/fwmg:dbb7b294e78f/0"},
+		{"filter", `-A INPUT -m comment --comment fwmg:db77fd01af95 -j DROP`, "filter/INPUT/fwmg:db77fd01af95/0"},
+		{"filter", `-A INPUT -p tcp -m tcp --dport 8080 -j ACCEPT`, ""},
+		{"filter", `-A INPUT -m comment --comment "managed by hand" -j ACCEPT`, ""},
+		{"filter", `-A INPUT -j LOG --log-prefix "fwmg:82f3e9c695dc "`, ""},
+		{"filter", `:INPUT ACCEPT [0:0]`, ""},
+	}
+	for _, tt := range tests {
+		key, canonical, ok := ruleKey(tt.table, tt.line, seen)
+		if tt.key == "" {
+			if ok {
+				t.Errorf("ruleKey(%s) = %q, want none", tt.line, key)
+			}
+			continue
+		}
+		if !ok || key != tt.key {
+			t.Errorf("ruleKey(%s) = %q, %v, want %q", tt.line, key, ok, tt.key)
+		}
+		if _, want, _ := canonicalRule(tt.line); canonical != want {
+			t.Errorf("ruleKey(%s) canonical = %q, want %q", tt.line, canonical, want)
+		}
+	}
+}
+
+// liveFilter is iptables-save -c output of the filter table.
+func liveFilter(policies [3]string, lines ...string) string {
+	return "# Generated by iptables-save v1.8.7 on Sun Oct 18 12:00:00 2026\n" +
+		"*filter\n" +
+		":INPUT " + policies[0] + "\n" +
+		":FORWARD " + policies[1] + "\n" +
+		":OUTPUT " + policies[2] + "\n" +
+		strings.Join(lines, "\n") + "\n" +
+		"COMMIT\n" +
+		"# Completed on Sun Oct 18 12:00:00 2026\n"
+}
+
+// renderedFilter is a filter table as buildRuleset renders it.
+func renderedFilter(lines ...string) string {
+	return "*filter\n:INPUT ACCEPT [0:0]\n:FORWARD DROP [0:0]\n:OUTPUT ACCEPT [0:0]\n" +
+		strings.Join(lines, "\n") + "\nCOMMIT\n"
+}
+
+func TestCarryCounters(t *testing.T) {
+	defaultPolicies := [3]string{"ACCEPT [120:9600]", "DROP [4:240]", "ACCEPT [80:7200]"}
+	tests := []struct {
+		name     string
+		ruleset  string
+		live     string
+		want     []string // counters of each rule line in order, "" for none
+		policies []string // counters of INPUT, FORWARD, OUTPUT
+		carried  int
+	}{
+		{
+			name: "unchanged rules",
+			ruleset: renderedFilter(
+				`-A INPUT -p tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc allow ssh" -j ACCEPT`,
+				`-A INPUT -s 203.0.113.0/24 -m comment --comment "fwmg:dbb7b294e78f/log" -m limit --limit 60/minute -j LOG --log-prefix "fwmg:dbb7b294e78f " --log-level 4`,
+				`-A INPUT -s 203.0.113.0/24 -m comment --comment "fwmg:dbb7b294e78f" -j REJECT`,
+			),
+			live: liveFilter(defaultPolicies,
+				`[310:18600] -A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc allow ssh" -j ACCEPT`,
+				`[12:720] -A INPUT -s 203.0.113.0/24 -m comment --comment "fwmg:dbb7b294e78f/log" -m limit --limit 1/sec -j LOG --log-prefix "fwmg:dbb7b294e78f "`,
+				`[15:900] -A INPUT -s 203.0.113.0/24 -m comment --comment "fwmg:dbb7b294e78f" -j REJECT --reject-with icmp-port-unreachable`,
+			),
+			want:     []string{"[310:18600]", "[12:720]", "[15:900]"},
+			policies: []string{"[120:9600]", "[4:240]", "[80:7200]"},
+			carried:  3,
+		},
+		{
+			name: "modified, new and moved rules",
+			ruleset: renderedFilter(
+				`-A INPUT -p tcp --dport 2222 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+				`-A INPUT -p udp --dport 53 -m comment --comment "fwmg:e49d63b2a8a7" -j ACCEPT`,
+				`-A OUTPUT -m comment --comment "fwmg:db77fd01af95" -j DROP`,
+				`-A INPUT -m comment --comment "fwmg:a2ec8adac7fd" -m time --timestart 08:00 --timestop 18:00 -j ACCEPT`,
+			),
+			live: liveFilter(defaultPolicies,
+				`[310:18600] -A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+				`[9:540] -A INPUT -m comment --comment "fwmg:db77fd01af95" -j DROP`,
+				`[4:240] -A INPUT -m comment --comment "fwmg:a2ec8adac7fd" -m time --timestart 08:00:00 --timestop 18:00:00 --kerneltz -j ACCEPT`,
+			),
+			want:     []string{"", "", "", ""},
+			policies: []string{"[120:9600]", "[4:240]", "[80:7200]"},
+		},
+		{
+			name: "kernel schedule in local time",
+			ruleset: renderedFilter(
+				`-A INPUT -p tcp --dport 22 -m comment --comment "fwmg:a2ec8adac7fd" -m time --timestart 08:00 --timestop 18:00 --weekdays Mon,Tue,Wed,Thu,Fri --kerneltz -j ACCEPT`,
+			),
+			live: liveFilter(defaultPolicies,
+				`[44:2640] -A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:a2ec8adac7fd" -m time --timestart 08:00:00 --timestop 18:00:00 --weekdays Mon,Tue,Wed,Thu,Fri --kerneltz -j ACCEPT`,
+			),
+			want:     []string{"[44:2640]"},
+			policies: []string{"[120:9600]", "[4:240]", "[80:7200]"},
+			carried:  1,
+		},
+		{
+			name: "repeated tag matched by occurrence",
+			ruleset: renderedFilter(
+				`-A INPUT -p tcp --dport 80 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+				`-A INPUT -p tcp --dport 8443 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			),
+			live: liveFilter(defaultPolicies,
+				`[5:300] -A INPUT -p tcp -m tcp --dport 80 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+				`[6:360] -A INPUT -p tcp -m tcp --dport 443 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			),
+			want:     []string{"[5:300]", ""},
+			policies: []string{"[120:9600]", "[4:240]", "[80:7200]"},
+			carried:  1,
+		},
+		{
+			name: "untagged rules and changed policy",
+			ruleset: renderedFilter(
+				`-A INPUT -p tcp --dport 8080 -m comment --comment "fwmg mgmt" -j ACCEPT`,
+			),
+			live: liveFilter([3]string{"DROP [120:9600]", "DROP [4:240]", "ACCEPT [80:7200]"},
+				`[99:5940] -A INPUT -p tcp -m tcp --dport 8080 -m comment --comment "fwmg mgmt" -j ACCEPT`,
+			),
+			want:     []string{""},
+			policies: []string{"[0:0]", "[4:240]", "[80:7200]"},
+		},
+		{
+			name: "no live table",
+			ruleset: renderedFilter(
+				`-A INPUT -p tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			),
+			live:     "",
+			want:     []string{""},
+			policies: []string{"[0:0]", "[0:0]", "[0:0]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, carried := carryCounters(tt.ruleset, tt.live)
+			if carried != tt.carried {
+				t.Errorf("carried %d, want %d", carried, tt.carried)
+			}
+			var rules, policies []string
+			for _, line := range strings.Split(out, "\n") {
+				switch {
+				case strings.HasPrefix(line, ":"):
+					fields := strings.Fields(line)
+					policies = append(policies, fields[len(fields)-1])
+				case strings.HasPrefix(line, "["):
+					rules = append(rules, line[:strings.Index(line, "]")+1])
+				case strings.HasPrefix(line, "-A"):
+					rules = append(rules, "")
+				}
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("rule counters %q, want %q\n%s", rules, tt.want, out)
+			}
+			if strings.Join(policies, ",") != strings.Join(tt.policies, ",") {
+				t.Errorf("policy counters %q, want %q", policies, tt.policies)
+			}
+			// The result must still be the same ruleset.
+			if parsed, want := parseSaveTables(out)["filter"], parseSaveTables(tt.ruleset)["filter"]; strings.Join(parsed.rules["INPUT"], "\n") != strings.Join(want.rules["INPUT"], "\n") {
+				t.Errorf("ruleset changed:\n%s", out)
+			}
+		})
+	}
+}
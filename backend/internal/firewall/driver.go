@@ -12,6 +12,20 @@ type FirewallDriver interface {
 	// It translates the Rule slice to iptables format and uses iptables-restore.
 	Apply(rules []*models.Rule) error
 
+	// Lockdown atomically replaces the filter table with a drop-everything
+	// ruleset that only lets through loopback, established sessions and the
+	// given allow rules.
+	Lockdown(allow []*models.Rule) error
+
+	// Verify re-reads the live state after an apply and checks that the
+	// rules, chain policies and IP forwarding rendered from the given inputs
+	// are in place. natRules and config are skipped when nil.
+	Verify(rules []*models.Rule, natRules []*models.NATRule, config *models.FirewallConfig) (*models.VerificationReport, error)
+
+	// Fingerprint returns a deterministic hash of the payload rendered from
+	// the same inputs as Verify.
+	Fingerprint(rules []*models.Rule, natRules []*models.NATRule, config *models.FirewallConfig) string
+
 	// GetCounters returns per-chain/rule packet and byte counters.
 	GetCounters() ([]*models.Counter, error)
 
@@ -21,6 +35,9 @@ type FirewallDriver interface {
 	// GetInterfaceCounters returns counters for a specific interface.
 	GetInterfaceCounters(iface string) (*models.InterfaceCounters, error)
 
+	// GetLinkCounters returns the counters of every interface, by name.
+	GetLinkCounters() (map[string]*models.InterfaceCounters, error)
+
 	// ApplyConfig applies firewall configuration (IP forwarding, etc)
 	ApplyConfig(config *models.FirewallConfig) error
 
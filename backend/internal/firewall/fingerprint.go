@@ -0,0 +1,30 @@
+package firewall
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// Fingerprint returns the SHA-256 of the payload Apply, ApplyNAT and
+// ApplyConfig would load for these inputs: the rendered filter table, the
+// rendered nat table and the sysctl values. natRules and config are left out
+// when nil, as in Verify. Equal inputs in equal order give equal hashes.
+func (d *IptablesDriver) Fingerprint(rules []*models.Rule, natRules []*models.NATRule, config *models.FirewallConfig) string {
+	var sb strings.Builder
+	sb.WriteString(d.buildRuleset(rules))
+	if natRules != nil {
+		sb.WriteString(d.buildNATRuleset(natRules))
+	}
+	if config != nil {
+		forward := "0"
+		if config.IPForwarding {
+			forward = "1"
+		}
+		sb.WriteString("net.ipv4.ip_forward=" + forward + "\n")
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
@@ -24,13 +24,15 @@ func NewIptablesDriver(log *logrus.Logger) *IptablesDriver {
 	return &IptablesDriver{log: log}
 }
 
-// Load runs iptables-save and returns the current ruleset as a string.
+// Load runs iptables-save -c and returns the current ruleset, with counters,
+// as a string.
 func (d *IptablesDriver) Load() (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// exec.CommandContext with a fixed binary path — no shell interpolation.
-	cmd := exec.CommandContext(ctx, "/sbin/iptables-save")
+	// -c includes per-rule counters, which Apply carries forward.
+	cmd := exec.CommandContext(ctx, "/sbin/iptables-save", "-c")
 	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
@@ -44,8 +46,18 @@ func (d *IptablesDriver) Load() (string, error) {
 
 // Apply translates rules to iptables-save format and pipes through iptables-restore.
 // This is the ONLY way rules reach the kernel — atomically, with no shell.
+// Counters of unchanged rules and chain policies survive the apply.
 func (d *IptablesDriver) Apply(rules []*models.Rule) error {
 	ruleset := d.buildRuleset(rules)
+
+	// Keep the counters of rules and policies the apply does not change.
+	if live, err := d.Load(); err != nil {
+		d.log.WithError(err).Warn("could not read counters before apply, all counters reset")
+	} else {
+		var carried int
+		ruleset, carried = carryCounters(ruleset, live)
+		d.log.WithField("rules_carried", carried).Debug("carried forward counters of unchanged rules")
+	}
 	d.log.WithField("ruleset_lines", strings.Count(ruleset, "\n")).Debug("applying ruleset")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -64,6 +76,27 @@ func (d *IptablesDriver) Apply(rules []*models.Rule) error {
 	return nil
 }
 
+// Lockdown replaces the filter table with the panic ruleset.
+func (d *IptablesDriver) Lockdown(allow []*models.Rule) error {
+	ruleset := d.buildLockdownRuleset(allow)
+	d.log.WithField("ruleset_lines", strings.Count(ruleset, "\n")).Debug("applying lockdown ruleset")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/sbin/iptables-restore")
+	cmd.Stdin = strings.NewReader(ruleset)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("iptables-restore lockdown failed: %w — stderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
 // ApplyConfig applies firewall configuration like IP forwarding
 func (d *IptablesDriver) ApplyConfig(config *models.FirewallConfig) error {
 	if config == nil {
@@ -119,6 +152,15 @@ func (d *IptablesDriver) ApplyNAT(natRules []*models.NATRule) error {
 	}
 
 	ruleset := d.buildNATRuleset(natRules)
+
+	// Keep the counters of NAT rules the apply does not change.
+	if live, err := d.Load(); err != nil {
+		d.log.WithError(err).Warn("could not read counters before NAT apply, all counters reset")
+	} else {
+		var carried int
+		ruleset, carried = carryCounters(ruleset, live)
+		d.log.WithField("rules_carried", carried).Debug("carried forward counters of unchanged NAT rules")
+	}
 	d.log.WithField("ruleset_lines", strings.Count(ruleset, "\n")).Debug("applying NAT ruleset")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -254,7 +296,7 @@ func (d *IptablesDriver) buildSNATRule(nr *models.NATRule, chain string) string
 	}
 
 	// Match packets FROM this source IP
-	if src := sanitizeCIDR(nr.SourceIP); src != "" {
+	if src := matchAddr(nr.SourceIP); src != "" {
 		parts = append(parts, "-s", src)
 	}
 
@@ -265,13 +307,8 @@ func (d *IptablesDriver) buildSNATRule(nr *models.NATRule, chain string) string
 		}
 	}
 
-	// Comment
-	if nr.Comment != "" {
-		comment := sanitizeComment(nr.Comment)
-		if comment != "" {
-			parts = append(parts, "-m", "comment", "--comment", comment)
-		}
-	}
+	// Comment, always carrying the rule's tag
+	parts = append(parts, "-m", "comment", "--comment", quoteComment(ruleComment(nr.ID, nr.Comment)))
 
 	// Build SNAT target (translate to new source IP)
 	snatTarget := d.buildSNATTarget(nr)
@@ -306,7 +343,7 @@ func (d *IptablesDriver) buildDNATRule(nr *models.NATRule, chain string) string
 	}
 
 	// Match packets TO this destination IP
-	if dst := sanitizeCIDR(nr.DestIP); dst != "" {
+	if dst := matchAddr(nr.DestIP); dst != "" {
 		parts = append(parts, "-d", dst)
 	}
 
@@ -317,13 +354,8 @@ func (d *IptablesDriver) buildDNATRule(nr *models.NATRule, chain string) string
 		}
 	}
 
-	// Comment
-	if nr.Comment != "" {
-		comment := sanitizeComment(nr.Comment)
-		if comment != "" {
-			parts = append(parts, "-m", "comment", "--comment", comment)
-		}
-	}
+	// Comment, always carrying the rule's tag
+	parts = append(parts, "-m", "comment", "--comment", quoteComment(ruleComment(nr.ID, nr.Comment)))
 
 	// Build DNAT target (redirect to internal IP)
 	dnatTarget := d.buildDNATTarget(nr)
@@ -397,117 +429,51 @@ func (d *IptablesDriver) GetInterfaces() ([]*models.Interface, error) {
 	return out, nil
 }
 
-// GetInterfaceCounters returns counters for a specific interface.
-func (d *IptablesDriver) GetInterfaceCounters(iface string) (*models.InterfaceCounters, error) {
-	sIface := sanitizeInterface(iface)
-	if sIface == "" {
-		return nil, fmt.Errorf("invalid interface name provided")
-	}
-
-	counters := &models.InterfaceCounters{}
-
-	// Get INPUT chain counters
-	inCounters, err := d.getChainCounters("INPUT", "in", sIface)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get INPUT counters for %s: %w", sIface, err)
-	}
-	counters.In = inCounters
-
-	// Get OUTPUT chain counters
-	outCounters, err := d.getChainCounters("OUTPUT", "out", sIface)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get OUTPUT counters for %s: %w", sIface, err)
-	}
-	counters.Out = outCounters
-	
-	// Get FORWARD chain drop counters
-	dropCounters, err := d.getChainCounters("FORWARD", "any", sIface)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get FORWARD counters for %s: %w", sIface, err)
-	}
-	counters.Drop = dropCounters
-
-	return counters, nil
-}
-
-// getChainCounters executes `iptables -L <chain> -v -n` and parses the byte/packet counts.
-func (d *IptablesDriver) getChainCounters(chain, direction, iface string) (models.CounterStats, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	args := []string{"-L", chain, "-v", "-n"}
-	if direction == "in" {
-		args = append(args, "-i", iface)
-	} else if direction == "out" {
-		args = append(args, "-o", iface)
-	}
-
-	cmd := exec.CommandContext(ctx, "/sbin/iptables", args...)
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// some old iptables versions complain if you filter by interface on a chain that doesn't have any rules for it.
-		// we can ignore this error and return zero counters.
-		if strings.Contains(stderr.String(), "No such file or directory") {
-			return models.CounterStats{}, nil
-		}
-		return models.CounterStats{}, fmt.Errorf("iptables -L failed for %s on %s: %w — %s", chain, iface, err, stderr.String())
-	}
-
-	return d.parseInterfaceCounters(out.String()), nil
-}
-
-// parseInterfaceCounters parses the verbose output of `iptables -L -v -n`.
-func (d *IptablesDriver) parseInterfaceCounters(raw string) models.CounterStats {
-	lines := strings.Split(raw, "\n")
-	var totalPkts, totalBytes uint64
+// buildRuleset produces an iptables-save-compatible text block from abstract rules.
+// All values are sanitized before being written — no raw user input ever enters a command.
+func (d *IptablesDriver) buildRuleset(rules []*models.Rule) string {
+	var sb strings.Builder
 
-	// Skip header lines
-	for _, line := range lines[2:] {
-		fields := strings.Fields(line)
-		if len(fields) < 2 {
-			continue
-		}
+	sb.WriteString("*filter\n")
+	sb.WriteString(":INPUT ACCEPT [0:0]\n")
+	sb.WriteString(":FORWARD DROP [0:0]\n")
+	sb.WriteString(":OUTPUT ACCEPT [0:0]\n")
 
-		pkts, err := strconv.ParseUint(fields[0], 10, 64)
-		if err != nil {
-			d.log.WithError(err).WithField("line", line).Warn("failed to parse packet count")
+	for _, r := range rules {
+		if !r.Enabled {
 			continue
 		}
-		totalPkts += pkts
-
-		bytes, err := strconv.ParseUint(fields[1], 10, 64)
-		if err != nil {
-			d.log.WithError(err).WithField("line", line).Warn("failed to parse byte count")
-			continue
+		for _, line := range d.ruleToIptablesLines(r) {
+			sb.WriteString(line)
+			sb.WriteString("\n")
 		}
-		totalBytes += bytes
 	}
 
-	return models.CounterStats{
-		Packets: totalPkts,
-		Bytes:   totalBytes,
-	}
+	sb.WriteString("COMMIT\n")
+	return sb.String()
 }
 
-// buildRuleset produces an iptables-save-compatible text block from abstract rules.
-// All values are sanitized before being written — no raw user input ever enters a command.
-func (d *IptablesDriver) buildRuleset(rules []*models.Rule) string {
+// buildLockdownRuleset drops everything by default. Replies and already
+// open connections keep flowing through conntrack, so the session that
+// triggered the lockdown survives.
+func (d *IptablesDriver) buildLockdownRuleset(allow []*models.Rule) string {
 	var sb strings.Builder
 
 	sb.WriteString("*filter\n")
-	sb.WriteString(":INPUT ACCEPT [0:0]\n")
+	sb.WriteString(":INPUT DROP [0:0]\n")
 	sb.WriteString(":FORWARD DROP [0:0]\n")
-	sb.WriteString(":OUTPUT ACCEPT [0:0]\n")
+	sb.WriteString(":OUTPUT DROP [0:0]\n")
+	sb.WriteString("-A INPUT -i lo -j ACCEPT\n")
+	sb.WriteString("-A OUTPUT -o lo -j ACCEPT\n")
+	for _, chain := range []string{"INPUT", "FORWARD", "OUTPUT"} {
+		sb.WriteString("-A " + chain + " -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT\n")
+	}
 
-	for _, r := range rules {
-		if !r.Enabled {
+	for _, r := range allow {
+		if !r.Enabled || r.Action != models.ActionACCEPT {
 			continue
 		}
-		line := d.ruleToIptablesLine(r)
-		if line != "" {
+		for _, line := range d.ruleToIptablesLines(r) {
 			sb.WriteString(line)
 			sb.WriteString("\n")
 		}
@@ -517,53 +483,132 @@ func (d *IptablesDriver) buildRuleset(rules []*models.Rule) string {
 	return sb.String()
 }
 
-// ruleToIptablesLine converts a Rule to an iptables-restore rule line.
+// ruleToIptablesLines converts a Rule to iptables-restore rule lines: one
+// line, or a log line followed by the verdict for a rule that logs before
+// it decides. It returns nil if the rule cannot be rendered.
 // Each field is written via explicit format functions — never interpolated from raw input.
-func (d *IptablesDriver) ruleToIptablesLine(r *models.Rule) string {
-	var parts []string
-
-	parts = append(parts, "-A", string(r.Chain))
+func (d *IptablesDriver) ruleToIptablesLines(r *models.Rule) []string {
+	var match []string
 
 	proto := sanitizeProtocol(r.Protocol)
 	if proto != "" && proto != "all" {
-		parts = append(parts, "-p", proto)
+		match = append(match, "-p", proto)
 	}
 
-	if src := sanitizeCIDR(r.Src); src != "" {
-		parts = append(parts, "-s", src)
+	if src := matchAddr(r.Src); src != "" {
+		match = append(match, "-s", src)
 	}
 
-	if dst := sanitizeCIDR(r.Dst); dst != "" {
-		parts = append(parts, "-d", dst)
+	if dst := matchAddr(r.Dst); dst != "" {
+		match = append(match, "-d", dst)
+	}
+
+	if in := sanitizeInterface(r.InInterface); in != "" {
+		match = append(match, "-i", in)
+	}
+
+	if out := sanitizeInterface(r.OutInterface); out != "" {
+		match = append(match, "-o", out)
 	}
 
 	if r.SrcPort != "" && proto != "" && proto != "icmp" && proto != "all" {
 		if port := sanitizePort(r.SrcPort); port != "" {
-			parts = append(parts, "--sport", port)
+			match = append(match, "--sport", port)
 		}
 	}
 
 	if r.DstPort != "" && proto != "" && proto != "icmp" && proto != "all" {
 		if port := sanitizePort(r.DstPort); port != "" {
-			parts = append(parts, "--dport", port)
+			match = append(match, "--dport", port)
 		}
 	}
 
-	if r.Comment != "" {
-		comment := sanitizeComment(r.Comment)
-		if comment != "" {
-			parts = append(parts, "-m", "comment", "--comment", comment)
+	var timeMatch []string
+	if r.Schedule != nil && r.Schedule.Mode == models.ScheduleModeKernel {
+		if timeMatch = timeMatchArgs(r.Schedule); timeMatch == nil {
+			d.log.WithField("rule_id", r.ID).Warn("rule has invalid schedule, skipping")
+			return nil
 		}
 	}
 
 	action := sanitizeAction(r.Action)
 	if action == "" {
 		d.log.WithField("rule_id", r.ID).Warn("rule has invalid action, skipping")
-		return ""
+		return nil
 	}
-	parts = append(parts, "-j", action)
 
-	return strings.Join(parts, " ")
+	// The comment always carries the rule's tag, so counters and log lines
+	// can be traced back to the rule.
+	line := func(comment string, target []string) string {
+		parts := append([]string{"-A", string(r.Chain)}, match...)
+		parts = append(parts, "-m", "comment", "--comment", quoteComment(comment))
+		parts = append(parts, timeMatch...)
+		return strings.Join(append(parts, target...), " ")
+	}
+
+	if r.Action != models.ActionLOG && r.Log == nil {
+		return []string{line(ruleComment(r.ID, r.Comment), []string{"-j", action})}
+	}
+	logTarget := logTargetArgs(r)
+	if logTarget == nil {
+		d.log.WithField("rule_id", r.ID).Warn("rule has invalid log options, skipping")
+		return nil
+	}
+	if r.Action == models.ActionLOG {
+		return []string{line(ruleComment(r.ID, r.Comment), logTarget)}
+	}
+	return []string{
+		line(RuleTag(r.ID)+logTagSuffix, logTarget),
+		line(ruleComment(r.ID, r.Comment), []string{"-j", action}),
+	}
+}
+
+// logTargetArgs renders a rule's log options as an optional limit match
+// and a LOG or NFLOG target. It returns nil if any option fails
+// validation.
+func logTargetArgs(r *models.Rule) []string {
+	opts := r.Log
+	if opts == nil {
+		opts = &models.LogOptions{}
+	}
+	prefix := LogPrefix(r)
+	if !ValidLogPrefix(prefix) {
+		return nil
+	}
+
+	var args []string
+	if opts.Limit != "" {
+		rate := sanitizeRate(opts.Limit)
+		if rate == "" {
+			return nil
+		}
+		args = append(args, "-m", "limit", "--limit", rate)
+		if opts.Burst != 0 {
+			if opts.Burst < 1 || opts.Burst > 10000 {
+				return nil
+			}
+			args = append(args, "--limit-burst", strconv.Itoa(opts.Burst))
+		}
+	}
+
+	if g := opts.NFLOGGroup; g != nil {
+		if *g < 0 || *g > 65535 || len(prefix) > MaxNFLOGPrefix+1 {
+			return nil
+		}
+		return append(args, "-j", "NFLOG", "--nflog-group", strconv.Itoa(*g), "--nflog-prefix", quoteComment(prefix))
+	}
+
+	level := models.LogLevels["warning"]
+	if opts.Level != "" {
+		var ok bool
+		if level, ok = models.LogLevels[opts.Level]; !ok {
+			return nil
+		}
+	}
+	if len(prefix) > MaxLogPrefix+1 {
+		return nil
+	}
+	return append(args, "-j", "LOG", "--log-prefix", quoteComment(prefix), "--log-level", strconv.Itoa(level))
 }
 
 // parseCounters extracts [packets:bytes] counters from iptables-save output.
@@ -571,10 +616,16 @@ func (d *IptablesDriver) parseCounters(raw string) []*models.Counter {
 	var counters []*models.Counter
 	lines := strings.Split(raw, "\n")
 
+	var currentTable string
 	var currentChain models.Chain
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 
+		if strings.HasPrefix(line, "*") {
+			currentTable = line[1:]
+			continue
+		}
+
 		// Chain policy line: ":INPUT ACCEPT [1234:56789]"
 		if strings.HasPrefix(line, ":") {
 			fields := strings.Fields(line)
@@ -583,6 +634,7 @@ func (d *IptablesDriver) parseCounters(raw string) []*models.Counter {
 				currentChain = models.Chain(chainName)
 				pkts, bytes_ := parseCounterBracket(fields[2])
 				counters = append(counters, &models.Counter{
+					Table:   currentTable,
 					Chain:   currentChain,
 					Rule:    "policy",
 					Packets: pkts,
@@ -597,9 +649,16 @@ func (d *IptablesDriver) parseCounters(raw string) []*models.Counter {
 			bracket := line[:strings.Index(line, "]")+1]
 			rest := strings.TrimSpace(line[len(bracket):])
 			pkts, bytes_ := parseCounterBracket(bracket)
+			args := splitRuleArgs(rest)
+			chain := currentChain
+			if len(args) >= 2 && args[0] == "-A" {
+				chain = models.Chain(args[1])
+			}
 			counters = append(counters, &models.Counter{
-				Chain:   currentChain,
+				Table:   currentTable,
+				Chain:   chain,
 				Rule:    rest,
+				Tag:     lineTag(args),
 				Packets: pkts,
 				Bytes:   bytes_,
 			})
@@ -620,6 +679,65 @@ func parseCounterBracket(s string) (uint64, uint64) {
 	return pkts, bytes_
 }
 
+// timeMatchArgs renders a kernel-mode schedule as an xt_time match.
+// It returns nil if any schedule field fails validation.
+func timeMatchArgs(s *models.Schedule) []string {
+	args := []string{"-m", "time"}
+
+	start, stop := s.StartTime, s.EndTime
+	if start == "" {
+		start = "00:00"
+	}
+	if stop == "" {
+		stop = "23:59:59"
+	}
+	if sanitizeClock(start) == "" || sanitizeClock(stop) == "" {
+		return nil
+	}
+	args = append(args, "--timestart", start, "--timestop", stop)
+
+	if len(s.Days) > 0 {
+		var days []string
+		for _, day := range s.Days {
+			wd, ok := weekdayNames[strings.ToLower(day)]
+			if !ok {
+				return nil
+			}
+			days = append(days, wd)
+		}
+		args = append(args, "--weekdays", strings.Join(days, ","))
+	}
+
+	// A window that ends before it starts runs past midnight; --contiguous
+	// keeps the weekday check tied to the day the window opened.
+	if s.EndTime != "" && s.StartTime > s.EndTime {
+		args = append(args, "--contiguous")
+	}
+
+	if s.StartDate != "" {
+		if sanitizeDate(s.StartDate) == "" {
+			return nil
+		}
+		args = append(args, "--datestart", s.StartDate+"T00:00:00")
+	}
+	if s.EndDate != "" {
+		if sanitizeDate(s.EndDate) == "" {
+			return nil
+		}
+		args = append(args, "--datestop", s.EndDate+"T23:59:59")
+	}
+
+	switch s.Timezone {
+	case "", "UTC":
+	case "Local":
+		args = append(args, "--kerneltz")
+	default:
+		return nil
+	}
+
+	return args
+}
+
 // --- Sanitization helpers ---
 // These functions ensure only allowlisted values reach the iptables command.
 
@@ -660,6 +778,19 @@ func sanitizeCIDR(s string) string {
 	return s
 }
 
+// matchAddr returns the -s/-d value for s, or "" if the match is left
+// out: when s is invalid, or a /0 network that matches every address.
+// iptables-save omits a /0 match, so rendering it would never verify.
+func matchAddr(s string) string {
+	s = sanitizeCIDR(s)
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		if ones, _ := n.Mask.Size(); ones == 0 {
+			return ""
+		}
+	}
+	return s
+}
+
 // sanitizePort validates port numbers and ranges like "80" or "1024:65535".
 func sanitizePort(s string) string {
 	if s == "" {
@@ -691,6 +822,75 @@ func sanitizeComment(s string) string {
 	return result
 }
 
+// MaxLogPrefix and MaxNFLOGPrefix are the longest log prefixes a rule may
+// set. The kernel allows one character more, which is the separating space.
+const (
+	MaxLogPrefix   = 28
+	MaxNFLOGPrefix = 62
+)
+
+// ValidLogPrefix reports whether a log prefix is safe to render: letters,
+// digits, spaces and "-_.:/" only.
+func ValidLogPrefix(s string) bool {
+	if strings.TrimSpace(s) == "" {
+		return false
+	}
+	for _, c := range s {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			c == ' ' || c == '-' || c == '_' || c == '.' || c == ':' || c == '/') {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeRate validates a limit match rate like "5/minute".
+func sanitizeRate(s string) string {
+	n, unit, ok := strings.Cut(s, "/")
+	if !ok {
+		return ""
+	}
+	if v, err := strconv.ParseUint(n, 10, 32); err != nil || v == 0 || v > 9999 {
+		return ""
+	}
+	switch unit {
+	case "second", "minute", "hour", "day":
+		return s
+	}
+	return ""
+}
+
+// quoteComment wraps a sanitized comment in double quotes so iptables-restore
+// reads a comment with spaces as one argument. sanitizeComment never lets a
+// quote or backslash through.
+func quoteComment(s string) string {
+	return `"` + s + `"`
+}
+
+var weekdayNames = map[string]string{
+	"mon": "Mon", "tue": "Tue", "wed": "Wed", "thu": "Thu",
+	"fri": "Fri", "sat": "Sat", "sun": "Sun",
+}
+
+// sanitizeClock validates a time of day like "08:30" or "23:59:59".
+func sanitizeClock(s string) string {
+	if _, err := time.Parse("15:04", s); err == nil {
+		return s
+	}
+	if _, err := time.Parse("15:04:05", s); err == nil {
+		return s
+	}
+	return ""
+}
+
+// sanitizeDate validates a calendar date like "2024-12-31".
+func sanitizeDate(s string) string {
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return ""
+	}
+	return s
+}
+
 // sanitizeInterface validates interface names (eth0, wlan0, etc.)
 func sanitizeInterface(s string) string {
 	if s == "" {
@@ -0,0 +1,49 @@
+package firewall
+
+import (
+	"fmt"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/vishvananda/netlink"
+)
+
+// GetLinkCounters reads the kernel's stats64 of every interface with a
+// single RTM_GETLINK dump.
+func (d *IptablesDriver) GetLinkCounters() (map[string]*models.InterfaceCounters, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	counters := make(map[string]*models.InterfaceCounters, len(links))
+	for _, link := range links {
+		attrs := link.Attrs()
+		counters[attrs.Name] = linkCounters(attrs.Statistics)
+	}
+	return counters, nil
+}
+
+// GetInterfaceCounters returns the stats64 of a specific interface.
+func (d *IptablesDriver) GetInterfaceCounters(iface string) (*models.InterfaceCounters, error) {
+	sIface := sanitizeInterface(iface)
+	if sIface == "" {
+		return nil, fmt.Errorf("invalid interface name provided")
+	}
+	link, err := netlink.LinkByName(sIface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find link %s: %w", sIface, err)
+	}
+	return linkCounters(link.Attrs().Statistics), nil
+}
+
+// linkCounters maps link statistics to received, transmitted and dropped
+// traffic. The kernel counts drops in packets only.
+func linkCounters(stats *netlink.LinkStatistics) *models.InterfaceCounters {
+	if stats == nil {
+		return &models.InterfaceCounters{}
+	}
+	return &models.InterfaceCounters{
+		In:   models.CounterStats{Packets: stats.RxPackets, Bytes: stats.RxBytes},
+		Out:  models.CounterStats{Packets: stats.TxPackets, Bytes: stats.TxBytes},
+		Drop: models.CounterStats{Packets: stats.RxDropped + stats.TxDropped},
+	}
+}
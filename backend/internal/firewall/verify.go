@@ -0,0 +1,488 @@
+package firewall
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// Verify reads the live ruleset back and checks it against what Apply,
+// ApplyNAT and ApplyConfig render from the same inputs: every rule present,
+// in order, in the right table and chain, plus the chain policies and IP
+// forwarding. natRules and config are skipped when nil, so callers only
+// verify what they applied. Mismatches are reported, not returned as errors.
+func (d *IptablesDriver) Verify(rules []*models.Rule, natRules []*models.NATRule, config *models.FirewallConfig) (*models.VerificationReport, error) {
+	raw, err := d.Load()
+	if err != nil {
+		return nil, fmt.Errorf("read live ruleset: %w", err)
+	}
+	live := parseSaveTables(raw)
+	report := &models.VerificationReport{OK: true, CheckedAt: time.Now().UTC()}
+
+	verifyTable(report, "filter", parseSaveTables(d.buildRuleset(rules))["filter"], live["filter"], true)
+	if natRules != nil {
+		// An empty NAT ruleset is applied by flushing the chains, which
+		// leaves their policies as they were.
+		verifyTable(report, "nat", parseSaveTables(d.buildNATRuleset(natRules))["nat"], live["nat"], len(natRules) > 0)
+	}
+
+	if config != nil {
+		want := "0"
+		if config.IPForwarding {
+			want = "1"
+		}
+		got, err := sysctlValue("net.ipv4.ip_forward")
+		if err != nil {
+			return nil, err
+		}
+		report.Sysctls++
+		if got != want {
+			report.Mismatch(models.VerificationMismatch{
+				Table:    "sysctl",
+				Expected: "net.ipv4.ip_forward=" + want,
+				Actual:   "net.ipv4.ip_forward=" + got,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// verifyTable compares the built-in chains of a rendered table with the live
+// one. Chains the live table has in addition are not checked.
+func verifyTable(report *models.VerificationReport, table string, want, got *savedTable, policies bool) {
+	if want == nil {
+		return
+	}
+	if got == nil {
+		// The table is missing entirely, e.g. its kernel module is not loaded.
+		got = newSavedTable()
+	}
+	for _, chain := range want.chains {
+		if policies && want.policies[chain] != "-" {
+			report.Policies++
+			if got.policies[chain] != want.policies[chain] {
+				report.Mismatch(models.VerificationMismatch{
+					Table: table, Chain: chain,
+					Expected: "policy " + want.policies[chain],
+					Actual:   "policy " + got.policies[chain],
+				})
+			}
+		}
+
+		exp, act := want.rules[chain], got.rules[chain]
+		report.Rules += len(exp)
+		for i := 0; i < len(exp) || i < len(act); i++ {
+			m := models.VerificationMismatch{Table: table, Chain: chain, Position: i + 1}
+			if i < len(exp) {
+				m.Expected = exp[i]
+			}
+			if i < len(act) {
+				m.Actual = act[i]
+			}
+			if m.Expected != m.Actual {
+				report.Mismatch(m)
+			}
+		}
+	}
+}
+
+// savedTable is one table of iptables-save output with its rules in
+// canonical form.
+type savedTable struct {
+	chains   []string            // in declaration order
+	policies map[string]string   // "-" for user-defined chains
+	rules    map[string][]string // per chain, in order
+}
+
+func newSavedTable() *savedTable {
+	return &savedTable{policies: make(map[string]string), rules: make(map[string][]string)}
+}
+
+// parseSaveTables splits iptables-save (or iptables-restore input) text into
+// tables. Counter brackets are ignored.
+func parseSaveTables(raw string) map[string]*savedTable {
+	tables := make(map[string]*savedTable)
+	var cur *savedTable
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "*"):
+			cur = newSavedTable()
+			tables[line[1:]] = cur
+		case cur == nil:
+		case line == "COMMIT":
+			cur = nil
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(line[1:])
+			if len(fields) >= 2 {
+				cur.chains = append(cur.chains, fields[0])
+				cur.policies[fields[0]] = fields[1]
+			}
+		default:
+			if strings.HasPrefix(line, "[") {
+				if end := strings.Index(line, "]"); end >= 0 {
+					line = strings.TrimSpace(line[end+1:])
+				}
+			}
+			if chain, rule, ok := canonicalRule(line); ok {
+				cur.rules[chain] = append(cur.rules[chain], rule)
+			}
+		}
+	}
+	return tables
+}
+
+// canonicalRule rewrites an "-A CHAIN ..." line into a form that is the same
+// for the line the driver rendered and the line iptables-save prints for the
+// loaded rule. iptables-save reorders the base matches, adds the implicit
+// protocol module ("-m tcp"), masks and suffixes addresses ("/32"), quotes
+// comments and prints some defaults, so the two cannot be compared as text.
+func canonicalRule(line string) (chain, rule string, ok bool) {
+	args := splitRuleArgs(line)
+	if len(args) < 2 || args[0] != "-A" {
+		return "", "", false
+	}
+	chain = args[1]
+
+	var base []ruleOption
+	var proto string
+	var modules []*ruleBlock
+	var target *ruleBlock
+	byName := make(map[string]*ruleBlock)
+	module := func(name string) *ruleBlock {
+		if b, ok := byName[name]; ok {
+			return b
+		}
+		b := &ruleBlock{name: name}
+		byName[name] = b
+		modules = append(modules, b)
+		return b
+	}
+
+	var cur *ruleBlock
+	negate := false
+	for i := 2; i < len(args); i++ {
+		arg := args[i]
+		if arg == "!" {
+			negate = true
+			continue
+		}
+		key := arg
+		if negate {
+			key = "! " + arg
+			negate = false
+		}
+		var values []string
+		if (arg == "--comment" || arg == "--log-prefix" || arg == "--nflog-prefix") && i+1 < len(args) {
+			// A comment or log prefix is one argument, even if it starts
+			// with "-".
+			i++
+			values = append(values, args[i])
+		}
+		for i+1 < len(args) && args[i+1] != "!" && !strings.HasPrefix(args[i+1], "-") {
+			i++
+			values = append(values, args[i])
+		}
+
+		switch arg {
+		case "-m", "--match":
+			if len(values) > 0 {
+				cur = module(values[0])
+			}
+		case "-j", "--jump":
+			if len(values) > 0 {
+				target = &ruleBlock{name: values[0]}
+				cur = target
+			}
+		case "-p", "--protocol", "-s", "--source", "-d", "--destination",
+			"-i", "--in-interface", "-o", "--out-interface":
+			short := shortBaseOption(arg)
+			if short == "-p" && len(values) > 0 {
+				proto = values[0]
+			}
+			if short == "-s" || short == "-d" {
+				for j, v := range values {
+					values[j] = canonicalAddr(v)
+				}
+				// iptables-save omits a match on every address.
+				if key == arg && len(values) == 1 && (values[0] == "0.0.0.0/0" || values[0] == "::/0") {
+					continue
+				}
+			}
+			base = append(base, ruleOption{key: strings.Replace(key, arg, short, 1), values: values})
+		default:
+			b := cur
+			if b == nil {
+				// Port matches rendered without "-m tcp" belong to the
+				// protocol's implicit module.
+				b = module(proto)
+			}
+			b.opts = append(b.opts, ruleOption{key: key, values: values})
+		}
+	}
+
+	order := map[string]int{"-i": 0, "-o": 1, "-p": 2, "-s": 3, "-d": 4}
+	sort.SliceStable(base, func(a, b int) bool {
+		return order[strings.TrimPrefix(base[a].key, "! ")] < order[strings.TrimPrefix(base[b].key, "! ")]
+	})
+	sort.SliceStable(modules, func(a, b int) bool { return modules[a].name < modules[b].name })
+
+	var parts []string
+	for _, o := range base {
+		parts = append(parts, o.key)
+		parts = append(parts, o.values...)
+	}
+	render := func(b *ruleBlock, flag string) {
+		opts := dropDefaultOptions(b.name, canonicalOptions(b.opts))
+		if flag == "-m" && len(opts) == 0 && b.name == proto {
+			return
+		}
+		sort.SliceStable(opts, func(i, j int) bool { return opts[i][0] < opts[j][0] })
+		parts = append(parts, flag, b.name)
+		for _, o := range opts {
+			parts = append(parts, o...)
+		}
+	}
+	for _, b := range modules {
+		render(b, "-m")
+	}
+	if target != nil {
+		render(target, "-j")
+	}
+	return chain, strings.Join(parts, " "), true
+}
+
+// ruleOption is one option of a rule line with its values; key carries a
+// leading "! " when negated.
+type ruleOption struct {
+	key    string
+	values []string
+}
+
+// ruleBlock is a match module or the target with its options.
+type ruleBlock struct {
+	name string
+	opts []ruleOption
+}
+
+// canonicalOptions normalizes option values that iptables-save prints in a
+// different format than the driver renders. Each result is key + values.
+func canonicalOptions(opts []ruleOption) [][]string {
+	var out [][]string
+	for _, o := range opts {
+		values := append([]string{}, o.values...)
+		switch o.key {
+		case "--comment", "--log-prefix", "--nflog-prefix":
+			for i, v := range values {
+				if strings.ContainsAny(v, " \t") {
+					values[i] = strconv.Quote(v)
+				}
+			}
+		case "--timestart", "--timestop":
+			for i, v := range values {
+				if strings.Count(v, ":") == 1 {
+					values[i] = v + ":00"
+				}
+			}
+		case "--weekdays":
+			for i, v := range values {
+				values[i] = canonicalWeekdays(v)
+			}
+		case "--limit":
+			for i, v := range values {
+				values[i] = canonicalRate(v)
+			}
+		}
+		out = append(out, append([]string{o.key}, values...))
+	}
+	return out
+}
+
+// dropDefaultOptions removes options that are in effect either way but that
+// only one of the two sides prints.
+func dropDefaultOptions(name string, opts [][]string) [][]string {
+	// xt_time omits the time of day when the window spans the whole day.
+	wholeDay := false
+	if name == "time" {
+		var start, stop string
+		for _, o := range opts {
+			if len(o) == 2 && o[0] == "--timestart" {
+				start = o[1]
+			}
+			if len(o) == 2 && o[0] == "--timestop" {
+				stop = o[1]
+			}
+		}
+		wholeDay = start == "00:00:00" && stop == "23:59:59"
+	}
+
+	kept := opts[:0]
+	for _, o := range opts {
+		switch {
+		case name == "REJECT" && len(o) == 2 && o[0] == "--reject-with" && o[1] == "icmp-port-unreachable":
+		case name == "time" && len(o) == 2 && o[0] == "--weekdays" && o[1] == "Mon,Tue,Wed,Thu,Fri,Sat,Sun":
+		case name == "limit" && len(o) == 2 && o[0] == "--limit-burst" && o[1] == "5":
+		case name == "LOG" && len(o) == 2 && o[0] == "--log-level" && o[1] == "4":
+		case name == "NFLOG" && len(o) == 2 && o[0] == "--nflog-group" && o[1] == "0":
+		case wholeDay && (o[0] == "--timestart" || o[0] == "--timestop"):
+		default:
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+func shortBaseOption(opt string) string {
+	switch opt {
+	case "--protocol":
+		return "-p"
+	case "--source":
+		return "-s"
+	case "--destination":
+		return "-d"
+	case "--in-interface":
+		return "-i"
+	case "--out-interface":
+		return "-o"
+	}
+	return opt
+}
+
+// canonicalAddr writes an address or network the way iptables-save does:
+// masked network address with an explicit prefix length.
+func canonicalAddr(s string) string {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n.String()
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			return ip.String() + "/32"
+		}
+		return ip.String() + "/128"
+	}
+	return s
+}
+
+// limitRates are the units of the limit match, largest first, with the
+// length of each in the kernel's 1/10000 s ticks.
+var limitRates = []struct {
+	name string
+	mult uint64
+}{
+	{"day", 10000 * 24 * 60 * 60},
+	{"hour", 10000 * 60 * 60},
+	{"min", 10000 * 60},
+	{"sec", 10000},
+}
+
+// canonicalRate writes a limit rate the way iptables-save does. The kernel
+// keeps the interval between packets rather than the rate, so "60/minute"
+// comes back as "1/sec".
+func canonicalRate(s string) string {
+	n, unit, ok := strings.Cut(s, "/")
+	r, err := strconv.ParseUint(n, 10, 32)
+	if err != nil || r == 0 {
+		return s
+	}
+	mult := uint64(0)
+	if !ok {
+		mult = 10000
+	}
+	for _, u := range []struct {
+		word string
+		secs uint64
+	}{{"second", 1}, {"minute", 60}, {"hour", 60 * 60}, {"day", 24 * 60 * 60}} {
+		if unit != "" && strings.HasPrefix(u.word, strings.ToLower(unit)) {
+			mult = 10000 * u.secs
+			break
+		}
+	}
+	period := mult / r
+	if period == 0 {
+		return s
+	}
+	i := 1
+	for ; i < len(limitRates); i++ {
+		if period > limitRates[i].mult || limitRates[i].mult/period < limitRates[i].mult%period {
+			break
+		}
+	}
+	return strconv.FormatUint(limitRates[i-1].mult/period, 10) + "/" + limitRates[i-1].name
+}
+
+// canonicalWeekdays orders a --weekdays list Monday first.
+func canonicalWeekdays(s string) string {
+	index := map[string]int{"Mon": 0, "Tue": 1, "Wed": 2, "Thu": 3, "Fri": 4, "Sat": 5, "Sun": 6}
+	days := strings.Split(s, ",")
+	sort.SliceStable(days, func(a, b int) bool { return index[days[a]] < index[days[b]] })
+	return strings.Join(days, ",")
+}
+
+// splitRuleArgs splits a rule line into arguments the way iptables-restore
+// does: on whitespace, with double quotes grouping and backslash escaping.
+// "--opt=value" is split into two arguments.
+func splitRuleArgs(line string) []string {
+	var args []string
+	var cur strings.Builder
+	inArg, quoted, escaped := false, false, false
+	flush := func() {
+		if inArg {
+			args = append(args, cur.String())
+		}
+		cur.Reset()
+		inArg = false
+	}
+	for _, c := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped, inArg = true, true
+		case c == '"':
+			quoted, inArg = !quoted, true
+		case (c == ' ' || c == '\t') && !quoted:
+			flush()
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	flush()
+
+	var out []string
+	for _, a := range args {
+		if key, value, ok := strings.Cut(a, "="); ok && strings.HasPrefix(key, "--") {
+			out = append(out, key, value)
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// sysctlValue reads a kernel setting with sysctl -n.
+func sysctlValue(key string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/sbin/sysctl", "-n", key)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("sysctl -n %s failed: %w — stderr: %s", key, err, stderr.String())
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,398 @@
+package firewall
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestDriver() *IptablesDriver {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewIptablesDriver(log)
+}
+
+// savedFilter wraps rule lines the way iptables-save -c prints the filter
+// table the driver renders.
+func savedFilter(lines ...string) string {
+	return "# Generated by iptables-save v1.8.7 on Sun Oct 18 12:00:00 2026\n" +
+		"*filter\n" +
+		":INPUT ACCEPT [120:9600]\n" +
+		":FORWARD DROP [0:0]\n" +
+		":OUTPUT ACCEPT [80:7200]\n" +
+		strings.Join(lines, "\n") + "\n" +
+		"COMMIT\n" +
+		"# Completed on Sun Oct 18 12:00:00 2026\n"
+}
+
+// verifyFilter checks the filter table rendered from rules against saved.
+func verifyFilter(rules []*models.Rule, saved string) *models.VerificationReport {
+	d := newTestDriver()
+	report := &models.VerificationReport{OK: true}
+	verifyTable(report, "filter", parseSaveTables(d.buildRuleset(rules))["filter"], parseSaveTables(saved)["filter"], true)
+	return report
+}
+
+func TestVerifyMatchesIptablesSave(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  *models.Rule
+		saved string
+	}{
+		{
+			name:  "any source",
+			rule:  &models.Rule{ID: "r1", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, Src: "0.0.0.0/0", DstPort: "22", Action: models.ActionACCEPT, Enabled: true},
+			saved: `[3:180] -A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+		},
+		{
+			name:  "any destination",
+			rule:  &models.Rule{ID: "r2", Chain: models.ChainOUTPUT, Protocol: models.ProtocolAll, Src: "10.0.0.0/8", Dst: "0.0.0.0/0", Action: models.ActionDROP, Enabled: true},
+			saved: `[0:0] -A OUTPUT -s 10.0.0.0/8 -m comment --comment "fwmg:db77fd01af95" -j DROP`,
+		},
+		{
+			name: "kernel schedule in local time",
+			rule: &models.Rule{ID: "r4", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "22", Action: models.ActionACCEPT, Enabled: true,
+				Schedule: &models.Schedule{Mode: models.ScheduleModeKernel, Days: []string{"mon", "tue", "wed", "thu", "fri"}, StartTime: "08:00", EndTime: "18:00", Timezone: "Local"}},
+			saved: `[0:0] -A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:a2ec8adac7fd" -m time --timestart 08:00:00 --timestop 18:00:00 --weekdays Mon,Tue,Wed,Thu,Fri --kerneltz -j ACCEPT`,
+		},
+		{
+			name: "overnight weekend window with dates",
+			rule: &models.Rule{ID: "r5", Chain: models.ChainINPUT, Protocol: models.ProtocolAll, Action: models.ActionDROP, Enabled: true,
+				Schedule: &models.Schedule{Mode: models.ScheduleModeKernel, Days: []string{"sun", "sat"}, StartTime: "22:00", EndTime: "06:00", StartDate: "2026-10-01", EndDate: "2026-12-31"}},
+			saved: `[0:0] -A INPUT -m comment --comment "fwmg:5eb242aeb685" -m time --timestart 22:00:00 --timestop 06:00:00 --weekdays Sat,Sun --datestart 2026-10-01T00:00:00 --datestop 2026-12-31T23:59:59 --contiguous -j DROP`,
+		},
+		{
+			name: "whole days",
+			rule: &models.Rule{ID: "r1", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "80", Action: models.ActionDROP, Enabled: true,
+				Schedule: &models.Schedule{Mode: models.ScheduleModeKernel, Days: []string{"sat", "sun"}}},
+			saved: `[0:0] -A INPUT -p tcp -m tcp --dport 80 -m comment --comment "fwmg:82f3e9c695dc" -m time --weekdays Sat,Sun -j DROP`,
+		},
+		{
+			name:  "LOG with default level",
+			rule:  &models.Rule{ID: "r6", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "23", Action: models.ActionLOG, Enabled: true},
+			saved: `[7:420] -A INPUT -p tcp -m tcp --dport 23 -m comment --comment "fwmg:25f1c790f16f" -j LOG --log-prefix "fwmg:25f1c790f16f "`,
+		},
+		{
+			name: "drop logged with a limit",
+			rule: &models.Rule{ID: "r7", Chain: models.ChainINPUT, Protocol: models.ProtocolAll, Src: "203.0.113.7/24", Action: models.ActionDROP, Enabled: true, Comment: "block scanners",
+				Log: &models.LogOptions{Limit: "60/minute", Burst: 5}},
+			saved: `[5:300] -A INPUT -s 203.0.113.0/24 -m comment --comment "fwmg:dbb7b294e78f/log" -m limit --limit 1/sec -j LOG --log-prefix "fwmg:dbb7b294e78f "
+[5:300] -A INPUT -s 203.0.113.0/24 -m comment --comment "fwmg:dbb7b294e78f block scanners" -j DROP`,
+		},
+		{
+			name: "reject logged to NFLOG group 0",
+			rule: &models.Rule{ID: "r8", Chain: models.ChainINPUT, Protocol: models.ProtocolUDP, DstPort: "161", Action: models.ActionREJECT, Enabled: true,
+				Log: &models.LogOptions{Prefix: "snmp-deny", NFLOGGroup: new(int)}},
+			saved: `[2:152] -A INPUT -p udp -m udp --dport 161 -m comment --comment "fwmg:99c66b8eedd6/log" -j NFLOG --nflog-prefix "snmp-deny "
+[2:152] -A INPUT -p udp -m udp --dport 161 -m comment --comment "fwmg:99c66b8eedd6" -j REJECT --reject-with icmp-port-unreachable`,
+		},
+		{
+			name: "limit in another unit and level",
+			rule: &models.Rule{ID: "r9", Chain: models.ChainOUTPUT, Protocol: models.ProtocolTCP, DstPort: "25", Action: models.ActionLOG, Enabled: true,
+				Log: &models.LogOptions{Limit: "120/minute", Burst: 20, Level: "info"}},
+			saved: `[0:0] -A OUTPUT -p tcp -m tcp --dport 25 -m comment --comment "fwmg:794d9d1a3026" -m limit --limit 2/sec --limit-burst 20 -j LOG --log-prefix "fwmg:794d9d1a3026 " --log-level 6`,
+		},
+		{
+			name:  "interfaces",
+			rule:  &models.Rule{ID: "r3", Chain: models.ChainFORWARD, Protocol: models.ProtocolUDP, InInterface: "eth1", OutInterface: "wg+", DstPort: "53", Action: models.ActionACCEPT, Enabled: true},
+			saved: `[12:840] -A FORWARD -i eth1 -o wg+ -p udp -m udp --dport 53 -m comment --comment "fwmg:e49d63b2a8a7" -j ACCEPT`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := verifyFilter([]*models.Rule{tt.rule}, savedFilter(strings.Split(tt.saved, "\n")...))
+			if !report.OK {
+				t.Errorf("mismatches: %+v", report.Mismatches)
+			}
+		})
+	}
+}
+
+func TestMatchAddr(t *testing.T) {
+	tests := map[string]string{
+		"":                "",
+		"10.0.0.1":        "10.0.0.1",
+		"10.0.0.0/8":      "10.0.0.0/8",
+		"0.0.0.0/0":       "",
+		"192.168.1.0/0":   "",
+		"::/0":            "",
+		"2001:db8::/32":   "2001:db8::/32",
+		"10.0.0.1;reboot": "",
+	}
+	for in, want := range tests {
+		if got := matchAddr(in); got != want {
+			t.Errorf("matchAddr(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCanonicalRuleDropsAnyAddress(t *testing.T) {
+	_, got, _ := canonicalRule(`-A INPUT -s 0.0.0.0/0 -d 10.0.0.1 -j ACCEPT`)
+	_, want, _ := canonicalRule(`-A INPUT -d 10.0.0.1/32 -j ACCEPT`)
+	if got != want {
+		t.Errorf("canonical form %q, want %q", got, want)
+	}
+	// A negated match on every address matches nothing and is kept.
+	if _, neg, _ := canonicalRule(`-A INPUT ! -s 0.0.0.0/0 -j ACCEPT`); !strings.Contains(neg, "! -s 0.0.0.0/0") {
+		t.Errorf("negated any-address match dropped: %q", neg)
+	}
+}
+
+func TestVerifyReportsMismatches(t *testing.T) {
+	rules := []*models.Rule{
+		{ID: "r1", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "22", Action: models.ActionACCEPT, Enabled: true},
+		{ID: "r2", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "80", Action: models.ActionACCEPT, Enabled: true},
+	}
+	// r2 is missing, a stray rule took its place and FORWARD accepts.
+	saved := strings.Replace(savedFilter(
+		`[3:180] -A INPUT -p tcp -m tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+		`[0:0] -A INPUT -p tcp -m tcp --dport 8080 -j ACCEPT`,
+	), ":FORWARD DROP", ":FORWARD ACCEPT", 1)
+
+	report := verifyFilter(rules, saved)
+	if report.OK {
+		t.Fatal("report is OK")
+	}
+	if len(report.Mismatches) != 2 {
+		t.Fatalf("mismatches: %+v", report.Mismatches)
+	}
+	if m := report.Mismatches[0]; m.Chain != "INPUT" || m.Position != 2 || !strings.Contains(m.Expected, "fwmg:db77fd01af95") || !strings.Contains(m.Actual, "8080") {
+		t.Errorf("rule mismatch %+v", m)
+	}
+	if m := report.Mismatches[1]; m.Chain != "FORWARD" || m.Expected != "policy DROP" || m.Actual != "policy ACCEPT" {
+		t.Errorf("policy mismatch %+v", m)
+	}
+}
+
+func TestCanonicalRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rendered string
+		saved    string
+		equal    bool
+	}{
+		{
+			"implicit protocol module and host prefix",
+			`-A INPUT -p tcp -d 10.0.0.1 --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			`-A INPUT -d 10.0.0.1/32 -p tcp -m tcp --dport 22 -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			true,
+		},
+		{
+			"long options and unmasked network",
+			`-A FORWARD --protocol udp --source 10.1.2.3/8 --in-interface eth0 --out-interface wg+ --sport 1024:65535 -j DROP`,
+			`-A FORWARD -s 10.0.0.0/8 -i eth0 -o wg+ -p udp -m udp --sport 1024:65535 -j DROP`,
+			true,
+		},
+		{
+			"option=value",
+			`-A INPUT -p tcp --dport=443 -j ACCEPT`,
+			`-A INPUT -p tcp -m tcp --dport 443 -j ACCEPT`,
+			true,
+		},
+		{
+			"negated source",
+			`-A INPUT ! -s 192.168.0.0/16 -j DROP`,
+			`-A INPUT -s 192.168.0.0/16 -j DROP`,
+			false,
+		},
+		{
+			"comment with spaces",
+			`-A INPUT -m comment --comment "fwmg:82f3e9c695dc allow ssh from office" -j ACCEPT`,
+			`-A INPUT -m comment --comment "fwmg:82f3e9c695dc allow ssh from office" -j ACCEPT`,
+			true,
+		},
+		{
+			"comment with escaped quotes",
+			`-A INPUT -m comment --comment "it's \"ours\"" -j ACCEPT`,
+			`-A INPUT -m comment --comment "it\'s \"ours\"" -j ACCEPT`,
+			true,
+		},
+		{
+			"comment starting with a dash",
+			`-A INPUT -m comment --comment "-- legacy" -j ACCEPT`,
+			`-A INPUT -m comment --comment "-- legacy" -j ACCEPT`,
+			true,
+		},
+		{
+			"different comment",
+			`-A INPUT -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			`-A INPUT -m comment --comment "fwmg:db77fd01af95" -j ACCEPT`,
+			false,
+		},
+		{
+			"time of day seconds and weekday order",
+			`-A INPUT -m time --timestart 08:00 --timestop 18:30 --weekdays Fri,Mon,Wed --kerneltz -j ACCEPT`,
+			`-A INPUT -m time --timestart 08:00:00 --timestop 18:30:00 --weekdays Mon,Wed,Fri --kerneltz -j ACCEPT`,
+			true,
+		},
+		{
+			"kerneltz lost",
+			`-A INPUT -m time --timestart 08:00 --timestop 18:00 --kerneltz -j ACCEPT`,
+			`-A INPUT -m time --timestart 08:00:00 --timestop 18:00:00 -j ACCEPT`,
+			false,
+		},
+		{
+			"contiguous and kerneltz in save order",
+			`-A INPUT -m time --timestart 22:00 --timestop 06:00 --contiguous --kerneltz -j DROP`,
+			`-A INPUT -m time --timestart 22:00:00 --timestop 06:00:00 --kerneltz --contiguous -j DROP`,
+			true,
+		},
+		{
+			"whole day omitted",
+			`-A INPUT -m time --timestart 00:00 --timestop 23:59:59 --weekdays Sat,Sun -j DROP`,
+			`-A INPUT -m time --weekdays Sat,Sun -j DROP`,
+			true,
+		},
+		{
+			"every weekday omitted",
+			`-A INPUT -m time --timestart 08:00 --timestop 18:00 --weekdays Mon,Tue,Wed,Thu,Fri,Sat,Sun -j ACCEPT`,
+			`-A INPUT -m time --timestart 08:00:00 --timestop 18:00:00 -j ACCEPT`,
+			true,
+		},
+		{
+			"default reject type",
+			`-A INPUT -p udp --dport 161 -j REJECT`,
+			`-A INPUT -p udp -m udp --dport 161 -j REJECT --reject-with icmp-port-unreachable`,
+			true,
+		},
+		{
+			"other reject type",
+			`-A INPUT -p tcp --dport 113 -j REJECT`,
+			`-A INPUT -p tcp -m tcp --dport 113 -j REJECT --reject-with tcp-reset`,
+			false,
+		},
+		{
+			"LOG level 4 omitted",
+			`-A INPUT -j LOG --log-prefix "fwmg:82f3e9c695dc " --log-level 4`,
+			`-A INPUT -j LOG --log-prefix "fwmg:82f3e9c695dc "`,
+			true,
+		},
+		{
+			"LOG level 6 kept",
+			`-A INPUT -j LOG --log-prefix "fwmg:82f3e9c695dc " --log-level 6`,
+			`-A INPUT -j LOG --log-prefix "fwmg:82f3e9c695dc "`,
+			false,
+		},
+		{
+			"LOG prefix trailing space",
+			`-A INPUT -j LOG --log-prefix "fwmg:82f3e9c695dc "`,
+			`-A INPUT -j LOG --log-prefix fwmg:82f3e9c695dc`,
+			false,
+		},
+		{
+			"NFLOG group 0 omitted",
+			`-A INPUT -j NFLOG --nflog-group 0 --nflog-prefix "ssh-deny "`,
+			`-A INPUT -j NFLOG --nflog-prefix "ssh-deny "`,
+			true,
+		},
+		{
+			"NFLOG group 5",
+			`-A INPUT -j NFLOG --nflog-group 5 --nflog-prefix "ssh-deny "`,
+			`-A INPUT -j NFLOG --nflog-prefix "ssh-deny " --nflog-group 5`,
+			true,
+		},
+		{
+			"limit unit and default burst",
+			`-A INPUT -m limit --limit 60/minute --limit-burst 5 -j LOG --log-prefix "x "`,
+			`-A INPUT -m limit --limit 1/sec -j LOG --log-prefix "x "`,
+			true,
+		},
+		{
+			"limit burst kept",
+			`-A INPUT -m limit --limit 60/minute --limit-burst 10 -j LOG --log-prefix "x "`,
+			`-A INPUT -m limit --limit 1/sec -j LOG --log-prefix "x "`,
+			false,
+		},
+		{
+			"match order",
+			`-A INPUT -m comment --comment "fwmg:82f3e9c695dc" -m time --kerneltz --timestart 08:00 --timestop 09:00 -m limit --limit 1/second -j ACCEPT`,
+			`-A INPUT -m limit --limit 1/sec -m time --timestart 08:00:00 --timestop 09:00:00 --kerneltz -m comment --comment "fwmg:82f3e9c695dc" -j ACCEPT`,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, r, ok1 := canonicalRule(tt.rendered)
+			sc, s, ok2 := canonicalRule(tt.saved)
+			if !ok1 || !ok2 {
+				t.Fatalf("not parsed as rules: %v %v", ok1, ok2)
+			}
+			if (rc == sc && r == s) != tt.equal {
+				t.Errorf("equal = %v, want %v\nrendered: %s %s\nsaved:    %s %s", !tt.equal, tt.equal, rc, r, sc, s)
+			}
+		})
+	}
+
+	for _, line := range []string{":INPUT ACCEPT [0:0]", "-I INPUT 1 -j ACCEPT", "-A", "COMMIT"} {
+		if _, _, ok := canonicalRule(line); ok {
+			t.Errorf("canonicalRule(%q) parsed a rule", line)
+		}
+	}
+}
+
+func TestCanonicalRate(t *testing.T) {
+	tests := map[string]string{
+		"1/second":  "1/sec",
+		"5/s":       "5/sec",
+		"2":         "2/sec",
+		"60/minute": "1/sec",
+		"120/min":   "2/sec",
+		"10/minute": "10/min",
+		"30/m":      "30/min",
+		"7/minute":  "7/min",
+		"1440/day":  "1/min",
+		"3600/hour": "1/sec",
+		"1000/day":  "1000/day",
+		"1/hour":    "1/hour",
+		"3/hour":    "3/hour",
+		"90/hour":   "90/hour",
+		"100/day":   "100/day",
+		"24/day":    "1/hour",
+		"0/second":  "0/second",
+		"fast":      "fast",
+	}
+	for in, want := range tests {
+		if got := canonicalRate(in); got != want {
+			t.Errorf("canonicalRate(%q) = %q, want %q", in, got, want)
+		}
+		// iptables-save output is already canonical.
+		if got := canonicalRate(want); got != want && want != "0/second" {
+			t.Errorf("canonicalRate(%q) = %q, not idempotent", want, got)
+		}
+	}
+}
+
+func TestDropDefaultOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		module string
+		opts   [][]string
+		want   [][]string
+	}{
+		{"reject default", "REJECT", [][]string{{"--reject-with", "icmp-port-unreachable"}}, nil},
+		{"reject tcp-reset", "REJECT", [][]string{{"--reject-with", "tcp-reset"}}, [][]string{{"--reject-with", "tcp-reset"}}},
+		{"log level 4", "LOG", [][]string{{"--log-prefix", `"x "`}, {"--log-level", "4"}}, [][]string{{"--log-prefix", `"x "`}}},
+		{"log level 7", "LOG", [][]string{{"--log-level", "7"}}, [][]string{{"--log-level", "7"}}},
+		{"nflog group 0", "NFLOG", [][]string{{"--nflog-group", "0"}}, nil},
+		{"nflog group 3", "NFLOG", [][]string{{"--nflog-group", "3"}}, [][]string{{"--nflog-group", "3"}}},
+		{"burst 5", "limit", [][]string{{"--limit", "1/sec"}, {"--limit-burst", "5"}}, [][]string{{"--limit", "1/sec"}}},
+		{"burst 6", "limit", [][]string{{"--limit-burst", "6"}}, [][]string{{"--limit-burst", "6"}}},
+		{"whole day", "time", [][]string{{"--timestart", "00:00:00"}, {"--timestop", "23:59:59"}, {"--kerneltz"}}, [][]string{{"--kerneltz"}}},
+		{"from midnight", "time", [][]string{{"--timestart", "00:00:00"}, {"--timestop", "12:00:00"}}, [][]string{{"--timestart", "00:00:00"}, {"--timestop", "12:00:00"}}},
+		{"every weekday", "time", [][]string{{"--weekdays", "Mon,Tue,Wed,Thu,Fri,Sat,Sun"}}, nil},
+		{"some weekdays", "time", [][]string{{"--weekdays", "Mon,Tue"}}, [][]string{{"--weekdays", "Mon,Tue"}}},
+		{"level 4 in another module", "NFLOG", [][]string{{"--log-level", "4"}}, [][]string{{"--log-level", "4"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dropDefaultOptions(tt.module, tt.opts)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("dropDefaultOptions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package fwlog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// filePollInterval is how often a followed file is checked for new lines.
+const filePollInterval = time.Second
+
+// FileSource follows a syslog file such as /var/log/kern.log, starting at
+// its end, and reopens it when it is rotated or truncated.
+type FileSource struct {
+	Path string
+}
+
+func (s *FileSource) Name() string { return "file" }
+
+func (s *FileSource) Run(ctx context.Context, emit func(*models.LogEntry)) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", s.Path, err)
+	}
+	defer func() { f.Close() }()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("seek %s: %w", s.Path, err)
+	}
+	r := bufio.NewReader(f)
+
+	ticker := time.NewTicker(filePollInterval)
+	defer ticker.Stop()
+
+	var partial string
+	for {
+		for {
+			line, err := r.ReadString('\n')
+			offset += int64(len(line))
+			if err != nil {
+				// Keep an unfinished last line until the rest is written.
+				partial += line
+				break
+			}
+			line = strings.TrimSuffix(partial+line, "\n")
+			partial = ""
+			if e, ok := Parse(line); ok {
+				e.Time = syslogTime(line, time.Now())
+				e.Source = s.Name()
+				emit(e)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		rotated, truncated, err := fileChanged(f, s.Path, offset)
+		if err != nil {
+			return err
+		}
+		switch {
+		case rotated:
+			next, err := os.Open(s.Path)
+			if err != nil {
+				return fmt.Errorf("reopen %s: %w", s.Path, err)
+			}
+			f.Close()
+			f, offset, partial = next, 0, ""
+			r.Reset(f)
+		case truncated:
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("seek %s: %w", s.Path, err)
+			}
+			offset, partial = 0, ""
+			r.Reset(f)
+		}
+	}
+}
+
+// fileChanged reports whether path now names a different file than f, or
+// f shrank below what was read.
+func fileChanged(f *os.File, path string, offset int64) (rotated, truncated bool, err error) {
+	cur, err := f.Stat()
+	if err != nil {
+		return false, false, fmt.Errorf("stat %s: %w", path, err)
+	}
+	named, err := os.Stat(path)
+	if err != nil {
+		// Between the rename and the new file being created.
+		return false, false, nil
+	}
+	if !os.SameFile(cur, named) {
+		return true, false, nil
+	}
+	return false, cur.Size() < offset, nil
+}
+
+// syslogTime reads the timestamp at the start of a syslog line, either
+// RFC 3339 or the traditional "Oct 18 12:00:00" in local time, which lacks
+// the year. It falls back to now.
+func syslogTime(line string, now time.Time) time.Time {
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			return t.UTC()
+		}
+	}
+	if len(line) >= len(time.Stamp) {
+		if t, err := time.ParseInLocation(time.Stamp, line[:len(time.Stamp)], time.Local); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			// A December line read in January.
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t.UTC()
+		}
+	}
+	return now.UTC()
+}
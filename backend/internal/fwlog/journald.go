@@ -0,0 +1,92 @@
+package fwlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// JournaldSource follows the kernel messages in the journal through
+// journalctl's export format, starting with new messages.
+type JournaldSource struct{}
+
+func (s *JournaldSource) Name() string { return "journald" }
+
+func (s *JournaldSource) Run(ctx context.Context, emit func(*models.LogEntry)) error {
+	cmd := exec.CommandContext(ctx, "journalctl", "--dmesg", "--follow", "--lines=0", "--output=export")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start journalctl: %w", err)
+	}
+
+	readErr := readExport(bufio.NewReader(stdout), func(fields map[string]string) {
+		e, ok := Parse(fields["MESSAGE"])
+		if !ok {
+			return
+		}
+		if usec, err := strconv.ParseInt(fields["__REALTIME_TIMESTAMP"], 10, 64); err == nil {
+			e.Time = time.UnixMicro(usec).UTC()
+		}
+		e.Source = s.Name()
+		emit(e)
+	})
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if readErr != nil {
+		return fmt.Errorf("read journal export: %w", readErr)
+	}
+	return fmt.Errorf("journalctl exited: %v: %s", waitErr, bytes.TrimSpace(stderr.Bytes()))
+}
+
+// readExport reads entries in the journal export format: "FIELD=value"
+// lines, or "FIELD" followed by a little-endian 64-bit length and the raw
+// value for values with newlines or binary data, and a blank line after
+// each entry.
+func readExport(r *bufio.Reader, entry func(map[string]string)) error {
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if len(line) == 0 {
+			if len(fields) > 0 {
+				entry(fields)
+				fields = make(map[string]string)
+			}
+			continue
+		}
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			continue
+		}
+		var size uint64
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return err
+		}
+		value := make([]byte, size+1) // and the trailing newline
+		if _, err := io.ReadFull(r, value); err != nil {
+			return err
+		}
+		fields[string(line)] = string(value[:size])
+	}
+}
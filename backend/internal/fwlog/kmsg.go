@@ -0,0 +1,97 @@
+package fwlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"golang.org/x/sys/unix"
+)
+
+// KmsgSource reads the kernel ring buffer from /dev/kmsg. It starts at the
+// end: older messages were read before a restart or predate the server.
+type KmsgSource struct {
+	Path string
+}
+
+func (s *KmsgSource) Name() string { return "kmsg" }
+
+func (s *KmsgSource) Run(ctx context.Context, emit func(*models.LogEntry)) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", s.Path, err)
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return fmt.Errorf("seek %s: %w", s.Path, err)
+	}
+	// Closing the file ends a blocked read.
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer func() {
+		if stop() {
+			f.Close()
+		}
+	}()
+
+	boot := bootTime()
+	// Each read returns one record; the kernel caps them below 8 KiB.
+	buf := make([]byte, 8192)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, syscall.EPIPE) {
+				// Records were overwritten before they were read.
+				continue
+			}
+			return fmt.Errorf("read %s: %w", s.Path, err)
+		}
+		at, msg, ok := parseKmsgRecord(buf[:n], boot)
+		if !ok {
+			continue
+		}
+		if e, ok := Parse(msg); ok {
+			e.Time = at
+			e.Source = s.Name()
+			emit(e)
+		}
+	}
+}
+
+// parseKmsgRecord splits a record "prio,seq,usec,flags;message\n" followed
+// by optional continuation lines into its time and message.
+func parseKmsgRecord(rec []byte, boot time.Time) (time.Time, string, bool) {
+	header, rest, ok := bytes.Cut(rec, []byte(";"))
+	if !ok {
+		return time.Time{}, "", false
+	}
+	msg, _, _ := bytes.Cut(rest, []byte("\n"))
+	fields := bytes.Split(header, []byte(","))
+	if len(fields) < 3 {
+		return time.Time{}, "", false
+	}
+	usec, err := strconv.ParseInt(string(fields[2]), 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return boot.Add(time.Duration(usec) * time.Microsecond).UTC(), string(msg), true
+}
+
+// bootTime is the wall time at which the monotonic clock that stamps kernel
+// messages started.
+func bootTime() time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return time.Now()
+	}
+	return time.Now().Add(-time.Duration(ts.Nano()))
+}
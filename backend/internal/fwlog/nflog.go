@@ -0,0 +1,283 @@
+package fwlog
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// nfnetlink_log message and attribute types, from
+// linux/netfilter/nfnetlink_log.h.
+const (
+	nfulnlMsgPacket = 0
+	nfulnlMsgConfig = 1
+
+	nfulaCfgCmd  = 1
+	nfulaCfgMode = 2
+
+	nfulnlCfgCmdBind = 1
+	nfulnlCopyPacket = 2
+
+	nfulaTimestamp = 3
+	nfulaInDev     = 4
+	nfulaOutDev    = 5
+	nfulaPayload   = 9
+	nfulaPrefix    = 10
+)
+
+// nflogCopyRange is how much of each packet is copied to us: enough for
+// the IP header with options or IPv6 extension headers, and the ports.
+const nflogCopyRange = 256
+
+// NFLogSource receives the packets of an NFLOG group over nfnetlink. The
+// kernel passes the packet instead of a text line, so the entry is built
+// from its headers.
+type NFLogSource struct {
+	Group uint16
+}
+
+func (s *NFLogSource) Name() string { return "nflog" }
+
+func (s *NFLogSource) Run(ctx context.Context, emit func(*models.LogEntry)) error {
+	sock, err := nl.Subscribe(unix.NETLINK_NETFILTER)
+	if err != nil {
+		return fmt.Errorf("open nfnetlink socket: %w", err)
+	}
+	// Closing the socket ends a blocked receive.
+	stop := context.AfterFunc(ctx, sock.Close)
+	defer func() {
+		if stop() {
+			sock.Close()
+		}
+	}()
+
+	mode := make([]byte, 6)
+	binary.BigEndian.PutUint32(mode, nflogCopyRange)
+	mode[4] = nfulnlCopyPacket
+	for _, attr := range []*nl.RtAttr{
+		nl.NewRtAttr(nfulaCfgCmd, []byte{nfulnlCfgCmdBind}),
+		nl.NewRtAttr(nfulaCfgMode, mode),
+	} {
+		if err := s.configure(sock, attr); err != nil {
+			return fmt.Errorf("nflog group %d: %w", s.Group, err)
+		}
+	}
+
+	for {
+		msgs, _, err := sock.Receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, unix.ENOBUFS) {
+				// The socket buffer overflowed and packets were lost.
+				continue
+			}
+			return fmt.Errorf("nflog group %d: %w", s.Group, err)
+		}
+		for _, m := range msgs {
+			if m.Header.Type != unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgPacket {
+				continue
+			}
+			if e, ok := parseNFLogPacket(m.Data); ok {
+				e.Source = s.Name()
+				emit(e)
+			}
+		}
+	}
+}
+
+// configure sends one config attribute for the group and waits for the
+// kernel's acknowledgement.
+func (s *NFLogSource) configure(sock *nl.NetlinkSocket, attr *nl.RtAttr) error {
+	req := nl.NewNetlinkRequest(unix.NFNL_SUBSYS_ULOG<<8|nfulnlMsgConfig, unix.NLM_F_ACK)
+	hdr := []byte{unix.AF_UNSPEC, unix.NFNETLINK_V0, 0, 0}
+	binary.BigEndian.PutUint16(hdr[2:], s.Group)
+	req.AddData(nfgenmsg(hdr))
+	req.AddData(attr)
+	if err := sock.Send(req); err != nil {
+		return err
+	}
+	for {
+		msgs, _, err := sock.Receive()
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Type != unix.NLMSG_ERROR || len(m.Data) < 4 {
+				continue
+			}
+			if errno := int32(nl.NativeEndian().Uint32(m.Data)); errno != 0 {
+				return syscall.Errno(-errno)
+			}
+			return nil
+		}
+	}
+}
+
+// nfgenmsg is the raw nfnetlink header of a request.
+type nfgenmsg []byte
+
+func (m nfgenmsg) Len() int          { return len(m) }
+func (m nfgenmsg) Serialize() []byte { return m }
+
+// parseNFLogPacket builds an entry from an NFULNL_MSG_PACKET message, in
+// the same form as a LOG line.
+func parseNFLogPacket(data []byte) (*models.LogEntry, bool) {
+	if len(data) < 4 {
+		return nil, false
+	}
+	attrs, err := nl.ParseRouteAttr(data[4:])
+	if err != nil {
+		return nil, false
+	}
+
+	e := &models.LogEntry{}
+	var payload []byte
+	for _, a := range attrs {
+		switch a.Attr.Type & nl.NLA_TYPE_MASK {
+		case nfulaPrefix:
+			e.Prefix = strings.TrimSpace(strings.TrimRight(string(a.Value), "\x00"))
+		case nfulaInDev:
+			e.In = ifaceName(a.Value)
+		case nfulaOutDev:
+			e.Out = ifaceName(a.Value)
+		case nfulaTimestamp:
+			if len(a.Value) >= 16 {
+				sec := binary.BigEndian.Uint64(a.Value)
+				usec := binary.BigEndian.Uint64(a.Value[8:])
+				e.Time = time.Unix(int64(sec), int64(usec)*1000).UTC()
+			}
+		case nfulaPayload:
+			payload = a.Value
+		}
+	}
+	if !parseIPPacket(payload, e) {
+		return nil, false
+	}
+
+	var sb strings.Builder
+	if e.Prefix != "" {
+		sb.WriteString(e.Prefix + " ")
+	}
+	fmt.Fprintf(&sb, "IN=%s OUT=%s SRC=%s DST=%s LEN=%d PROTO=%s", e.In, e.Out, e.Src, e.Dst, e.Length, e.Proto)
+	if e.SrcPort != 0 || e.DstPort != 0 {
+		fmt.Fprintf(&sb, " SPT=%d DPT=%d", e.SrcPort, e.DstPort)
+	}
+	e.Message = sb.String()
+	return e, true
+}
+
+// parseIPPacket fills in the addresses, protocol, length and ports from
+// the start of an IPv4 or IPv6 packet.
+func parseIPPacket(p []byte, e *models.LogEntry) bool {
+	if len(p) < 1 {
+		return false
+	}
+	var proto byte
+	var l4 []byte
+	switch p[0] >> 4 {
+	case 4:
+		ihl := int(p[0]&0x0f) * 4
+		if len(p) < 20 || ihl < 20 || len(p) < ihl {
+			return false
+		}
+		e.Length = int(binary.BigEndian.Uint16(p[2:]))
+		e.Src = net.IP(p[12:16]).String()
+		e.Dst = net.IP(p[16:20]).String()
+		proto = p[9]
+		// Only the first fragment carries the ports.
+		if binary.BigEndian.Uint16(p[6:])&0x1fff == 0 {
+			l4 = p[ihl:]
+		}
+	case 6:
+		if len(p) < 40 {
+			return false
+		}
+		e.Length = int(binary.BigEndian.Uint16(p[4:])) + 40
+		e.Src = net.IP(p[8:24]).String()
+		e.Dst = net.IP(p[24:40]).String()
+		proto, l4 = ipv6Upper(p[6], p[40:])
+	default:
+		return false
+	}
+
+	e.Proto = protoName(proto)
+	switch proto {
+	case unix.IPPROTO_TCP, unix.IPPROTO_UDP, unix.IPPROTO_UDPLITE, unix.IPPROTO_SCTP:
+		if len(l4) >= 4 {
+			e.SrcPort = int(binary.BigEndian.Uint16(l4))
+			e.DstPort = int(binary.BigEndian.Uint16(l4[2:]))
+		}
+	}
+	return true
+}
+
+// ipv6Upper skips the IPv6 extension headers and returns the upper-layer
+// protocol and its header, or nil if it is not in the copied part.
+func ipv6Upper(next byte, p []byte) (byte, []byte) {
+	for {
+		switch next {
+		case unix.IPPROTO_HOPOPTS, unix.IPPROTO_ROUTING, unix.IPPROTO_DSTOPTS:
+			if len(p) < 2 {
+				return next, nil
+			}
+			n := (int(p[1]) + 1) * 8
+			if len(p) < n {
+				return p[0], nil
+			}
+			next, p = p[0], p[n:]
+		case unix.IPPROTO_FRAGMENT:
+			if len(p) < 8 {
+				return next, nil
+			}
+			if binary.BigEndian.Uint16(p[2:])&0xfff8 != 0 {
+				return p[0], nil
+			}
+			next, p = p[0], p[8:]
+		default:
+			return next, p
+		}
+	}
+}
+
+// protoName names a protocol the way the LOG target prints it.
+func protoName(proto byte) string {
+	switch proto {
+	case unix.IPPROTO_TCP:
+		return "TCP"
+	case unix.IPPROTO_UDP:
+		return "UDP"
+	case unix.IPPROTO_UDPLITE:
+		return "UDPLITE"
+	case unix.IPPROTO_SCTP:
+		return "SCTP"
+	case unix.IPPROTO_ICMP:
+		return "ICMP"
+	case unix.IPPROTO_ICMPV6:
+		return "ICMPv6"
+	}
+	return strconv.Itoa(int(proto))
+}
+
+// ifaceName resolves an interface index attribute to its name.
+func ifaceName(v []byte) string {
+	if len(v) < 4 {
+		return ""
+	}
+	idx := int(binary.BigEndian.Uint32(v))
+	if iface, err := net.InterfaceByIndex(idx); err == nil {
+		return iface.Name
+	}
+	return strconv.Itoa(idx)
+}
@@ -0,0 +1,81 @@
+// Package fwlog reads the packets logged by firewall rules from the kernel
+// log (/dev/kmsg), the journal, a syslog file or NFLOG groups.
+package fwlog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// Parse parses a message of the LOG target, such as
+//
+//	fwmg:1a2b3c4d5e6f IN=eth0 OUT= MAC=... SRC=10.0.0.9 DST=10.0.0.1 LEN=60 ... PROTO=TCP SPT=51000 DPT=22 ...
+//
+// A syslog header before the prefix ("Oct 18 12:00:00 host kernel: [ 12.3] ")
+// is skipped. It reports false for lines that were not logged by a rule.
+func Parse(line string) (*models.LogEntry, bool) {
+	start := fieldsStart(line)
+	if start < 0 {
+		return nil, false
+	}
+	prefix := line[:start]
+	if i := strings.Index(prefix, "kernel: "); i >= 0 {
+		prefix = prefix[i+len("kernel: "):]
+	}
+	if strings.HasPrefix(prefix, "[") {
+		if i := strings.Index(prefix, "] "); i >= 0 {
+			prefix = prefix[i+2:]
+		}
+	}
+
+	e := &models.LogEntry{Prefix: strings.TrimSpace(prefix), Message: prefix + line[start:]}
+	for _, field := range strings.Fields(line[start:]) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "IN":
+			e.In = value
+		case "OUT":
+			e.Out = value
+		case "SRC":
+			e.Src = value
+		case "DST":
+			e.Dst = value
+		case "PROTO":
+			e.Proto = value
+		case "SPT":
+			e.SrcPort, _ = strconv.Atoi(value)
+		case "DPT":
+			e.DstPort, _ = strconv.Atoi(value)
+		case "LEN":
+			// The IP length comes first; UDP repeats LEN for its own header.
+			if e.Length == 0 {
+				e.Length, _ = strconv.Atoi(value)
+			}
+		}
+	}
+	return e, true
+}
+
+// fieldsStart returns where the "IN=" field of a LOG message starts, or -1.
+func fieldsStart(line string) int {
+	for off := 0; ; {
+		i := strings.Index(line[off:], "IN=")
+		if i < 0 {
+			return -1
+		}
+		i += off
+		if i == 0 || line[i-1] == ' ' {
+			// OUT= always follows the interface, which tells the fields
+			// from a prefix that happens to contain "IN=".
+			if end := strings.IndexByte(line[i:], ' '); end > 0 && strings.HasPrefix(line[i+end:], " OUT=") {
+				return i
+			}
+		}
+		off = i + len("IN=")
+	}
+}
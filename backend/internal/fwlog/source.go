@@ -0,0 +1,42 @@
+package fwlog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// Source reads logged packets and passes each to emit, from the goroutine
+// that called Run. Run returns when ctx is cancelled or the source fails;
+// the caller restarts it. Entries without a time are stamped by the caller.
+type Source interface {
+	Name() string
+	Run(ctx context.Context, emit func(*models.LogEntry)) error
+}
+
+// ParseSource returns the source named by spec: "kmsg", "journald",
+// "file:<path>" or "nflog:<group>".
+func ParseSource(spec string) (Source, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch kind {
+	case "kmsg":
+		return &KmsgSource{Path: "/dev/kmsg"}, nil
+	case "journald":
+		return &JournaldSource{}, nil
+	case "file":
+		if arg == "" {
+			return nil, fmt.Errorf("log source %q: file needs a path", spec)
+		}
+		return &FileSource{Path: arg}, nil
+	case "nflog":
+		group, err := strconv.ParseUint(arg, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("log source %q: nflog needs a group from 0 to 65535", spec)
+		}
+		return &NFLogSource{Group: uint16(group)}, nil
+	}
+	return nil, fmt.Errorf("unknown log source %q", spec)
+}
@@ -0,0 +1,145 @@
+// Package metrics renders metrics in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metric types.
+const (
+	Counter   = "counter"
+	Gauge     = "gauge"
+	Histogram = "histogram"
+)
+
+// Labels are label name and value pairs, in the order they are written.
+type Labels []string
+
+// Writer writes metric families. All samples of a family must be written
+// right after its Family call.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Family starts a metric family with its help text and type.
+func (w *Writer) Family(name, typ, help string) {
+	w.write("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.write("# TYPE " + name + " " + typ + "\n")
+}
+
+// Sample writes one sample of the current family.
+func (w *Writer) Sample(name string, labels Labels, value float64) {
+	w.write(name + formatLabels(labels) + " " + formatValue(value) + "\n")
+}
+
+// Histogram writes the buckets, sum and count of a histogram.
+func (w *Writer) Histogram(name string, labels Labels, h *Buckets) {
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		w.Sample(name+"_bucket", append(append(Labels{}, labels...), "le", formatValue(bound)), float64(cumulative))
+	}
+	w.Sample(name+"_bucket", append(append(Labels{}, labels...), "le", "+Inf"), float64(h.Count))
+	w.Sample(name+"_sum", labels, h.Sum)
+	w.Sample(name+"_count", labels, float64(h.Count))
+}
+
+// Flush writes out what is buffered and returns the first error.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) write(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+// Buckets counts observations for a histogram.
+type Buckets struct {
+	Bounds []float64 // upper bounds, ascending
+	Counts []uint64  // observations per bound, not cumulative; the rest are above the last bound
+	Sum    float64
+	Count  uint64
+}
+
+func NewBuckets(bounds ...float64) *Buckets {
+	return &Buckets{Bounds: bounds, Counts: make([]uint64, len(bounds))}
+}
+
+// Observe adds one observation.
+func (h *Buckets) Observe(v float64) {
+	for i, bound := range h.Bounds {
+		if v <= bound {
+			h.Counts[i]++
+			break
+		}
+	}
+	h.Sum += v
+	h.Count++
+}
+
+// Clone returns a copy that does not share the counts.
+func (h *Buckets) Clone() *Buckets {
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+	return &c
+}
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(labels[i])
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabel(labels[i+1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		// Counters stay readable instead of switching to exponents.
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+// AlertKind is what an alert rule watches
+type AlertKind string
+
+const (
+	// AlertThreshold compares a counter series with a value.
+	AlertThreshold AlertKind = "threshold"
+	// AlertEvent fires on events of the event stream.
+	AlertEvent AlertKind = "event"
+)
+
+// AlertMetric is the quantity of a counter series a threshold rule checks
+type AlertMetric string
+
+const (
+	AlertPPS     AlertMetric = "pps"     // packets per second over the window
+	AlertBPS     AlertMetric = "bps"     // bits per second over the window
+	AlertPackets AlertMetric = "packets" // packets in the window
+	AlertBytes   AlertMetric = "bytes"   // bytes in the window
+)
+
+// AlertSeverity ranks alerts for notifications
+type AlertSeverity string
+
+const (
+	SeverityInfo     AlertSeverity = "info"
+	SeverityWarning  AlertSeverity = "warning"
+	SeverityCritical AlertSeverity = "critical"
+)
+
+// AlertRule is a user-defined condition the alert engine evaluates.
+//
+// A threshold rule takes Metric of Series over the last WindowSeconds and
+// compares it with Value; the alert is pending while the condition holds
+// and fires once it has held for ForSeconds.
+//
+// An event rule fires on every event whose type matches EventType (a type
+// matches itself and the types below it) and, if set, ResourceID. It
+// resolves on a ResolveEvent or once no matching event arrived for
+// ResolveAfterSeconds.
+type AlertRule struct {
+	ID          string        `json:"id" db:"id"`
+	Name        string        `json:"name" db:"name"`
+	Description string        `json:"description" db:"description"`
+	Kind        AlertKind     `json:"kind" db:"kind"`
+	Severity    AlertSeverity `json:"severity" db:"severity"`
+	Enabled     bool          `json:"enabled" db:"enabled"`
+
+	Series        string      `json:"series,omitempty" db:"series"`
+	Metric        AlertMetric `json:"metric,omitempty" db:"metric"`
+	Operator      string      `json:"operator,omitempty" db:"operator"` // >, >=, < or <=
+	Value         float64     `json:"value,omitempty" db:"value"`
+	WindowSeconds int         `json:"windowSeconds,omitempty" db:"window_seconds"`
+	ForSeconds    int         `json:"forSeconds,omitempty" db:"for_seconds"`
+
+	EventType           string `json:"eventType,omitempty" db:"event_type"`
+	ResourceID          string `json:"resourceId,omitempty" db:"resource_id"`
+	ResolveEvent        string `json:"resolveEvent,omitempty" db:"resolve_event"`
+	ResolveAfterSeconds int    `json:"resolveAfterSeconds,omitempty" db:"resolve_after_seconds"`
+
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// AlertState is where an alert is in its lifecycle
+type AlertState string
+
+const (
+	AlertPending  AlertState = "pending"
+	AlertFiring   AlertState = "firing"
+	AlertResolved AlertState = "resolved"
+)
+
+// Alert is one occurrence of an alert rule's condition, from when it was
+// first seen until it resolved. An alert that resolves while pending never
+// fired. Silenced alerts go through the same states but are not published.
+type Alert struct {
+	ID         string        `json:"id" db:"id"`
+	RuleID     string        `json:"ruleId" db:"rule_id"`
+	RuleName   string        `json:"ruleName" db:"rule_name"`
+	Severity   AlertSeverity `json:"severity" db:"severity"`
+	State      AlertState    `json:"state" db:"state"`
+	Message    string        `json:"message" db:"message"`
+	Value      float64       `json:"value" db:"value"` // last value of a threshold rule
+	Count      int           `json:"count" db:"count"` // matching events of an event rule
+	Silenced   bool          `json:"silenced" db:"silenced"`
+	StartedAt  time.Time     `json:"startedAt" db:"started_at"`
+	FiredAt    *time.Time    `json:"firedAt,omitempty" db:"fired_at"`
+	ResolvedAt *time.Time    `json:"resolvedAt,omitempty" db:"resolved_at"`
+	// LastSeenAt is when the condition last held or the last matching
+	// event arrived.
+	LastSeenAt time.Time `json:"lastSeenAt" db:"last_seen_at"`
+}
+
+// Active reports whether the alert has not resolved yet.
+func (a *Alert) Active() bool {
+	return a.State != AlertResolved
+}
+
+// AlertFilter selects alerts of the history. Empty fields match everything.
+type AlertFilter struct {
+	RuleID string
+	State  AlertState
+	From   time.Time // started at or after
+	To     time.Time // started before
+	Limit  int
+}
+
+// AlertSilence mutes the alerts of one rule, or of all rules if RuleID is
+// empty, between StartsAt and EndsAt.
+type AlertSilence struct {
+	ID        string    `json:"id" db:"id"`
+	RuleID    string    `json:"ruleId,omitempty" db:"rule_id"`
+	StartsAt  time.Time `json:"startsAt" db:"starts_at"`
+	EndsAt    time.Time `json:"endsAt" db:"ends_at"`
+	Comment   string    `json:"comment" db:"comment"`
+	CreatedBy string    `json:"createdBy" db:"created_by"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Covers reports whether the silence mutes the rule at the given time.
+func (s *AlertSilence) Covers(ruleID string, at time.Time) bool {
+	return (s.RuleID == "" || s.RuleID == ruleID) && !at.Before(s.StartsAt) && at.Before(s.EndsAt)
+}
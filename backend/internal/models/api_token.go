@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// APIToken is a long-lived credential for automation. It acts with the roles
+// of the user who created it, further limited to its scopes. Only a hash of
+// the token is stored.
+type APIToken struct {
+	ID         string     `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"` // first characters, to recognize a token
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`    // e.g. rules:read, apply
+	OwnerID    string     `json:"ownerId" db:"owner_id"` // empty = created with the shared API key
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ApplyJobAction is the kernel change an apply job performs
+type ApplyJobAction string
+
+const (
+	ApplyJobApply    ApplyJobAction = "apply"
+	ApplyJobRollback ApplyJobAction = "rollback"
+)
+
+// ApplyJobStatus tracks an apply job through the queue
+type ApplyJobStatus string
+
+const (
+	ApplyJobQueued    ApplyJobStatus = "queued"
+	ApplyJobRunning   ApplyJobStatus = "running"
+	ApplyJobSucceeded ApplyJobStatus = "succeeded"
+	ApplyJobFailed    ApplyJobStatus = "failed"
+)
+
+// ApplyJobStep is one progress line logged while a job runs
+type ApplyJobStep struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// ApplyJob is an apply or rollback requested through the API. Jobs run one
+// at a time in submission order.
+type ApplyJob struct {
+	ID          string         `json:"id"`
+	Action      ApplyJobAction `json:"action"`
+	HistoryID   string         `json:"historyId,omitempty"` // rollback target, empty = latest snapshot
+	Status      ApplyJobStatus `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	RequestedBy string         `json:"requestedBy"`
+	Steps       []ApplyJobStep `json:"steps"`
+	QueuedAt    time.Time      `json:"queuedAt"`
+	StartedAt   *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time     `json:"finishedAt,omitempty"`
+	DurationMs  int64          `json:"durationMs,omitempty"` // running time, once finished
+}
+
+// Finished reports whether the job has reached a final status.
+func (j *ApplyJob) Finished() bool {
+	return j.Status == ApplyJobSucceeded || j.Status == ApplyJobFailed
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records one change. Entries form a hash chain: Hash covers the
+// entry's fields and PrevHash, so editing or deleting an entry breaks every
+// hash after it.
+type AuditEntry struct {
+	Seq        int64           `json:"seq" db:"seq"`
+	Timestamp  time.Time       `json:"timestamp" db:"timestamp"`
+	Actor      string          `json:"actor" db:"actor"`            // principal name, or "system"
+	ActorKind  string          `json:"actorKind" db:"actor_kind"`   // principal kind, or "system"
+	SourceIP   string          `json:"sourceIp" db:"source_ip"`     // empty for background jobs
+	Action     string          `json:"action" db:"action"`          // e.g. rule.update, apply
+	Resource   string          `json:"resource" db:"resource"`      // e.g. rule, nat-rule, ruleset
+	ResourceID string          `json:"resourceId" db:"resource_id"` // empty for singletons
+	Before     json.RawMessage `json:"before,omitempty" db:"before"`
+	After      json.RawMessage `json:"after,omitempty" db:"after"`
+	PrevHash   string          `json:"prevHash" db:"prev_hash"`
+	Hash       string          `json:"hash" db:"hash"`
+}
+
+// AuditFilter selects audit entries. Zero values match everything.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	Resource   string
+	ResourceID string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	BeforeSeq  int64 // page backwards from this sequence number
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // FirewallConfig holds global firewall settings
 type FirewallConfig struct {
@@ -22,6 +25,35 @@ type NetworkInterface struct {
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
+// InterfaceAddress is an IPv4 or IPv6 address assigned to an interface.
+// Flags are the kernel's address flags, such as "secondary", "permanent",
+// "tentative", "deprecated" or "temporary". Lifetimes are only set for
+// addresses that expire, like SLAAC and DHCP leases.
+type InterfaceAddress struct {
+	IP                string   `json:"ip"`
+	PrefixLen         int      `json:"prefixLen"`
+	Family            string   `json:"family"` // ipv4 or ipv6
+	Scope             string   `json:"scope"`  // global, site, link or host
+	Flags             []string `json:"flags,omitempty"`
+	ValidLifetime     int      `json:"validLifetime,omitempty"`     // seconds
+	PreferredLifetime int      `json:"preferredLifetime,omitempty"` // seconds
+}
+
+// CIDR returns the address in ip/prefix notation.
+func (a InterfaceAddress) CIDR() string {
+	return fmt.Sprintf("%s/%d", a.IP, a.PrefixLen)
+}
+
+// HasFlag reports whether the address carries flag.
+func (a InterfaceAddress) HasFlag(flag string) bool {
+	for _, f := range a.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
 // Zone represents a network zone/mastery (like firewalld)
 type Zone struct {
 	ID          string    `json:"id" db:"id"`
@@ -51,6 +83,7 @@ type NATRule struct {
 	Comment      string    `json:"comment" db:"comment"`
 	Enabled      bool      `json:"enabled" db:"enabled"`
 	Position     int       `json:"position" db:"position"`
+	Tags         []string  `json:"tags,omitempty" db:"tags"` // ownership tags for scoped roles
 	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
 }
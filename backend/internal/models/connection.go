@@ -0,0 +1,112 @@
+package models
+
+import (
+	"net"
+	"strings"
+)
+
+// Connection is an entry of the kernel's connection tracking table. The
+// original tuple is the first packet as it arrived; the reply tuple is what
+// the answer must look like, so it differs from the reversed original when
+// the connection is NATed.
+type Connection struct {
+	ID       uint32          `json:"id"`
+	Family   string          `json:"family"`          // ipv4 or ipv6
+	Protocol string          `json:"protocol"`        // tcp, udp, icmp, ... or the protocol number
+	State    string          `json:"state,omitempty"` // TCP state, e.g. ESTABLISHED
+	Flags    []string        `json:"flags,omitempty"` // UNREPLIED, ASSURED, SNAT, DNAT, OFFLOAD
+	Original ConnectionTuple `json:"original"`
+	Reply    ConnectionTuple `json:"reply"`
+	// SNAT and DNAT are the address (and port) the source or destination
+	// was translated to, if it was.
+	SNAT    string `json:"snat,omitempty"`
+	DNAT    string `json:"dnat,omitempty"`
+	Timeout uint32 `json:"timeout"` // seconds until the entry expires
+	Mark    uint32 `json:"mark,omitempty"`
+	Zone    uint16 `json:"zone,omitempty"`
+}
+
+// ConnectionTuple is one direction of a connection with its counters. The
+// counters stay zero unless net.netfilter.nf_conntrack_acct is enabled.
+type ConnectionTuple struct {
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+	SrcPort int    `json:"srcPort,omitempty"`
+	DstPort int    `json:"dstPort,omitempty"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// ConnectionFilter selects connections. Empty fields match everything.
+type ConnectionFilter struct {
+	ID       uint32 `json:"id,omitempty"`
+	Family   string `json:"family,omitempty"` // ipv4 or ipv6
+	Protocol string `json:"protocol,omitempty"`
+	// State matches the TCP state or one of the flags.
+	State string `json:"state,omitempty"`
+	// Addr matches any address of either tuple; Src and Dst the original
+	// source and destination. Each is an address or a CIDR.
+	Addr string `json:"addr,omitempty"`
+	Src  string `json:"src,omitempty"`
+	Dst  string `json:"dst,omitempty"`
+	// Port matches any port of either tuple.
+	Port int `json:"port,omitempty"`
+}
+
+// Empty reports whether the filter matches every connection.
+func (f ConnectionFilter) Empty() bool {
+	return f == ConnectionFilter{}
+}
+
+// Matches reports whether c passes the filter.
+func (f ConnectionFilter) Matches(c *Connection) bool {
+	if f.ID != 0 && c.ID != f.ID {
+		return false
+	}
+	if f.Family != "" && !strings.EqualFold(c.Family, f.Family) {
+		return false
+	}
+	if f.Protocol != "" && !strings.EqualFold(c.Protocol, f.Protocol) {
+		return false
+	}
+	if f.State != "" && !strings.EqualFold(c.State, f.State) && !containsFold(c.Flags, f.State) {
+		return false
+	}
+	if f.Src != "" && !addrIn(f.Src, c.Original.Src) {
+		return false
+	}
+	if f.Dst != "" && !addrIn(f.Dst, c.Original.Dst) {
+		return false
+	}
+	if f.Addr != "" && !addrIn(f.Addr, c.Original.Src) && !addrIn(f.Addr, c.Original.Dst) &&
+		!addrIn(f.Addr, c.Reply.Src) && !addrIn(f.Addr, c.Reply.Dst) {
+		return false
+	}
+	if f.Port != 0 && c.Original.SrcPort != f.Port && c.Original.DstPort != f.Port &&
+		c.Reply.SrcPort != f.Port && c.Reply.DstPort != f.Port {
+		return false
+	}
+	return true
+}
+
+// addrIn reports whether addr is spec, an address, or inside spec, a CIDR.
+func addrIn(spec, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	if _, n, err := net.ParseCIDR(spec); err == nil {
+		return n.Contains(ip)
+	}
+	other := net.ParseIP(spec)
+	return other != nil && other.Equal(ip)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// CounterSample is the traffic one counter series saw in the Span seconds
+// up to At. Series names are "rule/<id>", "nat/<id>",
+// "policy/<table>/<chain>", "iface/<name>/rx|tx|drop" and, for traffic
+// accounting, "host/<address>/rx|tx" and "peer/<address>/rx|tx".
+type CounterSample struct {
+	Series  string
+	At      time.Time
+	Span    int64
+	Packets uint64
+	Bytes   uint64
+}
+
+// CounterQuery selects series and a time window for a range query.
+type CounterQuery struct {
+	Series []string
+	From   time.Time
+	To     time.Time
+	Step   time.Duration // 0 picks one
+}
+
+// CounterSeries is the traffic of one series in steps of Step seconds.
+// Steps without samples, such as while the server was down, are left out.
+type CounterSeries struct {
+	Series string         `json:"series"`
+	Step   int64          `json:"step"`
+	Points []CounterPoint `json:"points"`
+}
+
+// CounterPoint is the traffic in the step starting at Time, with its rate
+// in packets and bits per second.
+type CounterPoint struct {
+	Time    time.Time `json:"time"`
+	Packets uint64    `json:"packets"`
+	Bytes   uint64    `json:"bytes"`
+	PPS     float64   `json:"pps"`
+	BPS     float64   `json:"bps"`
+}
@@ -12,6 +12,7 @@ type CounterStats struct {
 }
 
 // InterfaceCounters holds all the counters for a specific network interface.
+// Drop counts packets dropped on receive and transmit; it has no bytes.
 type InterfaceCounters struct {
 	In   CounterStats `json:"in"`
 	Out  CounterStats `json:"out"`
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Event is a change published to subscribers of the event stream. Types are
+// dotted names such as "rule.update", "job.succeeded" or "drift.detected".
+type Event struct {
+	ID         uint64    `json:"id"`
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor,omitempty"`
+	ResourceID string    `json:"resourceId,omitempty"`
+	Data       any       `json:"data,omitempty"`
+}
+
+// EventFilter selects events by type and resource. A type matches itself
+// and every type below it: "rule" matches "rule.create". Empty fields match
+// everything.
+type EventFilter struct {
+	Types      []string
+	ResourceID string
+}
+
+// Matches reports whether e passes the filter.
+func (f EventFilter) Matches(e *Event) bool {
+	if f.ResourceID != "" && f.ResourceID != e.ResourceID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if e.Type == t || strings.HasPrefix(e.Type, t+".") {
+			return true
+		}
+	}
+	return false
+}
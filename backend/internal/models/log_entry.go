@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// LogEntry is one packet logged by a LOG or NFLOG rule, as read from the
+// kernel log. Fields the message does not carry are left empty.
+type LogEntry struct {
+	ID      int64     `json:"id" db:"id"`
+	Time    time.Time `json:"time" db:"ts"`
+	Source  string    `json:"source" db:"source"`            // kmsg, journald, file or nflog
+	RuleID  string    `json:"ruleId,omitempty" db:"rule_id"` // the stored rule whose prefix matched
+	Prefix  string    `json:"prefix" db:"prefix"`
+	In      string    `json:"in,omitempty" db:"in_iface"`
+	Out     string    `json:"out,omitempty" db:"out_iface"`
+	Src     string    `json:"src,omitempty" db:"src"`
+	Dst     string    `json:"dst,omitempty" db:"dst"`
+	Proto   string    `json:"proto,omitempty" db:"proto"` // as the kernel prints it: TCP, UDP, ICMP, or a number
+	SrcPort int       `json:"srcPort,omitempty" db:"src_port"`
+	DstPort int       `json:"dstPort,omitempty" db:"dst_port"`
+	Length  int       `json:"length,omitempty" db:"length"`
+	Message string    `json:"message" db:"message"`
+}
+
+// LogFilter selects log entries. Zero values match everything.
+type LogFilter struct {
+	RuleID string
+	Prefix string
+	In     string
+	Out    string
+	Src    string
+	Dst    string
+	Proto  string
+	Port   int    // source or destination port
+	Text   string // substring of the message
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Before int64 // page backwards from this entry ID
+}
+
+// Matches reports whether e passes the filter; used for the live tail.
+// Limit and Before are ignored.
+func (f LogFilter) Matches(e *LogEntry) bool {
+	switch {
+	case f.RuleID != "" && f.RuleID != e.RuleID,
+		f.Prefix != "" && f.Prefix != e.Prefix,
+		f.In != "" && f.In != e.In,
+		f.Out != "" && f.Out != e.Out,
+		f.Src != "" && f.Src != e.Src,
+		f.Dst != "" && f.Dst != e.Dst,
+		f.Proto != "" && !strings.EqualFold(f.Proto, e.Proto),
+		f.Port != 0 && f.Port != e.SrcPort && f.Port != e.DstPort,
+		f.Text != "" && !strings.Contains(e.Message, f.Text),
+		!f.From.IsZero() && e.Time.Before(f.From),
+		!f.To.IsZero() && !e.Time.Before(f.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// NotificationSinkType is where a sink delivers notifications
+type NotificationSinkType string
+
+const (
+	// SinkWebhook POSTs the event as JSON, signed with HMAC-SHA256.
+	SinkWebhook NotificationSinkType = "webhook"
+	// SinkSlack POSTs a Slack-compatible {"text": ...} payload.
+	SinkSlack NotificationSinkType = "slack"
+	// SinkEmail sends a mail through an SMTP server.
+	SinkEmail NotificationSinkType = "email"
+	// SinkSyslog sends an RFC 5424 message over UDP or TCP.
+	SinkSyslog NotificationSinkType = "syslog"
+)
+
+// NotificationSink is a destination for notifications about events of the
+// event stream. Events lists the event types the sink subscribes to; a type
+// matches itself and the types below it, so "drift" covers "drift.detected"
+// and "drift.resolved".
+type NotificationSink struct {
+	ID        string                 `json:"id" db:"id"`
+	Name      string                 `json:"name" db:"name"`
+	Type      NotificationSinkType   `json:"type" db:"type"`
+	Enabled   bool                   `json:"enabled" db:"enabled"`
+	Events    []string               `json:"events" db:"events"`
+	Config    NotificationSinkConfig `json:"config" db:"config"`
+	CreatedAt time.Time              `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time              `json:"updatedAt" db:"updated_at"`
+}
+
+// NotificationSinkConfig holds the settings of every sink type; each type
+// uses its own subset. Secret and Password are write-only: the API returns
+// them masked.
+type NotificationSinkConfig struct {
+	// MaxAttempts is how often a failed delivery is tried in total.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// Webhook and Slack.
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"` // HMAC key of webhook signatures
+
+	// Email.
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+
+	// TLS of email and https webhook and Slack sinks. CACert is a PEM bundle
+	// trusted instead of the system roots.
+	CACert             string `json:"caCert,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+
+	// Syslog.
+	Network  string `json:"network,omitempty"` // udp or tcp
+	Address  string `json:"address,omitempty"` // host:port
+	Facility string `json:"facility,omitempty"`
+	AppName  string `json:"appName,omitempty"`
+}
+
+// NotificationDeliveryStatus is how far a delivery got
+type NotificationDeliveryStatus string
+
+const (
+	DeliveryPending   NotificationDeliveryStatus = "pending"
+	DeliveryDelivered NotificationDeliveryStatus = "delivered"
+	DeliveryFailed    NotificationDeliveryStatus = "failed"
+)
+
+// NotificationDelivery records one event sent to one sink, including its
+// retries. Error is the error of the last failed attempt.
+type NotificationDelivery struct {
+	ID         string                     `json:"id" db:"id"`
+	SinkID     string                     `json:"sinkId" db:"sink_id"`
+	SinkName   string                     `json:"sinkName" db:"sink_name"`
+	EventID    uint64                     `json:"eventId" db:"event_id"`
+	EventType  string                     `json:"eventType" db:"event_type"`
+	Summary    string                     `json:"summary" db:"summary"`
+	Status     NotificationDeliveryStatus `json:"status" db:"status"`
+	Attempts   int                        `json:"attempts" db:"attempts"`
+	Error      string                     `json:"error,omitempty" db:"error"`
+	CreatedAt  time.Time                  `json:"createdAt" db:"created_at"`
+	FinishedAt *time.Time                 `json:"finishedAt,omitempty" db:"finished_at"`
+}
+
+// NotificationDeliveryFilter selects deliveries of the log. Empty fields
+// match everything.
+type NotificationDeliveryFilter struct {
+	SinkID    string
+	Status    NotificationDeliveryStatus
+	EventType string // matches the type and the types below it
+	Limit     int
+}
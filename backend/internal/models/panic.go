@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PanicState records whether the emergency lockdown is in force. While it
+// is, the kernel drops everything except the management allowlist and
+// established sessions, and applies are refused until it is released.
+type PanicState struct {
+	Active      bool       `json:"active" db:"active"`
+	Reason      string     `json:"reason,omitempty" db:"reason"`
+	ActivatedBy string     `json:"activatedBy,omitempty" db:"activated_by"`
+	ActivatedAt *time.Time `json:"activatedAt,omitempty" db:"activated_at"`
+	// SnapshotID is the history entry restored on release; empty if the
+	// live ruleset could not be saved, in which case the stored rules are
+	// re-applied instead.
+	SnapshotID string `json:"snapshotId,omitempty" db:"snapshot_id"`
+	// CallerIP and CallerPort keep the activating session reachable, also
+	// when the lockdown is re-applied after a restart.
+	CallerIP   string `json:"callerIp,omitempty" db:"caller_ip"`
+	CallerPort int    `json:"callerPort,omitempty" db:"caller_port"`
+}
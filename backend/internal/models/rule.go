@@ -40,26 +40,56 @@ type Rule struct {
 	Dst      string    `json:"dst" db:"dst"`           // CIDR or empty
 	SrcPort  string    `json:"srcPort" db:"src_port"`  // single port or range "80:90"
 	DstPort  string    `json:"dstPort" db:"dst_port"`  // single port or range
+	InInterface  string `json:"inInterface,omitempty" db:"in_interface"`   // -i; INPUT and FORWARD only
+	OutInterface string `json:"outInterface,omitempty" db:"out_interface"` // -o; OUTPUT and FORWARD only
 	Action   Action    `json:"action" db:"action"`
 	Enabled  bool      `json:"enabled" db:"enabled"`
 	Comment  string    `json:"comment" db:"comment"`
 	Position int       `json:"position" db:"position"`
+	Schedule *Schedule `json:"schedule,omitempty" db:"schedule"` // nil = always active
+	Log      *LogOptions `json:"log,omitempty" db:"log_options"`  // nil = default logging for LOG, none otherwise
+	Tags     []string  `json:"tags,omitempty" db:"tags"`         // ownership tags for scoped roles
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
+// HistoryKind distinguishes restorable snapshots from informational records
+type HistoryKind string
+
+const (
+	HistoryKindSnapshot HistoryKind = "snapshot" // pre-apply state, usable for rollback
+	HistoryKindEvent    HistoryKind = "event"    // outcome record, e.g. of a scheduled job
+)
+
 // HistoryEntry records a snapshot of applied rules for rollback
 type HistoryEntry struct {
-	ID          string    `json:"id" db:"id"`
-	Snapshot    string    `json:"snapshot" db:"snapshot"` // iptables-save output
-	AppliedAt   time.Time `json:"appliedAt" db:"applied_at"`
-	Description string    `json:"description" db:"description"`
+	ID          string      `json:"id" db:"id"`
+	Kind        HistoryKind `json:"kind" db:"kind"`
+	Snapshot    string      `json:"snapshot" db:"snapshot"` // iptables-save output
+	AppliedAt   time.Time   `json:"appliedAt" db:"applied_at"`
+	Description string      `json:"description" db:"description"`
+	// PayloadHash and Verification describe the apply this snapshot
+	// preceded: the hash of its rendered payload and its post-apply check.
+	PayloadHash  string              `json:"payloadHash,omitempty" db:"payload_hash"`
+	Verification *VerificationReport `json:"verification,omitempty" db:"verification"`
 }
 
 // Counter holds traffic counter data for a rule or chain
 type Counter struct {
+	Table    string `json:"table,omitempty"`
 	Chain    Chain  `json:"chain"`
 	Rule     string `json:"rule"`
+	Tag      string `json:"tag,omitempty"` // rule tag from the comment, if generated by us
 	Packets  uint64 `json:"packets"`
 	Bytes    uint64 `json:"bytes"`
+}
+
+// RuleCounters is the live traffic matched by a stored rule, summed over the
+// kernel rules carrying its tag. LastHit is when the packet count was last
+// seen to grow; it is unknown until the counters have been read twice.
+type RuleCounters struct {
+	Tag     string     `json:"tag"`
+	Packets uint64     `json:"packets"`
+	Bytes   uint64     `json:"bytes"`
+	LastHit *time.Time `json:"lastHit,omitempty"`
 }
\ No newline at end of file
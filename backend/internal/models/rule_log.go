@@ -0,0 +1,29 @@
+package models
+
+// LogOptions control how a rule logs the packets it matches. On a LOG rule
+// they shape its single log line; on an ACCEPT, DROP or REJECT rule they
+// add a log line in front of the verdict, so the rule logs and then
+// decides.
+type LogOptions struct {
+	// Prefix starts every logged line; empty uses the rule's tag, which
+	// lets the firewall log attribute the line to the rule.
+	Prefix string `json:"prefix,omitempty"`
+	// Level is the syslog level of LOG lines, emerg to debug; empty is
+	// warning. Not used with NFLOG.
+	Level string `json:"level,omitempty"`
+	// Limit caps how many packets are logged, as "<n>/second", "/minute",
+	// "/hour" or "/day"; Burst is how many may be logged at once before the
+	// limit applies (default 5). Empty logs every packet.
+	Limit string `json:"limit,omitempty"`
+	Burst int    `json:"burst,omitempty"`
+	// NFLOGGroup sends packets to this NFLOG group instead of the kernel
+	// log.
+	NFLOGGroup *int `json:"nflogGroup,omitempty"`
+}
+
+// LogLevels maps the syslog level names accepted in LogOptions to their
+// numbers.
+var LogLevels = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "error": 3,
+	"warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
@@ -0,0 +1,25 @@
+package models
+
+// ScheduleMode selects how a rule's time window is enforced
+type ScheduleMode string
+
+const (
+	// ScheduleModeKernel compiles the window into the iptables time match.
+	ScheduleModeKernel ScheduleMode = "kernel"
+	// ScheduleModeScheduler has the server add/remove the rule and re-apply
+	// the ruleset whenever the window opens or closes.
+	ScheduleModeScheduler ScheduleMode = "scheduler"
+)
+
+// Schedule restricts a rule to recurring time windows.
+// A window whose EndTime is earlier than its StartTime runs past midnight
+// into the following day.
+type Schedule struct {
+	Mode      ScheduleMode `json:"mode"`
+	Days      []string     `json:"days,omitempty"`      // mon..sun, empty = every day
+	StartTime string       `json:"startTime,omitempty"` // HH:MM, empty = 00:00
+	EndTime   string       `json:"endTime,omitempty"`   // HH:MM, empty = end of day
+	Timezone  string       `json:"timezone,omitempty"`  // IANA name, "Local" or empty (UTC)
+	StartDate string       `json:"startDate,omitempty"` // YYYY-MM-DD, inclusive
+	EndDate   string       `json:"endDate,omitempty"`   // YYYY-MM-DD, inclusive
+}
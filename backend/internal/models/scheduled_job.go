@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// ScheduledJobAction is the operation a scheduled job performs
+type ScheduledJobAction string
+
+const (
+	ScheduledJobApply    ScheduledJobAction = "apply"
+	ScheduledJobRollback ScheduledJobAction = "rollback"
+)
+
+// ScheduledJobStatus tracks a scheduled job through its lifecycle
+type ScheduledJobStatus string
+
+const (
+	ScheduledJobPending   ScheduledJobStatus = "pending"
+	ScheduledJobRunning   ScheduledJobStatus = "running"
+	ScheduledJobSucceeded ScheduledJobStatus = "succeeded"
+	ScheduledJobFailed    ScheduledJobStatus = "failed"
+	ScheduledJobCancelled ScheduledJobStatus = "cancelled"
+	ScheduledJobSkipped   ScheduledJobStatus = "skipped"
+)
+
+// CatchUpPolicy decides what happens to a job whose run time passed while
+// the server was down
+type CatchUpPolicy string
+
+const (
+	CatchUpRun  CatchUpPolicy = "run"  // run as soon as the server is back
+	CatchUpSkip CatchUpPolicy = "skip" // mark the job skipped
+)
+
+// ScheduledJob is an apply or rollback queued for a future time
+type ScheduledJob struct {
+	ID           string             `json:"id" db:"id"`
+	Action       ScheduledJobAction `json:"action" db:"action"`
+	HistoryID    string             `json:"historyId,omitempty" db:"history_id"` // rollback target, empty = latest snapshot
+	Description  string             `json:"description" db:"description"`
+	RunAt        time.Time          `json:"runAt" db:"run_at"`
+	CatchUp      CatchUpPolicy      `json:"catchUp" db:"catch_up"`
+	GraceSeconds int                `json:"graceSeconds" db:"grace_seconds"` // lateness tolerated before the catch-up policy applies
+	Status       ScheduledJobStatus `json:"status" db:"status"`
+	Result       string             `json:"result" db:"result"`
+	CreatedBy    string             `json:"createdBy" db:"created_by"`
+	Creator      *JobCreator        `json:"-" db:"creator"`                         // nil for jobs scheduled before creators were recorded
+	ApplyJobID   string             `json:"applyJobId,omitempty" db:"apply_job_id"` // apply job the run was queued as
+	StartedAt    *time.Time         `json:"startedAt,omitempty" db:"started_at"`
+	FinishedAt   *time.Time         `json:"finishedAt,omitempty" db:"finished_at"`
+	CreatedAt    time.Time          `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time          `json:"updatedAt" db:"updated_at"`
+}
+
+// JobCreator records who scheduled a job and from which connection. The job
+// runs as the creator, and the lockout check sees the creator's connection.
+type JobCreator struct {
+	ID     string        `json:"id"`
+	Kind   string        `json:"kind"` // principal kind, "system" for jobs created internally
+	Roles  []RoleBinding `json:"roles"`
+	Scopes []string      `json:"scopes,omitempty"`
+
+	ClientIP     string `json:"clientIp,omitempty"`
+	ClientPort   int    `json:"clientPort,omitempty"`
+	LocalIP      string `json:"localIp,omitempty"`
+	LocalPort    int    `json:"localPort,omitempty"`
+	AllowLockout bool   `json:"allowLockout,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TrafficQuery ranks hosts or peers by their traffic in a time window.
+type TrafficQuery struct {
+	By        string // host (local hosts) or peer (remote peers)
+	Direction string // rx, tx or total
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+// Talker is the traffic of one address in a window. Directions are seen
+// from the local network: rx is what local hosts received, tx what they
+// sent, whether the address is a local host or a remote peer. BPS is the
+// average rate of both directions in bits per second.
+type Talker struct {
+	Address   string  `json:"address"`
+	RxBytes   uint64  `json:"rxBytes"`
+	TxBytes   uint64  `json:"txBytes"`
+	RxPackets uint64  `json:"rxPackets"`
+	TxPackets uint64  `json:"txPackets"`
+	Bytes     uint64  `json:"bytes"`
+	BPS       float64 `json:"bps"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Role is a named set of permissions
+type Role string
+
+const (
+	RoleViewer     Role = "viewer"      // read-only access
+	RoleRuleEditor Role = "rule-editor" // edit rules, NAT rules, zones and interfaces
+	RoleApplier    Role = "applier"     // apply and roll back the ruleset
+	RoleAdmin      Role = "admin"       // everything, including user management
+)
+
+// RoleBinding grants a role, optionally limited to one zone or one object tag.
+// An empty Zone and Tag make the binding global.
+type RoleBinding struct {
+	Role Role   `json:"role"`
+	Zone string `json:"zone,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+}
+
+// User is an account that can log in to the API and UI. Users signing in
+// through OIDC are created on first login and carry the provider's subject.
+type User struct {
+	ID           string        `json:"id" db:"id"`
+	Username     string        `json:"username" db:"username"`
+	PasswordHash string        `json:"-" db:"password_hash"` // bcrypt; empty for OIDC users
+	OIDCSubject  string        `json:"oidcSubject,omitempty" db:"oidc_subject"`
+	Disabled     bool          `json:"disabled" db:"disabled"`
+	Roles        []RoleBinding `json:"roles"`
+	CreatedAt    time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time     `json:"updatedAt" db:"updated_at"`
+}
+
+// Session is a logged-in user session. Only the token hash is stored.
+type Session struct {
+	ID        string    `json:"id" db:"id"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	UserID    string    `json:"userId" db:"user_id"`
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// VerificationReport compares what an apply rendered with the live kernel
+// state read back right after it.
+type VerificationReport struct {
+	OK         bool                   `json:"ok"`
+	CheckedAt  time.Time              `json:"checkedAt"`
+	Rules      int                    `json:"rules"`    // rendered rules checked
+	Policies   int                    `json:"policies"` // chain policies checked
+	Sysctls    int                    `json:"sysctls"`  // kernel settings checked
+	Mismatches []VerificationMismatch `json:"mismatches,omitempty"`
+}
+
+// VerificationMismatch is one difference between the rendered payload and
+// the live state. Expected or Actual is empty when a rule is missing or
+// unexpected.
+type VerificationMismatch struct {
+	Table    string `json:"table"` // filter, nat or sysctl
+	Chain    string `json:"chain,omitempty"`
+	Position int    `json:"position,omitempty"` // 1-based rule index in the chain
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Mismatch records a difference and marks the report failed.
+func (r *VerificationReport) Mismatch(m VerificationMismatch) {
+	r.OK = false
+	r.Mismatches = append(r.Mismatches, m)
+}
+
+// DriftState tells whether the kernel still holds what was last applied.
+type DriftState string
+
+const (
+	DriftInSync  DriftState = "in_sync"
+	DriftDrifted DriftState = "drifted"
+	// DriftUnknown means no verified payload is loaded: nothing has been
+	// applied since startup, or a rollback, schedule transition, panic mode
+	// or failed apply changed the kernel since.
+	DriftUnknown DriftState = "unknown"
+)
+
+// DriftStatus is the result of checking the live state against the payload
+// of the last verified apply.
+type DriftStatus struct {
+	State       DriftState          `json:"state"`
+	PayloadHash string              `json:"payloadHash,omitempty"`
+	CheckedAt   time.Time           `json:"checkedAt"`
+	Report      *VerificationReport `json:"report,omitempty"`
+}
@@ -1,27 +1,56 @@
 package network
 
 import (
+	"errors"
 	"fmt"
 	"net"
 
+	"github.com/firewall-manager/backend/internal/models"
 	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
-// Info holds details about a network interface.
+// ErrAddrExists and ErrAddrNotFound are returned by AddAddr and DelAddr.
+var (
+	ErrAddrExists   = errors.New("address already assigned")
+	ErrAddrNotFound = errors.New("address not assigned")
+)
+
+// Info holds details about a network interface. IP and Mask are the
+// primary IPv4 address, the first one that is not secondary; Addrs lists
+// every IPv4 and IPv6 address.
 type Info struct {
-	Name    string
-	IP      string
-	Mask    string
-	Gateway string
-	MAC     string
-	Enabled bool
+	Name     string
+	IP       string
+	Mask     string
+	Gateway  string
+	Gateway6 string
+	MAC      string
+	Enabled  bool
+	Addrs    []models.InterfaceAddress
+}
+
+// HasIP reports whether ip is assigned to the interface.
+func (i Info) HasIP(ip net.IP) bool {
+	for _, a := range i.Addrs {
+		if addr := net.ParseIP(a.IP); addr != nil && addr.Equal(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 // Driver defines the interface for network operations.
 type Driver interface {
 	GetInterfaces() ([]Info, error)
+	// ApplyConfig replaces the primary IPv4 address and sets the default
+	// gateway and link state. Secondary and IPv6 addresses are kept; an
+	// empty ip leaves the addresses alone.
 	ApplyConfig(ifaceName, ip, mask, gateway string, enabled bool) error
+	// AddAddr and DelAddr add or remove one address in ip/prefix notation.
+	AddAddr(ifaceName, cidr string) error
+	DelAddr(ifaceName, cidr string) error
 }
 
 // NetlinkDriver provides methods to interact with network interfaces using netlink.
@@ -48,32 +77,40 @@ func (d *NetlinkDriver) GetInterfaces() ([]Info, error) {
 			continue
 		}
 
-		addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
-		if err != nil {
-			d.log.WithError(err).WithField("interface", link.Attrs().Name).Warn("failed to get addresses for interface")
-		}
-
 		info := Info{
 			Name:    link.Attrs().Name,
 			MAC:     link.Attrs().HardwareAddr.String(),
 			Enabled: link.Attrs().Flags&net.FlagUp != 0,
 		}
 
-		if len(addrs) > 0 {
-			ipNet := addrs[0].IPNet
-			if ipNet != nil {
-				info.IP = ipNet.IP.String()
-				ones, _ := ipNet.Mask.Size()
-				info.Mask = fmt.Sprintf("%d", ones)
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			d.log.WithError(err).WithField("interface", link.Attrs().Name).Warn("failed to get addresses for interface")
+		}
+		for _, a := range addrs {
+			if a.IPNet == nil {
+				continue
+			}
+			addr := convertAddr(a)
+			info.Addrs = append(info.Addrs, addr)
+			if info.IP == "" && addr.Family == "ipv4" && !addr.HasFlag("secondary") {
+				info.IP = addr.IP
+				info.Mask = fmt.Sprintf("%d", addr.PrefixLen)
 			}
 		}
 
-		routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+		routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
 		if err == nil {
 			for _, r := range routes {
-				if r.Dst == nil && r.Gw != nil {
-					info.Gateway = r.Gw.String()
-					break
+				if !isDefaultRoute(r) || r.Gw == nil {
+					continue
+				}
+				if r.Gw.To4() != nil {
+					if info.Gateway == "" {
+						info.Gateway = r.Gw.String()
+					}
+				} else if info.Gateway6 == "" {
+					info.Gateway6 = r.Gw.String()
 				}
 			}
 		} else {
@@ -86,6 +123,76 @@ func (d *NetlinkDriver) GetInterfaces() ([]Info, error) {
 	return interfaces, nil
 }
 
+// isDefaultRoute reports whether r is a default route. Depending on the
+// kernel, netlink reports its destination as nil or as 0.0.0.0/0 or ::/0.
+func isDefaultRoute(r netlink.Route) bool {
+	if r.Dst == nil {
+		return true
+	}
+	ones, _ := r.Dst.Mask.Size()
+	return ones == 0 && r.Dst.IP.IsUnspecified()
+}
+
+// addrFlags names the IFA_F_* flags. IFA_F_SECONDARY and IFA_F_TEMPORARY
+// share a bit: IPv4 addresses are secondary, IPv6 ones temporary.
+var addrFlags = []struct {
+	bit  int
+	name string
+}{
+	{unix.IFA_F_NODAD, "nodad"},
+	{unix.IFA_F_OPTIMISTIC, "optimistic"},
+	{unix.IFA_F_DADFAILED, "dadfailed"},
+	{unix.IFA_F_HOMEADDRESS, "homeaddress"},
+	{unix.IFA_F_DEPRECATED, "deprecated"},
+	{unix.IFA_F_TENTATIVE, "tentative"},
+	{unix.IFA_F_PERMANENT, "permanent"},
+	{unix.IFA_F_MANAGETEMPADDR, "mngtmpaddr"},
+	{unix.IFA_F_NOPREFIXROUTE, "noprefixroute"},
+	{unix.IFA_F_MCAUTOJOIN, "autojoin"},
+	{unix.IFA_F_STABLE_PRIVACY, "stable-privacy"},
+}
+
+// lifetimeForever is the kernel's infinite address lifetime.
+const lifetimeForever = 0xFFFFFFFF
+
+func convertAddr(a netlink.Addr) models.InterfaceAddress {
+	ones, _ := a.Mask.Size()
+	addr := models.InterfaceAddress{IP: a.IP.String(), PrefixLen: ones, Family: "ipv6"}
+	if a.IP.To4() != nil {
+		addr.Family = "ipv4"
+	}
+
+	switch a.Scope {
+	case unix.RT_SCOPE_SITE:
+		addr.Scope = "site"
+	case unix.RT_SCOPE_LINK:
+		addr.Scope = "link"
+	case unix.RT_SCOPE_HOST:
+		addr.Scope = "host"
+	default:
+		addr.Scope = "global"
+	}
+
+	if a.Flags&unix.IFA_F_SECONDARY != 0 {
+		if addr.Family == "ipv4" {
+			addr.Flags = append(addr.Flags, "secondary")
+		} else {
+			addr.Flags = append(addr.Flags, "temporary")
+		}
+	}
+	for _, f := range addrFlags {
+		if a.Flags&f.bit != 0 {
+			addr.Flags = append(addr.Flags, f.name)
+		}
+	}
+	if a.ValidLft > 0 && uint32(a.ValidLft) != lifetimeForever {
+		addr.Flags = append(addr.Flags, "dynamic")
+		addr.ValidLifetime = a.ValidLft
+		addr.PreferredLifetime = a.PreferedLft
+	}
+	return addr
+}
+
 // ApplyConfig applies IP/mask/gateway configuration to an interface.
 // NOTE: This requires the application to run with CAP_NET_ADMIN capabilities.
 func (d *NetlinkDriver) ApplyConfig(ifaceName, ip, mask, gateway string, enabled bool) error {
@@ -102,27 +209,17 @@ func (d *NetlinkDriver) ApplyConfig(ifaceName, ip, mask, gateway string, enabled
 		return fmt.Errorf("failed to find link %s: %w", ifaceName, err)
 	}
 
-	// It's safer to bring the link down before changing IP addresses
-	if err := netlink.LinkSetDown(link); err != nil {
-		d.log.WithError(err).Warnf("failed to set link %s down before config change", ifaceName)
-	}
-
-	// Flush existing addresses to avoid conflicts
-	existingAddrs, _ := netlink.AddrList(link, netlink.FAMILY_V4)
-	for _, oldAddr := range existingAddrs {
-		if err := netlink.AddrDel(link, &oldAddr); err != nil {
-			d.log.WithError(err).Warnf("failed to delete old address %s", oldAddr.IP.String())
+	// Bringing the link down would flush its IPv6 addresses, so the link
+	// state is only changed when asked for.
+	if !enabled {
+		if err := netlink.LinkSetDown(link); err != nil {
+			return fmt.Errorf("failed to set link %s down: %w", ifaceName, err)
 		}
 	}
 
 	if ip != "" && mask != "" {
-		addrStr := fmt.Sprintf("%s/%s", ip, mask)
-		addr, err := netlink.ParseAddr(addrStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse address %s: %w", addrStr, err)
-		}
-		if err := netlink.AddrAdd(link, addr); err != nil {
-			return fmt.Errorf("failed to add address %s to %s: %w", addrStr, ifaceName, err)
+		if err := d.replacePrimary(link, fmt.Sprintf("%s/%s", ip, mask)); err != nil {
+			return err
 		}
 	}
 
@@ -146,3 +243,112 @@ func (d *NetlinkDriver) ApplyConfig(ifaceName, ip, mask, gateway string, enabled
 	d.log.WithField("interface", ifaceName).Info("Successfully applied network configuration")
 	return nil
 }
+
+// replacePrimary makes addrStr the primary IPv4 address of link. Deleting
+// a primary address also deletes the secondaries of its subnet unless
+// promote_secondaries is set, so any address that disappeared with it is
+// added back.
+func (d *NetlinkDriver) replacePrimary(link netlink.Link, addrStr string) error {
+	addr, err := netlink.ParseAddr(addrStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", addrStr, err)
+	}
+	if addr.IP.To4() == nil {
+		return fmt.Errorf("primary address %s is not IPv4", addrStr)
+	}
+	existing, err := netlink.AddrList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("failed to list addresses of %s: %w", link.Attrs().Name, err)
+	}
+
+	var primary *netlink.Addr
+	for i := range existing {
+		if existing[i].Flags&unix.IFA_F_SECONDARY == 0 {
+			primary = &existing[i]
+			break
+		}
+	}
+	if primary != nil && primary.IPNet.String() == addr.IPNet.String() {
+		return nil
+	}
+
+	// Add the new address first so the link is never left without one.
+	if err := netlink.AddrAdd(link, addr); err != nil && !errors.Is(err, unix.EEXIST) {
+		return fmt.Errorf("failed to add address %s to %s: %w", addrStr, link.Attrs().Name, err)
+	}
+	if primary == nil {
+		return nil
+	}
+	if err := netlink.AddrDel(link, primary); err != nil {
+		return fmt.Errorf("failed to delete old address %s: %w", primary.IPNet, err)
+	}
+
+	keep := []*netlink.Addr{addr}
+	for i := range existing {
+		if &existing[i] != primary {
+			keep = append(keep, &existing[i])
+		}
+	}
+	current, err := netlink.AddrList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("failed to list addresses of %s: %w", link.Attrs().Name, err)
+	}
+	for _, k := range keep {
+		if !containsAddr(current, k) {
+			readd := &netlink.Addr{IPNet: k.IPNet, Label: k.Label, Broadcast: k.Broadcast}
+			if err := netlink.AddrAdd(link, readd); err != nil && !errors.Is(err, unix.EEXIST) {
+				d.log.WithError(err).Warnf("failed to restore address %s", k.IPNet)
+			}
+		}
+	}
+	return nil
+}
+
+func containsAddr(addrs []netlink.Addr, a *netlink.Addr) bool {
+	for _, x := range addrs {
+		if x.IPNet.String() == a.IPNet.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAddr adds one IPv4 or IPv6 address without touching the others.
+func (d *NetlinkDriver) AddAddr(ifaceName, cidr string) error {
+	link, err := netlink.LinkByName(ifaceName)
+	if err != nil {
+		return fmt.Errorf("failed to find link %s: %w", ifaceName, err)
+	}
+	addr, err := netlink.ParseAddr(cidr)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", cidr, err)
+	}
+	if err := netlink.AddrAdd(link, addr); err != nil {
+		if errors.Is(err, unix.EEXIST) {
+			return fmt.Errorf("%w: %s on %s", ErrAddrExists, cidr, ifaceName)
+		}
+		return fmt.Errorf("failed to add address %s to %s: %w", cidr, ifaceName, err)
+	}
+	d.log.WithFields(logrus.Fields{"interface": ifaceName, "address": cidr}).Info("Added address")
+	return nil
+}
+
+// DelAddr removes one IPv4 or IPv6 address without touching the others.
+func (d *NetlinkDriver) DelAddr(ifaceName, cidr string) error {
+	link, err := netlink.LinkByName(ifaceName)
+	if err != nil {
+		return fmt.Errorf("failed to find link %s: %w", ifaceName, err)
+	}
+	addr, err := netlink.ParseAddr(cidr)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", cidr, err)
+	}
+	if err := netlink.AddrDel(link, addr); err != nil {
+		if errors.Is(err, unix.EADDRNOTAVAIL) {
+			return fmt.Errorf("%w: %s on %s", ErrAddrNotFound, cidr, ifaceName)
+		}
+		return fmt.Errorf("failed to delete address %s from %s: %w", cidr, ifaceName, err)
+	}
+	d.log.WithFields(logrus.Fields{"interface": ifaceName, "address": cidr}).Info("Deleted address")
+	return nil
+}
@@ -1,27 +1,74 @@
 package network
 
-import "github.com/sirupsen/logrus"
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
 
-// MockDriver is a mock network driver for testing.
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// MockDriver is a mock network driver for testing. It keeps the changes
+// made through it in memory.
 type MockDriver struct {
 	log *logrus.Logger
+
+	mu     sync.Mutex
+	ifaces []Info
 }
 
 // NewMockDriver creates a new MockDriver.
 func NewMockDriver(log *logrus.Logger) *MockDriver {
-	return &MockDriver{log: log}
+	return &MockDriver{log: log, ifaces: []Info{
+		{Name: "eth0", Gateway: "192.168.1.1", Gateway6: "fe80::1", MAC: "00:11:22:33:44:55", Enabled: true, Addrs: []models.InterfaceAddress{
+			mockAddr("192.168.1.100", 24),
+			mockAddr("2001:db8::100", 64),
+			{IP: "fe80::211:22ff:fe33:4455", PrefixLen: 64, Family: "ipv6", Scope: "link", Flags: []string{"permanent"}},
+		}},
+		{Name: "eth1", MAC: "AA:BB:CC:DD:EE:FF", Enabled: false, Addrs: []models.InterfaceAddress{
+			mockAddr("10.0.0.5", 8),
+		}},
+		{Name: "wg0", MAC: "DE:AD:BE:EF:00:00", Enabled: true, Addrs: []models.InterfaceAddress{
+			mockAddr("10.100.100.1", 24),
+			mockAddr("fd00:100::1", 64),
+		}},
+	}}
+}
+
+func mockAddr(ip string, prefixLen int) models.InterfaceAddress {
+	addr := models.InterfaceAddress{IP: ip, PrefixLen: prefixLen, Family: "ipv6", Scope: "global", Flags: []string{"permanent"}}
+	if net.ParseIP(ip).To4() != nil {
+		addr.Family = "ipv4"
+	}
+	return addr
 }
 
-// GetInterfaces returns a static list of interfaces for testing.
+// GetInterfaces returns the mock interfaces.
 func (d *MockDriver) GetInterfaces() ([]Info, error) {
-	return []Info{
-		{Name: "eth0", IP: "192.168.1.100", Mask: "24", Gateway: "192.168.1.1", MAC: "00:11:22:33:44:55", Enabled: true},
-		{Name: "eth1", IP: "10.0.0.5", Mask: "8", Gateway: "", MAC: "AA:BB:CC:DD:EE:FF", Enabled: false},
-		{Name: "wg0", IP: "10.100.100.1", Mask: "24", Gateway: "", MAC: "DE:AD:BE:EF:00:00", Enabled: true},
-	}, nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	infos := make([]Info, len(d.ifaces))
+	for i, iface := range d.ifaces {
+		iface.Addrs = append([]models.InterfaceAddress(nil), iface.Addrs...)
+		iface.IP, iface.Mask = "", ""
+		for j, a := range iface.Addrs {
+			// Like the kernel, flag IPv4 addresses after the first as
+			// secondary.
+			if a.Family == "ipv4" && iface.IP == "" {
+				iface.IP, iface.Mask = a.IP, strconv.Itoa(a.PrefixLen)
+			} else if a.Family == "ipv4" && !a.HasFlag("secondary") {
+				iface.Addrs[j].Flags = append([]string{"secondary"}, a.Flags...)
+			}
+		}
+		infos[i] = iface
+	}
+	return infos, nil
 }
 
-// ApplyConfig logs the configuration that would be applied.
+// ApplyConfig records the configuration that would be applied.
 func (d *MockDriver) ApplyConfig(ifaceName, ip, mask, gateway string, enabled bool) error {
 	d.log.WithFields(logrus.Fields{
 		"interface": ifaceName,
@@ -30,5 +77,93 @@ func (d *MockDriver) ApplyConfig(ifaceName, ip, mask, gateway string, enabled bo
 		"gateway":   gateway,
 		"enabled":   enabled,
 	}).Info("MOCK: Applying network configuration")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	iface := d.find(ifaceName)
+	if iface == nil {
+		return nil
+	}
+	iface.Enabled = enabled
+	if ip != "" && mask != "" {
+		prefixLen, err := strconv.Atoi(mask)
+		if err != nil || net.ParseIP(ip).To4() == nil {
+			return fmt.Errorf("failed to parse address %s/%s", ip, mask)
+		}
+		addrs := []models.InterfaceAddress{mockAddr(ip, prefixLen)}
+		primary := true
+		for _, a := range iface.Addrs {
+			if a.Family == "ipv4" && primary {
+				primary = false
+				continue
+			}
+			if a.IP != ip {
+				addrs = append(addrs, a)
+			}
+		}
+		iface.Addrs = addrs
+	}
+	if gateway != "" {
+		if net.ParseIP(gateway).To4() != nil {
+			iface.Gateway = gateway
+		} else {
+			iface.Gateway6 = gateway
+		}
+	}
+	return nil
+}
+
+// AddAddr records an added address.
+func (d *MockDriver) AddAddr(ifaceName, cidr string) error {
+	d.log.WithFields(logrus.Fields{"interface": ifaceName, "address": cidr}).Info("MOCK: Adding address")
+	ip, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", cidr, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	iface := d.find(ifaceName)
+	if iface == nil {
+		return fmt.Errorf("failed to find link %s", ifaceName)
+	}
+	if iface.HasIP(ip) {
+		return fmt.Errorf("%w: %s on %s", ErrAddrExists, cidr, ifaceName)
+	}
+	ones, _ := n.Mask.Size()
+	iface.Addrs = append(iface.Addrs, mockAddr(ip.String(), ones))
+	return nil
+}
+
+// DelAddr records a removed address.
+func (d *MockDriver) DelAddr(ifaceName, cidr string) error {
+	d.log.WithFields(logrus.Fields{"interface": ifaceName, "address": cidr}).Info("MOCK: Deleting address")
+	ip, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", cidr, err)
+	}
+	ones, _ := n.Mask.Size()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	iface := d.find(ifaceName)
+	if iface == nil {
+		return fmt.Errorf("failed to find link %s", ifaceName)
+	}
+	for i, a := range iface.Addrs {
+		if net.ParseIP(a.IP).Equal(ip) && a.PrefixLen == ones {
+			iface.Addrs = append(iface.Addrs[:i], iface.Addrs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s on %s", ErrAddrNotFound, cidr, ifaceName)
+}
+
+func (d *MockDriver) find(name string) *Info {
+	for i := range d.ifaces {
+		if d.ifaces[i].Name == name {
+			return &d.ifaces[i]
+		}
+	}
 	return nil
 }
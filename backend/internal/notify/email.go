@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// EmailSink sends a plain-text mail per message. It upgrades the connection
+// with STARTTLS when the server offers it and uses implicit TLS on port 465.
+// Credentials are only sent over TLS or to localhost.
+type EmailSink struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	TLS      *tls.Config // trusted roots and verification; nil uses the defaults
+}
+
+func newEmailSink(c models.NotificationSinkConfig) (*EmailSink, error) {
+	if c.Host == "" {
+		return nil, errors.New("email sink needs a host")
+	}
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+	if port < 1 || port > 65535 {
+		return nil, errors.New("port must be between 1 and 65535")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+	if len(c.To) == 0 {
+		return nil, errors.New("email sink needs at least one recipient")
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+	}
+	tlsCfg, err := tlsConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailSink{Host: c.Host, Port: port, Username: c.Username, Password: c.Password, From: c.From, To: c.To, TLS: tlsCfg}, nil
+}
+
+func (s *EmailSink) Send(ctx context.Context, msg *Message) error {
+	err := s.send(ctx, msg)
+	// 5xx replies reject the sender, a recipient or the message for good.
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &PermanentError{err}
+	}
+	return err
+}
+
+func (s *EmailSink) send(ctx context.Context, msg *Message) error {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	dialer := &net.Dialer{Timeout: Timeout}
+	var conn net.Conn
+	var err error
+	if s.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig()}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Hello(hostname()); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.compose(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// tlsConfig returns the TLS settings for a connection to Host.
+func (s *EmailSink) tlsConfig() *tls.Config {
+	cfg := &tls.Config{}
+	if s.TLS != nil {
+		cfg = s.TLS.Clone()
+	}
+	cfg.ServerName = s.Host
+	return cfg
+}
+
+// compose renders the mail: the summary, then the event as indented JSON.
+func (s *EmailSink) compose(msg *Message) []byte {
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Summary)
+	if msg.Severity <= SeverityWarning {
+		subject = fmt.Sprintf("[%s] %s", msg.Severity, subject)
+	}
+	event, _ := json.MarshalIndent(msg.Event, "", "  ")
+
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", s.From)
+	header("To", strings.Join(s.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", "[firewall-manager] "+subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", msg.DeliveryID, hostname()))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Summary)
+	b.WriteString("\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(string(event), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// smtpStub is an in-process SMTP server that records what it is sent.
+type smtpStub struct {
+	ln        net.Listener
+	tls       *tls.Config // offers STARTTLS when set
+	rcptReply string      // reply to RCPT TO
+
+	mu      sync.Mutex
+	auth    string // AUTH arguments, "" if none
+	authTLS bool   // whether AUTH came over TLS
+	from    string
+	rcpts   []string
+	data    string
+}
+
+func newSMTPStub(t *testing.T, tlsConfig *tls.Config) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln, tls: tlsConfig, rcptReply: "250 2.1.5 OK"}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// sink returns an email sink pointed at the stub.
+func (s *smtpStub) sink(t *testing.T, c models.NotificationSinkConfig) Sink {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	c.Host = host
+	c.Port, _ = strconv.Atoi(port)
+	c.From = "fw@example.com"
+	c.To = []string{"ops@example.com", "oncall@example.com"}
+	sink, err := New(&models.NotificationSink{Type: models.SinkEmail, Config: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sink
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	tp := textproto.NewConn(conn)
+	secure := false
+	tp.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ext := []string{"stub", "AUTH PLAIN", "8BITMIME"}
+			if s.tls != nil && !secure {
+				ext = append(ext, "STARTTLS")
+			}
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 2.0.0 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, secure = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			s.mu.Lock()
+			s.auth, s.authTLS = arg, secure
+			s.mu.Unlock()
+			tp.PrintfLine("235 2.7.0 authenticated")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = arg
+			s.mu.Unlock()
+			tp.PrintfLine("250 2.1.0 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, arg)
+			reply := s.rcptReply
+			s.mu.Unlock()
+			tp.PrintfLine("%s", reply)
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = string(data)
+			s.mu.Unlock()
+			tp.PrintfLine("250 2.0.0 queued")
+		case "QUIT":
+			tp.PrintfLine("221 2.0.0 bye")
+			return
+		default:
+			tp.PrintfLine("502 5.5.2 unknown command")
+		}
+	}
+}
+
+// selfSigned returns a TLS config for 127.0.0.1 and its certificate as PEM.
+func selfSigned(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp stub"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	return cfg, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestEmailSend(t *testing.T) {
+	stub := newSMTPStub(t, nil)
+	msg := testMessage()
+	msg.Summary = "drift detected\r\nBcc: evil@example.com"
+	msg.Severity = SeverityWarning
+
+	if err := stub.sink(t, models.NotificationSinkConfig{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if !strings.HasPrefix(stub.from, "FROM:<fw@example.com>") {
+		t.Errorf("MAIL %q", stub.from)
+	}
+	if len(stub.rcpts) != 2 || !strings.Contains(stub.rcpts[1], "oncall@example.com") {
+		t.Errorf("RCPT %q", stub.rcpts)
+	}
+	if stub.auth != "" {
+		t.Errorf("authenticated without credentials: %q", stub.auth)
+	}
+
+	mail, err := textproto.NewReader(bufio.NewReader(strings.NewReader(stub.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mail.Get("Subject"); got != "[firewall-manager] [warning] drift detected  Bcc: evil@example.com" {
+		t.Errorf("Subject %q", got)
+	}
+	if mail.Get("Bcc") != "" {
+		t.Error("summary injected a header")
+	}
+	if mail.Get("To") != "ops@example.com, oncall@example.com" || mail.Get("Message-Id") == "" {
+		t.Errorf("headers %v", mail)
+	}
+	if !strings.Contains(stub.data, `"type": "apply"`) {
+		t.Errorf("body lacks the event: %q", stub.data)
+	}
+}
+
+func TestEmailRejections(t *testing.T) {
+	tests := []struct {
+		reply     string
+		permanent bool
+	}{
+		{"550 5.1.1 no such user", true},
+		{"553 5.1.3 bad address", true},
+		{"451 4.3.0 try again later", false},
+		{"452 4.2.2 mailbox full", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			stub := newSMTPStub(t, nil)
+			stub.rcptReply = tt.reply
+			err := stub.sink(t, models.NotificationSinkConfig{}).Send(context.Background(), testMessage())
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+		})
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		stub := newSMTPStub(t, nil)
+		sink := stub.sink(t, models.NotificationSinkConfig{})
+		stub.ln.Close()
+		if err := sink.Send(context.Background(), testMessage()); err == nil || IsPermanent(err) {
+			t.Errorf("err = %v, want a retryable error", err)
+		}
+	})
+}
+
+func TestEmailStartTLS(t *testing.T) {
+	serverTLS, ca := selfSigned(t)
+	tests := []struct {
+		name   string
+		config models.NotificationSinkConfig
+		ok     bool
+	}{
+		{"system roots", models.NotificationSinkConfig{}, false},
+		{"ca bundle", models.NotificationSinkConfig{CACert: ca}, true},
+		{"skip verify", models.NotificationSinkConfig{InsecureSkipVerify: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newSMTPStub(t, serverTLS)
+			tt.config.Username, tt.config.Password = "fw", "pw"
+			err := stub.sink(t, tt.config).Send(context.Background(), testMessage())
+			if !tt.ok {
+				if err == nil || !strings.Contains(err.Error(), "starttls") {
+					t.Errorf("err = %v, want a starttls failure", err)
+				}
+				stub.mu.Lock()
+				defer stub.mu.Unlock()
+				if stub.auth != "" {
+					t.Error("credentials sent after a failed STARTTLS")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if !strings.HasPrefix(stub.auth, "PLAIN") || !stub.authTLS {
+				t.Errorf("AUTH %q over TLS=%v", stub.auth, stub.authTLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package notify delivers notifications about firewall events to external
+// systems: HTTP webhooks, Slack-compatible chat, email and syslog.
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// Timeout bounds a single delivery attempt.
+const Timeout = 10 * time.Second
+
+// Severity ranks a notification. The values are the syslog severities.
+type Severity int
+
+const (
+	SeverityCritical Severity = 2
+	SeverityError    Severity = 3
+	SeverityWarning  Severity = 4
+	SeverityNotice   Severity = 5
+	SeverityInfo     Severity = 6
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityCritical:
+		return "critical"
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNotice:
+		return "notice"
+	}
+	return "info"
+}
+
+// Message is one event to notify about.
+type Message struct {
+	DeliveryID string
+	Event      *models.Event
+	Summary    string // one line for humans
+	Severity   Severity
+}
+
+// Sink delivers messages to one destination. Send makes a single attempt;
+// the caller retries unless the error is permanent.
+type Sink interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// PermanentError marks a failure that retrying does not fix, such as a
+// rejected request.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err should not be retried.
+func IsPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
+
+// New returns the sink a configuration describes. It checks the settings
+// the sink type needs.
+func New(sink *models.NotificationSink) (Sink, error) {
+	c := sink.Config
+	switch sink.Type {
+	case models.SinkWebhook:
+		if err := checkURL(c.URL); err != nil {
+			return nil, err
+		}
+		client, err := newHTTPClient(c)
+		if err != nil {
+			return nil, err
+		}
+		return &WebhookSink{URL: c.URL, Secret: c.Secret, Client: client}, nil
+	case models.SinkSlack:
+		if err := checkURL(c.URL); err != nil {
+			return nil, err
+		}
+		client, err := newHTTPClient(c)
+		if err != nil {
+			return nil, err
+		}
+		return &SlackSink{URL: c.URL, Client: client}, nil
+	case models.SinkEmail:
+		return newEmailSink(c)
+	case models.SinkSyslog:
+		return newSyslogSink(c)
+	}
+	return nil, fmt.Errorf("unknown sink type %q", sink.Type)
+}
+
+// tlsConfig returns the TLS settings of a sink, or nil for the defaults.
+func tlsConfig(c models.NotificationSinkConfig) (*tls.Config, error) {
+	if c.CACert == "" && !c.InsecureSkipVerify {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CACert != "" {
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM([]byte(c.CACert)) {
+			return nil, errors.New("caCert contains no PEM certificates")
+		}
+	}
+	return cfg, nil
+}
+
+// hostname names this host in syslog messages and mail.
+func hostname() string {
+	if h, err := os.Hostname(); err == nil && h != "" {
+		return h
+	}
+	return "localhost"
+}
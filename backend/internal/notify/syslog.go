@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// maxSyslogDatagram is the message size RFC 5426 receivers must accept;
+// longer UDP messages are truncated.
+const maxSyslogDatagram = 2048
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogSink sends RFC 5424 messages, one per UDP datagram or with octet
+// counting framing (RFC 6587) over TCP. The message ID is the event type and
+// the text is the summary followed by the event as JSON.
+type SyslogSink struct {
+	Network  string // udp or tcp
+	Address  string
+	Facility int
+	AppName  string
+}
+
+func newSyslogSink(c models.NotificationSinkConfig) (*SyslogSink, error) {
+	s := &SyslogSink{Network: c.Network, Address: c.Address, AppName: c.AppName}
+	if s.Network == "" {
+		s.Network = "udp"
+	}
+	if s.Network != "udp" && s.Network != "tcp" {
+		return nil, errors.New("network must be udp or tcp")
+	}
+	host, port, err := net.SplitHostPort(s.Address)
+	if err != nil || host == "" || port == "" {
+		return nil, errors.New("syslog sink needs an address as host:port")
+	}
+	facility := c.Facility
+	if facility == "" {
+		facility = "daemon"
+	}
+	f, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	s.Facility = f
+	if s.AppName == "" {
+		s.AppName = "firewall-manager"
+	}
+	s.AppName = headerField(s.AppName, 48)
+	return s, nil
+}
+
+func (s *SyslogSink) Send(ctx context.Context, msg *Message) error {
+	line := s.Format(msg, time.Now())
+	dialer := &net.Dialer{Timeout: Timeout}
+	conn, err := dialer.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	if s.Network == "udp" {
+		if len(line) > maxSyslogDatagram {
+			line = line[:maxSyslogDatagram]
+		}
+		_, err = conn.Write(line)
+		return err
+	}
+	frame := append([]byte(strconv.Itoa(len(line))+" "), line...)
+	_, err = conn.Write(frame)
+	return err
+}
+
+// Format renders msg as an RFC 5424 message without structured data.
+func (s *SyslogSink) Format(msg *Message, at time.Time) []byte {
+	event, _ := json.Marshal(msg.Event)
+	pri := s.Facility*8 + int(msg.Severity)
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s %d %s - %s %s",
+		pri,
+		at.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(hostname(), 255),
+		s.AppName,
+		os.Getpid(),
+		headerField(msg.Event.Type, 32),
+		strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Summary),
+		event,
+	))
+}
+
+// headerField makes s a valid RFC 5424 header field: printable US-ASCII
+// without spaces, at most max characters, "-" if empty.
+func headerField(s string, max int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if c := s[i]; c > 32 && c < 127 {
+			b = append(b, c)
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+func TestSyslogFormat(t *testing.T) {
+	at := time.Date(2026, 10, 18, 14, 0, 0, 123456000, time.FixedZone("CEST", 2*3600))
+	tests := []struct {
+		name     string
+		config   models.NotificationSinkConfig
+		severity Severity
+		event    string
+		summary  string
+		header   string // expected text up to the MSGID
+		text     string
+	}{
+		{
+			name:     "defaults",
+			config:   models.NotificationSinkConfig{Address: "127.0.0.1:514"},
+			severity: SeverityNotice,
+			event:    "apply",
+			summary:  "alice applied 12 rules",
+			header:   "<29>1 2026-10-18T12:00:00.123456Z %s firewall-manager %d apply",
+			text:     "alice applied 12 rules",
+		},
+		{
+			name:     "facility and app name",
+			config:   models.NotificationSinkConfig{Address: "127.0.0.1:514", Facility: "local4", AppName: "fw edge 1"},
+			severity: SeverityCritical,
+			event:    "panic.enabled",
+			summary:  "panic mode on\nall traffic dropped",
+			header:   "<162>1 2026-10-18T12:00:00.123456Z %s fwedge1 %d panic.enabled",
+			text:     "panic mode on all traffic dropped",
+		},
+		{
+			name:     "long message id",
+			config:   models.NotificationSinkConfig{Address: "127.0.0.1:514", Facility: "auth"},
+			severity: SeverityWarning,
+			event:    "notification.delivery.failed.permanently.after.retries",
+			summary:  "x",
+			header:   "<36>1 2026-10-18T12:00:00.123456Z %s firewall-manager %d notification.delivery.failed.per",
+			text:     "x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSyslogSink(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := testMessage()
+			msg.Event.Type, msg.Summary, msg.Severity = tt.event, tt.summary, tt.severity
+
+			got := string(s.Format(msg, at))
+			want := fmt.Sprintf(tt.header, headerField(hostname(), 255), os.Getpid()) + " - " + tt.text + " {"
+			if !strings.HasPrefix(got, want) {
+				t.Errorf("Format =\n%s\nwant prefix\n%s", got, want)
+			}
+			if !strings.HasSuffix(got, `"actor":"alice"}`) {
+				t.Errorf("Format does not end with the event: %s", got)
+			}
+		})
+	}
+}
+
+func TestHeaderField(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"host-1", 255, "host-1"},
+		{"fw edge", 48, "fwedge"},
+		{"tab\there", 48, "tabhere"},
+		{"ümlaut", 48, "mlaut"},
+		{"", 48, "-"},
+		{"   ", 48, "-"},
+		{"abcdef", 4, "abcd"},
+	}
+	for _, tt := range tests {
+		if got := headerField(tt.in, tt.max); got != tt.want {
+			t.Errorf("headerField(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogSinkConfig(t *testing.T) {
+	tests := []struct {
+		config models.NotificationSinkConfig
+		ok     bool
+	}{
+		{models.NotificationSinkConfig{Address: "logs.example.com:514"}, true},
+		{models.NotificationSinkConfig{Network: "tcp", Address: "[2001:db8::1]:6514"}, true},
+		{models.NotificationSinkConfig{Network: "tls", Address: "logs:6514"}, false},
+		{models.NotificationSinkConfig{Address: "logs.example.com"}, false},
+		{models.NotificationSinkConfig{Address: ":514"}, false},
+		{models.NotificationSinkConfig{Address: "logs:514", Facility: "local8"}, false},
+	}
+	for _, tt := range tests {
+		if _, err := newSyslogSink(tt.config); (err == nil) != tt.ok {
+			t.Errorf("newSyslogSink(%+v) = %v, want ok=%v", tt.config, err, tt.ok)
+		}
+	}
+}
+
+func TestSyslogSendUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	s, err := newSyslogSink(models.NotificationSinkConfig{Address: pc.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		buf := make([]byte, 65536)
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+
+	msg := testMessage()
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	got := read()
+	if !strings.HasPrefix(got, "<29>1 ") || !strings.Contains(got, " apply - alice applied 12 rules {") {
+		t.Errorf("datagram %q", got)
+	}
+
+	// Long messages are cut to one RFC 5426 datagram.
+	msg.Summary = strings.Repeat("x", 3000)
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); len(got) != maxSyslogDatagram {
+		t.Errorf("datagram of %d bytes, want %d", len(got), maxSyslogDatagram)
+	}
+}
+
+func TestSyslogSendTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	s, err := newSyslogSink(models.NotificationSinkConfig{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streams := make(chan []byte, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			data, _ := io.ReadAll(conn)
+			conn.Close()
+			streams <- data
+		}
+	}()
+
+	summaries := []string{"alice applied 12 rules", strings.Repeat("y", 3000)}
+	for _, summary := range summaries {
+		msg := testMessage()
+		msg.Summary = summary
+		if err := s.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, summary := range summaries {
+		var data []byte
+		select {
+		case data = <-streams:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no connection")
+		}
+
+		// Octet counting: "<length> <message>", with nothing after it.
+		r := bufio.NewReader(strings.NewReader(string(data)))
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatalf("no frame length in %q", data)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil {
+			t.Fatalf("frame length %q: %v", prefix, err)
+		}
+		frame, _ := io.ReadAll(r)
+		if len(frame) != n {
+			t.Errorf("frame of %d bytes announced as %d", len(frame), n)
+		}
+		if !strings.HasPrefix(string(frame), "<29>1 ") || !strings.Contains(string(frame), " - "+summary+" {") {
+			t.Errorf("frame %q", frame)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// Webhook request headers. The signature is "sha256=" followed by the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the sink's secret, so a
+// receiver can reject both forged and replayed requests.
+const (
+	HeaderEvent     = "X-Firewall-Event"
+	HeaderDelivery  = "X-Firewall-Delivery"
+	HeaderTimestamp = "X-Firewall-Timestamp"
+	HeaderSignature = "X-Firewall-Signature"
+)
+
+var httpClient = &http.Client{Timeout: Timeout}
+
+// newHTTPClient returns a client with the sink's TLS settings, or nil to use
+// the shared one.
+func newHTTPClient(c models.NotificationSinkConfig) (*http.Client, error) {
+	cfg, err := tlsConfig(c)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return &http.Client{Timeout: Timeout, Transport: transport}, nil
+}
+
+// WebhookSink POSTs a JSON document with the event, its summary and its
+// severity. Requests are signed when Secret is set.
+type WebhookSink struct {
+	URL    string
+	Secret string
+	Client *http.Client // nil uses a shared client with the default TLS settings
+}
+
+type webhookPayload struct {
+	Summary  string `json:"summary"`
+	Severity string `json:"severity"`
+	Event    any    `json:"event"`
+}
+
+func (s *WebhookSink) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(webhookPayload{Summary: msg.Summary, Severity: msg.Severity.String(), Event: msg.Event})
+	if err != nil {
+		return &PermanentError{fmt.Errorf("encode payload: %w", err)}
+	}
+	header := http.Header{}
+	header.Set(HeaderEvent, msg.Event.Type)
+	header.Set(HeaderDelivery, msg.DeliveryID)
+	if s.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		header.Set(HeaderTimestamp, ts)
+		header.Set(HeaderSignature, Sign(s.Secret, ts, body))
+	}
+	return post(ctx, s.Client, s.URL, header, body)
+}
+
+// Sign returns the signature header value of a webhook body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SlackSink POSTs a Slack-compatible incoming-webhook payload. Mattermost,
+// Rocket.Chat and similar accept the same format.
+type SlackSink struct {
+	URL    string
+	Client *http.Client // nil uses a shared client with the default TLS settings
+}
+
+func (s *SlackSink) Send(ctx context.Context, msg *Message) error {
+	text := msg.Summary
+	if msg.Severity <= SeverityWarning {
+		text = fmt.Sprintf("*[%s]* %s", msg.Severity, msg.Summary)
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return &PermanentError{fmt.Errorf("encode payload: %w", err)}
+	}
+	return post(ctx, s.Client, s.URL, http.Header{}, body)
+}
+
+// post sends a JSON body with client, or the shared client if nil. Server
+// errors, 408 and 429 are worth retrying; other non-2xx responses are
+// permanent failures.
+func post(ctx context.Context, client *http.Client, target string, header http.Header, body []byte) error {
+	if client == nil {
+		client = httpClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{err}
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "firewall-manager")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(snippet))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return &PermanentError{err}
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+func testMessage() *Message {
+	return &Message{
+		DeliveryID: "d-1",
+		Event:      &models.Event{ID: 7, Type: "apply", Time: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), Actor: "alice"},
+		Summary:    "alice applied 12 rules",
+		Severity:   SeverityNotice,
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed independently with Python's hmac module.
+	want := "sha256=9af63492539a153eb57dfe398a47defa6055a507f934b3b0007a2a56648f7036"
+	if got := Sign("s3cret", "1700000000", []byte(`{"summary":"x"}`)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("s3cret", "1700000001", []byte(`{"summary":"x"}`)) == want {
+		t.Error("signature does not cover the timestamp")
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	sink := &WebhookSink{URL: srv.URL, Secret: "s3cret"}
+	if err := sink.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Get(HeaderEvent) != "apply" || got.Header.Get(HeaderDelivery) != "d-1" {
+		t.Errorf("headers %v", got.Header)
+	}
+	if want := Sign("s3cret", got.Header.Get(HeaderTimestamp), body); got.Header.Get(HeaderSignature) != want {
+		t.Errorf("signature %q, want %q", got.Header.Get(HeaderSignature), want)
+	}
+	var payload struct {
+		Summary  string        `json:"summary"`
+		Severity string        `json:"severity"`
+		Event    *models.Event `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Summary != "alice applied 12 rules" || payload.Severity != "notice" || payload.Event.ID != 7 {
+		t.Errorf("payload %+v", payload)
+	}
+}
+
+func TestPostClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		ok        bool
+		permanent bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNoContent, true, false},
+		{http.StatusBadRequest, false, true},
+		{http.StatusUnauthorized, false, true},
+		{http.StatusNotFound, false, true},
+		{http.StatusRequestTimeout, false, false},
+		{http.StatusTooManyRequests, false, false},
+		{http.StatusInternalServerError, false, false},
+		{http.StatusBadGateway, false, false},
+		{http.StatusServiceUnavailable, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, "  reason  ")
+			}))
+			defer srv.Close()
+
+			err := post(context.Background(), nil, srv.URL, http.Header{}, []byte(`{}`))
+			if tt.ok {
+				if err != nil {
+					t.Errorf("err = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+		})
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		url := srv.URL
+		srv.Close()
+		if err := post(context.Background(), nil, url, http.Header{}, nil); err == nil || IsPermanent(err) {
+			t.Errorf("err = %v, want a retryable error", err)
+		}
+	})
+	t.Run("invalid url", func(t *testing.T) {
+		if err := post(context.Background(), nil, "http://[::1", http.Header{}, nil); !IsPermanent(err) {
+			t.Errorf("err = %v, want a permanent error", err)
+		}
+	})
+}
+
+func TestWebhookTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	tests := []struct {
+		name   string
+		config models.NotificationSinkConfig
+		ok     bool
+	}{
+		{"system roots", models.NotificationSinkConfig{}, false},
+		{"ca bundle", models.NotificationSinkConfig{CACert: ca}, true},
+		{"skip verify", models.NotificationSinkConfig{InsecureSkipVerify: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.URL = srv.URL
+			sink, err := New(&models.NotificationSink{Type: models.SinkWebhook, Config: tt.config})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sink.Send(context.Background(), testMessage())
+			if (err == nil) != tt.ok {
+				t.Errorf("err = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+
+	if _, err := New(&models.NotificationSink{Type: models.SinkSlack, Config: models.NotificationSinkConfig{URL: srv.URL, CACert: "not pem"}}); err == nil {
+		t.Error("invalid caCert accepted")
+	}
+}
@@ -0,0 +1,190 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	// keyRefreshInterval is how long fetched keys are trusted before they are
+	// fetched again on the next use.
+	keyRefreshInterval = time.Hour
+	// keyRetryInterval limits refetches triggered by unknown key IDs, so
+	// forged tokens cannot make the server hammer the issuer.
+	keyRetryInterval = 30 * time.Second
+)
+
+// jwk is the subset of RFC 7517 fields needed for RSA and EC signing keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet caches the issuer's signing keys by key ID.
+type keySet struct {
+	load func(ctx context.Context) ([]byte, error)
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(load func(ctx context.Context) ([]byte, error)) *keySet {
+	return &keySet{load: load}
+}
+
+// key returns the key with the given ID, refetching the set when it is stale
+// or does not contain kid. An empty kid matches a set with a single key.
+func (s *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.fetchedAt) > keyRefreshInterval {
+		if err := s.refresh(ctx); err != nil && s.keys == nil {
+			return nil, err
+		}
+	}
+	if k, ok := s.lookup(kid); ok {
+		return k, nil
+	}
+	if time.Since(s.fetchedAt) > keyRetryInterval {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		if k, ok := s.lookup(kid); ok {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("no signing key with id %q", kid)
+}
+
+func (s *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	s.fetchedAt = time.Now()
+	data, err := s.load(ctx)
+	if err != nil {
+		return fmt.Errorf("load jwks: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+// parseJWKS decodes a JWK set, skipping keys not meant for signatures and key
+// types this package does not verify.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var (
+			pub crypto.PublicKey
+			err error
+		)
+		switch k.Kty {
+		case "RSA":
+			pub, err = rsaKey(k)
+		case "EC":
+			pub, err = ecKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse jwk %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+	if pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("rsa key shorter than 2048 bits")
+	}
+	return pub, nil
+}
+
+func ecKey(k jwk) (*ecdsa.PublicKey, error) {
+	var (
+		curve  elliptic.Curve
+		ecurve ecdh.Curve
+	)
+	switch k.Crv {
+	case "P-256":
+		curve, ecurve = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, ecurve = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, ecurve = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(x) != size {
+		return nil, fmt.Errorf("invalid x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil || len(y) != size {
+		return nil, fmt.Errorf("invalid y coordinate")
+	}
+	// ecdh rejects points that are not on the curve.
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecurve.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("invalid point: %w", err)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
@@ -0,0 +1,154 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyRotation(t *testing.T) {
+	iss := newTestIssuer(t)
+	old, next := newRSAKey(t), newRSAKey(t)
+	iss.setKeys(map[string]crypto.Signer{"old": old})
+	p := iss.provider(t)
+	ctx := context.Background()
+	verify := func(kid string, key crypto.Signer) error {
+		_, err := p.VerifyAccessToken(ctx, signToken(t, "RS256", kid, key, nil, iss.claims()))
+		return err
+	}
+
+	if err := verify("old", old); err != nil {
+		t.Fatalf("initial key rejected: %v", err)
+	}
+	if got := iss.hits(); got != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", got)
+	}
+
+	// The issuer rotates. Within keyRetryInterval of the last fetch an
+	// unknown kid does not refetch, so forged kids cannot hammer the issuer.
+	iss.setKeys(map[string]crypto.Signer{"next": next})
+	if err := verify("next", next); err == nil {
+		t.Fatal("new key accepted without a refetch")
+	}
+	if got := iss.hits(); got != 1 {
+		t.Fatalf("unknown kid refetched within the retry interval (%d fetches)", got)
+	}
+
+	// Once the retry interval has passed, the unknown kid triggers a refetch.
+	p.keys.fetchedAt = time.Now().Add(-keyRetryInterval - time.Second)
+	if err := verify("next", next); err != nil {
+		t.Fatalf("rotated key rejected after refetch: %v", err)
+	}
+	if got := iss.hits(); got != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", got)
+	}
+	if err := verify("old", old); err == nil {
+		t.Error("retired key still accepted")
+	}
+}
+
+func TestKeyRefreshWhenStale(t *testing.T) {
+	iss := newTestIssuer(t)
+	key := newRSAKey(t)
+	iss.setKeys(map[string]crypto.Signer{"k1": key})
+	p := iss.provider(t)
+	ctx := context.Background()
+	tok := signToken(t, "RS256", "k1", key, nil, iss.claims())
+
+	if _, err := p.VerifyAccessToken(ctx, tok); err != nil {
+		t.Fatal(err)
+	}
+	p.keys.fetchedAt = time.Now().Add(-keyRefreshInterval - time.Second)
+	if _, err := p.VerifyAccessToken(ctx, tok); err != nil {
+		t.Fatal(err)
+	}
+	if got := iss.hits(); got != 2 {
+		t.Errorf("stale key set fetched %d times, want 2", got)
+	}
+
+	// An issuer outage keeps the cached keys in use.
+	iss.mu.Lock()
+	iss.jwksError = true
+	iss.mu.Unlock()
+	p.keys.fetchedAt = time.Now().Add(-keyRefreshInterval - time.Second)
+	if _, err := p.VerifyAccessToken(ctx, tok); err != nil {
+		t.Errorf("cached key rejected during an outage: %v", err)
+	}
+}
+
+func TestKeyLookupWithoutKid(t *testing.T) {
+	iss := newTestIssuer(t)
+	a, b := newRSAKey(t), newRSAKey(t)
+	iss.setKeys(map[string]crypto.Signer{"a": a})
+	p := iss.provider(t)
+	ctx := context.Background()
+
+	// A token without kid is matched to the only key in the set.
+	if _, err := p.VerifyAccessToken(ctx, signToken(t, "RS256", "", a, nil, iss.claims())); err != nil {
+		t.Fatalf("kid-less token rejected with a single key: %v", err)
+	}
+
+	// With several keys it is ambiguous.
+	iss.setKeys(map[string]crypto.Signer{"a": a, "b": b})
+	p.keys.fetchedAt = time.Time{}
+	if _, err := p.VerifyAccessToken(ctx, signToken(t, "RS256", "", a, nil, iss.claims())); err == nil {
+		t.Error("kid-less token accepted with several keys")
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaPub := publicJWK("rsa", newRSAKey(t).Public())
+	ecPub := publicJWK("ec", newECKey(t).Public())
+	enc := base64.RawURLEncoding.EncodeToString
+	with := func(k jwk, mod func(*jwk)) jwk {
+		mod(&k)
+		return k
+	}
+
+	tests := []struct {
+		name string
+		keys []jwk
+		kids []string // usable keys; nil expects an error
+		err  string
+	}{
+		{"rsa and ec", []jwk{rsaPub, ecPub}, []string{"ec", "rsa"}, ""},
+		{"encryption key skipped", []jwk{rsaPub, with(ecPub, func(k *jwk) { k.Use = "enc" })}, []string{"rsa"}, ""},
+		{"symmetric key skipped", []jwk{rsaPub, {Kty: "oct", Kid: "hmac", Use: "sig"}}, []string{"rsa"}, ""},
+		{"no usable keys", []jwk{{Kty: "oct", Kid: "hmac"}}, nil, "no usable signing keys"},
+		{"short rsa key", []jwk{with(rsaPub, func(k *jwk) { k.N = enc(make([]byte, 128)) })}, nil, "shorter than 2048 bits"},
+		{"rsa exponent 1", []jwk{with(rsaPub, func(k *jwk) { k.E = "AQ" })}, nil, "invalid exponent"},
+		{"unsupported curve", []jwk{with(ecPub, func(k *jwk) { k.Crv = "secp256k1" })}, nil, "unsupported curve"},
+		{"point off the curve", []jwk{with(ecPub, func(k *jwk) { k.Y = k.X })}, nil, "invalid point"},
+		{"truncated coordinate", []jwk{with(ecPub, func(k *jwk) { k.X = enc(make([]byte, 31)) })}, nil, "invalid x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(map[string][]jwk{"keys": tt.keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := parseJWKS(data)
+			if tt.kids == nil {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != len(tt.kids) {
+				t.Errorf("got %d keys, want %v", len(keys), tt.kids)
+			}
+			for _, kid := range tt.kids {
+				if keys[kid] == nil {
+					t.Errorf("key %q missing", kid)
+				}
+			}
+		})
+	}
+}
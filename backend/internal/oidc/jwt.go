@@ -0,0 +1,197 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // register hash functions used by crypto.Hash
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clockSkew is tolerated when checking exp and nbf.
+const clockSkew = time.Minute
+
+// ErrInvalidToken is returned for tokens that fail parsing or verification.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims is a verified JWT payload
+type Claims map[string]any
+
+// String returns a string claim, or "" if missing or of another type.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim that may be a single string or a list of strings.
+// Dotted names ("realm_access.roles") descend into nested objects.
+func (c Claims) Strings(name string) []string {
+	var v any = map[string]any(c)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		return time.Unix(n, 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// LooksLikeJWT reports whether s has the three-segment compact JWS form.
+func LooksLikeJWT(s string) bool {
+	return strings.Count(s, ".") == 2
+}
+
+// splitToken decodes the header and payload of a compact JWS without
+// verifying it.
+func splitToken(raw string) (header, Claims, []byte, []byte, error) {
+	var h header
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return h, nil, nil, nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return h, nil, nil, nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return h, nil, nil, nil, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return h, nil, nil, nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	return h, claims, []byte(parts[0] + "." + parts[1]), sig, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature checks sig over signed with an asymmetric algorithm. Only
+// RS, PS and ES algorithms are accepted; "none" and HMAC are always refused.
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	var err error
+	switch alg[:2] {
+	case "RS", "PS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key type does not match alg %s", ErrInvalidToken, alg)
+		}
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(k, hash, digest, sig)
+		} else {
+			err = rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key type does not match alg %s", ErrInvalidToken, alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		curveHash := map[int]crypto.Hash{256: crypto.SHA256, 384: crypto.SHA384, 521: crypto.SHA512}[k.Curve.Params().BitSize]
+		if curveHash != hash || len(sig) != 2*size {
+			return fmt.Errorf("%w: curve does not match alg %s", ErrInvalidToken, alg)
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			err = errors.New("ecdsa verification failed")
+		}
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+// validateClaims checks issuer, audience and validity period.
+func validateClaims(claims Claims, issuer, audience string, now time.Time) error {
+	if claims.String("iss") != issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.String("iss"))
+	}
+	audOK := false
+	for _, aud := range claims.Strings("aud") {
+		if aud == audience {
+			audOK = true
+			break
+		}
+	}
+	if !audOK {
+		return fmt.Errorf("%w: audience does not include %q", ErrInvalidToken, audience)
+	}
+	exp, ok := claims.time("exp")
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(exp.Add(clockSkew)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Add(clockSkew).Before(nbf) {
+		return fmt.Errorf("%w: not yet valid", ErrInvalidToken)
+	}
+	if claims.String("sub") == "" {
+		return fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	return nil
+}
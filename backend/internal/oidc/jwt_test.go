@@ -0,0 +1,316 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testIssuer is an OpenID provider serving discovery and a JWKS that tests
+// can rotate.
+type testIssuer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	keys      map[string]crypto.Signer
+	jwksHits  int
+	jwksError bool
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	iss := &testIssuer{keys: make(map[string]crypto.Signer)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(metadata{Issuer: iss.URL, JWKSURI: iss.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		iss.mu.Lock()
+		defer iss.mu.Unlock()
+		iss.jwksHits++
+		if iss.jwksError {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, k := range iss.keys {
+			set.Keys = append(set.Keys, publicJWK(kid, k.Public()))
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// setKeys replaces the published keys.
+func (iss *testIssuer) setKeys(keys map[string]crypto.Signer) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.keys = keys
+}
+
+func (iss *testIssuer) hits() int {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	return iss.jwksHits
+}
+
+func (iss *testIssuer) provider(t *testing.T) *Provider {
+	t.Helper()
+	p, err := NewProvider(Config{Issuer: iss.URL, ClientID: "fwmg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// claims returns valid access token claims for iss.
+func (iss *testIssuer) claims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss": iss.URL,
+		"aud": "fwmg",
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func publicJWK(kid string, pub crypto.PublicKey) jwk {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return jwk{Kty: "RSA", Kid: kid, Use: "sig", N: enc(pub.N.Bytes()), E: "AQAB"}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return jwk{Kty: "EC", Kid: kid, Use: "sig", Crv: pub.Curve.Params().Name,
+			X: enc(pub.X.FillBytes(make([]byte, size))), Y: enc(pub.Y.FillBytes(make([]byte, size)))}
+	}
+	panic("unsupported key type")
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// signToken builds a compact JWS. alg "none" leaves the signature empty and
+// "HS256" uses secret as the HMAC key; the other algorithms sign with key.
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, secret []byte, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch alg {
+	case "none":
+	case "HS256":
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	case "PS256":
+		sig, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	default:
+		t.Fatalf("unsupported alg %s", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyAccessToken(t *testing.T) {
+	iss := newTestIssuer(t)
+	rsaKey, ecKey, otherKey := newRSAKey(t), newECKey(t), newRSAKey(t)
+	iss.setKeys(map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey})
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := iss.claims()
+	with := func(name string, v any) map[string]any {
+		c := iss.claims()
+		c[name] = v
+		return c
+	}
+	tampered := func() string {
+		tok := signToken(t, "RS256", "rsa", rsaKey, nil, valid)
+		parts := strings.Split(tok, ".")
+		parts[1] = strings.Split(signToken(t, "RS256", "rsa", rsaKey, nil, with("sub", "admin")), ".")[1]
+		return strings.Join(parts, ".")
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256", signToken(t, "RS256", "rsa", rsaKey, nil, valid), true},
+		{"PS256", signToken(t, "PS256", "rsa", rsaKey, nil, valid), true},
+		{"ES256", signToken(t, "ES256", "ec", ecKey, nil, valid), true},
+		{"audience list", signToken(t, "RS256", "rsa", rsaKey, nil, with("aud", []string{"other", "fwmg"})), true},
+		{"signed by another key", signToken(t, "RS256", "rsa", otherKey, nil, valid), false},
+		{"tampered payload", tampered(), false},
+		{"alg none", signToken(t, "none", "rsa", nil, nil, valid), false},
+		{"HS256 with the public key as secret", signToken(t, "HS256", "rsa", nil, pubDER, valid), false},
+		{"HS256 with the modulus as secret", signToken(t, "HS256", "rsa", nil, rsaKey.N.Bytes(), valid), false},
+		{"RS256 header on an EC key", signToken(t, "RS256", "ec", rsaKey, nil, valid), false},
+		{"ES256 header on an RSA key", signToken(t, "ES256", "rsa", ecKey, nil, valid), false},
+		{"unknown kid", signToken(t, "RS256", "gone", rsaKey, nil, valid), false},
+		{"wrong issuer", signToken(t, "RS256", "rsa", rsaKey, nil, with("iss", "https://evil.example")), false},
+		{"wrong audience", signToken(t, "RS256", "rsa", rsaKey, nil, with("aud", "other")), false},
+		{"expired", signToken(t, "RS256", "rsa", rsaKey, nil, with("exp", time.Now().Add(-time.Hour).Unix())), false},
+		{"malformed", "not.a-token", false},
+	}
+	p := iss.provider(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.VerifyAccessToken(context.Background(), tt.token)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("rejected: %v", err)
+				}
+				if claims.String("sub") != "user-1" {
+					t.Errorf("sub = %q", claims.String("sub"))
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenNonce(t *testing.T) {
+	iss := newTestIssuer(t)
+	key := newRSAKey(t)
+	iss.setKeys(map[string]crypto.Signer{"k1": key})
+	p := iss.provider(t)
+
+	c := iss.claims()
+	c["nonce"] = "n-1"
+	tok := signToken(t, "RS256", "k1", key, nil, c)
+	if _, err := p.VerifyIDToken(context.Background(), tok, "n-1"); err != nil {
+		t.Errorf("matching nonce rejected: %v", err)
+	}
+	if _, err := p.VerifyIDToken(context.Background(), tok, "n-2"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("replayed nonce: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) float64 { return float64(now.Add(d).Unix()) }
+	exp := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	claims := func(mod func(Claims)) Claims {
+		c := Claims{"iss": "https://idp.example", "aud": "fwmg", "sub": "user-1", "exp": at(time.Hour)}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		claims Claims
+		want   string // substring of the error, "" for valid
+	}{
+		{"valid", claims(nil), ""},
+		{"expired within skew", claims(func(c Claims) { c["exp"] = at(-30 * time.Second) }), ""},
+		{"expired beyond skew", claims(func(c Claims) { c["exp"] = at(-2 * time.Minute) }), "expired"},
+		{"nbf within skew", claims(func(c Claims) { c["nbf"] = at(30 * time.Second) }), ""},
+		{"nbf beyond skew", claims(func(c Claims) { c["nbf"] = at(2 * time.Minute) }), "not yet valid"},
+		{"exp as json.Number", claims(func(c Claims) { c["exp"] = json.Number(exp) }), ""},
+		{"exp as string", claims(func(c Claims) { c["exp"] = exp }), "missing exp"},
+		{"missing exp", claims(func(c Claims) { delete(c, "exp") }), "missing exp"},
+		{"issuer mismatch", claims(func(c Claims) { c["iss"] = "https://idp.example/" }), "unexpected issuer"},
+		{"audience mismatch", claims(func(c Claims) { c["aud"] = "fwmg-other" }), "audience"},
+		{"audience list", claims(func(c Claims) { c["aud"] = []any{"account", "fwmg"} }), ""},
+		{"audience list without us", claims(func(c Claims) { c["aud"] = []any{"account"} }), "audience"},
+		{"missing sub", claims(func(c Claims) { delete(c, "sub") }), "missing sub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateClaims(tt.claims, "https://idp.example", "fwmg", now)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("rejected: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimsStrings(t *testing.T) {
+	var c Claims
+	if err := json.Unmarshal([]byte(`{"groups":["a","b",3],"realm_access":{"roles":["admin"]},"aud":"fwmg"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string][]string{
+		"groups":             {"a", "b"},
+		"realm_access.roles": {"admin"},
+		"aud":                {"fwmg"},
+		"missing":            nil,
+		"aud.roles":          nil,
+	}
+	for name, want := range tests {
+		if got := c.Strings(name); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Strings(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
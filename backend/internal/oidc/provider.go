@@ -0,0 +1,265 @@
+// Package oidc validates JWTs issued by an OpenID Connect provider and
+// implements the authorization-code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config describes the identity provider
+type Config struct {
+	Issuer       string // expected "iss"; discovery is fetched from here
+	ClientID     string // required for the login flow and ID tokens
+	ClientSecret string
+	RedirectURL  string   // callback URL registered with the provider
+	Audience     string   // expected "aud" of API bearer tokens; defaults to ClientID
+	JWKSFile     string   // read keys from this file instead of the issuer
+	Scopes       []string // defaults to openid, profile, email, groups
+}
+
+// metadata is the subset of the discovery document used here
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider verifies tokens from one issuer. Discovery is fetched lazily so a
+// provider outage at startup does not keep the firewall API from starting.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	keys   *keySet
+
+	mu   sync.Mutex
+	meta *metadata
+}
+
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("oidc issuer is required")
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if cfg.Audience == "" {
+		cfg.Audience = cfg.ClientID
+	}
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("oidc audience or client id is required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email", "groups"}
+	}
+
+	p := &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+	if cfg.JWKSFile != "" {
+		p.keys = newKeySet(func(context.Context) ([]byte, error) {
+			return os.ReadFile(cfg.JWKSFile)
+		})
+	} else {
+		p.keys = newKeySet(p.fetchJWKS)
+	}
+	return p, nil
+}
+
+// LoginEnabled reports whether the authorization-code flow is configured.
+func (p *Provider) LoginEnabled() bool {
+	return p.cfg.ClientID != "" && p.cfg.RedirectURL != ""
+}
+
+// VerifyAccessToken verifies a bearer token presented to the API.
+func (p *Provider) VerifyAccessToken(ctx context.Context, raw string) (Claims, error) {
+	return p.verify(ctx, raw, p.cfg.Audience)
+}
+
+// VerifyIDToken verifies an ID token from the login flow, including its nonce.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (Claims, error) {
+	claims, err := p.verify(ctx, raw, p.cfg.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.String("nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+func (p *Provider) verify(ctx context.Context, raw, audience string) (Claims, error) {
+	h, claims, signed, sig, err := splitToken(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.keys.key(ctx, h.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := verifySignature(h.Alg, key, signed, sig); err != nil {
+		return nil, err
+	}
+	if err := validateClaims(claims, p.cfg.Issuer, audience, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// AuthRequest holds the per-login secrets that must be kept until the
+// callback: state and nonce guard against CSRF and replay, the verifier
+// completes PKCE.
+type AuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewAuthRequest builds the URL that sends the browser to the provider.
+func (p *Provider) NewAuthRequest(ctx context.Context) (*AuthRequest, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := &AuthRequest{}
+	for _, s := range []*string{&req.State, &req.Nonce, &req.Verifier} {
+		if *s, err = randomString(); err != nil {
+			return nil, err
+		}
+	}
+	challenge := sha256.Sum256([]byte(req.Verifier))
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	req.URL = meta.AuthorizationEndpoint + sep + q.Encode()
+	return req, nil
+}
+
+// Exchange trades an authorization code for tokens and verifies the ID token.
+func (p *Provider) Exchange(ctx context.Context, req *AuthRequest, code string) (Claims, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {req.Verifier},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		httpReq.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("parse token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	return p.VerifyIDToken(ctx, tokens.IDToken, req.Nonce)
+}
+
+// metadata fetches the discovery document once and caches it.
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func (p *Provider) fetchJWKS(ctx context.Context) ([]byte, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if meta.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document has no jwks_uri")
+	}
+	var raw json.RawMessage
+	if err := p.getJSON(ctx, meta.JWKSURI, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
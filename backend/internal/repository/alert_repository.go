@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// AlertRepository persists alert rules, the alerts they raised and silences
+type AlertRepository interface {
+	ListRules() ([]*models.AlertRule, error)
+	GetRule(id string) (*models.AlertRule, error)
+	CreateRule(rule *models.AlertRule) error
+	UpdateRule(rule *models.AlertRule) error
+	DeleteRule(id string) error
+
+	ListAlerts(filter models.AlertFilter) ([]*models.Alert, error)
+	ListActiveAlerts() ([]*models.Alert, error)
+	CreateAlert(alert *models.Alert) error
+	UpdateAlert(alert *models.Alert) error
+
+	ListSilences() ([]*models.AlertSilence, error)
+	CreateSilence(silence *models.AlertSilence) error
+	DeleteSilence(id string) error
+}
+
+type alertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(db *sql.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+const alertRuleColumns = `id, name, description, kind, severity, enabled, series, metric, operator, value,
+		       window_seconds, for_seconds, event_type, resource_id, resolve_event, resolve_after_seconds,
+		       created_at, updated_at`
+
+func scanAlertRule(scan func(dest ...any) error) (*models.AlertRule, error) {
+	r := &models.AlertRule{}
+	err := scan(&r.ID, &r.Name, &r.Description, &r.Kind, &r.Severity, &r.Enabled, &r.Series, &r.Metric,
+		&r.Operator, &r.Value, &r.WindowSeconds, &r.ForSeconds, &r.EventType, &r.ResourceID,
+		&r.ResolveEvent, &r.ResolveAfterSeconds, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *alertRepository) ListRules() ([]*models.AlertRule, error) {
+	rows, err := r.db.Query(`SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *alertRepository) GetRule(id string) (*models.AlertRule, error) {
+	row := r.db.QueryRow(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = ?`, id)
+	rule, err := scanAlertRule(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("alert rule not found: %s", id)
+	}
+	return rule, err
+}
+
+func (r *alertRepository) CreateRule(rule *models.AlertRule) error {
+	_, err := r.db.Exec(`
+		INSERT INTO alert_rules (id, name, description, kind, severity, enabled, series, metric, operator, value,
+			window_seconds, for_seconds, event_type, resource_id, resolve_event, resolve_after_seconds,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rule.ID, rule.Name, rule.Description, rule.Kind, rule.Severity, rule.Enabled, rule.Series, rule.Metric,
+		rule.Operator, rule.Value, rule.WindowSeconds, rule.ForSeconds, rule.EventType, rule.ResourceID,
+		rule.ResolveEvent, rule.ResolveAfterSeconds, rule.CreatedAt, rule.UpdatedAt)
+	return err
+}
+
+func (r *alertRepository) UpdateRule(rule *models.AlertRule) error {
+	rule.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE alert_rules
+		SET name = ?, description = ?, kind = ?, severity = ?, enabled = ?, series = ?, metric = ?, operator = ?,
+			value = ?, window_seconds = ?, for_seconds = ?, event_type = ?, resource_id = ?, resolve_event = ?,
+			resolve_after_seconds = ?, updated_at = ?
+		WHERE id = ?
+	`, rule.Name, rule.Description, rule.Kind, rule.Severity, rule.Enabled, rule.Series, rule.Metric, rule.Operator,
+		rule.Value, rule.WindowSeconds, rule.ForSeconds, rule.EventType, rule.ResourceID, rule.ResolveEvent,
+		rule.ResolveAfterSeconds, rule.UpdatedAt, rule.ID)
+	return err
+}
+
+func (r *alertRepository) DeleteRule(id string) error {
+	_, err := r.db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	return err
+}
+
+const alertColumns = `id, rule_id, rule_name, severity, state, message, value, count, silenced,
+		       started_at, fired_at, resolved_at, last_seen_at`
+
+func scanAlert(scan func(dest ...any) error) (*models.Alert, error) {
+	a := &models.Alert{}
+	var firedAt, resolvedAt sql.NullTime
+	err := scan(&a.ID, &a.RuleID, &a.RuleName, &a.Severity, &a.State, &a.Message, &a.Value, &a.Count,
+		&a.Silenced, &a.StartedAt, &firedAt, &resolvedAt, &a.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	if firedAt.Valid {
+		a.FiredAt = &firedAt.Time
+	}
+	if resolvedAt.Valid {
+		a.ResolvedAt = &resolvedAt.Time
+	}
+	return a, nil
+}
+
+// ListAlerts returns matching alerts, most recently started first.
+func (r *alertRepository) ListAlerts(f models.AlertFilter) ([]*models.Alert, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.RuleID != "" {
+		add("rule_id = ?", f.RuleID)
+	}
+	if f.State != "" {
+		add("state = ?", f.State)
+	}
+	if !f.From.IsZero() {
+		add("started_at >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("started_at < ?", f.To.UTC())
+	}
+
+	q := `SELECT ` + alertColumns + ` FROM alerts`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += ` ORDER BY started_at DESC LIMIT ?`
+	args = append(args, f.Limit)
+	return r.queryAlerts(q, args...)
+}
+
+// ListActiveAlerts returns the pending and firing alerts.
+func (r *alertRepository) ListActiveAlerts() ([]*models.Alert, error) {
+	return r.queryAlerts(`SELECT `+alertColumns+` FROM alerts WHERE state != ? ORDER BY started_at`, models.AlertResolved)
+}
+
+func (r *alertRepository) queryAlerts(q string, args ...any) ([]*models.Alert, error) {
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*models.Alert
+	for rows.Next() {
+		a, err := scanAlert(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *alertRepository) CreateAlert(a *models.Alert) error {
+	_, err := r.db.Exec(`
+		INSERT INTO alerts (id, rule_id, rule_name, severity, state, message, value, count, silenced,
+			started_at, fired_at, resolved_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.ID, a.RuleID, a.RuleName, a.Severity, a.State, a.Message, a.Value, a.Count, a.Silenced,
+		a.StartedAt, a.FiredAt, a.ResolvedAt, a.LastSeenAt)
+	return err
+}
+
+func (r *alertRepository) UpdateAlert(a *models.Alert) error {
+	_, err := r.db.Exec(`
+		UPDATE alerts
+		SET state = ?, message = ?, value = ?, count = ?, silenced = ?, fired_at = ?, resolved_at = ?, last_seen_at = ?
+		WHERE id = ?
+	`, a.State, a.Message, a.Value, a.Count, a.Silenced, a.FiredAt, a.ResolvedAt, a.LastSeenAt, a.ID)
+	return err
+}
+
+func (r *alertRepository) ListSilences() ([]*models.AlertSilence, error) {
+	rows, err := r.db.Query(`
+		SELECT id, rule_id, starts_at, ends_at, comment, created_by, created_at
+		FROM alert_silences
+		ORDER BY starts_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var silences []*models.AlertSilence
+	for rows.Next() {
+		s := &models.AlertSilence{}
+		if err := rows.Scan(&s.ID, &s.RuleID, &s.StartsAt, &s.EndsAt, &s.Comment, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		silences = append(silences, s)
+	}
+	return silences, rows.Err()
+}
+
+func (r *alertRepository) CreateSilence(s *models.AlertSilence) error {
+	_, err := r.db.Exec(`
+		INSERT INTO alert_silences (id, rule_id, starts_at, ends_at, comment, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, s.ID, s.RuleID, s.StartsAt, s.EndsAt, s.Comment, s.CreatedBy, s.CreatedAt)
+	return err
+}
+
+func (r *alertRepository) DeleteSilence(id string) error {
+	res, err := r.db.Exec(`DELETE FROM alert_silences WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("alert silence not found: %s", id)
+	}
+	return nil
+}
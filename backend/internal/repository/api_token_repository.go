@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// APITokenRepository stores API tokens by hash
+type APITokenRepository interface {
+	List(ownerID string) ([]*models.APIToken, error)
+	Get(id string) (*models.APIToken, error)
+	GetByHash(hash string) (*models.APIToken, error)
+	Create(token *models.APIToken) error
+	Revoke(id string, at time.Time) error
+	Touch(id string, at time.Time) error
+}
+
+type apiTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAPITokenRepository(db *sql.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+const apiTokenColumns = `id, name, prefix, token_hash, scopes, owner_id, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIToken(scan func(dest ...any) error) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	var scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := scan(&token.ID, &token.Name, &token.Prefix, &token.TokenHash, &scopes, &token.OwnerID,
+		&token.ExpiresAt, &lastUsedAt, &revokedAt, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	token.Scopes = decodeTags(scopes)
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, nil
+}
+
+// List returns the tokens owned by ownerID, or all tokens if ownerID is "*".
+func (r *apiTokenRepository) List(ownerID string) ([]*models.APIToken, error) {
+	q := `SELECT ` + apiTokenColumns + ` FROM api_tokens`
+	var args []any
+	if ownerID != "*" {
+		q += ` WHERE owner_id = ?`
+		args = append(args, ownerID)
+	}
+	rows, err := r.db.Query(q+` ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *apiTokenRepository) Get(id string) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE id = ?`, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api token not found: %s", id)
+	}
+	return token, err
+}
+
+func (r *apiTokenRepository) GetByHash(hash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, hash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api token not found")
+	}
+	return token, err
+}
+
+func (r *apiTokenRepository) Create(token *models.APIToken) error {
+	_, err := r.db.Exec(`
+		INSERT INTO api_tokens (`+apiTokenColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, token.ID, token.Name, token.Prefix, token.TokenHash, encodeTags(token.Scopes), token.OwnerID,
+		token.ExpiresAt, token.LastUsedAt, token.RevokedAt, token.CreatedAt)
+	return err
+}
+
+func (r *apiTokenRepository) Revoke(id string, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("api token not found or already revoked: %s", id)
+	}
+	return nil
+}
+
+// Touch records that the token was just used.
+func (r *apiTokenRepository) Touch(id string, at time.Time) error {
+	_, err := r.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, at, id)
+	return err
+}
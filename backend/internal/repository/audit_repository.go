@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// AuditRepository appends to and reads the audit log. Entries are never
+// updated or deleted through it.
+type AuditRepository interface {
+	Append(entry *models.AuditEntry) error
+	Last() (*models.AuditEntry, error)
+	Query(filter models.AuditFilter) ([]*models.AuditEntry, error)
+	Range(afterSeq int64, limit int) ([]*models.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+const auditColumns = `seq, timestamp, actor, actor_kind, source_ip, action, resource, resource_id, before, after, prev_hash, hash`
+
+func scanAuditEntry(scan func(dest ...any) error) (*models.AuditEntry, error) {
+	e := &models.AuditEntry{}
+	var before, after string
+	if err := scan(&e.Seq, &e.Timestamp, &e.Actor, &e.ActorKind, &e.SourceIP, &e.Action,
+		&e.Resource, &e.ResourceID, &before, &after, &e.PrevHash, &e.Hash); err != nil {
+		return nil, err
+	}
+	if before != "" {
+		e.Before = []byte(before)
+	}
+	if after != "" {
+		e.After = []byte(after)
+	}
+	return e, nil
+}
+
+func (r *auditRepository) Append(e *models.AuditEntry) error {
+	_, err := r.db.Exec(`
+		INSERT INTO audit_log (`+auditColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Seq, e.Timestamp, e.Actor, e.ActorKind, e.SourceIP, e.Action,
+		e.Resource, e.ResourceID, string(e.Before), string(e.After), e.PrevHash, e.Hash)
+	return err
+}
+
+// Last returns the newest entry, or nil if the log is empty.
+func (r *auditRepository) Last() (*models.AuditEntry, error) {
+	e, err := scanAuditEntry(r.db.QueryRow(`SELECT ` + auditColumns + ` FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// Query returns matching entries, newest first.
+func (r *auditRepository) Query(f models.AuditFilter) ([]*models.AuditEntry, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+	if f.Resource != "" {
+		add("resource = ?", f.Resource)
+	}
+	if f.ResourceID != "" {
+		add("resource_id = ?", f.ResourceID)
+	}
+	if !f.From.IsZero() {
+		add("timestamp >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("timestamp < ?", f.To.UTC())
+	}
+	if f.BeforeSeq > 0 {
+		add("seq < ?", f.BeforeSeq)
+	}
+
+	q := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += ` ORDER BY seq DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	return r.list(q, args...)
+}
+
+// Range returns up to limit entries with seq greater than afterSeq, oldest
+// first.
+func (r *auditRepository) Range(afterSeq int64, limit int) ([]*models.AuditEntry, error) {
+	return r.list(`SELECT `+auditColumns+` FROM audit_log WHERE seq > ? ORDER BY seq LIMIT ?`, afterSeq, limit)
+}
+
+func (r *auditRepository) list(q string, args ...any) ([]*models.AuditEntry, error) {
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		e, err := scanAuditEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -229,7 +229,7 @@ func (r *natRuleRepository) List() ([]*models.NATRule, error) {
 	rows, err := r.db.Query(`
 		SELECT id, name, type, protocol, in_interface, out_interface, source_ip, source_port,
 		       dest_ip, dest_port, natto_ip, natto_port, comment, enabled, position,
-		       created_at, updated_at
+		       tags, created_at, updated_at
 		FROM nat_rules
 		ORDER BY position
 	`)
@@ -241,12 +241,14 @@ func (r *natRuleRepository) List() ([]*models.NATRule, error) {
 	var rules []*models.NATRule
 	for rows.Next() {
 		rule := &models.NATRule{}
+		var tags string
 		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Type, &rule.Protocol, &rule.InInterface,
 			&rule.OutInterface, &rule.SourceIP, &rule.SourcePort, &rule.DestIP, &rule.DestPort,
 			&rule.NATtoIP, &rule.NATtoPort, &rule.Comment, &rule.Enabled, &rule.Position,
-			&rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			&tags, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
 			return nil, err
 		}
+		rule.Tags = decodeTags(tags)
 		rules = append(rules, rule)
 	}
 	return rules, rows.Err()
@@ -256,27 +258,29 @@ func (r *natRuleRepository) Get(id string) (*models.NATRule, error) {
 	row := r.db.QueryRow(`
 		SELECT id, name, type, protocol, in_interface, out_interface, source_ip, source_port,
 		       dest_ip, dest_port, natto_ip, natto_port, comment, enabled, position,
-		       created_at, updated_at
+		       tags, created_at, updated_at
 		FROM nat_rules
 		WHERE id = ?
 	`, id)
 
 	rule := &models.NATRule{}
+	var tags string
 	err := row.Scan(&rule.ID, &rule.Name, &rule.Type, &rule.Protocol, &rule.InInterface,
 		&rule.OutInterface, &rule.SourceIP, &rule.SourcePort, &rule.DestIP, &rule.DestPort,
 		&rule.NATtoIP, &rule.NATtoPort, &rule.Comment, &rule.Enabled, &rule.Position,
-		&rule.CreatedAt, &rule.UpdatedAt)
+		&tags, &rule.CreatedAt, &rule.UpdatedAt)
+	rule.Tags = decodeTags(tags)
 	return rule, err
 }
 
 func (r *natRuleRepository) Create(rule *models.NATRule) error {
 	_, err := r.db.Exec(`
 		INSERT INTO nat_rules (id, name, type, protocol, in_interface, out_interface, source_ip, source_port,
-			dest_ip, dest_port, natto_ip, natto_port, comment, enabled, position, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			dest_ip, dest_port, natto_ip, natto_port, comment, enabled, position, tags, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, rule.ID, rule.Name, rule.Type, rule.Protocol, rule.InInterface, rule.OutInterface,
 		rule.SourceIP, rule.SourcePort, rule.DestIP, rule.DestPort, rule.NATtoIP, rule.NATtoPort,
-		rule.Comment, rule.Enabled, rule.Position, rule.CreatedAt, rule.UpdatedAt)
+		rule.Comment, rule.Enabled, rule.Position, encodeTags(rule.Tags), rule.CreatedAt, rule.UpdatedAt)
 	return err
 }
 
@@ -286,11 +290,11 @@ func (r *natRuleRepository) Update(rule *models.NATRule) error {
 		UPDATE nat_rules
 		SET name = ?, type = ?, protocol = ?, in_interface = ?, out_interface = ?,
 		    source_ip = ?, source_port = ?, dest_ip = ?, dest_port = ?, natto_ip = ?,
-		    natto_port = ?, comment = ?, enabled = ?, position = ?, updated_at = ?
+		    natto_port = ?, comment = ?, enabled = ?, position = ?, tags = ?, updated_at = ?
 		WHERE id = ?
 	`, rule.Name, rule.Type, rule.Protocol, rule.InInterface, rule.OutInterface,
 		rule.SourceIP, rule.SourcePort, rule.DestIP, rule.DestPort, rule.NATtoIP,
-		rule.NATtoPort, rule.Comment, rule.Enabled, rule.Position, rule.UpdatedAt, rule.ID)
+		rule.NATtoPort, rule.Comment, rule.Enabled, rule.Position, encodeTags(rule.Tags), rule.UpdatedAt, rule.ID)
 	return err
 }
 
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// CounterSampleRepository stores the counter time series. Times are kept
+// as Unix seconds so samples can be bucketed in SQL.
+type CounterSampleRepository interface {
+	Insert(samples []*models.CounterSample) error
+	Range(series []string, from, to time.Time) ([]*models.CounterSample, error)
+	Series() ([]string, error)
+	Totals(prefix string, from, to time.Time) ([]*models.CounterSample, error)
+	Downsample(before time.Time, span int64) (int64, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+type counterSampleRepository struct {
+	db *sql.DB
+}
+
+func NewCounterSampleRepository(db *sql.DB) CounterSampleRepository {
+	return &counterSampleRepository{db: db}
+}
+
+func (r *counterSampleRepository) Insert(samples []*models.CounterSample) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO counter_samples (series, ts, span, packets, bytes) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, s := range samples {
+		if _, err := stmt.Exec(s.Series, s.At.Unix(), s.Span, s.Packets, s.Bytes); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Range returns the samples of the given series that end in (from, to],
+// oldest first.
+func (r *counterSampleRepository) Range(series []string, from, to time.Time) ([]*models.CounterSample, error) {
+	if len(series) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(series)+2)
+	for _, s := range series {
+		args = append(args, s)
+	}
+	args = append(args, from.Unix(), to.Unix())
+	rows, err := r.db.Query(`
+		SELECT series, ts, span, packets, bytes FROM counter_samples
+		WHERE series IN (?`+strings.Repeat(", ?", len(series)-1)+`) AND ts > ? AND ts <= ?
+		ORDER BY ts
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*models.CounterSample
+	for rows.Next() {
+		s := &models.CounterSample{}
+		var ts int64
+		if err := rows.Scan(&s.Series, &ts, &s.Span, &s.Packets, &s.Bytes); err != nil {
+			return nil, err
+		}
+		s.At = time.Unix(ts, 0).UTC()
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// Series lists the names of all stored series.
+func (r *counterSampleRepository) Series() ([]string, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT series FROM counter_samples ORDER BY series`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Totals sums the samples of every series starting with prefix that end
+// in (from, to], one sample per series.
+func (r *counterSampleRepository) Totals(prefix string, from, to time.Time) ([]*models.CounterSample, error) {
+	rows, err := r.db.Query(`
+		SELECT series, SUM(packets), SUM(bytes) FROM counter_samples
+		WHERE ts > ? AND ts <= ? AND substr(series, 1, ?) = ?
+		GROUP BY series
+	`, from.Unix(), to.Unix(), len(prefix), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.CounterSample
+	for rows.Next() {
+		s := &models.CounterSample{At: to, Span: int64(to.Sub(from).Seconds())}
+		if err := rows.Scan(&s.Series, &s.Packets, &s.Bytes); err != nil {
+			return nil, err
+		}
+		totals = append(totals, s)
+	}
+	return totals, rows.Err()
+}
+
+// Downsample merges the samples finer than span that end before the given
+// time into one sample per series and span-aligned interval. before should
+// be aligned to span so that no interval is split. It returns the number of
+// samples merged.
+func (r *counterSampleRepository) Downsample(before time.Time, span int64) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cutoff := before.Unix()
+	if _, err := tx.Exec(`
+		INSERT INTO counter_samples (series, ts, span, packets, bytes)
+		SELECT series, ((ts + ? - 1) / ?) * ?, ?, SUM(packets), SUM(bytes)
+		FROM counter_samples WHERE span < ? AND ts <= ?
+		GROUP BY series, (ts + ? - 1) / ?
+	`, span, span, span, span, span, cutoff, span, span); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM counter_samples WHERE span < ? AND ts <= ?`, span, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteBefore drops the samples that end before the given time.
+func (r *counterSampleRepository) DeleteBefore(before time.Time) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM counter_samples WHERE ts < ?`, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -30,14 +30,20 @@ func Migrate(db *sql.DB) error {
 			enabled     INTEGER NOT NULL DEFAULT 1,
 			comment     TEXT NOT NULL DEFAULT '',
 			position    INTEGER NOT NULL DEFAULT 0,
+			schedule    TEXT NOT NULL DEFAULT '',
+			log_options TEXT NOT NULL DEFAULT '',
+			tags        TEXT NOT NULL DEFAULT '',
 			created_at  DATETIME NOT NULL,
 			updated_at  DATETIME NOT NULL
 		);
 
 		CREATE TABLE IF NOT EXISTS history (
 			id          TEXT PRIMARY KEY,
+			kind        TEXT NOT NULL DEFAULT 'snapshot',
 			snapshot    TEXT NOT NULL,
 			description TEXT NOT NULL DEFAULT '',
+			verification TEXT NOT NULL DEFAULT '',
+			payload_hash TEXT NOT NULL DEFAULT '',
 			applied_at  DATETIME NOT NULL
 		);
 
@@ -86,9 +92,265 @@ func Migrate(db *sql.DB) error {
 			comment         TEXT NOT NULL DEFAULT '',
 			enabled         INTEGER NOT NULL DEFAULT 1,
 			position        INTEGER NOT NULL DEFAULT 0,
+			tags            TEXT NOT NULL DEFAULT '',
 			created_at      DATETIME NOT NULL,
 			updated_at      DATETIME NOT NULL
 		);
+
+		CREATE TABLE IF NOT EXISTS scheduled_jobs (
+			id              TEXT PRIMARY KEY,
+			action          TEXT NOT NULL,
+			history_id      TEXT NOT NULL DEFAULT '',
+			description     TEXT NOT NULL DEFAULT '',
+			run_at          DATETIME NOT NULL,
+			catch_up        TEXT NOT NULL DEFAULT 'run',
+			grace_seconds   INTEGER NOT NULL DEFAULT 0,
+			status          TEXT NOT NULL,
+			result          TEXT NOT NULL DEFAULT '',
+			created_by      TEXT NOT NULL DEFAULT '',
+			creator         TEXT NOT NULL DEFAULT '',
+			apply_job_id    TEXT NOT NULL DEFAULT '',
+			started_at      DATETIME,
+			finished_at     DATETIME,
+			created_at      DATETIME NOT NULL,
+			updated_at      DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS users (
+			id              TEXT PRIMARY KEY,
+			username        TEXT NOT NULL UNIQUE,
+			password_hash   TEXT NOT NULL,
+			oidc_subject    TEXT NOT NULL DEFAULT '',
+			disabled        INTEGER NOT NULL DEFAULT 0,
+			created_at      DATETIME NOT NULL,
+			updated_at      DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS user_roles (
+			user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			role        TEXT NOT NULL,
+			zone        TEXT NOT NULL DEFAULT '',
+			tag         TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (user_id, role, zone, tag)
+		);
+
+		CREATE TABLE IF NOT EXISTS sessions (
+			id          TEXT PRIMARY KEY,
+			token_hash  TEXT NOT NULL UNIQUE,
+			user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at  DATETIME NOT NULL,
+			created_at  DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id              TEXT PRIMARY KEY,
+			name            TEXT NOT NULL,
+			prefix          TEXT NOT NULL,
+			token_hash      TEXT NOT NULL UNIQUE,
+			scopes          TEXT NOT NULL DEFAULT '',
+			owner_id        TEXT NOT NULL DEFAULT '',
+			expires_at      DATETIME NOT NULL,
+			last_used_at    DATETIME,
+			revoked_at      DATETIME,
+			created_at      DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS audit_log (
+			seq             INTEGER PRIMARY KEY,
+			timestamp       DATETIME NOT NULL,
+			actor           TEXT NOT NULL,
+			actor_kind      TEXT NOT NULL,
+			source_ip       TEXT NOT NULL DEFAULT '',
+			action          TEXT NOT NULL,
+			resource        TEXT NOT NULL,
+			resource_id     TEXT NOT NULL DEFAULT '',
+			before          TEXT NOT NULL DEFAULT '',
+			after           TEXT NOT NULL DEFAULT '',
+			prev_hash       TEXT NOT NULL,
+			hash            TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+
+		CREATE TABLE IF NOT EXISTS panic_state (
+			id              INTEGER PRIMARY KEY CHECK (id = 1),
+			active          INTEGER NOT NULL DEFAULT 0,
+			reason          TEXT NOT NULL DEFAULT '',
+			activated_by    TEXT NOT NULL DEFAULT '',
+			activated_at    DATETIME,
+			snapshot_id     TEXT NOT NULL DEFAULT '',
+			caller_ip       TEXT NOT NULL DEFAULT '',
+			caller_port     INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS counter_samples (
+			series          TEXT NOT NULL,
+			ts              INTEGER NOT NULL,
+			span            INTEGER NOT NULL,
+			packets         INTEGER NOT NULL,
+			bytes           INTEGER NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_counter_samples_series_ts ON counter_samples(series, ts);
+		CREATE INDEX IF NOT EXISTS idx_counter_samples_ts ON counter_samples(ts);
+
+		CREATE TABLE IF NOT EXISTS log_entries (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts              DATETIME NOT NULL,
+			source          TEXT NOT NULL,
+			rule_id         TEXT NOT NULL DEFAULT '',
+			prefix          TEXT NOT NULL DEFAULT '',
+			in_iface        TEXT NOT NULL DEFAULT '',
+			out_iface       TEXT NOT NULL DEFAULT '',
+			src             TEXT NOT NULL DEFAULT '',
+			dst             TEXT NOT NULL DEFAULT '',
+			proto           TEXT NOT NULL DEFAULT '',
+			src_port        INTEGER NOT NULL DEFAULT 0,
+			dst_port        INTEGER NOT NULL DEFAULT 0,
+			length          INTEGER NOT NULL DEFAULT 0,
+			message         TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_log_entries_ts ON log_entries(ts);
+		CREATE INDEX IF NOT EXISTS idx_log_entries_rule_id ON log_entries(rule_id);
+		CREATE INDEX IF NOT EXISTS idx_log_entries_src ON log_entries(src);
+		CREATE INDEX IF NOT EXISTS idx_log_entries_dst ON log_entries(dst);
+
+		CREATE TABLE IF NOT EXISTS alert_rules (
+			id                    TEXT PRIMARY KEY,
+			name                  TEXT NOT NULL,
+			description           TEXT NOT NULL DEFAULT '',
+			kind                  TEXT NOT NULL,
+			severity              TEXT NOT NULL,
+			enabled               INTEGER NOT NULL DEFAULT 1,
+			series                TEXT NOT NULL DEFAULT '',
+			metric                TEXT NOT NULL DEFAULT '',
+			operator              TEXT NOT NULL DEFAULT '',
+			value                 REAL NOT NULL DEFAULT 0,
+			window_seconds        INTEGER NOT NULL DEFAULT 0,
+			for_seconds           INTEGER NOT NULL DEFAULT 0,
+			event_type            TEXT NOT NULL DEFAULT '',
+			resource_id           TEXT NOT NULL DEFAULT '',
+			resolve_event         TEXT NOT NULL DEFAULT '',
+			resolve_after_seconds INTEGER NOT NULL DEFAULT 0,
+			created_at            DATETIME NOT NULL,
+			updated_at            DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS alerts (
+			id              TEXT PRIMARY KEY,
+			rule_id         TEXT NOT NULL,
+			rule_name       TEXT NOT NULL,
+			severity        TEXT NOT NULL,
+			state           TEXT NOT NULL,
+			message         TEXT NOT NULL DEFAULT '',
+			value           REAL NOT NULL DEFAULT 0,
+			count           INTEGER NOT NULL DEFAULT 0,
+			silenced        INTEGER NOT NULL DEFAULT 0,
+			started_at      DATETIME NOT NULL,
+			fired_at        DATETIME,
+			resolved_at     DATETIME,
+			last_seen_at    DATETIME NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_alerts_started_at ON alerts(started_at);
+		CREATE INDEX IF NOT EXISTS idx_alerts_state ON alerts(state);
+
+		CREATE TABLE IF NOT EXISTS alert_silences (
+			id              TEXT PRIMARY KEY,
+			rule_id         TEXT NOT NULL DEFAULT '',
+			starts_at       DATETIME NOT NULL,
+			ends_at         DATETIME NOT NULL,
+			comment         TEXT NOT NULL DEFAULT '',
+			created_by      TEXT NOT NULL DEFAULT '',
+			created_at      DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS notification_sinks (
+			id              TEXT PRIMARY KEY,
+			name            TEXT NOT NULL,
+			type            TEXT NOT NULL,
+			enabled         BOOLEAN NOT NULL DEFAULT 1,
+			events          TEXT NOT NULL DEFAULT '',
+			config          TEXT NOT NULL DEFAULT '{}',
+			created_at      DATETIME NOT NULL,
+			updated_at      DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS notification_deliveries (
+			id              TEXT PRIMARY KEY,
+			sink_id         TEXT NOT NULL,
+			sink_name       TEXT NOT NULL DEFAULT '',
+			event_id        INTEGER NOT NULL DEFAULT 0,
+			event_type      TEXT NOT NULL,
+			summary         TEXT NOT NULL DEFAULT '',
+			status          TEXT NOT NULL,
+			attempts        INTEGER NOT NULL DEFAULT 0,
+			error           TEXT NOT NULL DEFAULT '',
+			created_at      DATETIME NOT NULL,
+			finished_at     DATETIME
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_notification_deliveries_created ON notification_deliveries(created_at);
 	`)
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema. CREATE TABLE IF NOT EXISTS
+	// leaves existing databases untouched, so add them explicitly.
+	columns := []struct{ table, column, def string }{
+		{"rules", "schedule", "TEXT NOT NULL DEFAULT ''"},
+		{"history", "kind", "TEXT NOT NULL DEFAULT 'snapshot'"},
+		{"rules", "tags", "TEXT NOT NULL DEFAULT ''"},
+		{"nat_rules", "tags", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "oidc_subject", "TEXT NOT NULL DEFAULT ''"},
+		{"history", "verification", "TEXT NOT NULL DEFAULT ''"},
+		{"history", "payload_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"rules", "log_options", "TEXT NOT NULL DEFAULT ''"},
+		{"rules", "in_interface", "TEXT NOT NULL DEFAULT ''"},
+		{"rules", "out_interface", "TEXT NOT NULL DEFAULT ''"},
+		{"scheduled_jobs", "created_by", "TEXT NOT NULL DEFAULT ''"},
+		{"scheduled_jobs", "creator", "TEXT NOT NULL DEFAULT ''"},
+		{"scheduled_jobs", "apply_job_id", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, col := range columns {
+		if err := addColumn(db, col.table, col.column, col.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumn adds a column to an existing table unless it is already present.
+func addColumn(db *sql.DB, table, column, def string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
 	return err
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/firewall-manager/backend/internal/models"
@@ -9,8 +10,10 @@ import (
 
 type HistoryRepository interface {
 	Save(entry *models.HistoryEntry) error
+	Get(id string) (*models.HistoryEntry, error)
 	Latest() (*models.HistoryEntry, error)
 	List(limit int) ([]*models.HistoryEntry, error)
+	Count() (int, error)
 }
 
 type sqliteHistoryRepository struct {
@@ -22,21 +25,48 @@ func NewHistoryRepository(db *sql.DB) HistoryRepository {
 }
 
 func (r *sqliteHistoryRepository) Save(entry *models.HistoryEntry) error {
-	_, err := r.db.Exec(`
-		INSERT INTO history (id, snapshot, description, applied_at)
-		VALUES (?, ?, ?, ?)
-	`, entry.ID, entry.Snapshot, entry.Description, entry.AppliedAt)
+	if entry.Kind == "" {
+		entry.Kind = models.HistoryKindSnapshot
+	}
+	verification, err := encodeVerification(entry.Verification)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO history (id, kind, snapshot, description, payload_hash, verification, applied_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.ID, entry.Kind, entry.Snapshot, entry.Description, entry.PayloadHash, verification, entry.AppliedAt)
 	return err
 }
 
+func (r *sqliteHistoryRepository) Get(id string) (*models.HistoryEntry, error) {
+	entry, err := scanHistoryEntry(r.db.QueryRow(`
+		SELECT id, kind, snapshot, description, payload_hash, verification, applied_at
+		FROM history
+		WHERE id = ?
+	`, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("history entry not found: %s", id)
+	}
+	return entry, err
+}
+
+// Count returns the number of history entries of all kinds.
+func (r *sqliteHistoryRepository) Count() (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM history`).Scan(&n)
+	return n, err
+}
+
+// Latest returns the most recent restorable snapshot.
 func (r *sqliteHistoryRepository) Latest() (*models.HistoryEntry, error) {
-	entry := &models.HistoryEntry{}
-	err := r.db.QueryRow(`
-		SELECT id, snapshot, description, applied_at
+	entry, err := scanHistoryEntry(r.db.QueryRow(`
+		SELECT id, kind, snapshot, description, payload_hash, verification, applied_at
 		FROM history
+		WHERE kind = ?
 		ORDER BY applied_at DESC
 		LIMIT 1
-	`).Scan(&entry.ID, &entry.Snapshot, &entry.Description, &entry.AppliedAt)
+	`, models.HistoryKindSnapshot).Scan)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("no history entries found")
 	}
@@ -45,7 +75,7 @@ func (r *sqliteHistoryRepository) Latest() (*models.HistoryEntry, error) {
 
 func (r *sqliteHistoryRepository) List(limit int) ([]*models.HistoryEntry, error) {
 	rows, err := r.db.Query(`
-		SELECT id, snapshot, description, applied_at
+		SELECT id, kind, snapshot, description, payload_hash, verification, applied_at
 		FROM history
 		ORDER BY applied_at DESC
 		LIMIT ?
@@ -57,11 +87,39 @@ func (r *sqliteHistoryRepository) List(limit int) ([]*models.HistoryEntry, error
 
 	var entries []*models.HistoryEntry
 	for rows.Next() {
-		e := &models.HistoryEntry{}
-		if err := rows.Scan(&e.ID, &e.Snapshot, &e.Description, &e.AppliedAt); err != nil {
+		e, err := scanHistoryEntry(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		entries = append(entries, e)
 	}
 	return entries, rows.Err()
-}
\ No newline at end of file
+}
+
+func scanHistoryEntry(scan func(dest ...any) error) (*models.HistoryEntry, error) {
+	e := &models.HistoryEntry{}
+	var verification string
+	if err := scan(&e.ID, &e.Kind, &e.Snapshot, &e.Description, &e.PayloadHash, &verification, &e.AppliedAt); err != nil {
+		return nil, err
+	}
+	if verification != "" {
+		e.Verification = &models.VerificationReport{}
+		if err := json.Unmarshal([]byte(verification), e.Verification); err != nil {
+			return nil, fmt.Errorf("decode verification: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// encodeVerification serializes a verification report for the verification
+// column. A nil report is stored as the empty string.
+func encodeVerification(v *models.VerificationReport) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode verification: %w", err)
+	}
+	return string(b), nil
+}
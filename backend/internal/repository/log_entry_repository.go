@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// LogEntryRepository stores logged packets. The table is rotated by
+// deleting the oldest entries.
+type LogEntryRepository interface {
+	Insert(entries []*models.LogEntry) error
+	Query(filter models.LogFilter) ([]*models.LogEntry, error)
+	Rotate(keep int, before time.Time) (int64, error)
+}
+
+type logEntryRepository struct {
+	db *sql.DB
+}
+
+func NewLogEntryRepository(db *sql.DB) LogEntryRepository {
+	return &logEntryRepository{db: db}
+}
+
+const logEntryColumns = `id, ts, source, rule_id, prefix, in_iface, out_iface, src, dst, proto, src_port, dst_port, length, message`
+
+func scanLogEntry(scan func(dest ...any) error) (*models.LogEntry, error) {
+	e := &models.LogEntry{}
+	if err := scan(&e.ID, &e.Time, &e.Source, &e.RuleID, &e.Prefix, &e.In, &e.Out,
+		&e.Src, &e.Dst, &e.Proto, &e.SrcPort, &e.DstPort, &e.Length, &e.Message); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Insert stores the entries in one transaction and sets their IDs.
+func (r *logEntryRepository) Insert(entries []*models.LogEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO log_entries (ts, source, rule_id, prefix, in_iface, out_iface, src, dst, proto, src_port, dst_port, length, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range entries {
+		res, err := stmt.Exec(e.Time.UTC(), e.Source, e.RuleID, e.Prefix, e.In, e.Out,
+			e.Src, e.Dst, e.Proto, e.SrcPort, e.DstPort, e.Length, e.Message)
+		if err != nil {
+			return err
+		}
+		if e.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Query returns matching entries, newest first.
+func (r *logEntryRepository) Query(f models.LogFilter) ([]*models.LogEntry, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.RuleID != "" {
+		add("rule_id = ?", f.RuleID)
+	}
+	if f.Prefix != "" {
+		add("prefix = ?", f.Prefix)
+	}
+	if f.In != "" {
+		add("in_iface = ?", f.In)
+	}
+	if f.Out != "" {
+		add("out_iface = ?", f.Out)
+	}
+	if f.Src != "" {
+		add("src = ?", f.Src)
+	}
+	if f.Dst != "" {
+		add("dst = ?", f.Dst)
+	}
+	if f.Proto != "" {
+		add("proto = ? COLLATE NOCASE", f.Proto)
+	}
+	if f.Port != 0 {
+		where = append(where, "(src_port = ? OR dst_port = ?)")
+		args = append(args, f.Port, f.Port)
+	}
+	if f.Text != "" {
+		add("instr(message, ?) > 0", f.Text)
+	}
+	if !f.From.IsZero() {
+		add("ts >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("ts < ?", f.To.UTC())
+	}
+	if f.Before > 0 {
+		add("id < ?", f.Before)
+	}
+
+	q := `SELECT ` + logEntryColumns + ` FROM log_entries`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.LogEntry
+	for rows.Next() {
+		e, err := scanLogEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Rotate deletes all but the newest keep entries, and those logged before
+// before. A zero keep or before disables that limit.
+func (r *logEntryRepository) Rotate(keep int, before time.Time) (int64, error) {
+	var deleted int64
+	if keep > 0 {
+		res, err := r.db.Exec(`
+			DELETE FROM log_entries WHERE id <= (
+				SELECT id FROM log_entries ORDER BY id DESC LIMIT 1 OFFSET ?
+			)
+		`, keep)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if !before.IsZero() {
+		res, err := r.db.Exec(`DELETE FROM log_entries WHERE ts < ?`, before.UTC())
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
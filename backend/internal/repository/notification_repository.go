@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// NotificationRepository persists notification sinks and their delivery log
+type NotificationRepository interface {
+	ListSinks() ([]*models.NotificationSink, error)
+	GetSink(id string) (*models.NotificationSink, error)
+	CreateSink(sink *models.NotificationSink) error
+	UpdateSink(sink *models.NotificationSink) error
+	DeleteSink(id string) error
+
+	ListDeliveries(filter models.NotificationDeliveryFilter) ([]*models.NotificationDelivery, error)
+	CreateDelivery(d *models.NotificationDelivery) error
+	UpdateDelivery(d *models.NotificationDelivery) error
+	// PruneDeliveries keeps the newest keep deliveries and deletes the rest.
+	PruneDeliveries(keep int) (int64, error)
+}
+
+type notificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+const notificationSinkColumns = `id, name, type, enabled, events, config, created_at, updated_at`
+
+func scanNotificationSink(scan func(dest ...any) error) (*models.NotificationSink, error) {
+	s := &models.NotificationSink{}
+	var events, config string
+	if err := scan(&s.ID, &s.Name, &s.Type, &s.Enabled, &events, &config, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.Events = decodeTags(events)
+	if err := json.Unmarshal([]byte(config), &s.Config); err != nil {
+		return nil, fmt.Errorf("decode sink config: %w", err)
+	}
+	return s, nil
+}
+
+// encodeSinkConfig serializes a sink's settings for the config column.
+func encodeSinkConfig(c models.NotificationSinkConfig) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encode sink config: %w", err)
+	}
+	return string(b), nil
+}
+
+func (r *notificationRepository) ListSinks() ([]*models.NotificationSink, error) {
+	rows, err := r.db.Query(`SELECT ` + notificationSinkColumns + ` FROM notification_sinks ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sinks []*models.NotificationSink
+	for rows.Next() {
+		sink, err := scanNotificationSink(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, rows.Err()
+}
+
+func (r *notificationRepository) GetSink(id string) (*models.NotificationSink, error) {
+	row := r.db.QueryRow(`SELECT `+notificationSinkColumns+` FROM notification_sinks WHERE id = ?`, id)
+	sink, err := scanNotificationSink(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification sink not found: %s", id)
+	}
+	return sink, err
+}
+
+func (r *notificationRepository) CreateSink(sink *models.NotificationSink) error {
+	config, err := encodeSinkConfig(sink.Config)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO notification_sinks (id, name, type, enabled, events, config, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, sink.ID, sink.Name, sink.Type, sink.Enabled, encodeTags(sink.Events), config, sink.CreatedAt, sink.UpdatedAt)
+	return err
+}
+
+func (r *notificationRepository) UpdateSink(sink *models.NotificationSink) error {
+	config, err := encodeSinkConfig(sink.Config)
+	if err != nil {
+		return err
+	}
+	sink.UpdatedAt = time.Now()
+	_, err = r.db.Exec(`
+		UPDATE notification_sinks
+		SET name = ?, type = ?, enabled = ?, events = ?, config = ?, updated_at = ?
+		WHERE id = ?
+	`, sink.Name, sink.Type, sink.Enabled, encodeTags(sink.Events), config, sink.UpdatedAt, sink.ID)
+	return err
+}
+
+func (r *notificationRepository) DeleteSink(id string) error {
+	res, err := r.db.Exec(`DELETE FROM notification_sinks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("notification sink not found: %s", id)
+	}
+	return nil
+}
+
+const notificationDeliveryColumns = `id, sink_id, sink_name, event_id, event_type, summary, status, attempts, error,
+		       created_at, finished_at`
+
+// ListDeliveries returns matching deliveries, newest first.
+func (r *notificationRepository) ListDeliveries(f models.NotificationDeliveryFilter) ([]*models.NotificationDelivery, error) {
+	var where []string
+	var args []any
+	if f.SinkID != "" {
+		where = append(where, "sink_id = ?")
+		args = append(args, f.SinkID)
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, f.Status)
+	}
+	if f.EventType != "" {
+		where = append(where, "(event_type = ? OR event_type LIKE ?)")
+		args = append(args, f.EventType, f.EventType+".%")
+	}
+
+	q := `SELECT ` + notificationDeliveryColumns + ` FROM notification_deliveries`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.NotificationDelivery
+	for rows.Next() {
+		d := &models.NotificationDelivery{}
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.SinkID, &d.SinkName, &d.EventID, &d.EventType, &d.Summary, &d.Status,
+			&d.Attempts, &d.Error, &d.CreatedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			d.FinishedAt = &finishedAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *notificationRepository) CreateDelivery(d *models.NotificationDelivery) error {
+	_, err := r.db.Exec(`
+		INSERT INTO notification_deliveries (id, sink_id, sink_name, event_id, event_type, summary, status,
+			attempts, error, created_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.ID, d.SinkID, d.SinkName, d.EventID, d.EventType, d.Summary, d.Status, d.Attempts, d.Error,
+		d.CreatedAt, d.FinishedAt)
+	return err
+}
+
+func (r *notificationRepository) UpdateDelivery(d *models.NotificationDelivery) error {
+	_, err := r.db.Exec(`
+		UPDATE notification_deliveries
+		SET status = ?, attempts = ?, error = ?, finished_at = ?
+		WHERE id = ?
+	`, d.Status, d.Attempts, d.Error, d.FinishedAt, d.ID)
+	return err
+}
+
+func (r *notificationRepository) PruneDeliveries(keep int) (int64, error) {
+	res, err := r.db.Exec(`
+		DELETE FROM notification_deliveries
+		WHERE id NOT IN (SELECT id FROM notification_deliveries ORDER BY created_at DESC LIMIT ?)
+	`, keep)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
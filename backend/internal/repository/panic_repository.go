@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// PanicRepository stores the single panic state row.
+type PanicRepository interface {
+	Get() (*models.PanicState, error)
+	Save(state *models.PanicState) error
+}
+
+type panicRepository struct {
+	db *sql.DB
+}
+
+func NewPanicRepository(db *sql.DB) PanicRepository {
+	return &panicRepository{db: db}
+}
+
+// Get returns the stored state, or an inactive one if none was saved.
+func (r *panicRepository) Get() (*models.PanicState, error) {
+	state := &models.PanicState{}
+	var activatedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT active, reason, activated_by, activated_at, snapshot_id, caller_ip, caller_port
+		FROM panic_state
+		WHERE id = 1
+	`).Scan(&state.Active, &state.Reason, &state.ActivatedBy, &activatedAt, &state.SnapshotID,
+		&state.CallerIP, &state.CallerPort)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if activatedAt.Valid {
+		t := activatedAt.Time
+		state.ActivatedAt = &t
+	}
+	return state, nil
+}
+
+func (r *panicRepository) Save(state *models.PanicState) error {
+	_, err := r.db.Exec(`
+		INSERT INTO panic_state (id, active, reason, activated_by, activated_at, snapshot_id, caller_ip, caller_port)
+		VALUES (1, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			active = excluded.active,
+			reason = excluded.reason,
+			activated_by = excluded.activated_by,
+			activated_at = excluded.activated_at,
+			snapshot_id = excluded.snapshot_id,
+			caller_ip = excluded.caller_ip,
+			caller_port = excluded.caller_port
+	`, state.Active, state.Reason, state.ActivatedBy, state.ActivatedAt, state.SnapshotID,
+		state.CallerIP, state.CallerPort)
+	return err
+}
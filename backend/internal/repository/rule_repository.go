@@ -2,7 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/firewall-manager/backend/internal/models"
@@ -26,8 +28,8 @@ func NewRuleRepository(db *sql.DB) RuleRepository {
 
 func (r *sqliteRuleRepository) List() ([]*models.Rule, error) {
 	rows, err := r.db.Query(`
-		SELECT id, chain, protocol, src, dst, src_port, dst_port,
-		       action, enabled, comment, position, created_at, updated_at
+		SELECT id, chain, protocol, src, dst, src_port, dst_port, in_interface, out_interface,
+		       action, enabled, comment, position, schedule, log_options, tags, created_at, updated_at
 		FROM rules
 		ORDER BY position ASC, created_at ASC
 	`)
@@ -40,16 +42,24 @@ func (r *sqliteRuleRepository) List() ([]*models.Rule, error) {
 	for rows.Next() {
 		rule := &models.Rule{}
 		var enabled int
+		var schedule, logOptions, tags string
 		err := rows.Scan(
 			&rule.ID, &rule.Chain, &rule.Protocol,
-			&rule.Src, &rule.Dst, &rule.SrcPort, &rule.DstPort,
+			&rule.Src, &rule.Dst, &rule.SrcPort, &rule.DstPort, &rule.InInterface, &rule.OutInterface,
 			&rule.Action, &enabled, &rule.Comment,
-			&rule.Position, &rule.CreatedAt, &rule.UpdatedAt,
+			&rule.Position, &schedule, &logOptions, &tags, &rule.CreatedAt, &rule.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 		rule.Enabled = enabled == 1
+		rule.Tags = decodeTags(tags)
+		if rule.Schedule, err = decodeSchedule(schedule); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		if rule.Log, err = decodeLogOptions(logOptions); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
 		rules = append(rules, rule)
 	}
 	return rules, rows.Err()
@@ -58,15 +68,16 @@ func (r *sqliteRuleRepository) List() ([]*models.Rule, error) {
 func (r *sqliteRuleRepository) GetByID(id string) (*models.Rule, error) {
 	rule := &models.Rule{}
 	var enabled int
+	var schedule, logOptions, tags string
 	err := r.db.QueryRow(`
-		SELECT id, chain, protocol, src, dst, src_port, dst_port,
-		       action, enabled, comment, position, created_at, updated_at
+		SELECT id, chain, protocol, src, dst, src_port, dst_port, in_interface, out_interface,
+		       action, enabled, comment, position, schedule, log_options, tags, created_at, updated_at
 		FROM rules WHERE id = ?
 	`, id).Scan(
 		&rule.ID, &rule.Chain, &rule.Protocol,
-		&rule.Src, &rule.Dst, &rule.SrcPort, &rule.DstPort,
+		&rule.Src, &rule.Dst, &rule.SrcPort, &rule.DstPort, &rule.InInterface, &rule.OutInterface,
 		&rule.Action, &enabled, &rule.Comment,
-		&rule.Position, &rule.CreatedAt, &rule.UpdatedAt,
+		&rule.Position, &schedule, &logOptions, &tags, &rule.CreatedAt, &rule.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("rule not found: %s", id)
@@ -75,6 +86,13 @@ func (r *sqliteRuleRepository) GetByID(id string) (*models.Rule, error) {
 		return nil, err
 	}
 	rule.Enabled = enabled == 1
+	rule.Tags = decodeTags(tags)
+	if rule.Schedule, err = decodeSchedule(schedule); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
+	if rule.Log, err = decodeLogOptions(logOptions); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
 	return rule, nil
 }
 
@@ -83,15 +101,23 @@ func (r *sqliteRuleRepository) Create(rule *models.Rule) error {
 	if rule.Enabled {
 		enabled = 1
 	}
-	_, err := r.db.Exec(`
-		INSERT INTO rules (id, chain, protocol, src, dst, src_port, dst_port,
-		                   action, enabled, comment, position, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	schedule, err := encodeSchedule(rule.Schedule)
+	if err != nil {
+		return err
+	}
+	logOptions, err := encodeLogOptions(rule.Log)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO rules (id, chain, protocol, src, dst, src_port, dst_port, in_interface, out_interface,
+		                   action, enabled, comment, position, schedule, log_options, tags, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		rule.ID, rule.Chain, rule.Protocol,
-		rule.Src, rule.Dst, rule.SrcPort, rule.DstPort,
+		rule.Src, rule.Dst, rule.SrcPort, rule.DstPort, rule.InInterface, rule.OutInterface,
 		rule.Action, enabled, rule.Comment,
-		rule.Position, rule.CreatedAt, rule.UpdatedAt,
+		rule.Position, schedule, logOptions, encodeTags(rule.Tags), rule.CreatedAt, rule.UpdatedAt,
 	)
 	return err
 }
@@ -101,17 +127,25 @@ func (r *sqliteRuleRepository) Update(rule *models.Rule) error {
 	if rule.Enabled {
 		enabled = 1
 	}
+	schedule, err := encodeSchedule(rule.Schedule)
+	if err != nil {
+		return err
+	}
+	logOptions, err := encodeLogOptions(rule.Log)
+	if err != nil {
+		return err
+	}
 	rule.UpdatedAt = time.Now()
 	result, err := r.db.Exec(`
 		UPDATE rules
-		SET chain=?, protocol=?, src=?, dst=?, src_port=?, dst_port=?,
-		    action=?, enabled=?, comment=?, position=?, updated_at=?
+		SET chain=?, protocol=?, src=?, dst=?, src_port=?, dst_port=?, in_interface=?, out_interface=?,
+		    action=?, enabled=?, comment=?, position=?, schedule=?, log_options=?, tags=?, updated_at=?
 		WHERE id=?
 	`,
 		rule.Chain, rule.Protocol,
-		rule.Src, rule.Dst, rule.SrcPort, rule.DstPort,
+		rule.Src, rule.Dst, rule.SrcPort, rule.DstPort, rule.InInterface, rule.OutInterface,
 		rule.Action, enabled, rule.Comment,
-		rule.Position, rule.UpdatedAt, rule.ID,
+		rule.Position, schedule, logOptions, encodeTags(rule.Tags), rule.UpdatedAt, rule.ID,
 	)
 	if err != nil {
 		return err
@@ -133,4 +167,65 @@ func (r *sqliteRuleRepository) Delete(id string) error {
 		return fmt.Errorf("rule not found: %s", id)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// encodeSchedule serializes a rule schedule for the schedule column.
+// A nil schedule is stored as the empty string.
+func encodeSchedule(s *models.Schedule) (string, error) {
+	if s == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("encode schedule: %w", err)
+	}
+	return string(b), nil
+}
+
+func decodeSchedule(raw string) (*models.Schedule, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	s := &models.Schedule{}
+	if err := json.Unmarshal([]byte(raw), s); err != nil {
+		return nil, fmt.Errorf("decode schedule: %w", err)
+	}
+	return s, nil
+}
+
+// encodeLogOptions serializes a rule's log options for the log_options
+// column. Nil options are stored as the empty string.
+func encodeLogOptions(o *models.LogOptions) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		return "", fmt.Errorf("encode log options: %w", err)
+	}
+	return string(b), nil
+}
+
+func decodeLogOptions(raw string) (*models.LogOptions, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	o := &models.LogOptions{}
+	if err := json.Unmarshal([]byte(raw), o); err != nil {
+		return nil, fmt.Errorf("decode log options: %w", err)
+	}
+	return o, nil
+}
+
+// encodeTags stores tags as a comma-separated list. Tags are validated by the
+// service layer and never contain commas.
+func encodeTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+func decodeTags(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// ScheduledJobRepository persists scheduled apply/rollback jobs
+type ScheduledJobRepository interface {
+	List() ([]*models.ScheduledJob, error)
+	Get(id string) (*models.ScheduledJob, error)
+	ListByStatus(status models.ScheduledJobStatus) ([]*models.ScheduledJob, error)
+	Create(job *models.ScheduledJob) error
+	Update(job *models.ScheduledJob) error
+}
+
+type scheduledJobRepository struct {
+	db *sql.DB
+}
+
+func NewScheduledJobRepository(db *sql.DB) ScheduledJobRepository {
+	return &scheduledJobRepository{db: db}
+}
+
+const scheduledJobColumns = `id, action, history_id, description, run_at, catch_up, grace_seconds,
+		       status, result, created_by, creator, apply_job_id, started_at, finished_at, created_at, updated_at`
+
+func scanScheduledJob(scan func(dest ...any) error) (*models.ScheduledJob, error) {
+	job := &models.ScheduledJob{}
+	var creator string
+	var startedAt, finishedAt sql.NullTime
+	err := scan(&job.ID, &job.Action, &job.HistoryID, &job.Description, &job.RunAt, &job.CatchUp,
+		&job.GraceSeconds, &job.Status, &job.Result, &job.CreatedBy, &creator, &job.ApplyJobID,
+		&startedAt, &finishedAt, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if job.Creator, err = decodeJobCreator(creator); err != nil {
+		return nil, err
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
+
+func (r *scheduledJobRepository) List() ([]*models.ScheduledJob, error) {
+	return r.query(`SELECT ` + scheduledJobColumns + ` FROM scheduled_jobs ORDER BY run_at DESC`)
+}
+
+// ListByStatus returns jobs in the given status, earliest run time first.
+func (r *scheduledJobRepository) ListByStatus(status models.ScheduledJobStatus) ([]*models.ScheduledJob, error) {
+	return r.query(`SELECT `+scheduledJobColumns+` FROM scheduled_jobs WHERE status = ? ORDER BY run_at ASC`, status)
+}
+
+func (r *scheduledJobRepository) query(q string, args ...any) ([]*models.ScheduledJob, error) {
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.ScheduledJob
+	for rows.Next() {
+		job, err := scanScheduledJob(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *scheduledJobRepository) Get(id string) (*models.ScheduledJob, error) {
+	row := r.db.QueryRow(`SELECT `+scheduledJobColumns+` FROM scheduled_jobs WHERE id = ?`, id)
+	job, err := scanScheduledJob(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("scheduled job not found: %s", id)
+	}
+	return job, err
+}
+
+func (r *scheduledJobRepository) Create(job *models.ScheduledJob) error {
+	creator, err := encodeJobCreator(job.Creator)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO scheduled_jobs (id, action, history_id, description, run_at, catch_up, grace_seconds,
+			status, result, created_by, creator, apply_job_id, started_at, finished_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.ID, job.Action, job.HistoryID, job.Description, job.RunAt, job.CatchUp, job.GraceSeconds,
+		job.Status, job.Result, job.CreatedBy, creator, job.ApplyJobID, job.StartedAt, job.FinishedAt,
+		job.CreatedAt, job.UpdatedAt)
+	return err
+}
+
+func (r *scheduledJobRepository) Update(job *models.ScheduledJob) error {
+	job.UpdatedAt = time.Now()
+	result, err := r.db.Exec(`
+		UPDATE scheduled_jobs
+		SET status = ?, result = ?, apply_job_id = ?, started_at = ?, finished_at = ?, updated_at = ?
+		WHERE id = ?
+	`, job.Status, job.Result, job.ApplyJobID, job.StartedAt, job.FinishedAt, job.UpdatedAt, job.ID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("scheduled job not found: %s", job.ID)
+	}
+	return nil
+}
+
+// encodeJobCreator serializes a job's creator for the creator column. A nil
+// creator is stored as the empty string.
+func encodeJobCreator(c *models.JobCreator) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encode job creator: %w", err)
+	}
+	return string(b), nil
+}
+
+func decodeJobCreator(raw string) (*models.JobCreator, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	c := &models.JobCreator{}
+	if err := json.Unmarshal([]byte(raw), c); err != nil {
+		return nil, fmt.Errorf("decode job creator: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// UserRepository manages local user accounts and their role bindings
+type UserRepository interface {
+	List() ([]*models.User, error)
+	Get(id string) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	GetByOIDCSubject(subject string) (*models.User, error)
+	Count() (int, error)
+	Create(user *models.User) error
+	Update(user *models.User) error
+	Delete(id string) error
+}
+
+type userRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) List() ([]*models.User, error) {
+	rows, err := r.db.Query(`
+		SELECT id, username, password_hash, oidc_subject, disabled, created_at, updated_at
+		FROM users
+		ORDER BY username
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.OIDCSubject, &user.Disabled, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, user := range users {
+		if user.Roles, err = r.roles(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+func (r *userRepository) Get(id string) (*models.User, error) {
+	return r.getBy("id", id)
+}
+
+func (r *userRepository) GetByUsername(username string) (*models.User, error) {
+	return r.getBy("username", username)
+}
+
+func (r *userRepository) GetByOIDCSubject(subject string) (*models.User, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("user not found")
+	}
+	return r.getBy("oidc_subject", subject)
+}
+
+func (r *userRepository) getBy(column, value string) (*models.User, error) {
+	user := &models.User{}
+	err := r.db.QueryRow(`
+		SELECT id, username, password_hash, oidc_subject, disabled, created_at, updated_at
+		FROM users
+		WHERE `+column+` = ?
+	`, value).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.OIDCSubject, &user.Disabled, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found: %s", value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Roles, err = r.roles(user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) roles(userID string) ([]models.RoleBinding, error) {
+	rows, err := r.db.Query(`
+		SELECT role, zone, tag
+		FROM user_roles
+		WHERE user_id = ?
+		ORDER BY role, zone, tag
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bindings := []models.RoleBinding{}
+	for rows.Next() {
+		var b models.RoleBinding
+		if err := rows.Scan(&b.Role, &b.Zone, &b.Tag); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, rows.Err()
+}
+
+func (r *userRepository) Count() (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+func (r *userRepository) Create(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO users (id, username, password_hash, oidc_subject, disabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, user.ID, user.Username, user.PasswordHash, user.OIDCSubject, user.Disabled, user.CreatedAt, user.UpdatedAt); err != nil {
+		return err
+	}
+	if err := replaceRoles(tx, user.ID, user.Roles); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *userRepository) Update(user *models.User) error {
+	user.UpdatedAt = time.Now()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET username = ?, password_hash = ?, disabled = ?, updated_at = ?
+		WHERE id = ?
+	`, user.Username, user.PasswordHash, user.Disabled, user.UpdatedAt, user.ID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("user not found: %s", user.ID)
+	}
+	if err := replaceRoles(tx, user.ID, user.Roles); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRoles(tx *sql.Tx, userID string, roles []models.RoleBinding) error {
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, b := range roles {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO user_roles (user_id, role, zone, tag)
+			VALUES (?, ?, ?, ?)
+		`, userID, b.Role, b.Zone, b.Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *userRepository) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("user not found: %s", id)
+	}
+	return nil
+}
+
+// SessionRepository stores login sessions keyed by token hash
+type SessionRepository interface {
+	Create(session *models.Session) error
+	GetByTokenHash(hash string) (*models.Session, error)
+	Delete(id string) error
+	DeleteExpired(now time.Time) error
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	_, err := r.db.Exec(`
+		INSERT INTO sessions (id, token_hash, user_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, session.ID, session.TokenHash, session.UserID, session.ExpiresAt, session.CreatedAt)
+	return err
+}
+
+func (r *sessionRepository) GetByTokenHash(hash string) (*models.Session, error) {
+	session := &models.Session{}
+	err := r.db.QueryRow(`
+		SELECT id, token_hash, user_id, expires_at, created_at
+		FROM sessions
+		WHERE token_hash = ?
+	`, hash).Scan(&session.ID, &session.TokenHash, &session.UserID, &session.ExpiresAt, &session.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found")
+	}
+	return session, err
+}
+
+func (r *sessionRepository) Delete(id string) error {
+	_, err := r.db.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	return err
+}
+
+func (r *sessionRepository) DeleteExpired(now time.Time) error {
+	_, err := r.db.Exec(`DELETE FROM sessions WHERE expires_at < ?`, now.UTC())
+	return err
+}
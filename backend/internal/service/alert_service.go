@@ -0,0 +1,718 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidAlertRule is returned for alert rules and silences that fail
+// validation.
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+const (
+	defaultAlertWindow       = 300
+	minAlertWindow           = 10
+	maxAlertWindow           = 7 * 24 * 3600
+	defaultAlertResolveAfter = 3600
+	defaultAlertLimit        = 100
+	maxAlertLimit            = 1000
+)
+
+// alertSeriesPrefixes are the counter series a threshold rule may watch.
+var alertSeriesPrefixes = []string{"rule/", "nat/", "policy/", "iface/", "host/", "peer/"}
+
+// AlertRuleDTO is the input for creating or replacing an alert rule
+type AlertRuleDTO struct {
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Kind        models.AlertKind     `json:"kind" binding:"required"`
+	Severity    models.AlertSeverity `json:"severity"`
+	Enabled     *bool                `json:"enabled"`
+
+	Series        string             `json:"series"`
+	Metric        models.AlertMetric `json:"metric"`
+	Operator      string             `json:"operator"`
+	Value         float64            `json:"value"`
+	WindowSeconds int                `json:"windowSeconds"`
+	ForSeconds    int                `json:"forSeconds"`
+
+	EventType           string `json:"eventType"`
+	ResourceID          string `json:"resourceId"`
+	ResolveEvent        string `json:"resolveEvent"`
+	ResolveAfterSeconds int    `json:"resolveAfterSeconds"`
+}
+
+// CreateAlertSilenceDTO is the input for silencing alerts
+type CreateAlertSilenceDTO struct {
+	RuleID   string     `json:"ruleId"`
+	StartsAt *time.Time `json:"startsAt"` // default now
+	EndsAt   time.Time  `json:"endsAt" binding:"required"`
+	Comment  string     `json:"comment"`
+}
+
+// AlertService manages alert rules and silences, serves the alert history
+// and runs the engine that raises the alerts.
+type AlertService interface {
+	ListRules(ctx context.Context) ([]*models.AlertRule, error)
+	GetRule(ctx context.Context, id string) (*models.AlertRule, error)
+	CreateRule(ctx context.Context, dto AlertRuleDTO) (*models.AlertRule, error)
+	UpdateRule(ctx context.Context, id string, dto AlertRuleDTO) (*models.AlertRule, error)
+	DeleteRule(ctx context.Context, id string) error
+
+	ListAlerts(ctx context.Context, filter models.AlertFilter) ([]*models.Alert, error)
+
+	ListSilences(ctx context.Context) ([]*models.AlertSilence, error)
+	CreateSilence(ctx context.Context, dto CreateAlertSilenceDTO) (*models.AlertSilence, error)
+	DeleteSilence(ctx context.Context, id string) error
+
+	Run(ctx context.Context)
+}
+
+type alertService struct {
+	repo     repository.AlertRepository
+	samples  repository.CounterSampleRepository
+	filters  repository.RuleRepository
+	natRules repository.NATRuleRepository
+	ifaces   repository.InterfaceRepository
+	events   *EventBus
+	audit    Auditor
+	interval time.Duration
+	log      *logrus.Logger
+
+	reload chan struct{} // nudges the engine when rules or silences change
+
+	// Touched only by Run.
+	rules    []*models.AlertRule
+	silences []*models.AlertSilence
+	active   map[string]*models.Alert // by rule ID
+}
+
+func NewAlertService(
+	repo repository.AlertRepository,
+	samples repository.CounterSampleRepository,
+	filters repository.RuleRepository,
+	natRules repository.NATRuleRepository,
+	ifaces repository.InterfaceRepository,
+	events *EventBus,
+	audit Auditor,
+	interval time.Duration,
+	log *logrus.Logger,
+) AlertService {
+	return &alertService{
+		repo:     repo,
+		samples:  samples,
+		filters:  filters,
+		natRules: natRules,
+		ifaces:   ifaces,
+		events:   events,
+		audit:    audit,
+		interval: interval,
+		log:      log,
+		reload:   make(chan struct{}, 1),
+	}
+}
+
+func (s *alertService) ListRules(_ context.Context) ([]*models.AlertRule, error) {
+	return s.repo.ListRules()
+}
+
+func (s *alertService) GetRule(_ context.Context, id string) (*models.AlertRule, error) {
+	return s.repo.GetRule(id)
+}
+
+func (s *alertService) CreateRule(ctx context.Context, dto AlertRuleDTO) (*models.AlertRule, error) {
+	now := time.Now().UTC()
+	rule := &models.AlertRule{ID: uuid.New().String(), CreatedAt: now, UpdatedAt: now}
+	if err := applyAlertRuleDTO(rule, dto); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "alert-rule.create", "alert-rule", rule.ID, nil, rule)
+	s.wake()
+	return rule, nil
+}
+
+func (s *alertService) UpdateRule(ctx context.Context, id string, dto AlertRuleDTO) (*models.AlertRule, error) {
+	rule, err := s.repo.GetRule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	before := *rule
+	if err := applyAlertRuleDTO(rule, dto); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "alert-rule.update", "alert-rule", rule.ID, before, rule)
+	s.wake()
+	return rule, nil
+}
+
+// DeleteRule deletes the rule; an alert it raised resolves at the next
+// evaluation.
+func (s *alertService) DeleteRule(ctx context.Context, id string) error {
+	rule, err := s.repo.GetRule(id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeRule(ctx, rule); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRule(id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "alert-rule.delete", "alert-rule", id, rule, nil)
+	s.wake()
+	return nil
+}
+
+// applyAlertRuleDTO validates dto and copies it onto rule, filling in
+// defaults and clearing the fields of the other kind.
+func applyAlertRuleDTO(rule *models.AlertRule, dto AlertRuleDTO) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidAlertRule, fmt.Sprintf(format, args...))
+	}
+	if strings.TrimSpace(dto.Name) == "" {
+		return invalid("name is required")
+	}
+	switch dto.Severity {
+	case "":
+		dto.Severity = models.SeverityWarning
+	case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+	default:
+		return invalid("unknown severity: %s", dto.Severity)
+	}
+
+	switch dto.Kind {
+	case models.AlertThreshold:
+		known := false
+		for _, prefix := range alertSeriesPrefixes {
+			if strings.HasPrefix(dto.Series, prefix) && len(dto.Series) > len(prefix) {
+				known = true
+			}
+		}
+		if !known {
+			return invalid("series must name a counter series such as rule/<id> or iface/<name>/drop")
+		}
+		switch dto.Metric {
+		case models.AlertPPS, models.AlertBPS, models.AlertPackets, models.AlertBytes:
+		default:
+			return invalid("metric must be pps, bps, packets or bytes")
+		}
+		switch dto.Operator {
+		case ">", ">=", "<", "<=":
+		default:
+			return invalid("operator must be >, >=, < or <=")
+		}
+		if dto.WindowSeconds == 0 {
+			dto.WindowSeconds = defaultAlertWindow
+		}
+		if dto.WindowSeconds < minAlertWindow || dto.WindowSeconds > maxAlertWindow {
+			return invalid("windowSeconds must be between %d and %d", minAlertWindow, maxAlertWindow)
+		}
+		if dto.ForSeconds < 0 {
+			return invalid("forSeconds must not be negative")
+		}
+		dto.EventType, dto.ResourceID, dto.ResolveEvent, dto.ResolveAfterSeconds = "", "", "", 0
+	case models.AlertEvent:
+		if dto.EventType == "" {
+			return invalid("eventType is required")
+		}
+		// Alerts publish alert.* events themselves.
+		for _, t := range []string{dto.EventType, dto.ResolveEvent} {
+			if t == "alert" || strings.HasPrefix(t, "alert.") {
+				return invalid("alert events cannot raise or resolve alerts")
+			}
+		}
+		if dto.ResolveAfterSeconds < 0 {
+			return invalid("resolveAfterSeconds must not be negative")
+		}
+		if dto.ResolveEvent == "" && dto.ResolveAfterSeconds == 0 {
+			dto.ResolveAfterSeconds = defaultAlertResolveAfter
+		}
+		dto.Series, dto.Metric, dto.Operator, dto.Value, dto.WindowSeconds, dto.ForSeconds = "", "", "", 0, 0, 0
+	default:
+		return invalid("kind must be threshold or event")
+	}
+
+	rule.Name = strings.TrimSpace(dto.Name)
+	rule.Description = dto.Description
+	rule.Kind = dto.Kind
+	rule.Severity = dto.Severity
+	rule.Enabled = dto.Enabled == nil || *dto.Enabled
+	rule.Series = dto.Series
+	rule.Metric = dto.Metric
+	rule.Operator = dto.Operator
+	rule.Value = dto.Value
+	rule.WindowSeconds = dto.WindowSeconds
+	rule.ForSeconds = dto.ForSeconds
+	rule.EventType = dto.EventType
+	rule.ResourceID = dto.ResourceID
+	rule.ResolveEvent = dto.ResolveEvent
+	rule.ResolveAfterSeconds = dto.ResolveAfterSeconds
+	return nil
+}
+
+func (s *alertService) ListAlerts(_ context.Context, filter models.AlertFilter) ([]*models.Alert, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAlertLimit
+	}
+	if filter.Limit > maxAlertLimit {
+		filter.Limit = maxAlertLimit
+	}
+	alerts, err := s.repo.ListAlerts(filter)
+	if alerts == nil && err == nil {
+		alerts = []*models.Alert{}
+	}
+	return alerts, err
+}
+
+func (s *alertService) ListSilences(_ context.Context) ([]*models.AlertSilence, error) {
+	return s.repo.ListSilences()
+}
+
+func (s *alertService) CreateSilence(ctx context.Context, dto CreateAlertSilenceDTO) (*models.AlertSilence, error) {
+	now := time.Now().UTC()
+	silence := &models.AlertSilence{
+		ID:        uuid.New().String(),
+		RuleID:    dto.RuleID,
+		StartsAt:  now,
+		EndsAt:    dto.EndsAt.UTC(),
+		Comment:   dto.Comment,
+		CreatedAt: now,
+	}
+	if dto.StartsAt != nil {
+		silence.StartsAt = dto.StartsAt.UTC()
+	}
+	if !silence.EndsAt.After(silence.StartsAt) || !silence.EndsAt.After(now) {
+		return nil, fmt.Errorf("%w: endsAt must be after startsAt and in the future", ErrInvalidAlertRule)
+	}
+	if err := s.authorizeSilence(ctx, silence.RuleID); err != nil {
+		return nil, err
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		silence.CreatedBy = p.Name
+	}
+	if err := s.repo.CreateSilence(silence); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "alert-silence.create", "alert-silence", silence.ID, nil, silence)
+	s.wake()
+	return silence, nil
+}
+
+func (s *alertService) DeleteSilence(ctx context.Context, id string) error {
+	silences, err := s.repo.ListSilences()
+	if err != nil {
+		return err
+	}
+	var silence *models.AlertSilence
+	for _, candidate := range silences {
+		if candidate.ID == id {
+			silence = candidate
+			break
+		}
+	}
+	if silence == nil {
+		return fmt.Errorf("alert silence not found: %s", id)
+	}
+	if err := s.authorizeSilence(ctx, silence.RuleID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteSilence(id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "alert-silence.delete", "alert-silence", id, nil, nil)
+	s.wake()
+	return nil
+}
+
+// authorizeRule checks that the caller may edit alerts on what rule
+// watches. A rule on the counters or events of one filter rule, NAT rule or
+// interface is scoped like that object. Anything else, such as policies,
+// hosts or every event of a type, spans all zones and needs a global
+// binding.
+func (s *alertService) authorizeRule(ctx context.Context, rule *models.AlertRule) error {
+	var kind, id string
+	switch rule.Kind {
+	case models.AlertThreshold:
+		kind, id, _ = strings.Cut(rule.Series, "/")
+		if kind == "iface" {
+			id, _, _ = strings.Cut(id, "/")
+		}
+	case models.AlertEvent:
+		if rule.ResourceID != "" {
+			kind, _, _ = strings.Cut(rule.EventType, ".")
+			id = rule.ResourceID
+		}
+	}
+
+	switch kind {
+	case "rule":
+		if r, err := s.filters.GetByID(id); err == nil {
+			return authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, r.Tags, r.InInterface, r.OutInterface)
+		}
+	case "nat", "nat-rule":
+		if r, err := s.natRules.Get(id); err == nil {
+			return authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, r.Tags, r.InInterface, r.OutInterface)
+		}
+	case "iface":
+		return authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, nil, id)
+	case "interface":
+		if iface, err := s.ifaces.Get(id); err == nil {
+			return authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, nil, iface.Name)
+		}
+	}
+	return auth.Authorize(ctx, auth.PermEdit, "", nil)
+}
+
+// authorizeSilence checks that the caller may silence the alert rule, or
+// all alerts if ruleID is empty.
+func (s *alertService) authorizeSilence(ctx context.Context, ruleID string) error {
+	if ruleID == "" {
+		return auth.Authorize(ctx, auth.PermEdit, "", nil)
+	}
+	rule, err := s.repo.GetRule(ruleID)
+	if err != nil {
+		return err
+	}
+	return s.authorizeRule(ctx, rule)
+}
+
+func (s *alertService) wake() {
+	select {
+	case s.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Run evaluates the threshold rules every interval and the event rules on
+// every event until ctx is cancelled. A zero interval disables the engine.
+// Alerts that were active at the last shutdown carry on.
+func (s *alertService) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	s.active = make(map[string]*models.Alert)
+	active, err := s.repo.ListActiveAlerts()
+	if err != nil {
+		s.log.WithError(err).Error("failed to load active alerts")
+	}
+	for _, a := range active {
+		s.active[a.RuleID] = a
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var lastEvent uint64
+	subscribe := func() (<-chan *models.Event, context.CancelFunc) {
+		subCtx, cancel := context.WithCancel(ctx)
+		return s.events.Subscribe(subCtx, models.EventFilter{}, lastEvent), cancel
+	}
+	events, unsubscribe := subscribe()
+	defer func() { unsubscribe() }()
+
+	s.evaluate(ctx, time.Now().UTC())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// Fell behind; pick up from the backlog.
+				unsubscribe()
+				events, unsubscribe = subscribe()
+				continue
+			}
+			lastEvent = e.ID
+			s.handleEvent(ctx, e)
+		case <-s.reload:
+			s.evaluate(ctx, time.Now().UTC())
+		case <-ticker.C:
+			s.evaluate(ctx, time.Now().UTC())
+		}
+	}
+}
+
+// evaluate reloads the rules and silences, resolves the alerts of rules
+// that were deleted or disabled, and checks the threshold rules and the
+// quiet period of event rules.
+func (s *alertService) evaluate(ctx context.Context, now time.Time) {
+	rules, err := s.repo.ListRules()
+	if err != nil {
+		s.log.WithError(err).Error("failed to load alert rules")
+		return
+	}
+	silences, err := s.repo.ListSilences()
+	if err != nil {
+		s.log.WithError(err).Error("failed to load alert silences")
+		return
+	}
+	s.rules, s.silences = rules, silences
+
+	byID := make(map[string]*models.AlertRule, len(rules))
+	for _, r := range rules {
+		byID[r.ID] = r
+	}
+	for ruleID, a := range s.active {
+		if r := byID[ruleID]; r == nil || !r.Enabled {
+			a.Message = "rule deleted or disabled"
+			s.transition(ctx, a, models.AlertResolved, now)
+		}
+	}
+
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		a := s.active[r.ID]
+		if a != nil {
+			s.updateSilenced(ctx, a, now)
+		}
+		switch r.Kind {
+		case models.AlertThreshold:
+			s.evaluateThreshold(ctx, r, a, now)
+		case models.AlertEvent:
+			if a != nil && r.ResolveAfterSeconds > 0 &&
+				now.Sub(a.LastSeenAt) >= time.Duration(r.ResolveAfterSeconds)*time.Second {
+				s.transition(ctx, a, models.AlertResolved, now)
+			}
+		}
+	}
+}
+
+func (s *alertService) evaluateThreshold(ctx context.Context, r *models.AlertRule, a *models.Alert, now time.Time) {
+	value, ok, err := s.measure(r, now)
+	if err != nil {
+		s.log.WithError(err).WithField("rule_id", r.ID).Error("alert rule evaluation failed")
+		return
+	}
+	if !ok {
+		// No samples in the window: leave the alert as it is.
+		return
+	}
+	holds := compareAlert(value, r.Operator, r.Value)
+	message := fmt.Sprintf("%s of %s is %.4g (%s %g)", r.Metric, r.Series, value, r.Operator, r.Value)
+
+	switch {
+	case holds && a == nil:
+		a = s.newAlert(r, now)
+		a.Value, a.Message, a.LastSeenAt = value, message, now
+		if r.ForSeconds == 0 {
+			s.transition(ctx, a, models.AlertFiring, now)
+		} else {
+			s.transition(ctx, a, models.AlertPending, now)
+		}
+	case holds:
+		a.Value, a.Message, a.LastSeenAt = value, message, now
+		if a.State == models.AlertPending && now.Sub(a.StartedAt) >= time.Duration(r.ForSeconds)*time.Second {
+			s.transition(ctx, a, models.AlertFiring, now)
+		} else {
+			s.save(a)
+		}
+	case a != nil:
+		a.Value, a.Message = value, message
+		s.transition(ctx, a, models.AlertResolved, now)
+	}
+}
+
+// measure returns the rule's metric over its window, or false if the
+// window holds no samples. Rates are taken over the time the samples
+// cover, so a series that only started recording is not diluted.
+func (s *alertService) measure(r *models.AlertRule, now time.Time) (float64, bool, error) {
+	window := time.Duration(r.WindowSeconds) * time.Second
+	samples, err := s.samples.Range([]string{r.Series}, now.Add(-window), now)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(samples) == 0 {
+		return 0, false, nil
+	}
+	var packets, bytes uint64
+	var covered int64
+	for _, sample := range samples {
+		packets += sample.Packets
+		bytes += sample.Bytes
+		covered += sample.Span
+	}
+	if covered > int64(r.WindowSeconds) {
+		covered = int64(r.WindowSeconds)
+	}
+	if covered <= 0 {
+		covered = 1
+	}
+	switch r.Metric {
+	case models.AlertPPS:
+		return float64(packets) / float64(covered), true, nil
+	case models.AlertBPS:
+		return float64(bytes) * 8 / float64(covered), true, nil
+	case models.AlertPackets:
+		return float64(packets), true, nil
+	}
+	return float64(bytes), true, nil
+}
+
+func compareAlert(value float64, op string, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+// handleEvent fires or resolves the alerts of the event rules e matches.
+func (s *alertService) handleEvent(ctx context.Context, e *models.Event) {
+	if strings.HasPrefix(e.Type, "alert.") || e.Type == "counters.sample" {
+		return
+	}
+	now := e.Time
+	for _, r := range s.rules {
+		if !r.Enabled || r.Kind != models.AlertEvent {
+			continue
+		}
+		a := s.active[r.ID]
+		if r.ResolveEvent != "" && (models.EventFilter{Types: []string{r.ResolveEvent}, ResourceID: r.ResourceID}).Matches(e) {
+			if a != nil {
+				a.Message = "resolved by " + e.Type
+				s.transition(ctx, a, models.AlertResolved, now)
+			}
+			continue
+		}
+		if !(models.EventFilter{Types: []string{r.EventType}, ResourceID: r.ResourceID}).Matches(e) {
+			continue
+		}
+		message := e.Type
+		if e.ResourceID != "" {
+			message += " " + e.ResourceID
+		}
+		if e.Actor != "" {
+			message += " by " + e.Actor
+		}
+		if a == nil {
+			a = s.newAlert(r, now)
+			a.Count, a.Message, a.LastSeenAt = 1, message, now
+			s.transition(ctx, a, models.AlertFiring, now)
+			continue
+		}
+		a.Count++
+		a.Message, a.LastSeenAt = message, now
+		s.save(a)
+	}
+}
+
+func (s *alertService) newAlert(r *models.AlertRule, now time.Time) *models.Alert {
+	return &models.Alert{
+		ID:        uuid.New().String(),
+		RuleID:    r.ID,
+		RuleName:  r.Name,
+		Severity:  r.Severity,
+		StartedAt: now,
+		Silenced:  s.silenced(r.ID, now),
+	}
+}
+
+func (s *alertService) silenced(ruleID string, now time.Time) bool {
+	for _, silence := range s.silences {
+		if silence.Covers(ruleID, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSilenced follows the alert's silence. A firing alert whose silence
+// ended is published as firing then.
+func (s *alertService) updateSilenced(ctx context.Context, a *models.Alert, now time.Time) {
+	silenced := s.silenced(a.RuleID, now)
+	if silenced == a.Silenced {
+		return
+	}
+	a.Silenced = silenced
+	s.save(a)
+	if !silenced && a.State == models.AlertFiring {
+		s.publish(ctx, a)
+	}
+}
+
+// transition moves the alert to state, stores it and publishes the change
+// unless the alert is silenced. A new alert is created in the store.
+func (s *alertService) transition(ctx context.Context, a *models.Alert, state models.AlertState, now time.Time) {
+	created := a.State == ""
+	fired := a.FiredAt != nil
+	a.State = state
+	switch state {
+	case models.AlertFiring:
+		a.FiredAt = &now
+		s.active[a.RuleID] = a
+	case models.AlertPending:
+		s.active[a.RuleID] = a
+	case models.AlertResolved:
+		a.ResolvedAt = &now
+		delete(s.active, a.RuleID)
+	}
+
+	var err error
+	if created {
+		err = s.repo.CreateAlert(a)
+	} else {
+		err = s.repo.UpdateAlert(a)
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("rule_id", a.RuleID).Error("failed to store alert")
+	}
+
+	entry := s.log.WithFields(logrus.Fields{"rule": a.RuleName, "alert_id": a.ID, "silenced": a.Silenced})
+	switch {
+	case state == models.AlertFiring:
+		entry.WithField("message", a.Message).Warn("alert firing")
+	case state == models.AlertResolved && fired:
+		entry.Info("alert resolved")
+	}
+	// Pending alerts and those that resolve without firing are only
+	// recorded.
+	if a.Silenced || state == models.AlertPending || (state == models.AlertResolved && !fired) {
+		return
+	}
+	s.publish(ctx, a)
+}
+
+// publish sends a copy of the alert as an "alert.<state>" event, since the
+// engine keeps changing the active alert while subscribers encode it.
+func (s *alertService) publish(ctx context.Context, a *models.Alert) {
+	snapshot := *a
+	s.events.Publish(ctx, "alert."+string(a.State), a.ID, &snapshot)
+}
+
+func (s *alertService) save(a *models.Alert) {
+	if err := s.repo.UpdateAlert(a); err != nil {
+		s.log.WithError(err).WithField("rule_id", a.RuleID).Error("failed to store alert")
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeAlertRepo struct {
+	rules    []*models.AlertRule
+	alerts   []*models.Alert
+	silences []*models.AlertSilence
+}
+
+func (r *fakeAlertRepo) ListRules() ([]*models.AlertRule, error) { return r.rules, nil }
+func (r *fakeAlertRepo) GetRule(id string) (*models.AlertRule, error) {
+	for _, rule := range r.rules {
+		if rule.ID == id {
+			cp := *rule
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("alert rule not found: " + id)
+}
+func (r *fakeAlertRepo) CreateRule(rule *models.AlertRule) error {
+	r.rules = append(r.rules, rule)
+	return nil
+}
+func (r *fakeAlertRepo) UpdateRule(rule *models.AlertRule) error { return nil }
+func (r *fakeAlertRepo) DeleteRule(id string) error              { return nil }
+func (r *fakeAlertRepo) ListAlerts(filter models.AlertFilter) ([]*models.Alert, error) {
+	return r.alerts, nil
+}
+func (r *fakeAlertRepo) ListActiveAlerts() ([]*models.Alert, error) { return nil, nil }
+func (r *fakeAlertRepo) CreateAlert(alert *models.Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+func (r *fakeAlertRepo) UpdateAlert(alert *models.Alert) error { return nil }
+func (r *fakeAlertRepo) ListSilences() ([]*models.AlertSilence, error) {
+	return r.silences, nil
+}
+func (r *fakeAlertRepo) CreateSilence(silence *models.AlertSilence) error {
+	r.silences = append(r.silences, silence)
+	return nil
+}
+func (r *fakeAlertRepo) DeleteSilence(id string) error { return nil }
+
+func newTestAlertService(repo *fakeAlertRepo, rules ...*models.Rule) *alertService {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	ifaces := &fakeInterfaceRepo{ifaces: []*models.NetworkInterface{
+		{ID: "i0", Name: "eth0", Zone: "lan"},
+		{ID: "i1", Name: "eth1", Zone: "dmz"},
+	}}
+	return NewAlertService(repo, nil, &fakeRuleRepo{rules: rules}, nil, ifaces, NewEventBus(log), nopAuditor{}, time.Minute, log).(*alertService)
+}
+
+func TestAlertEditsScopedByZone(t *testing.T) {
+	repo := &fakeAlertRepo{
+		rules: []*models.AlertRule{
+			{ID: "a-dmz", Kind: models.AlertThreshold, Series: "rule/dmz"},
+			{ID: "a-lan", Kind: models.AlertThreshold, Series: "rule/lan"},
+		},
+		silences: []*models.AlertSilence{{ID: "all"}, {ID: "dmz", RuleID: "a-dmz"}},
+	}
+	s := newTestAlertService(repo,
+		&models.Rule{ID: "dmz", Chain: models.ChainINPUT, InInterface: "eth1", Action: models.ActionACCEPT, Enabled: true},
+		&models.Rule{ID: "lan", Chain: models.ChainINPUT, InInterface: "eth0", Action: models.ActionACCEPT, Enabled: true},
+	)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:     "dmz-editor",
+		Bindings: []models.RoleBinding{{Role: models.RoleRuleEditor, Zone: "dmz"}},
+	})
+
+	threshold := func(series string) AlertRuleDTO {
+		return AlertRuleDTO{Name: series, Kind: models.AlertThreshold, Series: series, Metric: models.AlertPPS, Operator: ">", Value: 100}
+	}
+	event := func(eventType, resourceID string) AlertRuleDTO {
+		return AlertRuleDTO{Name: eventType, Kind: models.AlertEvent, EventType: eventType, ResourceID: resourceID}
+	}
+	tests := []struct {
+		name string
+		dto  AlertRuleDTO
+		ok   bool
+	}{
+		{"rule in own zone", threshold("rule/dmz"), true},
+		{"rule in another zone", threshold("rule/lan"), false},
+		{"interface in own zone", threshold("iface/eth1/drop"), true},
+		{"interface in another zone", threshold("iface/eth0/rx"), false},
+		{"unmanaged interface", threshold("iface/tun0/rx"), false},
+		{"chain policy", threshold("policy/filter/INPUT"), false},
+		{"host", threshold("host/192.0.2.10/rx"), false},
+		{"events of a rule in own zone", event("rule", "dmz"), true},
+		{"events of an interface in own zone", event("interface.update", "i1"), true},
+		{"events of a rule in another zone", event("rule.update", "lan"), false},
+		{"every apply failure", event("apply.failed", ""), false},
+		{"every rule change", event("rule", ""), false},
+	}
+	for _, tt := range tests {
+		_, err := s.CreateRule(ctx, tt.dto)
+		if tt.ok && err != nil {
+			t.Errorf("create %s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, auth.ErrForbidden) {
+			t.Errorf("create %s: %v, want forbidden", tt.name, err)
+		}
+	}
+
+	if _, err := s.UpdateRule(ctx, "a-dmz", threshold("rule/lan")); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("update pointing a rule at another zone: %v, want forbidden", err)
+	}
+	if _, err := s.UpdateRule(ctx, "a-lan", threshold("rule/dmz")); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("update taking over another zone's rule: %v, want forbidden", err)
+	}
+	if err := s.DeleteRule(ctx, "a-lan"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("delete another zone's rule: %v, want forbidden", err)
+	}
+	if err := s.DeleteRule(ctx, "a-dmz"); err != nil {
+		t.Errorf("delete own rule: %v", err)
+	}
+
+	silence := func(ruleID string) error {
+		_, err := s.CreateSilence(ctx, CreateAlertSilenceDTO{RuleID: ruleID, EndsAt: time.Now().Add(time.Hour)})
+		return err
+	}
+	if err := silence("a-dmz"); err != nil {
+		t.Errorf("silence own rule: %v", err)
+	}
+	if err := silence("a-lan"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("silence another zone's rule: %v, want forbidden", err)
+	}
+	if err := silence(""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("silence every alert: %v, want forbidden", err)
+	}
+	if err := s.DeleteSilence(ctx, "all"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("delete a global silence: %v, want forbidden", err)
+	}
+	if err := s.DeleteSilence(ctx, "dmz"); err != nil {
+		t.Errorf("delete own silence: %v", err)
+	}
+
+	// A global editor may do all of it.
+	global := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:     "editor",
+		Bindings: []models.RoleBinding{{Role: models.RoleRuleEditor}},
+	})
+	if _, err := s.CreateRule(global, threshold("policy/filter/INPUT")); err != nil {
+		t.Errorf("global create: %v", err)
+	}
+	if _, err := s.CreateSilence(global, CreateAlertSilenceDTO{EndsAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Errorf("global silence: %v", err)
+	}
+}
+
+func TestFailedApplyFiresEventAlert(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fw, driver, rules, _ := newTestFirewallService(&models.Rule{ID: "a", Chain: models.ChainINPUT, Action: models.ActionACCEPT, Enabled: true})
+	bus := NewEventBus(fw.log)
+	fw.audit = NewPublishingAuditor(nopAuditor{}, bus)
+	events := bus.Subscribe(ctx, models.EventFilter{}, 0)
+
+	repo := &fakeAlertRepo{rules: []*models.AlertRule{
+		{ID: "apply-failed", Name: "apply failed", Kind: models.AlertEvent, EventType: "apply.failed", Enabled: true},
+		{ID: "rollback-failed", Name: "rollback failed", Kind: models.AlertEvent, EventType: "rollback.failed", Enabled: true},
+	}}
+	alerts := newTestAlertService(repo)
+	alerts.active = make(map[string]*models.Alert)
+	alerts.evaluate(ctx, time.Now().UTC())
+
+	apply := func() *models.Event {
+		t.Helper()
+		fw.ApplyRules(ctx)
+		select {
+		case e := <-events:
+			alerts.handleEvent(ctx, e)
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no event published")
+			return nil
+		}
+	}
+
+	if e := apply(); e.Type != "apply" || len(repo.alerts) != 0 {
+		t.Fatalf("successful apply published %s and raised %d alerts", e.Type, len(repo.alerts))
+	}
+
+	// A changed ruleset, so the apply reaches the kernel.
+	rules.rules = append(rules.rules, &models.Rule{ID: "b", Chain: models.ChainINPUT, Action: models.ActionDROP, Enabled: true})
+	driver.applyErr = errors.New("iptables-restore: line 4 failed")
+	if e := apply(); e.Type != "apply.failed" {
+		t.Fatalf("failed apply published %s", e.Type)
+	}
+	if len(repo.alerts) != 1 {
+		t.Fatalf("failed apply raised %d alerts, want 1", len(repo.alerts))
+	}
+	if a := repo.alerts[0]; a.RuleID != "apply-failed" || a.State != models.AlertFiring {
+		t.Errorf("alert %s is %s", a.RuleID, a.State)
+	}
+}
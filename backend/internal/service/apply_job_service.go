@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxApplyJobs is how many jobs are kept for status lookups; the oldest
+	// finished jobs are dropped first.
+	maxApplyJobs = 200
+	// maxQueuedApplyJobs bounds the queue so a stuck kernel call cannot
+	// pile up requests without limit.
+	maxQueuedApplyJobs = 50
+)
+
+var (
+	// ErrJobNotFound is returned for unknown or expired job IDs.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobQueueFull is returned when too many jobs are waiting.
+	ErrJobQueueFull = errors.New("too many apply jobs queued")
+)
+
+// ApplyJobService runs API applies and rollbacks as queued jobs on a single
+// worker, so they neither race each other nor depend on the HTTP request
+// staying open. Jobs are kept in memory.
+type ApplyJobService interface {
+	SubmitApply(ctx context.Context) (*models.ApplyJob, error)
+	SubmitRollback(ctx context.Context, historyID string) (*models.ApplyJob, error)
+	ListJobs(ctx context.Context) []*models.ApplyJob
+	GetJob(ctx context.Context, id string) (*models.ApplyJob, error)
+	// Watch returns a channel that receives the job whenever it changes and
+	// is closed once the job has finished or ctx is done.
+	Watch(ctx context.Context, id string) (<-chan *models.ApplyJob, error)
+	Run(ctx context.Context)
+}
+
+type applyJob struct {
+	job      models.ApplyJob
+	ctx      context.Context // request context without its cancellation
+	watchers []chan *models.ApplyJob
+}
+
+type applyJobService struct {
+	fw     FirewallService
+	events EventPublisher
+	log    *logrus.Logger
+
+	mu    sync.Mutex
+	jobs  map[string]*applyJob
+	order []string // job IDs, oldest first
+	queue []*applyJob
+	wake  chan struct{}
+}
+
+func NewApplyJobService(fw FirewallService, events EventPublisher, log *logrus.Logger) ApplyJobService {
+	return &applyJobService{
+		fw:     fw,
+		events: events,
+		log:    log,
+		jobs:   make(map[string]*applyJob),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+func (s *applyJobService) SubmitApply(ctx context.Context) (*models.ApplyJob, error) {
+	return s.submit(ctx, models.ApplyJobApply, "")
+}
+
+func (s *applyJobService) SubmitRollback(ctx context.Context, historyID string) (*models.ApplyJob, error) {
+	return s.submit(ctx, models.ApplyJobRollback, historyID)
+}
+
+func (s *applyJobService) submit(ctx context.Context, action models.ApplyJobAction, historyID string) (*models.ApplyJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) >= maxQueuedApplyJobs {
+		return nil, ErrJobQueueFull
+	}
+
+	j := &applyJob{
+		job: models.ApplyJob{
+			ID:          uuid.New().String(),
+			Action:      action,
+			HistoryID:   historyID,
+			Status:      models.ApplyJobQueued,
+			RequestedBy: "system",
+			QueuedAt:    time.Now().UTC(),
+		},
+		// The job outlives the request but keeps its principal and
+		// connection for auditing and the lockout check.
+		ctx: context.WithoutCancel(ctx),
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		j.job.RequestedBy = p.Name
+	}
+	s.jobs[j.job.ID] = j
+	s.order = append(s.order, j.job.ID)
+	s.queue = append(s.queue, j)
+	s.prune()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	s.log.WithFields(logrus.Fields{"job_id": j.job.ID, "action": action}).Info("apply job queued")
+	snap := snapshotJob(&j.job)
+	s.events.Publish(j.ctx, "job."+string(snap.Status), snap.ID, snap)
+	return snap, nil
+}
+
+func (s *applyJobService) ListJobs(_ context.Context) []*models.ApplyJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*models.ApplyJob, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		result = append(result, snapshotJob(&s.jobs[s.order[i]].job))
+	}
+	return result
+}
+
+func (s *applyJobService) GetJob(_ context.Context, id string) (*models.ApplyJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return snapshotJob(&j.job), nil
+}
+
+func (s *applyJobService) Watch(ctx context.Context, id string) (<-chan *models.ApplyJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	ch := make(chan *models.ApplyJob, 1)
+	ch <- snapshotJob(&j.job)
+	if j.job.Finished() {
+		close(ch)
+		return ch, nil
+	}
+	j.watchers = append(j.watchers, ch)
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, w := range j.watchers {
+			if w == ch {
+				j.watchers = append(j.watchers[:i], j.watchers[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Run executes queued jobs one at a time until ctx is cancelled.
+func (s *applyJobService) Run(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		var j *applyJob
+		if len(s.queue) > 0 {
+			j = s.queue[0]
+			s.queue = s.queue[1:]
+		}
+		s.mu.Unlock()
+
+		if j == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			}
+			continue
+		}
+		s.execute(j)
+	}
+}
+
+func (s *applyJobService) execute(j *applyJob) {
+	s.update(j, func(job *models.ApplyJob) {
+		now := time.Now().UTC()
+		job.Status = models.ApplyJobRunning
+		job.StartedAt = &now
+	})
+	s.step(j, fmt.Sprintf("started after %s in queue", time.Since(j.job.QueuedAt).Round(time.Millisecond)))
+
+	ctx := withStepLogger(j.ctx, func(msg string) { s.step(j, msg) })
+	var err error
+	switch j.job.Action {
+	case models.ApplyJobApply:
+		err = s.fw.ApplyRules(ctx)
+	case models.ApplyJobRollback:
+		err = s.fw.RollbackTo(ctx, j.job.HistoryID)
+	default:
+		err = fmt.Errorf("unknown job action: %s", j.job.Action)
+	}
+
+	s.update(j, func(job *models.ApplyJob) {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		job.DurationMs = now.Sub(*job.StartedAt).Milliseconds()
+		if err != nil {
+			job.Status = models.ApplyJobFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = models.ApplyJobSucceeded
+		}
+	})
+
+	entry := s.log.WithFields(logrus.Fields{"job_id": j.job.ID, "action": j.job.Action})
+	if err != nil {
+		entry.WithError(err).Error("apply job failed")
+	} else {
+		entry.Info("apply job succeeded")
+	}
+}
+
+// step appends a progress line to a running job.
+func (s *applyJobService) step(j *applyJob, msg string) {
+	s.update(j, func(job *models.ApplyJob) {
+		job.Steps = append(job.Steps, models.ApplyJobStep{At: time.Now().UTC(), Message: msg})
+	})
+}
+
+// update changes a job under the lock and notifies its watchers, closing
+// their channels once the job has finished. Status changes are published.
+func (s *applyJobService) update(j *applyJob, fn func(job *models.ApplyJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := j.job.Status
+	fn(&j.job)
+	snap := snapshotJob(&j.job)
+	if snap.Status != status {
+		s.events.Publish(j.ctx, "job."+string(snap.Status), snap.ID, snap)
+	}
+	for _, w := range j.watchers {
+		// Watchers only need the latest state; replace an unread one.
+		select {
+		case <-w:
+		default:
+		}
+		w <- snap
+		if j.job.Finished() {
+			close(w)
+		}
+	}
+	if j.job.Finished() {
+		j.watchers = nil
+	}
+}
+
+// prune drops the oldest finished jobs beyond maxApplyJobs. Callers hold mu.
+func (s *applyJobService) prune() {
+	excess := len(s.order) - maxApplyJobs
+	if excess <= 0 {
+		return
+	}
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if excess > 0 && s.jobs[id].job.Finished() {
+			delete(s.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func snapshotJob(job *models.ApplyJob) *models.ApplyJob {
+	cp := *job
+	cp.Steps = append([]models.ApplyJobStep{}, job.Steps...)
+	return &cp
+}
+
+type stepLoggerKey struct{}
+
+// withStepLogger attaches a progress callback that long-running service
+// calls report their steps to.
+func withStepLogger(ctx context.Context, fn func(string)) context.Context {
+	return context.WithValue(ctx, stepLoggerKey{}, fn)
+}
+
+// logStep reports a progress step if ctx carries a step logger.
+func logStep(ctx context.Context, format string, args ...any) {
+	if fn, ok := ctx.Value(stepLoggerKey{}).(func(string)); ok {
+		fn(fmt.Sprintf(format, args...))
+	}
+}
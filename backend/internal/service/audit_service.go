@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// genesisHash is the PrevHash of the first audit entry.
+var genesisHash = strings.Repeat("0", 64)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	auditVerifyBatch  = 500
+)
+
+// Auditor records changes. Services call it after a mutation succeeds (or an
+// apply fails); recording errors are logged and never undo the change.
+type Auditor interface {
+	Record(ctx context.Context, action, resource, resourceID string, before, after any)
+}
+
+// nopAuditor discards records; used where no audit log is configured.
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, string, string, string, any, any) {}
+
+// AuditVerification is the result of walking the hash chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	HeadSeq  int64  `json:"headSeq"`
+	HeadHash string `json:"headHash"` // record externally to detect truncation
+	BrokenAt int64  `json:"brokenAt,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// AuditService appends to and queries the tamper-evident audit log
+type AuditService interface {
+	Auditor
+	Query(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error)
+	Verify(ctx context.Context) (*AuditVerification, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+	log  *logrus.Logger
+
+	// mu serializes appends so every entry links to its predecessor.
+	mu sync.Mutex
+}
+
+func NewAuditService(repo repository.AuditRepository, log *logrus.Logger) AuditService {
+	return &auditService{repo: repo, log: log}
+}
+
+func (s *auditService) Record(ctx context.Context, action, resource, resourceID string, before, after any) {
+	entry := &models.AuditEntry{
+		// Truncated so the value survives the SQLite round trip unchanged.
+		Timestamp:  time.Now().UTC().Truncate(time.Microsecond),
+		Actor:      "system",
+		ActorKind:  "system",
+		SourceIP:   auth.SourceIP(ctx),
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		entry.Actor = p.Name
+		entry.ActorKind = p.Kind
+	}
+
+	var err error
+	if entry.Before, err = marshalAuditValue(before); err == nil {
+		entry.After, err = marshalAuditValue(after)
+	}
+	if err == nil {
+		err = s.append(entry)
+	}
+	if err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{
+			"action":      action,
+			"resource":    resource,
+			"resource_id": resourceID,
+		}).Error("could not write audit entry")
+	}
+}
+
+func (s *auditService) append(entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, err := s.repo.Last()
+	if err != nil {
+		return err
+	}
+	entry.Seq, entry.PrevHash = 1, genesisHash
+	if last != nil {
+		entry.Seq, entry.PrevHash = last.Seq+1, last.Hash
+	}
+	entry.Hash = auditHash(entry)
+	return s.repo.Append(entry)
+}
+
+func (s *auditService) Query(_ context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	entries, err := s.repo.Query(filter)
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+	return entries, err
+}
+
+// Verify recomputes every hash and checks that sequence numbers are
+// contiguous and each entry links to its predecessor.
+func (s *auditService) Verify(_ context.Context) (*AuditVerification, error) {
+	v := &AuditVerification{Valid: true, HeadHash: genesisHash}
+	for {
+		batch, err := s.repo.Range(v.HeadSeq, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range batch {
+			switch {
+			case e.Seq != v.HeadSeq+1:
+				v.Problem = fmt.Sprintf("entries %d to %d are missing", v.HeadSeq+1, e.Seq-1)
+			case e.PrevHash != v.HeadHash:
+				v.Problem = "entry does not link to its predecessor"
+			case auditHash(e) != e.Hash:
+				v.Problem = "entry content does not match its hash"
+			}
+			if v.Problem != "" {
+				v.Valid = false
+				v.BrokenAt = e.Seq
+				return v, nil
+			}
+			v.Entries++
+			v.HeadSeq, v.HeadHash = e.Seq, e.Hash
+		}
+		if len(batch) < auditVerifyBatch {
+			return v, nil
+		}
+	}
+}
+
+// auditHash returns the chain hash of e: SHA-256 over a fixed JSON encoding
+// of every field except Hash itself.
+func auditHash(e *models.AuditEntry) string {
+	data, _ := json.Marshal(struct {
+		Seq        int64  `json:"seq"`
+		Timestamp  string `json:"timestamp"`
+		Actor      string `json:"actor"`
+		ActorKind  string `json:"actorKind"`
+		SourceIP   string `json:"sourceIp"`
+		Action     string `json:"action"`
+		Resource   string `json:"resource"`
+		ResourceID string `json:"resourceId"`
+		Before     string `json:"before"`
+		After      string `json:"after"`
+		PrevHash   string `json:"prevHash"`
+	}{
+		e.Seq, e.Timestamp.UTC().Format(time.RFC3339Nano), e.Actor, e.ActorKind, e.SourceIP,
+		e.Action, e.Resource, e.ResourceID, string(e.Before), string(e.After), e.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func marshalAuditValue(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit value: %w", err)
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return data, nil
+}
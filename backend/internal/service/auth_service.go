@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/oidc"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/firewall-manager/backend/internal/tlsutil"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned for unknown, expired or revoked credentials.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// LoginDTO is the input for a password login
+type LoginDTO struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResult carries a new session token. The token is only returned once.
+type LoginResult struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	User      *models.User `json:"user"`
+}
+
+// AuthConfig configures the ways callers can authenticate
+type AuthConfig struct {
+	// APIKey keeps the legacy shared key working as a global admin
+	// credential. Empty disables it.
+	APIKey     string
+	SessionTTL time.Duration
+
+	// OIDC enables JWT bearer tokens and, if configured, the login flow.
+	OIDC          *oidc.Provider
+	UsernameClaim string // defaults to preferred_username
+	GroupsClaim   string // defaults to groups
+	GroupRoles    map[string][]models.RoleBinding
+
+	// CertRoles maps verified client certificate identities ("cn:name",
+	// "dns:host", ...) to roles.
+	CertRoles map[string][]models.RoleBinding
+}
+
+// AuthService logs users in and resolves bearer credentials to principals
+type AuthService interface {
+	Login(ctx context.Context, dto LoginDTO) (*LoginResult, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, credential string) (*auth.Principal, error)
+	AuthenticateCertificate(ctx context.Context, cert *x509.Certificate) (*auth.Principal, error)
+	ResolvePrincipal(ctx context.Context, p *auth.Principal) (*auth.Principal, error)
+	BeginOIDCLogin(ctx context.Context) (string, error)
+	CompleteOIDCLogin(ctx context.Context, state, code string) (*LoginResult, error)
+}
+
+type authService struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	tokens   repository.APITokenRepository
+	cfg      AuthConfig
+	log      *logrus.Logger
+
+	pendingMu sync.Mutex
+	pending   map[string]pendingLogin // by OIDC state
+}
+
+func NewAuthService(
+	users repository.UserRepository,
+	sessions repository.SessionRepository,
+	tokens repository.APITokenRepository,
+	cfg AuthConfig,
+	log *logrus.Logger,
+) AuthService {
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &authService{
+		users:    users,
+		sessions: sessions,
+		tokens:   tokens,
+		cfg:      cfg,
+		log:      log,
+		pending:  make(map[string]pendingLogin),
+	}
+}
+
+func (s *authService) Login(_ context.Context, dto LoginDTO) (*LoginResult, error) {
+	user, err := s.users.GetByUsername(dto.Username)
+	if err != nil {
+		// Spend the same time as a real check so usernames cannot be probed.
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(dto.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if user.PasswordHash == "" {
+		// OIDC users have no password.
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(dto.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(dto.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	result, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithField("username", user.Username).Info("user logged in")
+	return result, nil
+}
+
+// startSession creates a server-side session for user.
+func (s *authService) startSession(user *models.User) (*LoginResult, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:        uuid.New().String(),
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(s.cfg.SessionTTL),
+		CreatedAt: now,
+	}
+	if err := s.sessions.Create(session); err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	if err := s.sessions.DeleteExpired(now); err != nil {
+		s.log.WithError(err).Warn("could not purge expired sessions")
+	}
+	return &LoginResult{Token: token, ExpiresAt: session.ExpiresAt, User: user}, nil
+}
+
+func (s *authService) Logout(_ context.Context, token string) error {
+	session, err := s.sessions.GetByTokenHash(hashToken(token))
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+	return s.sessions.Delete(session.ID)
+}
+
+func (s *authService) Authenticate(ctx context.Context, credential string) (*auth.Principal, error) {
+	if s.cfg.APIKey != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(s.cfg.APIKey)) == 1 {
+		return apiKeyPrincipal(), nil
+	}
+
+	if isAPIToken(credential) {
+		return s.authenticateToken(credential)
+	}
+	if s.cfg.OIDC != nil && oidc.LooksLikeJWT(credential) {
+		return s.authenticateJWT(ctx, credential)
+	}
+
+	session, err := s.sessions.GetByTokenHash(hashToken(credential))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidCredentials
+	}
+	user, err := s.users.Get(session.UserID)
+	if err != nil || user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+	return userPrincipal(user), nil
+}
+
+// AuthenticateCertificate resolves a verified client certificate to the
+// roles its identities are mapped to.
+func (s *authService) AuthenticateCertificate(_ context.Context, cert *x509.Certificate) (*auth.Principal, error) {
+	ids := tlsutil.Identities(cert)
+	bindings := auth.MappedBindings(s.cfg.CertRoles, ids)
+	if len(bindings) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	return &auth.Principal{
+		ID:       ids[0],
+		Name:     cert.Subject.String(),
+		Kind:     "certificate",
+		Bindings: bindings,
+	}, nil
+}
+
+// authenticateToken resolves an API token to a principal carrying the
+// owner's current roles and the token's scopes.
+func (s *authService) authenticateToken(credential string) (*auth.Principal, error) {
+	token, err := s.tokens.GetByHash(hashToken(credential))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	p, err := s.tokenPrincipal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenTouchInterval {
+		if err := s.tokens.Touch(token.ID, now); err != nil {
+			s.log.WithError(err).Warn("could not record api token use")
+		}
+	}
+	return p, nil
+}
+
+// tokenPrincipal checks that token is still valid and builds its principal.
+func (s *authService) tokenPrincipal(token *models.APIToken) (*auth.Principal, error) {
+	if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidCredentials
+	}
+
+	bindings := []models.RoleBinding{{Role: models.RoleAdmin}}
+	if token.OwnerID != "" {
+		user, err := s.users.Get(token.OwnerID)
+		if err != nil || user.Disabled {
+			return nil, ErrInvalidCredentials
+		}
+		bindings = user.Roles
+	}
+
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &auth.Principal{
+		ID:       token.ID,
+		Name:     "token:" + token.Name,
+		Kind:     "token",
+		Bindings: bindings,
+		Scopes:   scopes,
+	}, nil
+}
+
+// ResolvePrincipal checks a principal recorded earlier, such as the creator
+// of a scheduled job, against the current accounts, tokens and role
+// mappings, and returns it with the roles it holds now.
+func (s *authService) ResolvePrincipal(_ context.Context, p *auth.Principal) (*auth.Principal, error) {
+	switch p.Kind {
+	case "user":
+		user, err := s.users.Get(p.ID)
+		if err != nil || user.Disabled {
+			return nil, ErrInvalidCredentials
+		}
+		return userPrincipal(user), nil
+	case "token":
+		token, err := s.tokens.Get(p.ID)
+		if err != nil {
+			return nil, ErrInvalidCredentials
+		}
+		return s.tokenPrincipal(token)
+	case "api-key":
+		if s.cfg.APIKey == "" {
+			return nil, ErrInvalidCredentials
+		}
+		return apiKeyPrincipal(), nil
+	case "certificate":
+		// Only the first identity of the certificate is recorded.
+		bindings := auth.MappedBindings(s.cfg.CertRoles, []string{p.ID})
+		if len(bindings) == 0 {
+			return nil, ErrInvalidCredentials
+		}
+		return &auth.Principal{ID: p.ID, Name: p.Name, Kind: p.Kind, Bindings: bindings}, nil
+	case "oidc":
+		// The roles came from the identity provider's token and cannot be
+		// checked again; a synced account that was disabled still counts.
+		if s.cfg.OIDC == nil {
+			return nil, ErrInvalidCredentials
+		}
+		if user, err := s.users.GetByOIDCSubject(p.ID); err == nil && user.Disabled {
+			return nil, ErrInvalidCredentials
+		}
+		return p, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+func apiKeyPrincipal() *auth.Principal {
+	return &auth.Principal{
+		ID:       "api-key",
+		Name:     "api-key",
+		Kind:     "api-key",
+		Bindings: []models.RoleBinding{{Role: models.RoleAdmin}},
+	}
+}
+
+func userPrincipal(user *models.User) *auth.Principal {
+	return &auth.Principal{
+		ID:       user.ID,
+		Name:     user.Username,
+		Kind:     "user",
+		Bindings: user.Roles,
+	}
+}
+
+// dummyPasswordHash is compared against when the username does not exist.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// newToken returns a random bearer token.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the form in which bearer tokens are stored. Tokens are
+// high-entropy, so a fast hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
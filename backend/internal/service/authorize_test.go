@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+type fakeInterfaceRepo struct{ ifaces []*models.NetworkInterface }
+
+func (r *fakeInterfaceRepo) List() ([]*models.NetworkInterface, error) { return r.ifaces, nil }
+func (r *fakeInterfaceRepo) Get(id string) (*models.NetworkInterface, error) {
+	for _, iface := range r.ifaces {
+		if iface.ID == id {
+			return iface, nil
+		}
+	}
+	return nil, errors.New("interface not found: " + id)
+}
+func (r *fakeInterfaceRepo) Create(iface *models.NetworkInterface) error { return nil }
+func (r *fakeInterfaceRepo) Update(iface *models.NetworkInterface) error { return nil }
+func (r *fakeInterfaceRepo) Delete(id string) error                      { return nil }
+
+func TestRuleEditsScopedByInterfaceZone(t *testing.T) {
+	s, _, _, _ := newTestFirewallService(
+		&models.Rule{ID: "dmz", Chain: models.ChainINPUT, InInterface: "eth1", Action: models.ActionACCEPT, Enabled: true},
+		&models.Rule{ID: "lan", Chain: models.ChainINPUT, InInterface: "eth0", Action: models.ActionACCEPT, Enabled: true},
+		&models.Rule{ID: "global", Chain: models.ChainINPUT, Action: models.ActionACCEPT, Enabled: true},
+	)
+	s.ifaces = &fakeInterfaceRepo{ifaces: []*models.NetworkInterface{
+		{ID: "i0", Name: "eth0", Zone: "lan"},
+		{ID: "i1", Name: "eth1", Zone: "dmz"},
+	}}
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:     "dmz-editor",
+		Bindings: []models.RoleBinding{{Role: models.RoleRuleEditor, Zone: "dmz"}},
+	})
+
+	create := func(in, out string) error {
+		_, err := s.CreateRule(ctx, CreateRuleDTO{Chain: models.ChainFORWARD, Protocol: models.ProtocolTCP, InInterface: in, OutInterface: out, Action: models.ActionACCEPT})
+		return err
+	}
+	if err := create("eth1", ""); err != nil {
+		t.Errorf("create in own zone: %v", err)
+	}
+	if err := create("eth1", "eth0"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("create reaching into another zone: %v, want forbidden", err)
+	}
+	if err := create("", ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("create without interface: %v, want forbidden", err)
+	}
+	if err := create("tun0", ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("create on unmanaged interface: %v, want forbidden", err)
+	}
+
+	if err := s.DeleteRule(ctx, "dmz"); err != nil {
+		t.Errorf("delete in own zone: %v", err)
+	}
+	for _, id := range []string{"lan", "global"} {
+		if err := s.DeleteRule(ctx, id); !errors.Is(err, auth.ErrForbidden) {
+			t.Errorf("delete %s: %v, want forbidden", id, err)
+		}
+	}
+
+	// Moving a rule out of the zone gives it away.
+	_, err := s.UpdateRule(ctx, "lan", UpdateRuleDTO{Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, InInterface: "eth1", Action: models.ActionACCEPT})
+	if !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("update taking over another zone's rule: %v, want forbidden", err)
+	}
+}
+
+func TestValidateRuleInterfaces(t *testing.T) {
+	tests := []struct {
+		chain   models.Chain
+		in, out string
+		ok      bool
+	}{
+		{models.ChainINPUT, "eth0", "", true},
+		{models.ChainINPUT, "", "eth0", false},
+		{models.ChainOUTPUT, "", "wg+", true},
+		{models.ChainOUTPUT, "eth0", "", false},
+		{models.ChainFORWARD, "eth0", "eth1", true},
+		{models.ChainFORWARD, "eth0;x", "", false},
+		{models.ChainFORWARD, "averyveryverylongname", "", false},
+	}
+	for _, tt := range tests {
+		err := validateRuleInterfaces(tt.chain, tt.in, tt.out)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateRuleInterfaces(%s, %q, %q) = %v", tt.chain, tt.in, tt.out, err)
+		}
+	}
+}
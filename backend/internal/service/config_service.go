@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
+	"github.com/firewall-manager/backend/internal/auth"
 	"github.com/firewall-manager/backend/internal/firewall"
 	"github.com/firewall-manager/backend/internal/models"
 	"github.com/firewall-manager/backend/internal/network"
@@ -28,13 +32,15 @@ type ConfigService interface {
 type configService struct {
 	configRepo repository.ConfigRepository
 	driver     firewall.FirewallDriver
+	audit      Auditor
 	log        *logrus.Logger
 }
 
-func NewConfigService(configRepo repository.ConfigRepository, driver firewall.FirewallDriver, log *logrus.Logger) ConfigService {
+func NewConfigService(configRepo repository.ConfigRepository, driver firewall.FirewallDriver, audit Auditor, log *logrus.Logger) ConfigService {
 	return &configService{
 		configRepo: configRepo,
 		driver:     driver,
+		audit:      audit,
 		log:        log,
 	}
 }
@@ -48,6 +54,7 @@ func (s *configService) UpdateConfig(ctx context.Context, dto ConfigUpdateDTO) (
 	if err != nil {
 		return nil, err
 	}
+	before := *cfg
 
 	cfg.IPForwarding = dto.IPForwarding
 	cfg.NATEnabled = dto.NATEnabled
@@ -58,6 +65,7 @@ func (s *configService) UpdateConfig(ctx context.Context, dto ConfigUpdateDTO) (
 		s.log.WithError(err).Error("failed to save config to database")
 		return nil, err
 	}
+	s.audit.Record(ctx, "config.update", "config", "", before, cfg)
 
 	// Then immediately apply the configuration to the system (e.g., sysctl for IP forwarding)
 	if err := s.driver.ApplyConfig(cfg); err != nil {
@@ -95,13 +103,25 @@ type UpdateInterfaceDTO struct {
 	Gateway string `json:"gateway"`
 }
 
-// InterfaceWithStatus combines DB model with live system info
+// InterfaceAddressDTO is the input for adding an address to an interface
+type InterfaceAddressDTO struct {
+	Address string `json:"address" binding:"required"` // ip/prefix
+}
+
+// ErrInvalidInterfaceAddress is returned for addresses that are not a
+// usable unicast ip/prefix.
+var ErrInvalidInterfaceAddress = errors.New("invalid interface address")
+
+// InterfaceWithStatus combines DB model with live system info. IP and Mask
+// are the primary IPv4 address; Addresses lists every IPv4 and IPv6 address.
 type InterfaceWithStatus struct {
 	*models.NetworkInterface
-	IP      string `json:"ip"`
-	Mask    string `json:"mask"`
-	Gateway string `json:"gateway"`
-	MAC     string `json:"mac"`
+	IP        string                    `json:"ip"`
+	Mask      string                    `json:"mask"`
+	Gateway   string                    `json:"gateway"`
+	Gateway6  string                    `json:"gateway6,omitempty"`
+	MAC       string                    `json:"mac"`
+	Addresses []models.InterfaceAddress `json:"addresses"`
 }
 
 type InterfaceService interface {
@@ -109,18 +129,26 @@ type InterfaceService interface {
 	CreateInterface(ctx context.Context, dto CreateInterfaceDTO) (*models.NetworkInterface, error)
 	UpdateInterface(ctx context.Context, id string, dto UpdateInterfaceDTO) (*models.NetworkInterface, error)
 	DeleteInterface(ctx context.Context, id string) error
+	// AddAddress and RemoveAddress change one address and return the
+	// interface's addresses afterwards. The other addresses are kept.
+	AddAddress(ctx context.Context, id string, dto InterfaceAddressDTO) ([]models.InterfaceAddress, error)
+	RemoveAddress(ctx context.Context, id, address string) ([]models.InterfaceAddress, error)
 }
 
 type interfaceService struct {
 	ifaceRepo repository.InterfaceRepository
 	netDriver network.Driver
+	guard     *LockoutGuard
+	audit     Auditor
 	log       *logrus.Logger
 }
 
-func NewInterfaceService(ifaceRepo repository.InterfaceRepository, netDriver network.Driver, log *logrus.Logger) InterfaceService {
+func NewInterfaceService(ifaceRepo repository.InterfaceRepository, netDriver network.Driver, guard *LockoutGuard, audit Auditor, log *logrus.Logger) InterfaceService {
 	return &interfaceService{
 		ifaceRepo: ifaceRepo,
 		netDriver: netDriver,
+		guard:     guard,
+		audit:     audit,
 		log:       log,
 	}
 }
@@ -179,7 +207,9 @@ func (s *interfaceService) ListInterfaces(ctx context.Context) ([]InterfaceWithS
 			IP:               sys.IP,
 			Mask:             sys.Mask,
 			Gateway:          sys.Gateway,
+			Gateway6:         sys.Gateway6,
 			MAC:              sys.MAC,
+			Addresses:        sys.Addrs,
 		})
 	}
 
@@ -187,6 +217,10 @@ func (s *interfaceService) ListInterfaces(ctx context.Context) ([]InterfaceWithS
 }
 
 func (s *interfaceService) CreateInterface(ctx context.Context, dto CreateInterfaceDTO) (*models.NetworkInterface, error) {
+	if err := auth.Authorize(ctx, auth.PermEdit, dto.Zone, nil); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	iface := &models.NetworkInterface{
 		ID:        uuid.New().String(),
@@ -201,6 +235,7 @@ func (s *interfaceService) CreateInterface(ctx context.Context, dto CreateInterf
 	if err := s.ifaceRepo.Create(iface); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, "interface.create", "interface", iface.ID, nil, iface)
 
 	return iface, nil
 }
@@ -210,16 +245,27 @@ func (s *interfaceService) UpdateInterface(ctx context.Context, id string, dto U
 	if err != nil {
 		return nil, fmt.Errorf("interface not found: %w", err)
 	}
+	if err := auth.Authorize(ctx, auth.PermEdit, iface.Zone, nil); err != nil {
+		return nil, err
+	}
+	before := *iface
 
 	if dto.Name != "" {
 		iface.Name = dto.Name
 	}
 	if dto.Zone != "" {
+		if err := auth.Authorize(ctx, auth.PermEdit, dto.Zone, nil); err != nil {
+			return nil, err
+		}
 		iface.Zone = dto.Zone
 	}
 	iface.Enabled = dto.Enabled
 	iface.Notes = dto.Notes
 
+	if err := s.guard.CheckInterfaceChange(ctx, iface.ID, iface.Name, dto.IP, dto.Enabled); err != nil {
+		return nil, err
+	}
+
 	// Apply config to system
 	if err := s.netDriver.ApplyConfig(iface.Name, dto.IP, dto.Mask, dto.Gateway, dto.Enabled); err != nil {
 		return nil, fmt.Errorf("failed to apply network config: %w", err)
@@ -228,12 +274,118 @@ func (s *interfaceService) UpdateInterface(ctx context.Context, id string, dto U
 	if err := s.ifaceRepo.Update(iface); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, "interface.update", "interface", iface.ID, before, InterfaceWithStatus{
+		NetworkInterface: iface,
+		IP:               dto.IP,
+		Mask:             dto.Mask,
+		Gateway:          dto.Gateway,
+	})
 
 	return iface, nil
 }
 
 func (s *interfaceService) DeleteInterface(ctx context.Context, id string) error {
-	return s.ifaceRepo.Delete(id)
+	iface, err := s.ifaceRepo.Get(id)
+	if err != nil {
+		return fmt.Errorf("interface not found: %w", err)
+	}
+	if err := auth.Authorize(ctx, auth.PermEdit, iface.Zone, nil); err != nil {
+		return err
+	}
+	if err := s.ifaceRepo.Delete(id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "interface.delete", "interface", id, iface, nil)
+	return nil
+}
+
+func (s *interfaceService) AddAddress(ctx context.Context, id string, dto InterfaceAddressDTO) ([]models.InterfaceAddress, error) {
+	iface, err := s.ifaceRepo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("interface not found: %w", err)
+	}
+	if err := auth.Authorize(ctx, auth.PermEdit, iface.Zone, nil); err != nil {
+		return nil, err
+	}
+	ip, n, err := net.ParseCIDR(strings.TrimSpace(dto.Address))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not in ip/prefix notation", ErrInvalidInterfaceAddress, dto.Address)
+	}
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() {
+		return nil, fmt.Errorf("%w: %s is not a unicast address", ErrInvalidInterfaceAddress, ip)
+	}
+	ones, _ := n.Mask.Size()
+	cidr := fmt.Sprintf("%s/%d", ip, ones)
+
+	if err := s.netDriver.AddAddr(iface.Name, cidr); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "interface.address.add", "interface", iface.ID, nil, map[string]string{
+		"interface": iface.Name,
+		"address":   cidr,
+	})
+	return s.addresses(iface.Name)
+}
+
+// RemoveAddress removes an address given as ip/prefix or as a bare ip.
+func (s *interfaceService) RemoveAddress(ctx context.Context, id, address string) ([]models.InterfaceAddress, error) {
+	iface, err := s.ifaceRepo.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("interface not found: %w", err)
+	}
+	if err := auth.Authorize(ctx, auth.PermEdit, iface.Zone, nil); err != nil {
+		return nil, err
+	}
+	address = strings.TrimSpace(address)
+	ipStr, prefix, hasPrefix := strings.Cut(address, "/")
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %s is not an ip or ip/prefix", ErrInvalidInterfaceAddress, address)
+	}
+
+	current, err := s.addresses(iface.Name)
+	if err != nil {
+		return nil, err
+	}
+	var found *models.InterfaceAddress
+	for i, a := range current {
+		if net.ParseIP(a.IP).Equal(ip) && (!hasPrefix || prefix == fmt.Sprint(a.PrefixLen)) {
+			found = &current[i]
+			break
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s on %s", network.ErrAddrNotFound, address, iface.Name)
+	}
+
+	if err := s.guard.CheckAddressRemoval(ctx, iface.ID, iface.Name, ip); err != nil {
+		return nil, err
+	}
+	if err := s.netDriver.DelAddr(iface.Name, found.CIDR()); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "interface.address.remove", "interface", iface.ID, map[string]string{
+		"interface": iface.Name,
+		"address":   found.CIDR(),
+	}, nil)
+	return s.addresses(iface.Name)
+}
+
+// addresses returns the live addresses of the named interface.
+func (s *interfaceService) addresses(name string) ([]models.InterfaceAddress, error) {
+	sysIfaces, err := s.netDriver.GetInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, sys := range sysIfaces {
+		if sys.Name == name {
+			if sys.Addrs == nil {
+				return []models.InterfaceAddress{}, nil
+			}
+			return sys.Addrs, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s not found on the system", name)
 }
 
 // ZoneService manages firewall zones
@@ -262,12 +414,14 @@ type ZoneService interface {
 
 type zoneService struct {
 	zoneRepo repository.ZoneRepository
+	audit    Auditor
 	log      *logrus.Logger
 }
 
-func NewZoneService(zoneRepo repository.ZoneRepository, log *logrus.Logger) ZoneService {
+func NewZoneService(zoneRepo repository.ZoneRepository, audit Auditor, log *logrus.Logger) ZoneService {
 	return &zoneService{
 		zoneRepo: zoneRepo,
+		audit:    audit,
 		log:      log,
 	}
 }
@@ -277,6 +431,10 @@ func (s *zoneService) ListZones(ctx context.Context) ([]*models.Zone, error) {
 }
 
 func (s *zoneService) CreateZone(ctx context.Context, dto CreateZoneDTO) (*models.Zone, error) {
+	if err := auth.Authorize(ctx, auth.PermEdit, dto.Name, nil); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	zone := &models.Zone{
 		ID:          uuid.New().String(),
@@ -292,6 +450,7 @@ func (s *zoneService) CreateZone(ctx context.Context, dto CreateZoneDTO) (*model
 	if err := s.zoneRepo.Create(zone); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, "zone.create", "zone", zone.ID, nil, zone)
 
 	return zone, nil
 }
@@ -301,8 +460,15 @@ func (s *zoneService) UpdateZone(ctx context.Context, id string, dto UpdateZoneD
 	if err != nil {
 		return nil, fmt.Errorf("zone not found: %w", err)
 	}
+	if err := auth.Authorize(ctx, auth.PermEdit, zone.Name, nil); err != nil {
+		return nil, err
+	}
+	before := *zone
 
 	if dto.Name != "" {
+		if err := auth.Authorize(ctx, auth.PermEdit, dto.Name, nil); err != nil {
+			return nil, err
+		}
 		zone.Name = dto.Name
 	}
 	if dto.Description != "" {
@@ -321,76 +487,149 @@ func (s *zoneService) UpdateZone(ctx context.Context, id string, dto UpdateZoneD
 	if err := s.zoneRepo.Update(zone); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, "zone.update", "zone", zone.ID, before, zone)
 
 	return zone, nil
 }
 
 func (s *zoneService) DeleteZone(ctx context.Context, id string) error {
-	return s.zoneRepo.Delete(id)
+	zone, err := s.zoneRepo.Get(id)
+	if err != nil {
+		return fmt.Errorf("zone not found: %w", err)
+	}
+	if err := auth.Authorize(ctx, auth.PermEdit, zone.Name, nil); err != nil {
+		return err
+	}
+	if err := s.zoneRepo.Delete(id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "zone.delete", "zone", id, zone, nil)
+	return nil
 }
 
 // NATRuleService manages NAT rules
 type CreateNATRuleDTO struct {
-	Name         string `json:"name" binding:"required"`
-	Type         string `json:"type" binding:"required"` // SNAT or DNAT
-	Protocol     string `json:"protocol"`
-	InInterface  string `json:"inInterface"`
-	OutInterface string `json:"outInterface"`
-	SourceIP     string `json:"sourceIP"`
-	SourcePort   string `json:"sourcePort"`
-	DestIP       string `json:"destIP"`
-	DestPort     string `json:"destPort"`
-	NATtoIP      string `json:"nattoIP" binding:"required"`
-	NATtoPort    string `json:"nattoPort"`
-	Comment      string `json:"comment"`
-	Enabled      bool   `json:"enabled"`
-	Position     int    `json:"position"`
+	Name         string   `json:"name" binding:"required"`
+	Type         string   `json:"type" binding:"required"` // SNAT or DNAT
+	Protocol     string   `json:"protocol"`
+	InInterface  string   `json:"inInterface"`
+	OutInterface string   `json:"outInterface"`
+	SourceIP     string   `json:"sourceIP"`
+	SourcePort   string   `json:"sourcePort"`
+	DestIP       string   `json:"destIP"`
+	DestPort     string   `json:"destPort"`
+	NATtoIP      string   `json:"nattoIP" binding:"required"`
+	NATtoPort    string   `json:"nattoPort"`
+	Comment      string   `json:"comment"`
+	Enabled      bool     `json:"enabled"`
+	Position     int      `json:"position"`
+	Tags         []string `json:"tags"`
 }
 
 type UpdateNATRuleDTO struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	Protocol     string `json:"protocol"`
-	InInterface  string `json:"inInterface"`
-	OutInterface string `json:"outInterface"`
-	SourceIP     string `json:"sourceIP"`
-	SourcePort   string `json:"sourcePort"`
-	DestIP       string `json:"destIP"`
-	DestPort     string `json:"destPort"`
-	NATtoIP      string `json:"nattoIP"`
-	NATtoPort    string `json:"nattoPort"`
-	Comment      string `json:"comment"`
-	Enabled      bool   `json:"enabled"`
-	Position     int    `json:"position"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Protocol     string   `json:"protocol"`
+	InInterface  string   `json:"inInterface"`
+	OutInterface string   `json:"outInterface"`
+	SourceIP     string   `json:"sourceIP"`
+	SourcePort   string   `json:"sourcePort"`
+	DestIP       string   `json:"destIP"`
+	DestPort     string   `json:"destPort"`
+	NATtoIP      string   `json:"nattoIP"`
+	NATtoPort    string   `json:"nattoPort"`
+	Comment      string   `json:"comment"`
+	Enabled      bool     `json:"enabled"`
+	Position     int      `json:"position"`
+	Tags         []string `json:"tags"`
+}
+
+// NATRuleWithCounters is a stored NAT rule with its live counters.
+type NATRuleWithCounters struct {
+	*models.NATRule
+	models.RuleCounters
 }
 
 type NATRuleService interface {
-	ListNATRules(ctx context.Context) ([]*models.NATRule, error)
+	ListNATRules(ctx context.Context) ([]NATRuleWithCounters, error)
+	GetNATRuleCounters(ctx context.Context, id string) (*models.RuleCounters, error)
 	CreateNATRule(ctx context.Context, dto CreateNATRuleDTO) (*models.NATRule, error)
 	UpdateNATRule(ctx context.Context, id string, dto UpdateNATRuleDTO) (*models.NATRule, error)
 	DeleteNATRule(ctx context.Context, id string) error
 }
 
 type natRuleService struct {
-	natRepo repository.NATRuleRepository
-	log     *logrus.Logger
+	natRepo  repository.NATRuleRepository
+	ifaces   repository.InterfaceRepository
+	counters *CounterTracker
+	audit    Auditor
+	log      *logrus.Logger
 }
 
-func NewNATRuleService(natRepo repository.NATRuleRepository, log *logrus.Logger) NATRuleService {
+func NewNATRuleService(natRepo repository.NATRuleRepository, ifaces repository.InterfaceRepository, counters *CounterTracker, audit Auditor, log *logrus.Logger) NATRuleService {
 	return &natRuleService{
-		natRepo: natRepo,
-		log:     log,
+		natRepo:  natRepo,
+		ifaces:   ifaces,
+		counters: counters,
+		audit:    audit,
+		log:      log,
 	}
 }
 
-func (s *natRuleService) ListNATRules(ctx context.Context) ([]*models.NATRule, error) {
-	return s.natRepo.List()
+func (s *natRuleService) ListNATRules(ctx context.Context) ([]NATRuleWithCounters, error) {
+	rules, err := s.natRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var counters map[string]*models.RuleCounters
+	if s.counters != nil {
+		ids := make([]string, len(rules))
+		for i, r := range rules {
+			ids[i] = r.ID
+		}
+		if counters, err = s.counters.Read(ids); err != nil {
+			s.log.WithError(err).Debug("could not read NAT rule counters")
+		}
+	}
+
+	result := make([]NATRuleWithCounters, 0, len(rules))
+	for _, r := range rules {
+		item := NATRuleWithCounters{NATRule: r}
+		item.Tag = firewall.RuleTag(r.ID)
+		if c, ok := counters[r.ID]; ok {
+			item.RuleCounters = *c
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// GetNATRuleCounters returns the live counters of one NAT rule.
+func (s *natRuleService) GetNATRuleCounters(_ context.Context, id string) (*models.RuleCounters, error) {
+	if _, err := s.natRepo.Get(id); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+	}
+	if s.counters == nil {
+		return nil, fmt.Errorf("rule counters are not configured")
+	}
+	counters, err := s.counters.Read([]string{id})
+	if err != nil {
+		return nil, fmt.Errorf("read counters: %w", err)
+	}
+	return counters[id], nil
 }
 
 func (s *natRuleService) CreateNATRule(ctx context.Context, dto CreateNATRuleDTO) (*models.NATRule, error) {
 	if dto.Type != "SNAT" && dto.Type != "DNAT" {
 		return nil, fmt.Errorf("invalid NAT type: must be SNAT or DNAT")
 	}
+	if err := validateTags(dto.Tags); err != nil {
+		return nil, err
+	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, dto.Tags, dto.InInterface, dto.OutInterface); err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	rule := &models.NATRule{
@@ -409,6 +648,7 @@ func (s *natRuleService) CreateNATRule(ctx context.Context, dto CreateNATRuleDTO
 		Comment:      dto.Comment,
 		Enabled:      dto.Enabled,
 		Position:     dto.Position,
+		Tags:         dto.Tags,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -416,6 +656,7 @@ func (s *natRuleService) CreateNATRule(ctx context.Context, dto CreateNATRuleDTO
 	if err := s.natRepo.Create(rule); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, "nat-rule.create", "nat-rule", rule.ID, nil, rule)
 
 	return rule, nil
 }
@@ -425,6 +666,10 @@ func (s *natRuleService) UpdateNATRule(ctx context.Context, id string, dto Updat
 	if err != nil {
 		return nil, fmt.Errorf("NAT rule not found: %w", err)
 	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, rule.Tags, rule.InInterface, rule.OutInterface); err != nil {
+		return nil, err
+	}
+	before := *rule
 
 	if dto.Name != "" {
 		rule.Name = dto.Name
@@ -462,16 +707,37 @@ func (s *natRuleService) UpdateNATRule(ctx context.Context, id string, dto Updat
 	if dto.Comment != "" {
 		rule.Comment = dto.Comment
 	}
+	if dto.Tags != nil {
+		if err := validateTags(dto.Tags); err != nil {
+			return nil, err
+		}
+		rule.Tags = dto.Tags
+	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, rule.Tags, rule.InInterface, rule.OutInterface); err != nil {
+		return nil, err
+	}
 	rule.Enabled = dto.Enabled
 	rule.Position = dto.Position
 
 	if err := s.natRepo.Update(rule); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, "nat-rule.update", "nat-rule", rule.ID, before, rule)
 
 	return rule, nil
 }
 
 func (s *natRuleService) DeleteNATRule(ctx context.Context, id string) error {
-	return s.natRepo.Delete(id)
+	rule, err := s.natRepo.Get(id)
+	if err != nil {
+		return fmt.Errorf("NAT rule not found: %w", err)
+	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, rule.Tags, rule.InInterface, rule.OutInterface); err != nil {
+		return err
+	}
+	if err := s.natRepo.Delete(id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "nat-rule.delete", "nat-rule", id, rule, nil)
+	return nil
 }
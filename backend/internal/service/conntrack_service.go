@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/conntrack"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultConnectionLimit = 500
+	maxConnectionLimit     = 10000
+)
+
+// ConntrackService lists and deletes the connections the kernel tracks.
+// Deleting an entry makes the next packet of the connection go through the
+// ruleset again, so a rule that now drops it takes effect at once instead
+// of only for new connections.
+type ConntrackService interface {
+	// List returns up to limit matching connections and how many matched.
+	List(ctx context.Context, filter models.ConnectionFilter, limit int) ([]*models.Connection, int, error)
+	Delete(ctx context.Context, filter models.ConnectionFilter) (int, error)
+	// FlushDenied deletes the connections the compiled ruleset now drops or
+	// rejects, if the caller or the configuration asked for it.
+	FlushDenied(ctx context.Context, compiled []*models.Rule, at time.Time) (int, error)
+}
+
+type conntrackService struct {
+	driver       conntrack.Driver
+	flushOnApply bool
+	audit        Auditor
+	log          *logrus.Logger
+}
+
+func NewConntrackService(driver conntrack.Driver, flushOnApply bool, audit Auditor, log *logrus.Logger) ConntrackService {
+	return &conntrackService{driver: driver, flushOnApply: flushOnApply, audit: audit, log: log}
+}
+
+type conntrackFlushKey struct{}
+
+// WithConntrackFlush overrides for this request whether an apply deletes
+// the connections the new ruleset denies.
+func WithConntrackFlush(ctx context.Context, flush bool) context.Context {
+	return context.WithValue(ctx, conntrackFlushKey{}, flush)
+}
+
+func (s *conntrackService) List(_ context.Context, filter models.ConnectionFilter, limit int) ([]*models.Connection, int, error) {
+	if limit <= 0 {
+		limit = defaultConnectionLimit
+	}
+	if limit > maxConnectionLimit {
+		limit = maxConnectionLimit
+	}
+	conns, err := s.driver.List()
+	if err != nil {
+		return nil, 0, fmt.Errorf("list connections: %w", err)
+	}
+	matched := make([]*models.Connection, 0)
+	total := 0
+	for _, c := range conns {
+		if !filter.Matches(c) {
+			continue
+		}
+		total++
+		if len(matched) < limit {
+			matched = append(matched, c)
+		}
+	}
+	return matched, total, nil
+}
+
+// Delete removes the matching connections. An empty filter deletes every
+// connection, which the handler only passes on when asked to explicitly.
+func (s *conntrackService) Delete(ctx context.Context, filter models.ConnectionFilter) (int, error) {
+	n, err := s.driver.Delete(filter.Matches)
+	if n > 0 || err != nil {
+		after := map[string]any{"filter": filter, "deleted": n}
+		if err != nil {
+			after["error"] = err.Error()
+		}
+		s.audit.Record(ctx, "conntrack.delete", "conntrack", "", nil, after)
+	}
+	if err != nil {
+		return n, err
+	}
+	s.log.WithField("entries", n).Info("conntrack entries deleted")
+	return n, nil
+}
+
+func (s *conntrackService) FlushDenied(ctx context.Context, compiled []*models.Rule, at time.Time) (int, error) {
+	flush := s.flushOnApply
+	if v, ok := ctx.Value(conntrackFlushKey{}).(bool); ok {
+		flush = v
+	}
+	if !flush {
+		return 0, nil
+	}
+
+	local := localAddrs()
+	denied := make(map[string]int)
+	n, err := s.driver.Delete(func(c *models.Connection) bool {
+		r := deniedBy(c, compiled, local, at)
+		if r != nil {
+			denied[r.ID]++
+		}
+		return r != nil
+	})
+	if n > 0 || err != nil {
+		after := map[string]any{"deleted": n, "rules": denied}
+		if err != nil {
+			after["error"] = err.Error()
+		}
+		s.audit.Record(ctx, "conntrack.flush", "conntrack", "", nil, after)
+	}
+	if err != nil {
+		return n, err
+	}
+	s.log.WithField("entries", n).Info("conntrack entries of denied connections deleted")
+	return n, nil
+}
+
+// deniedBy returns the rule that drops or rejects the next packet of c in
+// its original direction, or nil if it is accepted or no rule decides.
+// Only IPv4 connections are considered, as the ruleset is loaded with
+// iptables. The chain is INPUT for connections to a local address, OUTPUT
+// for those from one, and FORWARD otherwise.
+func deniedBy(c *models.Connection, compiled []*models.Rule, local map[string]bool, at time.Time) *models.Rule {
+	if c.Family != "ipv4" {
+		return nil
+	}
+	// Destination NAT happens before the filter table, so the rules see
+	// the translated destination, which is where the reply comes from.
+	src, dst := net.ParseIP(c.Original.Src), net.ParseIP(c.Reply.Src)
+	if src == nil || dst == nil {
+		return nil
+	}
+	sport, dport := c.Original.SrcPort, c.Reply.SrcPort
+
+	chain := models.ChainFORWARD
+	if local[dst.String()] {
+		chain = models.ChainINPUT
+	} else if local[src.String()] {
+		chain = models.ChainOUTPUT
+	}
+
+	for _, r := range compiled {
+		if r.Chain != chain || !ruleActiveAt(r, at) {
+			continue
+		}
+		if r.Protocol != models.ProtocolAll && !strings.EqualFold(string(r.Protocol), c.Protocol) {
+			continue
+		}
+		// Ports are only rendered for TCP and UDP rules.
+		ports := r.Protocol == models.ProtocolTCP || r.Protocol == models.ProtocolUDP
+		if !addrMatches(r.Src, src, true) || !addrMatches(r.Dst, dst, true) ||
+			(ports && (!portMatches(r.SrcPort, sport) || !portMatches(r.DstPort, dport))) {
+			continue
+		}
+		// Conntrack does not record interfaces, so a rule bound to one
+		// might not apply: assume it accepts rather than drops, which at
+		// worst leaves a connection open.
+		if r.InInterface != "" || r.OutInterface != "" {
+			if r.Action == models.ActionACCEPT {
+				return nil
+			}
+			continue
+		}
+		switch r.Action {
+		case models.ActionACCEPT:
+			return nil
+		case models.ActionDROP, models.ActionREJECT:
+			return r
+		}
+		// LOG does not terminate; keep walking the chain.
+	}
+	return nil
+}
+
+// localAddrs returns the addresses of this host's interfaces.
+func localAddrs() map[string]bool {
+	local := make(map[string]bool)
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return local
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			local[n.IP.String()] = true
+		}
+	}
+	return local
+}
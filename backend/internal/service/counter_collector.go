@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/firewall"
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// ErrInterfaceNotFound is returned for counters of an unknown interface.
+var ErrInterfaceNotFound = errors.New("interface not found")
+
+// CounterSnapshot is one collection of the live rule and interface counters.
+// It is shared between callers and must not be modified.
+type CounterSnapshot struct {
+	Rules      []*models.Counter
+	RulesErr   error
+	Interfaces map[string]*models.InterfaceCounters
+	LinksErr   error
+	At         time.Time
+}
+
+// CounterCollector reads the rule counters with one iptables-save -c and the
+// interface counters with one netlink dump, and serves the result to every
+// request for ttl. Requests arriving while a collection runs wait for it
+// instead of starting their own. Failed reads are cached as well, so a
+// missing iptables-save is not retried on every request.
+type CounterCollector struct {
+	driver firewall.FirewallDriver
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	last     *CounterSnapshot
+	inflight chan struct{} // closed when the running collection finishes
+}
+
+func NewCounterCollector(driver firewall.FirewallDriver, ttl time.Duration) *CounterCollector {
+	return &CounterCollector{driver: driver, ttl: ttl}
+}
+
+// Snapshot returns the cached counters, collecting them first if they are
+// older than the TTL.
+func (c *CounterCollector) Snapshot() *CounterSnapshot {
+	c.mu.Lock()
+	if c.last != nil && time.Since(c.last.At) < c.ttl {
+		snap := c.last
+		c.mu.Unlock()
+		return snap
+	}
+	if wait := c.inflight; wait != nil {
+		c.mu.Unlock()
+		<-wait
+		c.mu.Lock()
+		snap := c.last
+		c.mu.Unlock()
+		return snap
+	}
+	done := make(chan struct{})
+	c.inflight = done
+	c.mu.Unlock()
+
+	snap := &CounterSnapshot{}
+	snap.Rules, snap.RulesErr = c.driver.GetCounters()
+	snap.Interfaces, snap.LinksErr = c.driver.GetLinkCounters()
+	snap.At = time.Now().UTC()
+
+	c.mu.Lock()
+	c.last = snap
+	c.inflight = nil
+	c.mu.Unlock()
+	close(done)
+	return snap
+}
+
+// Rules returns the counters of every rule and chain policy.
+func (c *CounterCollector) Rules() ([]*models.Counter, error) {
+	snap := c.Snapshot()
+	return snap.Rules, snap.RulesErr
+}
+
+// Interfaces returns the counters of every interface, by name.
+func (c *CounterCollector) Interfaces() (map[string]*models.InterfaceCounters, error) {
+	snap := c.Snapshot()
+	return snap.Interfaces, snap.LinksErr
+}
+
+// Interface returns the counters of one interface.
+func (c *CounterCollector) Interface(name string) (*models.InterfaceCounters, error) {
+	ifaces, err := c.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	counters, ok := ifaces[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInterfaceNotFound, name)
+	}
+	return counters, nil
+}
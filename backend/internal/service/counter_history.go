@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/firewall"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidCounterQuery is returned for range queries that name no series,
+// have an empty window or would return too many points.
+var ErrInvalidCounterQuery = errors.New("invalid counter query")
+
+const (
+	// maxCounterPoints caps the steps per series in one range query.
+	maxCounterPoints = 10000
+	// defaultCounterPoints is what the step is picked for when none is given.
+	defaultCounterPoints = 120
+	// counterMaintenanceInterval is how often samples are downsampled and
+	// expired.
+	counterMaintenanceInterval = time.Hour
+)
+
+// counterTiers are the downsampling steps: samples older than age are
+// merged into intervals of span seconds.
+var counterTiers = []struct {
+	age  time.Duration
+	span int64
+}{
+	{24 * time.Hour, 300},
+	{7 * 24 * time.Hour, 3600},
+}
+
+// CounterHistoryService samples the live counters into a time series and
+// answers range queries over it.
+type CounterHistoryService interface {
+	Run(ctx context.Context)
+	Series(ctx context.Context) ([]string, error)
+	Query(ctx context.Context, q models.CounterQuery) ([]*models.CounterSeries, error)
+}
+
+type counterHistoryService struct {
+	samples   repository.CounterSampleRepository
+	rules     repository.RuleRepository
+	natRules  repository.NATRuleRepository
+	collector *CounterCollector
+	events    EventPublisher
+	interval  time.Duration
+	retention time.Duration
+	log       *logrus.Logger
+
+	// Touched only by Run.
+	prev   map[string]models.CounterStats
+	prevAt time.Time
+}
+
+func NewCounterHistoryService(
+	samples repository.CounterSampleRepository,
+	rules repository.RuleRepository,
+	natRules repository.NATRuleRepository,
+	collector *CounterCollector,
+	events EventPublisher,
+	interval, retention time.Duration,
+	log *logrus.Logger,
+) CounterHistoryService {
+	return &counterHistoryService{
+		samples:   samples,
+		rules:     rules,
+		natRules:  natRules,
+		collector: collector,
+		events:    events,
+		interval:  interval,
+		retention: retention,
+		log:       log,
+	}
+}
+
+// Run samples the counters every interval, and downsamples and expires old
+// samples every hour, until ctx is cancelled. A zero interval disables
+// sampling; the samples other services record are still maintained.
+func (s *counterHistoryService) Run(ctx context.Context) {
+	interval := s.interval
+	if interval <= 0 {
+		interval = counterMaintenanceInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var maintained time.Time
+	for {
+		if s.interval > 0 {
+			if err := s.sample(ctx); err != nil {
+				s.log.WithError(err).Error("counter sampling failed")
+			}
+		}
+		if time.Since(maintained) >= counterMaintenanceInterval {
+			s.maintain(time.Now())
+			maintained = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample stores how much every counter grew since the previous sample and
+// publishes the values read as a "counters.sample" event. The first sample
+// after a start only records the current values.
+func (s *counterHistoryService) sample(ctx context.Context) error {
+	snap := s.collector.Snapshot()
+	s.publish(ctx, snap)
+	next := make(map[string]models.CounterStats)
+
+	if snap.RulesErr != nil {
+		s.log.WithError(snap.RulesErr).Debug("rule counters unavailable for sampling")
+		s.keepPrev(next, func(series string) bool { return !strings.HasPrefix(series, "iface/") })
+	} else {
+		tags, err := s.tagSeries()
+		if err != nil {
+			return err
+		}
+		for _, c := range snap.Rules {
+			name := ""
+			if c.Rule == "policy" {
+				name = "policy/" + c.Table + "/" + string(c.Chain)
+			} else if c.Tag != "" {
+				name = tags[c.Tag]
+			}
+			if name == "" {
+				continue
+			}
+			sum := next[name]
+			sum.Packets += c.Packets
+			sum.Bytes += c.Bytes
+			next[name] = sum
+		}
+	}
+
+	if snap.LinksErr != nil {
+		s.log.WithError(snap.LinksErr).Debug("interface counters unavailable for sampling")
+		s.keepPrev(next, func(series string) bool { return strings.HasPrefix(series, "iface/") })
+	} else {
+		for name, c := range snap.Interfaces {
+			next["iface/"+name+"/rx"] = c.In
+			next["iface/"+name+"/tx"] = c.Out
+			next["iface/"+name+"/drop"] = c.Drop
+		}
+	}
+
+	prev, prevAt := s.prev, s.prevAt
+	s.prev, s.prevAt = next, snap.At
+	if prev == nil {
+		return nil
+	}
+	span := int64(math.Round(snap.At.Sub(prevAt).Seconds()))
+	if span <= 0 {
+		return nil
+	}
+
+	samples := make([]*models.CounterSample, 0, len(next))
+	for name, cur := range next {
+		old, ok := prev[name]
+		if !ok {
+			continue
+		}
+		samples = append(samples, &models.CounterSample{
+			Series:  name,
+			At:      snap.At,
+			Span:    span,
+			Packets: counterDelta(old.Packets, cur.Packets),
+			Bytes:   counterDelta(old.Bytes, cur.Bytes),
+		})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return s.samples.Insert(samples)
+}
+
+// CounterSampleEvent is the data of a "counters.sample" event: the live
+// counters read for a sample. A part that could not be read is left out.
+type CounterSampleEvent struct {
+	Time       time.Time                            `json:"time"`
+	Rules      []*models.Counter                    `json:"rules,omitempty"`
+	Interfaces map[string]*models.InterfaceCounters `json:"interfaces,omitempty"`
+}
+
+func (s *counterHistoryService) publish(ctx context.Context, snap *CounterSnapshot) {
+	data := &CounterSampleEvent{Time: snap.At}
+	if snap.RulesErr == nil {
+		data.Rules = snap.Rules
+	}
+	if snap.LinksErr == nil {
+		data.Interfaces = snap.Interfaces
+	}
+	s.events.Publish(ctx, "counters.sample", "", data)
+}
+
+// keepPrev carries the previous values of the series that could not be read
+// this time, so they are not seeded again.
+func (s *counterHistoryService) keepPrev(next map[string]models.CounterStats, match func(string) bool) {
+	for name, v := range s.prev {
+		if match(name) {
+			next[name] = v
+		}
+	}
+}
+
+// tagSeries maps the tags of the stored filter and NAT rules to their
+// series names.
+func (s *counterHistoryService) tagSeries() (map[string]string, error) {
+	rules, err := s.rules.List()
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %w", err)
+	}
+	natRules, err := s.natRules.List()
+	if err != nil {
+		return nil, fmt.Errorf("list nat rules: %w", err)
+	}
+	tags := make(map[string]string, len(rules)+len(natRules))
+	for _, r := range rules {
+		tags[firewall.RuleTag(r.ID)] = "rule/" + r.ID
+	}
+	for _, r := range natRules {
+		tags[firewall.RuleTag(r.ID)] = "nat/" + r.ID
+	}
+	return tags, nil
+}
+
+// counterDelta returns how much a counter grew from prev to cur. A counter
+// that went down was either reset (an apply replaced the rule, the interface
+// was recreated) and has counted cur since, or wrapped around: a 32-bit
+// counter from a driver without 64-bit stats, or, in theory, a 64-bit one.
+func counterDelta(prev, cur uint64) uint64 {
+	switch {
+	case cur >= prev:
+		return cur - prev
+	case prev <= math.MaxUint32 && prev-cur > math.MaxUint32/2:
+		return math.MaxUint32 - prev + cur + 1
+	case prev > math.MaxUint64/2 && prev-cur > math.MaxUint64/2:
+		return math.MaxUint64 - prev + cur + 1
+	}
+	return cur
+}
+
+// maintain downsamples old samples into coarser intervals and drops those
+// past the retention period.
+func (s *counterHistoryService) maintain(now time.Time) {
+	for _, tier := range counterTiers {
+		if s.retention > 0 && tier.age >= s.retention {
+			break
+		}
+		cutoff := now.Add(-tier.age).Truncate(time.Duration(tier.span) * time.Second)
+		n, err := s.samples.Downsample(cutoff, tier.span)
+		if err != nil {
+			s.log.WithError(err).WithField("span", tier.span).Error("counter downsampling failed")
+			continue
+		}
+		if n > 0 {
+			s.log.WithFields(logrus.Fields{"span": tier.span, "samples": n}).Debug("counter samples downsampled")
+		}
+	}
+	if s.retention > 0 {
+		n, err := s.samples.DeleteBefore(now.Add(-s.retention))
+		if err != nil {
+			s.log.WithError(err).Error("counter retention failed")
+		} else if n > 0 {
+			s.log.WithField("samples", n).Debug("expired counter samples deleted")
+		}
+	}
+}
+
+func (s *counterHistoryService) Series(_ context.Context) ([]string, error) {
+	return s.samples.Series()
+}
+
+// Query sums the samples of each series into steps aligned to q.From. The
+// step is raised to the coarsest interval stored in the window, since a
+// downsampled sample cannot be split.
+func (s *counterHistoryService) Query(_ context.Context, q models.CounterQuery) ([]*models.CounterSeries, error) {
+	if len(q.Series) == 0 {
+		return nil, fmt.Errorf("%w: no series given", ErrInvalidCounterQuery)
+	}
+	if !q.To.After(q.From) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidCounterQuery)
+	}
+	window := q.To.Sub(q.From)
+	step := int64(math.Ceil(q.Step.Seconds()))
+	if step <= 0 {
+		step = int64(math.Ceil(window.Seconds() / defaultCounterPoints))
+	}
+
+	samples, err := s.samples.Range(q.Series, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		if sample.Span > step {
+			step = sample.Span
+		}
+	}
+	if step <= 0 {
+		step = 1
+	}
+	if points := int64(math.Ceil(window.Seconds() / float64(step))); points > maxCounterPoints {
+		return nil, fmt.Errorf("%w: %d points per series, at most %d allowed; raise step",
+			ErrInvalidCounterQuery, points, maxCounterPoints)
+	}
+
+	from := q.From.Unix()
+	result := make([]*models.CounterSeries, 0, len(q.Series))
+	index := make(map[string]*models.CounterSeries, len(q.Series))
+	for _, name := range q.Series {
+		if _, ok := index[name]; ok {
+			continue
+		}
+		series := &models.CounterSeries{Series: name, Step: step, Points: []models.CounterPoint{}}
+		result = append(result, series)
+		index[name] = series
+	}
+	for _, sample := range samples {
+		series := index[sample.Series]
+		// Sample ends in (from, to]; step n covers (from+n*step, from+(n+1)*step].
+		start := from + (sample.At.Unix()-from-1)/step*step
+		at := time.Unix(start, 0).UTC()
+		last := len(series.Points) - 1
+		if last < 0 || !series.Points[last].Time.Equal(at) {
+			series.Points = append(series.Points, models.CounterPoint{Time: at})
+			last++
+		}
+		series.Points[last].Packets += sample.Packets
+		series.Points[last].Bytes += sample.Bytes
+	}
+	for _, series := range result {
+		for i := range series.Points {
+			p := &series.Points[i]
+			p.PPS = float64(p.Packets) / float64(step)
+			p.BPS = float64(p.Bytes) * 8 / float64(step)
+		}
+	}
+	return result, nil
+}
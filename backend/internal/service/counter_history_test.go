@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// fakeSampleRepo keeps samples in memory, in the order they were inserted.
+type fakeSampleRepo struct {
+	samples []*models.CounterSample
+}
+
+func (r *fakeSampleRepo) Insert(samples []*models.CounterSample) error {
+	r.samples = append(r.samples, samples...)
+	return nil
+}
+func (r *fakeSampleRepo) Range(series []string, from, to time.Time) ([]*models.CounterSample, error) {
+	var out []*models.CounterSample
+	for _, s := range r.samples {
+		for _, name := range series {
+			if s.Series == name && s.At.After(from) && !s.At.After(to) {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+func (r *fakeSampleRepo) Series() ([]string, error) { return nil, nil }
+func (r *fakeSampleRepo) Totals(prefix string, from, to time.Time) ([]*models.CounterSample, error) {
+	return nil, nil
+}
+func (r *fakeSampleRepo) Downsample(before time.Time, span int64) (int64, error) { return 0, nil }
+func (r *fakeSampleRepo) DeleteBefore(before time.Time) (int64, error)           { return 0, nil }
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur uint64
+		want      uint64
+	}{
+		{"growth", 10, 25, 15},
+		{"unchanged", 5, 5, 0},
+		{"reset", 1000, 10, 10},
+		{"reset of a large 32-bit value", 3e9, 2e9, 2e9},
+		{"32-bit wrap", math.MaxUint32 - 5, 10, 16},
+		{"32-bit wrap to zero", math.MaxUint32, 0, 1},
+		{"reset of a 64-bit counter", 5e9, 100, 100},
+		{"64-bit wrap", math.MaxUint64 - 1, 2, 4},
+		{"small drop near the 64-bit limit", math.MaxUint64 - 10, math.MaxUint64 - 20, math.MaxUint64 - 20},
+	}
+	for _, tt := range tests {
+		if got := counterDelta(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%s: counterDelta(%d, %d) = %d, want %d", tt.name, tt.prev, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestCounterQueryBuckets(t *testing.T) {
+	from := time.Date(2026, 10, 19, 12, 0, 30, 0, time.UTC) // steps align to from, not to the minute
+	sample := func(series string, after time.Duration, packets uint64) *models.CounterSample {
+		return &models.CounterSample{Series: series, At: from.Add(after), Span: 10, Packets: packets, Bytes: packets * 100}
+	}
+	repo := &fakeSampleRepo{samples: []*models.CounterSample{
+		sample("rule/a", 0, 999), // ends at from, belongs to the step before
+		sample("rule/a", 10*time.Second, 20),
+		sample("rule/a", time.Minute, 40), // ends exactly at the step boundary
+		sample("rule/a", 70*time.Second, 30),
+		sample("rule/b", 90*time.Second, 6),
+		sample("rule/a", 5*time.Minute+time.Second, 12),
+		sample("rule/b", 10*time.Minute, 3),
+	}}
+	s := &counterHistoryService{samples: repo}
+
+	got, err := s.Query(context.Background(), models.CounterQuery{
+		Series: []string{"rule/a", "rule/b", "rule/c", "rule/a"},
+		From:   from,
+		To:     from.Add(10 * time.Minute),
+		Step:   time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type point struct {
+		after   time.Duration
+		packets uint64
+	}
+	want := map[string][]point{
+		"rule/a": {{0, 60}, {time.Minute, 30}, {5 * time.Minute, 12}},
+		"rule/b": {{time.Minute, 6}, {9 * time.Minute, 3}},
+		"rule/c": {},
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d series, want 3", len(got))
+	}
+	for _, series := range got {
+		if series.Step != 60 {
+			t.Errorf("%s: step %d, want 60", series.Series, series.Step)
+		}
+		if series.Points == nil {
+			t.Errorf("%s: nil points", series.Series)
+		}
+		points := want[series.Series]
+		if len(series.Points) != len(points) {
+			t.Errorf("%s: %d points, want %d: %+v", series.Series, len(series.Points), len(points), series.Points)
+			continue
+		}
+		for i, p := range series.Points {
+			w := points[i]
+			if !p.Time.Equal(from.Add(w.after)) || p.Packets != w.packets || p.Bytes != w.packets*100 {
+				t.Errorf("%s[%d] = %+v, want %d packets at %s", series.Series, i, p, w.packets, from.Add(w.after))
+			}
+			if pps := float64(w.packets) / 60; p.PPS != pps || p.BPS != pps*800 {
+				t.Errorf("%s[%d] rate %v pps %v bps", series.Series, i, p.PPS, p.BPS)
+			}
+		}
+	}
+}
+
+func TestCounterQueryStep(t *testing.T) {
+	from := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		window  time.Duration
+		step    time.Duration
+		samples []*models.CounterSample
+		want    int64 // 0 expects ErrInvalidCounterQuery
+	}{
+		{"given step", time.Hour, time.Minute, nil, 60},
+		{"fractional step rounds up", time.Hour, 1500 * time.Millisecond, nil, 2},
+		{"default step", 2 * time.Hour, 0, nil, 60},
+		{"default step of a short window", time.Minute, 0, nil, 1},
+		{"raised to a downsampled span", time.Hour, time.Minute, []*models.CounterSample{
+			{Series: "rule/a", At: from.Add(10 * time.Minute), Span: 10},
+			{Series: "rule/a", At: from.Add(15 * time.Minute), Span: 300},
+		}, 300},
+		{"too many points", 30 * 24 * time.Hour, time.Second, nil, 0},
+		{"empty window", 0, time.Minute, nil, 0},
+		{"reversed window", -time.Hour, time.Minute, nil, 0},
+	}
+	for _, tt := range tests {
+		s := &counterHistoryService{samples: &fakeSampleRepo{samples: tt.samples}}
+		got, err := s.Query(context.Background(), models.CounterQuery{Series: []string{"rule/a"}, From: from, To: from.Add(tt.window), Step: tt.step})
+		if tt.want == 0 {
+			if !errors.Is(err, ErrInvalidCounterQuery) {
+				t.Errorf("%s: err = %v, want ErrInvalidCounterQuery", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got[0].Step != tt.want {
+			t.Errorf("%s: step %d, want %d", tt.name, got[0].Step, tt.want)
+		}
+	}
+
+	s := &counterHistoryService{samples: &fakeSampleRepo{}}
+	if _, err := s.Query(context.Background(), models.CounterQuery{From: from, To: from.Add(time.Hour)}); !errors.Is(err, ErrInvalidCounterQuery) {
+		t.Errorf("query without series: err = %v", err)
+	}
+}
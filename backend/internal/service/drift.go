@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// loadedPayload is what the last verified apply rendered, kept so the live
+// state can be checked against it later.
+type loadedPayload struct {
+	compiled []*models.Rule
+	natRules []*models.NATRule
+	config   *models.FirewallConfig
+}
+
+// Drift checks whether the kernel still holds the payload of the last
+// verified apply. While an apply, rollback or panic is changing the kernel
+// the previous result is returned instead of reading a half-loaded state.
+func (s *firewallService) Drift(_ context.Context) (*models.DriftStatus, error) {
+	if !s.writeMu.TryLock() {
+		s.mu.Lock()
+		last := s.lastDrift
+		s.mu.Unlock()
+		if last != nil {
+			return last, nil
+		}
+		return &models.DriftStatus{State: models.DriftUnknown, CheckedAt: time.Now().UTC()}, nil
+	}
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	hash, payload := s.loadedHash, s.loadedPayload
+	s.mu.Unlock()
+
+	status := &models.DriftStatus{State: models.DriftUnknown, PayloadHash: hash, CheckedAt: time.Now().UTC()}
+	if hash != "" && payload != nil {
+		report, err := s.driver.Verify(payload.compiled, payload.natRules, payload.config)
+		if err != nil {
+			return nil, fmt.Errorf("read live state: %w", err)
+		}
+		status.Report = report
+		status.State = models.DriftInSync
+		if !report.OK {
+			status.State = models.DriftDrifted
+		}
+	}
+
+	s.mu.Lock()
+	s.lastDrift = status
+	s.mu.Unlock()
+	return status, nil
+}
+
+// DriftMonitor checks for drift periodically and publishes "drift.detected"
+// when the kernel stops matching the last apply and "drift.resolved" when
+// it matches again.
+type DriftMonitor struct {
+	fw       FirewallService
+	events   EventPublisher
+	interval time.Duration
+	log      *logrus.Logger
+}
+
+func NewDriftMonitor(fw FirewallService, events EventPublisher, interval time.Duration, log *logrus.Logger) *DriftMonitor {
+	return &DriftMonitor{fw: fw, events: events, interval: interval, log: log}
+}
+
+// Run checks every interval until ctx is cancelled. A zero interval
+// disables the checks.
+func (m *DriftMonitor) Run(ctx context.Context) {
+	if m.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	state := models.DriftUnknown
+	for {
+		status, err := m.fw.Drift(ctx)
+		if err != nil {
+			m.log.WithError(err).Error("drift check failed")
+		} else if status.State != models.DriftUnknown && status.State != state {
+			switch {
+			case status.State == models.DriftDrifted:
+				m.log.WithField("payload_hash", status.PayloadHash).Warn("live ruleset drifted from the last apply")
+				m.events.Publish(ctx, "drift.detected", "", status)
+			case state == models.DriftDrifted:
+				m.log.WithField("payload_hash", status.PayloadHash).Info("live ruleset matches the last apply again")
+				m.events.Publish(ctx, "drift.resolved", "", status)
+			}
+			state = status.State
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
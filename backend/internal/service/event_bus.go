@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// eventBacklog is how many recent events are kept for subscribers that
+	// reconnect with the last ID they saw.
+	eventBacklog = 1000
+	// eventSubscriberBuffer is how many events may wait for a subscriber.
+	// A subscriber that falls further behind is disconnected and can resume
+	// from the backlog.
+	eventSubscriberBuffer = 256
+)
+
+// EventPublisher publishes changes to the event stream.
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType, resourceID string, data any)
+}
+
+// nopPublisher discards events; used where no event bus is configured.
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, string, string, any) {}
+
+// EventBus fans events out to in-process subscribers. Events are numbered
+// in publish order and not persisted.
+type EventBus struct {
+	log *logrus.Logger
+
+	mu      sync.Mutex
+	seq     uint64
+	backlog []*models.Event
+	subs    map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	ch     chan *models.Event
+	filter models.EventFilter
+	allow  func(eventType string) bool
+}
+
+func NewEventBus(log *logrus.Logger) *EventBus {
+	return &EventBus{log: log, subs: make(map[*eventSubscriber]struct{})}
+}
+
+// Publish numbers the event and hands it to every matching subscriber
+// without blocking. The actor is the principal in ctx, if any.
+func (b *EventBus) Publish(ctx context.Context, eventType, resourceID string, data any) {
+	e := &models.Event{Type: eventType, Time: time.Now().UTC(), ResourceID: resourceID, Data: data}
+	if p := auth.FromContext(ctx); p != nil {
+		e.Actor = p.Name
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.ID = b.seq
+	b.backlog = append(b.backlog, e)
+	if len(b.backlog) > eventBacklog {
+		b.backlog = b.backlog[len(b.backlog)-eventBacklog:]
+	}
+	for sub := range b.subs {
+		if !sub.wants(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			b.log.WithField("event_id", e.ID).Warn("event subscriber too slow, disconnecting")
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of the events that match filter and that the
+// principal in ctx may read, starting after the event with ID after (0 for
+// only new events) as far as the backlog reaches. The channel is closed
+// when ctx is done or the subscriber falls too far behind.
+func (b *EventBus) Subscribe(ctx context.Context, filter models.EventFilter, after uint64) <-chan *models.Event {
+	sub := &eventSubscriber{
+		ch:     make(chan *models.Event, eventSubscriberBuffer),
+		filter: filter,
+		allow:  eventAllowed(auth.FromContext(ctx)),
+	}
+
+	b.mu.Lock()
+	if after > 0 {
+		for _, e := range b.backlog {
+			if e.ID > after && sub.wants(e) && len(sub.ch) < cap(sub.ch) {
+				sub.ch <- e
+			}
+		}
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[sub]; ok {
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}()
+	return sub.ch
+}
+
+func (s *eventSubscriber) wants(e *models.Event) bool {
+	return s.filter.Matches(e) && s.allow(e.Type)
+}
+
+// eventScopes maps the first part of an event type to the token scope
+// needed to receive it, the same one that reads the resource.
+var eventScopes = map[string]string{
+	"rule":      "rules",
+	"nat-rule":  "nat",
+	"zone":      "zones",
+	"interface": "interfaces",
+	"config":    "config",
+	"apply":     "apply",
+	"rollback":  "apply",
+	"panic":     "apply",
+	"job":       "apply",
+	"drift":     "apply",
+	"lockout":   "apply",
+	"counters":  "counters",
+	"alert":     "alerts",
+}
+
+// eventAllowed limits scoped API tokens to the events of their resources.
+func eventAllowed(p *auth.Principal) func(eventType string) bool {
+	return func(eventType string) bool {
+		if p == nil {
+			return true
+		}
+		prefix, _, _ := strings.Cut(eventType, ".")
+		scope, ok := eventScopes[prefix]
+		return ok && p.HasScope(scope, false)
+	}
+}
+
+// publishedResources are the audited resources whose changes are also
+// published as events. Accounts and tokens are left out.
+var publishedResources = map[string]bool{
+	"rule": true, "nat-rule": true, "zone": true, "interface": true,
+	"config": true, "ruleset": true,
+}
+
+// publishingAuditor records to the audit log and publishes every audited
+// change to a published resource as an event named after the audit action.
+// The event carries the new state, or the old one for deletions.
+type publishingAuditor struct {
+	next   Auditor
+	events EventPublisher
+}
+
+func NewPublishingAuditor(next Auditor, events EventPublisher) Auditor {
+	return &publishingAuditor{next: next, events: events}
+}
+
+func (a *publishingAuditor) Record(ctx context.Context, action, resource, resourceID string, before, after any) {
+	a.next.Record(ctx, action, resource, resourceID, before, after)
+	if !publishedResources[resource] {
+		return
+	}
+	data := after
+	if data == nil {
+		data = before
+	}
+	a.events.Publish(ctx, action, resourceID, data)
+}
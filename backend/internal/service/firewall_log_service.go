@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/firewall"
+	"github.com/firewall-manager/backend/internal/fwlog"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLogLimit = 100
+	maxLogLimit     = 1000
+
+	// logQueueSize is how many read entries may wait to be stored. Entries
+	// read while it is full are dropped and counted.
+	logQueueSize = 10000
+	// logBatchSize and logFlushInterval bound how many entries are stored
+	// per transaction and how long an entry waits for its batch.
+	logBatchSize     = 500
+	logFlushInterval = time.Second
+	// logRotateInterval is how often the table is trimmed.
+	logRotateInterval = time.Minute
+	// logPrefixRefresh is how long the map of rule prefixes is reused.
+	logPrefixRefresh = 30 * time.Second
+	// logTailBuffer is how many entries may wait for a tail subscriber;
+	// entries beyond it are skipped for that subscriber.
+	logTailBuffer = 256
+	// logSourceRetry is the longest wait before a failed source is
+	// restarted.
+	logSourceRetry = time.Minute
+)
+
+// FirewallLogService collects the packets logged by firewall rules from the
+// configured sources, attributes them to rules by their log prefix, and
+// stores them for search and live tailing.
+type FirewallLogService interface {
+	Run(ctx context.Context)
+	Query(ctx context.Context, filter models.LogFilter) ([]*models.LogEntry, error)
+	Tail(ctx context.Context, filter models.LogFilter) <-chan *models.LogEntry
+}
+
+type firewallLogService struct {
+	entries   repository.LogEntryRepository
+	rules     repository.RuleRepository
+	sources   []fwlog.Source
+	keep      int
+	retention time.Duration
+	log       *logrus.Logger
+
+	queue chan *models.LogEntry
+
+	mu      sync.Mutex
+	dropped uint64
+	tails   map[*logTail]struct{}
+
+	// Touched only by Run.
+	prefixes   map[string]string // trimmed log prefix -> rule ID
+	prefixesAt time.Time
+}
+
+type logTail struct {
+	ch     chan *models.LogEntry
+	filter models.LogFilter
+}
+
+func NewFirewallLogService(
+	entries repository.LogEntryRepository,
+	rules repository.RuleRepository,
+	sources []fwlog.Source,
+	keep int,
+	retention time.Duration,
+	log *logrus.Logger,
+) FirewallLogService {
+	return &firewallLogService{
+		entries:   entries,
+		rules:     rules,
+		sources:   sources,
+		keep:      keep,
+		retention: retention,
+		log:       log,
+		queue:     make(chan *models.LogEntry, logQueueSize),
+		tails:     make(map[*logTail]struct{}),
+	}
+}
+
+// Run reads every source and stores what they read until ctx is cancelled.
+// A source that fails is restarted with a growing delay.
+func (s *firewallLogService) Run(ctx context.Context) {
+	if len(s.sources) == 0 {
+		return
+	}
+	for _, src := range s.sources {
+		go s.runSource(ctx, src)
+	}
+
+	flush := time.NewTicker(logFlushInterval)
+	defer flush.Stop()
+	rotate := time.NewTicker(logRotateInterval)
+	defer rotate.Stop()
+
+	var batch []*models.LogEntry
+	for {
+		select {
+		case <-ctx.Done():
+			s.store(batch)
+			return
+		case e := <-s.queue:
+			batch = append(batch, e)
+			if len(batch) >= logBatchSize {
+				s.store(batch)
+				batch = nil
+			}
+		case <-flush.C:
+			s.store(batch)
+			batch = nil
+		case <-rotate.C:
+			s.rotate(time.Now())
+		}
+	}
+}
+
+func (s *firewallLogService) runSource(ctx context.Context, src fwlog.Source) {
+	fields := logrus.Fields{"source": src.Name()}
+	s.log.WithFields(fields).Info("reading firewall log")
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := src.Run(ctx, s.enqueue)
+		if ctx.Err() != nil {
+			return
+		}
+		// A source that ran for a while failed afresh.
+		if time.Since(started) > logSourceRetry {
+			delay = time.Second
+		}
+		s.log.WithFields(fields).WithError(err).WithField("retry_in", delay.String()).Error("firewall log source failed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > logSourceRetry {
+			delay = logSourceRetry
+		}
+	}
+}
+
+// enqueue hands an entry to Run without blocking the source.
+func (s *firewallLogService) enqueue(e *models.LogEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	select {
+	case s.queue <- e:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+	}
+}
+
+// store attributes a batch to rules, saves it and passes it to the tails.
+func (s *firewallLogService) store(batch []*models.LogEntry) {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+	if dropped > 0 {
+		s.log.WithField("entries", dropped).Warn("firewall log queue full, entries dropped")
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	prefixes := s.rulePrefixes()
+	for _, e := range batch {
+		e.RuleID = prefixes[e.Prefix]
+	}
+	if err := s.entries.Insert(batch); err != nil {
+		s.log.WithError(err).WithField("entries", len(batch)).Error("store firewall log entries failed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for tail := range s.tails {
+		for _, e := range batch {
+			if !tail.filter.Matches(e) {
+				continue
+			}
+			select {
+			case tail.ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+// rulePrefixes maps the log prefixes of the stored rules that log to their
+// IDs. A prefix that several rules share maps to "", as its lines cannot be
+// attributed. A failed refresh keeps the previous map.
+func (s *firewallLogService) rulePrefixes() map[string]string {
+	if s.prefixes != nil && time.Since(s.prefixesAt) < logPrefixRefresh {
+		return s.prefixes
+	}
+	rules, err := s.rules.List()
+	if err != nil {
+		s.log.WithError(err).Warn("list rules for log attribution failed")
+		return s.prefixes
+	}
+	prefixes := make(map[string]string)
+	for _, r := range rules {
+		if r.Action != models.ActionLOG && r.Log == nil {
+			continue
+		}
+		prefix := strings.TrimSpace(firewall.LogPrefix(r))
+		if _, dup := prefixes[prefix]; dup {
+			prefixes[prefix] = ""
+			continue
+		}
+		prefixes[prefix] = r.ID
+	}
+	s.prefixes, s.prefixesAt = prefixes, time.Now()
+	return prefixes
+}
+
+// rotate keeps the newest entries and drops those past the retention.
+func (s *firewallLogService) rotate(now time.Time) {
+	var before time.Time
+	if s.retention > 0 {
+		before = now.Add(-s.retention)
+	}
+	n, err := s.entries.Rotate(s.keep, before)
+	if err != nil {
+		s.log.WithError(err).Error("firewall log rotation failed")
+		return
+	}
+	if n > 0 {
+		s.log.WithField("entries", n).Debug("old firewall log entries deleted")
+	}
+}
+
+func (s *firewallLogService) Query(_ context.Context, filter models.LogFilter) ([]*models.LogEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLogLimit
+	}
+	if filter.Limit > maxLogLimit {
+		filter.Limit = maxLogLimit
+	}
+	return s.entries.Query(filter)
+}
+
+// Tail returns a channel of the matching entries as they are stored. It is
+// closed when ctx is done. A subscriber that falls behind misses entries.
+func (s *firewallLogService) Tail(ctx context.Context, filter models.LogFilter) <-chan *models.LogEntry {
+	tail := &logTail{ch: make(chan *models.LogEntry, logTailBuffer), filter: filter}
+	s.mu.Lock()
+	s.tails[tail] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.tails, tail)
+		close(tail.ch)
+	}()
+	return tail.ch
+}
@@ -3,8 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sync"
 	"time"
 
+	"github.com/firewall-manager/backend/internal/auth"
 	"github.com/firewall-manager/backend/internal/firewall"
 	"github.com/firewall-manager/backend/internal/models"
 	"github.com/firewall-manager/backend/internal/repository"
@@ -14,52 +17,107 @@ import (
 
 // CreateRuleDTO is the input for creating a rule — no raw iptables exposed.
 type CreateRuleDTO struct {
-	Chain    models.Chain    `json:"chain" binding:"required"`
-	Protocol models.Protocol `json:"protocol" binding:"required"`
-	Src      string          `json:"src"`
-	Dst      string          `json:"dst"`
-	SrcPort  string          `json:"srcPort"`
-	DstPort  string          `json:"dstPort"`
-	Action   models.Action   `json:"action" binding:"required"`
-	Enabled  bool            `json:"enabled"`
-	Comment  string          `json:"comment"`
-	Position int             `json:"position"`
+	Chain        models.Chain       `json:"chain" binding:"required"`
+	Protocol     models.Protocol    `json:"protocol" binding:"required"`
+	Src          string             `json:"src"`
+	Dst          string             `json:"dst"`
+	SrcPort      string             `json:"srcPort"`
+	DstPort      string             `json:"dstPort"`
+	InInterface  string             `json:"inInterface"`
+	OutInterface string             `json:"outInterface"`
+	Action       models.Action      `json:"action" binding:"required"`
+	Enabled      bool               `json:"enabled"`
+	Comment      string             `json:"comment"`
+	Position     int                `json:"position"`
+	Schedule     *models.Schedule   `json:"schedule"`
+	Log          *models.LogOptions `json:"log"`
+	Tags         []string           `json:"tags"`
 }
 
 // UpdateRuleDTO is the input for updating a rule.
 type UpdateRuleDTO struct {
-	Chain    models.Chain    `json:"chain" binding:"required"`
-	Protocol models.Protocol `json:"protocol" binding:"required"`
-	Src      string          `json:"src"`
-	Dst      string          `json:"dst"`
-	SrcPort  string          `json:"srcPort"`
-	DstPort  string          `json:"dstPort"`
-	Action   models.Action   `json:"action" binding:"required"`
-	Enabled  bool            `json:"enabled"`
-	Comment  string          `json:"comment"`
-	Position int             `json:"position"`
+	Chain        models.Chain       `json:"chain" binding:"required"`
+	Protocol     models.Protocol    `json:"protocol" binding:"required"`
+	Src          string             `json:"src"`
+	Dst          string             `json:"dst"`
+	SrcPort      string             `json:"srcPort"`
+	DstPort      string             `json:"dstPort"`
+	InInterface  string             `json:"inInterface"`
+	OutInterface string             `json:"outInterface"`
+	Action       models.Action      `json:"action" binding:"required"`
+	Enabled      bool               `json:"enabled"`
+	Comment      string             `json:"comment"`
+	Position     int                `json:"position"`
+	Schedule     *models.Schedule   `json:"schedule"`
+	Log          *models.LogOptions `json:"log"`
+	Tags         []string           `json:"tags"`
+}
+
+// RuleWithStatus combines a stored rule with its schedule state and live
+// counters.
+type RuleWithStatus struct {
+	*models.Rule
+	Active         bool       `json:"active"`                   // enabled and inside its schedule window
+	NextTransition *time.Time `json:"nextTransition,omitempty"` // when Active next flips, if scheduled
+	models.RuleCounters
 }
 
 type FirewallService interface {
-	ListRules(ctx context.Context) ([]*models.Rule, error)
+	ListRules(ctx context.Context) ([]RuleWithStatus, error)
 	CreateRule(ctx context.Context, dto CreateRuleDTO) (*models.Rule, error)
 	UpdateRule(ctx context.Context, id string, dto UpdateRuleDTO) (*models.Rule, error)
 	DeleteRule(ctx context.Context, id string) error
+	GetRuleCounters(ctx context.Context, id string) (*models.RuleCounters, error)
 	ApplyRules(ctx context.Context) error
 	Rollback(ctx context.Context) error
+	RollbackTo(ctx context.Context, historyID string) error
+	ListHistory(ctx context.Context, limit int) ([]*models.HistoryEntry, error)
+	ReconcileSchedules(ctx context.Context) error
 	GetCounters(ctx context.Context) ([]*models.Counter, error)
 	GetInterfaces(ctx context.Context) ([]*models.Interface, error)
 	GetInterfaceCounters(ctx context.Context, iface string) (*models.InterfaceCounters, error)
 	GetAggregatedCounters(ctx context.Context) (*models.InterfaceCounters, error)
+	Panic(ctx context.Context, reason string) (*models.PanicState, error)
+	ReleasePanic(ctx context.Context) (*models.PanicState, error)
+	GetPanicState(ctx context.Context) (*models.PanicState, error)
+	RestorePanic(ctx context.Context) error
+	LoadedPayloadHash(ctx context.Context) string
+	Drift(ctx context.Context) (*models.DriftStatus, error)
+	OperationStats(ctx context.Context) (apply, rollback OperationStats)
 }
 
 type firewallService struct {
-	rules    repository.RuleRepository
-	history  repository.HistoryRepository
-	config   repository.ConfigRepository
-	natRules repository.NATRuleRepository
-	driver   firewall.FirewallDriver
-	log      *logrus.Logger
+	rules     repository.RuleRepository
+	ifaces    repository.InterfaceRepository
+	history   repository.HistoryRepository
+	config    repository.ConfigRepository
+	natRules  repository.NATRuleRepository
+	panics    repository.PanicRepository
+	driver    firewall.FirewallDriver
+	guard     *LockoutGuard
+	collector *CounterCollector
+	counters  *CounterTracker
+	conntrack ConntrackService
+	audit     Auditor
+	log       *logrus.Logger
+
+	// writeMu is the single-writer lock for the kernel ruleset: applies,
+	// rollbacks, schedule transitions and panic mode take it, so none of
+	// them interleave and an apply cannot slip in under a lockdown.
+	writeMu sync.Mutex
+
+	// mu guards the state of the last apply, which the rule scheduler
+	// re-renders when a scheduler-mode rule opens or closes.
+	mu          sync.Mutex
+	lastApplied []*models.Rule
+	scheduleKey string
+	loadedHash  string // payload hash of the last verified apply; "" if unknown
+	// loadedPayload is what loadedHash was computed from.
+	loadedPayload *loadedPayload
+	lastDrift     *models.DriftStatus
+
+	applyStats    OperationStats
+	rollbackStats OperationStats
 }
 
 func NewFirewallService(
@@ -69,74 +127,157 @@ func NewFirewallService(
 	log *logrus.Logger,
 ) FirewallService {
 	return &firewallService{
-		rules:   rules,
-		history: history,
-		driver:  driver,
-		log:     log,
+		rules:     rules,
+		history:   history,
+		driver:    driver,
+		collector: NewCounterCollector(driver, 0),
+		audit:     nopAuditor{},
+		log:       log,
 	}
 }
 
 func NewFirewallServiceWithConfig(
 	rules repository.RuleRepository,
+	ifaces repository.InterfaceRepository,
 	history repository.HistoryRepository,
 	config repository.ConfigRepository,
 	natRules repository.NATRuleRepository,
+	panics repository.PanicRepository,
 	driver firewall.FirewallDriver,
+	guard *LockoutGuard,
+	collector *CounterCollector,
+	counters *CounterTracker,
+	conntrack ConntrackService,
+	audit Auditor,
 	log *logrus.Logger,
 ) FirewallService {
 	return &firewallService{
-		rules:    rules,
-		history:  history,
-		config:   config,
-		natRules: natRules,
-		driver:   driver,
-		log:      log,
+		rules:     rules,
+		ifaces:    ifaces,
+		history:   history,
+		config:    config,
+		natRules:  natRules,
+		panics:    panics,
+		driver:    driver,
+		guard:     guard,
+		collector: collector,
+		counters:  counters,
+		conntrack: conntrack,
+		audit:     audit,
+		log:       log,
 	}
 }
 
-func (s *firewallService) ListRules(_ context.Context) ([]*models.Rule, error) {
-	return s.rules.List()
+func (s *firewallService) ListRules(_ context.Context) ([]RuleWithStatus, error) {
+	rules, err := s.rules.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// Counters are best effort; the list is still useful without them.
+	counters := s.readCounters(rules)
+
+	now := time.Now()
+	result := make([]RuleWithStatus, 0, len(rules))
+	for _, r := range rules {
+		status := RuleWithStatus{Rule: r, Active: ruleActiveAt(r, now)}
+		status.Tag = firewall.RuleTag(r.ID)
+		if c, ok := counters[r.ID]; ok {
+			status.RuleCounters = *c
+		}
+		if r.Enabled && r.Schedule != nil {
+			if c, err := compileSchedule(r.Schedule); err == nil {
+				if next, ok := c.nextTransition(now); ok {
+					status.NextTransition = &next
+				}
+			}
+		}
+		result = append(result, status)
+	}
+	return result, nil
 }
 
-func (s *firewallService) CreateRule(_ context.Context, dto CreateRuleDTO) (*models.Rule, error) {
+func (s *firewallService) CreateRule(ctx context.Context, dto CreateRuleDTO) (*models.Rule, error) {
 	if err := validateDTO(dto.Chain, dto.Protocol, dto.Action, dto.Src, dto.Dst, dto.SrcPort, dto.DstPort); err != nil {
 		return nil, err
 	}
+	if err := validateSchedule(dto.Schedule); err != nil {
+		return nil, err
+	}
+	if err := validateLogOptions(dto.Log, dto.Action); err != nil {
+		return nil, err
+	}
+	if err := validateTags(dto.Tags); err != nil {
+		return nil, err
+	}
+	if err := validateRuleInterfaces(dto.Chain, dto.InInterface, dto.OutInterface); err != nil {
+		return nil, err
+	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, dto.Tags, dto.InInterface, dto.OutInterface); err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	rule := &models.Rule{
-		ID:        uuid.New().String(),
-		Chain:     dto.Chain,
-		Protocol:  dto.Protocol,
-		Src:       dto.Src,
-		Dst:       dto.Dst,
-		SrcPort:   dto.SrcPort,
-		DstPort:   dto.DstPort,
-		Action:    dto.Action,
-		Enabled:   dto.Enabled,
-		Comment:   dto.Comment,
-		Position:  dto.Position,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:           uuid.New().String(),
+		Chain:        dto.Chain,
+		Protocol:     dto.Protocol,
+		Src:          dto.Src,
+		Dst:          dto.Dst,
+		SrcPort:      dto.SrcPort,
+		DstPort:      dto.DstPort,
+		InInterface:  dto.InInterface,
+		OutInterface: dto.OutInterface,
+		Action:       dto.Action,
+		Enabled:      dto.Enabled,
+		Comment:      dto.Comment,
+		Position:     dto.Position,
+		Schedule:     dto.Schedule,
+		Log:          dto.Log,
+		Tags:         dto.Tags,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 
 	if err := s.rules.Create(rule); err != nil {
 		return nil, fmt.Errorf("create rule: %w", err)
 	}
+	s.audit.Record(ctx, "rule.create", "rule", rule.ID, nil, rule)
 
 	s.log.WithField("rule_id", rule.ID).Info("rule created")
 	return rule, nil
 }
 
-func (s *firewallService) UpdateRule(_ context.Context, id string, dto UpdateRuleDTO) (*models.Rule, error) {
+func (s *firewallService) UpdateRule(ctx context.Context, id string, dto UpdateRuleDTO) (*models.Rule, error) {
 	if err := validateDTO(dto.Chain, dto.Protocol, dto.Action, dto.Src, dto.Dst, dto.SrcPort, dto.DstPort); err != nil {
 		return nil, err
 	}
+	if err := validateSchedule(dto.Schedule); err != nil {
+		return nil, err
+	}
+	if err := validateLogOptions(dto.Log, dto.Action); err != nil {
+		return nil, err
+	}
+	if err := validateTags(dto.Tags); err != nil {
+		return nil, err
+	}
+	if err := validateRuleInterfaces(dto.Chain, dto.InInterface, dto.OutInterface); err != nil {
+		return nil, err
+	}
 
 	existing, err := s.rules.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	// The caller must own the rule both before and after the change, so a
+	// scoped editor can neither take over nor give away rules.
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, existing.Tags, existing.InInterface, existing.OutInterface); err != nil {
+		return nil, err
+	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, dto.Tags, dto.InInterface, dto.OutInterface); err != nil {
+		return nil, err
+	}
+	before := *existing
 
 	existing.Chain = dto.Chain
 	existing.Protocol = dto.Protocol
@@ -144,98 +285,409 @@ func (s *firewallService) UpdateRule(_ context.Context, id string, dto UpdateRul
 	existing.Dst = dto.Dst
 	existing.SrcPort = dto.SrcPort
 	existing.DstPort = dto.DstPort
+	existing.InInterface = dto.InInterface
+	existing.OutInterface = dto.OutInterface
 	existing.Action = dto.Action
 	existing.Enabled = dto.Enabled
 	existing.Comment = dto.Comment
 	existing.Position = dto.Position
+	existing.Schedule = dto.Schedule
+	existing.Log = dto.Log
+	existing.Tags = dto.Tags
 
 	if err := s.rules.Update(existing); err != nil {
 		return nil, fmt.Errorf("update rule: %w", err)
 	}
+	s.audit.Record(ctx, "rule.update", "rule", id, before, existing)
 
 	s.log.WithField("rule_id", id).Info("rule updated")
 	return existing, nil
 }
 
-func (s *firewallService) DeleteRule(_ context.Context, id string) error {
+func (s *firewallService) DeleteRule(ctx context.Context, id string) error {
+	existing, err := s.rules.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("delete rule: %w", err)
+	}
+	if err := authorizeInterfaces(ctx, s.ifaces, auth.PermEdit, existing.Tags, existing.InInterface, existing.OutInterface); err != nil {
+		return err
+	}
 	if err := s.rules.Delete(id); err != nil {
 		return fmt.Errorf("delete rule: %w", err)
 	}
+	s.audit.Record(ctx, "rule.delete", "rule", id, existing, nil)
 	s.log.WithField("rule_id", id).Info("rule deleted")
 	return nil
 }
 
-func (s *firewallService) ApplyRules(_ context.Context) error {
+// GetRuleCounters returns the live counters of one rule.
+func (s *firewallService) GetRuleCounters(_ context.Context, id string) (*models.RuleCounters, error) {
+	if _, err := s.rules.GetByID(id); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+	}
+	if s.counters == nil {
+		return nil, fmt.Errorf("rule counters are not configured")
+	}
+	counters, err := s.counters.Read([]string{id})
+	if err != nil {
+		return nil, fmt.Errorf("read counters: %w", err)
+	}
+	return counters[id], nil
+}
+
+func (s *firewallService) readCounters(rules []*models.Rule) map[string]*models.RuleCounters {
+	if s.counters == nil {
+		return nil
+	}
+	ids := make([]string, len(rules))
+	for i, r := range rules {
+		ids[i] = r.ID
+	}
+	counters, err := s.counters.Read(ids)
+	if err != nil {
+		s.log.WithError(err).Debug("could not read rule counters")
+		return nil
+	}
+	return counters
+}
+
+// ApplyRules pushes the stored ruleset to the kernel. The audit entry holds
+// the previously applied rules (if known since startup) and the new ones.
+// The apply is refused if it would drop the caller's own connection, and
+// rolled back if the kernel does not hold what was rendered afterwards.
+func (s *firewallService) ApplyRules(ctx context.Context) (err error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer func(start time.Time) { s.recordOperation(&s.applyStats, start, err) }(time.Now())
+	if err := s.refuseInPanic(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	before := s.lastApplied
+	s.mu.Unlock()
+
+	rules, err := s.applyRules(ctx)
+	if err != nil {
+		s.audit.Record(ctx, "apply.failed", "ruleset", "", before, map[string]string{"error": err.Error()})
+		return err
+	}
+	s.audit.Record(ctx, "apply", "ruleset", "", before, rules)
+	s.flushDenied(ctx, rules)
+	return nil
+}
+
+// flushDenied deletes the tracked connections the applied rules now deny,
+// if asked to. A failure does not fail the apply.
+func (s *firewallService) flushDenied(ctx context.Context, rules []*models.Rule) {
+	if s.conntrack == nil {
+		return
+	}
+	now := time.Now()
+	n, err := s.conntrack.FlushDenied(ctx, s.compile(rules, now), now)
+	if err != nil {
+		s.log.WithError(err).Warn("could not delete conntrack entries of denied connections")
+		logStep(ctx, "conntrack entries not deleted: %v", err)
+		return
+	}
+	if n > 0 {
+		logStep(ctx, "%d conntrack entries of denied connections deleted", n)
+	}
+}
+
+func (s *firewallService) applyRules(ctx context.Context) ([]*models.Rule, error) {
+	rules, err := s.rules.List()
+	if err != nil {
+		return nil, fmt.Errorf("load rules from db: %w", err)
+	}
+
+	var cfg *models.FirewallConfig
+	if s.config != nil {
+		if cfg, err = s.config.Get(); err != nil {
+			s.log.WithError(err).Warn("could not load firewall config")
+			cfg = nil
+		}
+	}
+	var natRules []*models.NATRule
+	if s.natRules != nil {
+		list, err := s.natRules.List()
+		if err != nil {
+			s.log.WithError(err).Warn("could not load NAT rules")
+		} else {
+			// Non-nil even when empty, so the flushed table is hashed and
+			// verified.
+			natRules = append([]*models.NATRule{}, list...)
+		}
+	}
+
+	if err := s.applyRuleset(ctx, rules, natRules, cfg, time.Now()); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// applyRuleset loads rules as scheduled at now, with the NAT rules and
+// config, into the kernel: lockout check, snapshot, apply, verification
+// with automatic rollback, and history. A nil natRules or cfg leaves that
+// part alone.
+func (s *firewallService) applyRuleset(ctx context.Context, rules []*models.Rule, natRules []*models.NATRule, cfg *models.FirewallConfig, now time.Time) error {
+	compiled := s.compile(rules, now)
+	logStep(ctx, "compiled %d rules (%d stored)", len(compiled), len(rules))
+	// The lockout check also sees scheduler-mode rules outside their
+	// window, which are loaded later without one.
+	checked := append(append([]*models.Rule{}, s.guard.Allowlist()...), rules...)
+	if err := s.guard.CheckRules(ctx, checked, now); err != nil {
+		return err
+	}
+	logStep(ctx, "lockout check passed")
+
+	hash := s.driver.Fingerprint(compiled, natRules, cfg)
+	logStep(ctx, "payload hash %s", hash)
+	if s.unchanged(hash, compiled, natRules, cfg) {
+		logStep(ctx, "payload matches the loaded one and the live state; kernel not reloaded")
+		s.applied(rules, now, hash, &loadedPayload{compiled, natRules, cfg})
+		s.log.WithField("payload_hash", hash).Info("ruleset unchanged, apply skipped")
+		return nil
+	}
+
 	// Snapshot current live state before applying (for rollback).
 	snapshot, err := s.driver.Load()
 	if err != nil {
 		s.log.WithError(err).Warn("could not snapshot current ruleset before apply")
+		logStep(ctx, "could not snapshot live ruleset: %v", err)
 		snapshot = ""
+	} else {
+		logStep(ctx, "snapshot of live ruleset taken")
 	}
 
-	rules, err := s.rules.List()
-	if err != nil {
-		return fmt.Errorf("load rules from db: %w", err)
-	}
+	// The filter table changes from here on, so the payload is no longer
+	// known until the apply has been verified.
+	s.setLoadedHash("")
 
-	if err := s.driver.Apply(rules); err != nil {
+	if err := s.driver.Apply(compiled); err != nil {
 		return fmt.Errorf("apply rules to kernel: %w", err)
 	}
+	logStep(ctx, "filter ruleset applied")
 
 	// Apply firewall configuration (IP forwarding, etc.)
-	if s.config != nil {
-		cfg, err := s.config.Get()
-		if err != nil {
-			s.log.WithError(err).Warn("could not load firewall config")
-		} else if err := s.driver.ApplyConfig(cfg); err != nil {
+	complete := true
+	var appliedConfig *models.FirewallConfig
+	if cfg != nil {
+		if err := s.driver.ApplyConfig(cfg); err != nil {
 			s.log.WithError(err).Warn("failed to apply firewall config")
+			logStep(ctx, "firewall config not applied: %v", err)
+			complete = false
+		} else {
+			appliedConfig = cfg
+			logStep(ctx, "firewall config applied")
 		}
 	}
 
 	// Apply NAT rules if enabled
-	if s.natRules != nil {
-		natRules, err := s.natRules.List()
-		if err != nil {
-			s.log.WithError(err).Warn("could not load NAT rules")
-		} else if err := s.driver.ApplyNAT(natRules); err != nil {
+	var appliedNAT []*models.NATRule
+	if natRules != nil {
+		if err := s.driver.ApplyNAT(natRules); err != nil {
 			s.log.WithError(err).Warn("failed to apply NAT rules")
+			logStep(ctx, "NAT rules not applied: %v", err)
+			complete = false
+		} else {
+			appliedNAT = natRules
+			logStep(ctx, "%d NAT rules applied", len(natRules))
 		}
 	}
 
-	// Persist snapshot to history after successful apply.
+	// Only the parts that were applied are verified; a failed NAT or config
+	// step has already been logged above.
+	report, verifyErr := s.verifyApply(ctx, compiled, appliedNAT, appliedConfig, snapshot)
+
+	// Persist snapshot to history, with the payload hash and verification.
 	if snapshot != "" {
+		description := fmt.Sprintf("snapshot before apply at %s", time.Now().Format(time.RFC3339))
+		if verifyErr != nil {
+			description += " (verification failed)"
+		}
 		entry := &models.HistoryEntry{
-			ID:          uuid.New().String(),
-			Snapshot:    snapshot,
-			Description: fmt.Sprintf("snapshot before apply at %s", time.Now().Format(time.RFC3339)),
-			AppliedAt:   time.Now(),
+			ID:           uuid.New().String(),
+			Kind:         models.HistoryKindSnapshot,
+			Snapshot:     snapshot,
+			Description:  description,
+			AppliedAt:    time.Now(),
+			PayloadHash:  hash,
+			Verification: report,
 		}
 		if err := s.history.Save(entry); err != nil {
 			s.log.WithError(err).Warn("could not save history entry")
+		} else {
+			logStep(ctx, "snapshot saved to history as %s", entry.ID)
 		}
 	}
+	if verifyErr != nil {
+		return verifyErr
+	}
+
+	// A partly applied payload is not recorded as loaded, so the next apply
+	// retries it in full.
+	if !complete {
+		hash = ""
+	}
+	s.applied(rules, now, hash, &loadedPayload{compiled, appliedNAT, appliedConfig})
 
-	s.log.WithField("rule_count", len(rules)).Info("ruleset applied to kernel")
+	s.log.WithFields(logrus.Fields{"rule_count": len(rules), "payload_hash": hash}).Info("ruleset applied to kernel")
 	return nil
 }
 
-func (s *firewallService) Rollback(_ context.Context) error {
-	entry, err := s.history.Latest()
+// unchanged reports whether hash is the payload already loaded and the live
+// state still matches it, in which case an apply can be skipped and the
+// kernel counters survive. Out-of-band changes fail the check.
+func (s *firewallService) unchanged(hash string, compiled []*models.Rule, natRules []*models.NATRule, cfg *models.FirewallConfig) bool {
+	s.mu.Lock()
+	loaded := s.loadedHash
+	s.mu.Unlock()
+	if loaded == "" || loaded != hash {
+		return false
+	}
+	report, err := s.driver.Verify(compiled, natRules, cfg)
+	if err != nil || !report.OK {
+		s.log.WithField("payload_hash", hash).Warn("loaded payload has drifted, re-applying")
+		return false
+	}
+	return true
+}
+
+// applied records the state of a successful apply.
+func (s *firewallService) applied(rules []*models.Rule, now time.Time, hash string, payload *loadedPayload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastApplied = rules
+	s.scheduleKey = scheduleKey(rules, now)
+	s.loadedHash = hash
+	s.loadedPayload = payload
+}
+
+// forgetApplied drops the state of the last apply once something else has
+// replaced the kernel ruleset, a rollback or panic mode, so a schedule
+// transition cannot bring the replaced rules back.
+func (s *firewallService) forgetApplied() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastApplied = nil
+	s.scheduleKey = ""
+	s.loadedHash = ""
+	s.loadedPayload = nil
+}
+
+// setLoadedHash records the hash of the payload in the kernel; "" means
+// unknown.
+func (s *firewallService) setLoadedHash(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedHash = hash
+}
+
+// LoadedPayloadHash returns the hash of the payload the last verified apply
+// loaded, or "" if the kernel has been changed by anything else since.
+func (s *firewallService) LoadedPayloadHash(_ context.Context) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadedHash
+}
+
+func (s *firewallService) Rollback(ctx context.Context) error {
+	return s.RollbackTo(ctx, "")
+}
+
+// RollbackTo restores the given history snapshot, or the latest one if
+// historyID is empty.
+func (s *firewallService) RollbackTo(ctx context.Context, historyID string) (err error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	defer func(start time.Time) { s.recordOperation(&s.rollbackStats, start, err) }(time.Now())
+	if err := s.refuseInPanic(); err != nil {
+		return err
+	}
+
+	var entry *models.HistoryEntry
+	if historyID == "" {
+		entry, err = s.history.Latest()
+	} else {
+		entry, err = s.history.Get(historyID)
+	}
 	if err != nil {
 		return fmt.Errorf("no snapshot to rollback to: %w", err)
 	}
+	if entry.Kind != models.HistoryKindSnapshot {
+		return fmt.Errorf("history entry %s is not a snapshot", entry.ID)
+	}
+	logStep(ctx, "restoring snapshot %s from %s", entry.ID, entry.AppliedAt.Format(time.RFC3339))
+	s.forgetApplied()
 
 	cmd := rollbackFromSnapshot(entry.Snapshot)
 	if err := cmd(); err != nil {
+		s.audit.Record(ctx, "rollback.failed", "ruleset", entry.ID, nil, map[string]string{"error": err.Error()})
 		return fmt.Errorf("rollback failed: %w", err)
 	}
+	s.audit.Record(ctx, "rollback", "ruleset", entry.ID, nil, entry)
+	logStep(ctx, "snapshot restored")
 
 	s.log.WithField("history_id", entry.ID).Info("rolled back to previous snapshot")
 	return nil
 }
 
+// ReconcileSchedules re-applies the last applied ruleset if a scheduler-mode
+// rule has entered or left its window since it was loaded. It goes through
+// the same lockout check, verification and history as an apply, with the
+// NAT rules and config that apply loaded. Until the first apply after
+// startup, or after a rollback or panic, there is nothing to reconcile: the
+// stored rules may hold edits that have not been applied.
+func (s *firewallService) ReconcileSchedules(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.refuseInPanic() != nil {
+		// The lockdown stays until it is released.
+		return nil
+	}
+
+	s.mu.Lock()
+	rules, payload, key := s.lastApplied, s.loadedPayload, s.scheduleKey
+	s.mu.Unlock()
+	if rules == nil {
+		return nil
+	}
+	now := time.Now()
+	if scheduleKey(rules, now) == key {
+		return nil
+	}
+
+	var natRules []*models.NATRule
+	var cfg *models.FirewallConfig
+	if payload != nil {
+		natRules, cfg = payload.natRules, payload.config
+	}
+	if err := s.applyRuleset(ctx, rules, natRules, cfg, now); err != nil {
+		s.audit.Record(ctx, "apply.schedule.failed", "ruleset", "", nil, map[string]string{"error": err.Error()})
+		return fmt.Errorf("apply scheduled rules to kernel: %w", err)
+	}
+	s.audit.Record(ctx, "apply.schedule", "ruleset", "", nil, s.compile(rules, now))
+
+	s.log.WithField("rule_count", len(rules)).Info("ruleset re-applied for schedule transition")
+	return nil
+}
+
+// compile returns the ruleset handed to the driver at time t: the
+// management allowlist followed by the rules whose schedule is in effect.
+func (s *firewallService) compile(rules []*models.Rule, t time.Time) []*models.Rule {
+	allowlist := s.guard.Allowlist()
+	compiled := make([]*models.Rule, 0, len(allowlist)+len(rules))
+	compiled = append(compiled, allowlist...)
+	return append(compiled, scheduledRules(rules, t)...)
+}
+
+func (s *firewallService) ListHistory(_ context.Context, limit int) ([]*models.HistoryEntry, error) {
+	return s.history.List(limit)
+}
+
 func (s *firewallService) GetCounters(_ context.Context) ([]*models.Counter, error) {
-	return s.driver.GetCounters()
+	return s.collector.Rules()
 }
 
 // rollbackFromSnapshot delegates to restoreSnapshot (defined in rollback.go).
@@ -243,6 +695,75 @@ func rollbackFromSnapshot(snapshot string) func() error {
 	return restoreSnapshot(snapshot)
 }
 
+// validateSchedule checks an optional rule schedule.
+func validateSchedule(sched *models.Schedule) error {
+	if sched == nil {
+		return nil
+	}
+	_, err := compileSchedule(sched)
+	return err
+}
+
+// logRateRe matches a log rate limit such as "5/minute".
+var logRateRe = regexp.MustCompile(`^[1-9][0-9]{0,3}/(second|minute|hour|day)$`)
+
+// validateLogOptions checks optional log options. They apply to LOG rules,
+// and to verdict rules that log before they decide.
+func validateLogOptions(opts *models.LogOptions, action models.Action) error {
+	if opts == nil {
+		return nil
+	}
+	switch action {
+	case models.ActionLOG, models.ActionACCEPT, models.ActionDROP, models.ActionREJECT:
+	default:
+		return fmt.Errorf("log options are not supported for action %s", action)
+	}
+
+	maxPrefix := firewall.MaxLogPrefix
+	if opts.NFLOGGroup != nil {
+		if *opts.NFLOGGroup < 0 || *opts.NFLOGGroup > 65535 {
+			return fmt.Errorf("invalid nflog group: %d", *opts.NFLOGGroup)
+		}
+		if opts.Level != "" {
+			return fmt.Errorf("log level does not apply to nflog")
+		}
+		maxPrefix = firewall.MaxNFLOGPrefix
+	}
+	if opts.Prefix != "" {
+		if len(opts.Prefix) > maxPrefix {
+			return fmt.Errorf("log prefix longer than %d characters", maxPrefix)
+		}
+		if !firewall.ValidLogPrefix(opts.Prefix) {
+			return fmt.Errorf("invalid log prefix: %q", opts.Prefix)
+		}
+	}
+	if _, ok := models.LogLevels[opts.Level]; opts.Level != "" && !ok {
+		return fmt.Errorf("invalid log level: %s", opts.Level)
+	}
+	if opts.Limit != "" && !logRateRe.MatchString(opts.Limit) {
+		return fmt.Errorf("invalid log limit: %s (want <n>/second, minute, hour or day)", opts.Limit)
+	}
+	if opts.Burst != 0 {
+		if opts.Limit == "" {
+			return fmt.Errorf("log burst requires a limit")
+		}
+		if opts.Burst < 1 || opts.Burst > 10000 {
+			return fmt.Errorf("invalid log burst: %d", opts.Burst)
+		}
+	}
+	return nil
+}
+
+// validateTags checks ownership tags used for scoped role bindings.
+func validateTags(tags []string) error {
+	for _, t := range tags {
+		if !validName(t) {
+			return fmt.Errorf("invalid tag: %s", t)
+		}
+	}
+	return nil
+}
+
 // validateDTO checks all field values against allowlists.
 func validateDTO(chain models.Chain, proto models.Protocol, action models.Action, src, dst, srcPort, dstPort string) error {
 	validChains := map[models.Chain]bool{
@@ -271,28 +792,82 @@ func validateDTO(chain models.Chain, proto models.Protocol, action models.Action
 	return nil
 }
 
+// interfaceNameRe matches the interface names the driver renders; a
+// trailing "+" matches every interface with that prefix.
+var interfaceNameRe = regexp.MustCompile(`^[A-Za-z0-9_+-]{1,15}$`)
+
+// validateRuleInterfaces checks a rule's interfaces against its chain:
+// packets in INPUT have no output interface and those in OUTPUT no input
+// interface.
+func validateRuleInterfaces(chain models.Chain, in, out string) error {
+	for _, name := range []string{in, out} {
+		if name != "" && !interfaceNameRe.MatchString(name) {
+			return fmt.Errorf("invalid interface name: %s", name)
+		}
+	}
+	if chain == models.ChainINPUT && out != "" {
+		return fmt.Errorf("INPUT rules cannot match an output interface")
+	}
+	if chain == models.ChainOUTPUT && in != "" {
+		return fmt.Errorf("OUTPUT rules cannot match an input interface")
+	}
+	return nil
+}
+
+// authorizeInterfaces checks perm on an object that matches on the named
+// interfaces: the caller needs it in the zone of every interface, or
+// through one of tags. An object on no interface, or on one that is not
+// managed here, is in no zone and needs a global or tag binding.
+func authorizeInterfaces(ctx context.Context, ifaces repository.InterfaceRepository, perm auth.Permission, tags []string, names ...string) error {
+	zoneOf := make(map[string]string)
+	if ifaces != nil {
+		list, err := ifaces.List()
+		if err != nil {
+			return fmt.Errorf("load interfaces: %w", err)
+		}
+		for _, iface := range list {
+			zoneOf[iface.Name] = iface.Zone
+		}
+	}
+
+	var zones []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if zone := zoneOf[name]; !seen[zone] {
+			seen[zone] = true
+			zones = append(zones, zone)
+		}
+	}
+	if len(zones) == 0 {
+		zones = []string{""}
+	}
+	for _, zone := range zones {
+		if err := auth.Authorize(ctx, perm, zone, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *firewallService) GetInterfaces(_ context.Context) ([]*models.Interface, error) {
 	return s.driver.GetInterfaces()
 }
 
 func (s *firewallService) GetInterfaceCounters(_ context.Context, iface string) (*models.InterfaceCounters, error) {
-	return s.driver.GetInterfaceCounters(iface)
+	return s.collector.Interface(iface)
 }
 
-func (s *firewallService) GetAggregatedCounters(ctx context.Context) (*models.InterfaceCounters, error) {
-	interfaces, err := s.driver.GetInterfaces()
+func (s *firewallService) GetAggregatedCounters(_ context.Context) (*models.InterfaceCounters, error) {
+	interfaces, err := s.collector.Interfaces()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get interfaces for aggregation: %w", err)
+		return nil, fmt.Errorf("failed to get interface counters for aggregation: %w", err)
 	}
 
 	totalCounters := &models.InterfaceCounters{}
-
-	for _, iface := range interfaces {
-		counters, err := s.driver.GetInterfaceCounters(iface.Name)
-		if err != nil {
-			s.log.WithError(err).WithField("interface", iface.Name).Warn("failed to get counters for interface")
-			continue
-		}
+	for _, counters := range interfaces {
 		totalCounters.In.Packets += counters.In.Packets
 		totalCounters.In.Bytes += counters.In.Bytes
 		totalCounters.Out.Packets += counters.Out.Packets
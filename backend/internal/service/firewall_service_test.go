@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeDriver records the rulesets handed to it and verifies them as loaded.
+type fakeDriver struct {
+	applies  [][]*models.Rule
+	applyErr error // returned by Apply when set
+	verifies int
+}
+
+func (d *fakeDriver) Load() (string, error)               { return "*filter\nCOMMIT\n", nil }
+func (d *fakeDriver) Lockdown(allow []*models.Rule) error { return nil }
+func (d *fakeDriver) Apply(rules []*models.Rule) error {
+	if d.applyErr != nil {
+		return d.applyErr
+	}
+	d.applies = append(d.applies, rules)
+	return nil
+}
+func (d *fakeDriver) Verify(rules []*models.Rule, natRules []*models.NATRule, config *models.FirewallConfig) (*models.VerificationReport, error) {
+	d.verifies++
+	return &models.VerificationReport{OK: true, Rules: len(rules)}, nil
+}
+func (d *fakeDriver) Fingerprint(rules []*models.Rule, natRules []*models.NATRule, config *models.FirewallConfig) string {
+	var ids []string
+	for _, r := range rules {
+		ids = append(ids, r.ID)
+	}
+	return fmt.Sprint(ids)
+}
+func (d *fakeDriver) GetCounters() ([]*models.Counter, error)         { return nil, nil }
+func (d *fakeDriver) GetInterfaces() ([]*models.Interface, error)     { return nil, nil }
+func (d *fakeDriver) ApplyConfig(config *models.FirewallConfig) error { return nil }
+func (d *fakeDriver) ApplyNAT(natRules []*models.NATRule) error       { return nil }
+func (d *fakeDriver) GetInterfaceCounters(string) (*models.InterfaceCounters, error) {
+	return nil, nil
+}
+func (d *fakeDriver) GetLinkCounters() (map[string]*models.InterfaceCounters, error) {
+	return nil, nil
+}
+
+type fakeRuleRepo struct{ rules []*models.Rule }
+
+func (r *fakeRuleRepo) List() ([]*models.Rule, error) { return r.rules, nil }
+func (r *fakeRuleRepo) GetByID(id string) (*models.Rule, error) {
+	for _, rule := range r.rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, errors.New("rule not found: " + id)
+}
+func (r *fakeRuleRepo) Create(rule *models.Rule) error { r.rules = append(r.rules, rule); return nil }
+func (r *fakeRuleRepo) Update(rule *models.Rule) error { return nil }
+func (r *fakeRuleRepo) Delete(id string) error         { return nil }
+
+type fakeHistoryRepo struct{ entries []*models.HistoryEntry }
+
+func (h *fakeHistoryRepo) Save(e *models.HistoryEntry) error {
+	h.entries = append(h.entries, e)
+	return nil
+}
+func (h *fakeHistoryRepo) Get(id string) (*models.HistoryEntry, error) {
+	for _, e := range h.entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, errors.New("history entry not found: " + id)
+}
+func (h *fakeHistoryRepo) Latest() (*models.HistoryEntry, error) {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].Kind == models.HistoryKindSnapshot {
+			return h.entries[i], nil
+		}
+	}
+	return nil, errors.New("no history")
+}
+func (h *fakeHistoryRepo) List(limit int) ([]*models.HistoryEntry, error) { return h.entries, nil }
+func (h *fakeHistoryRepo) Count() (int, error)                            { return len(h.entries), nil }
+
+func newTestFirewallService(rules ...*models.Rule) (*firewallService, *fakeDriver, *fakeRuleRepo, *fakeHistoryRepo) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	driver := &fakeDriver{}
+	repo := &fakeRuleRepo{rules: rules}
+	history := &fakeHistoryRepo{}
+	s := NewFirewallService(repo, history, driver, log).(*firewallService)
+	return s, driver, repo, history
+}
+
+func TestReconcileSchedulesNeedsAnApply(t *testing.T) {
+	s, driver, _, _ := newTestFirewallService(&models.Rule{ID: "a", Enabled: true})
+
+	// Before any apply the kernel is not known to hold the stored rules,
+	// so a transition must not push them.
+	s.scheduleKey = "stale"
+	if err := s.ReconcileSchedules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.applies) != 0 {
+		t.Fatalf("reconcile before apply loaded %d rulesets", len(driver.applies))
+	}
+}
+
+func TestReconcileSchedulesReappliesLastApplied(t *testing.T) {
+	sched := &models.Schedule{Mode: models.ScheduleModeScheduler}
+	s, driver, repo, history := newTestFirewallService(
+		&models.Rule{ID: "a", Enabled: true},
+		&models.Rule{ID: "s", Enabled: true, Schedule: sched},
+	)
+	ctx := context.Background()
+	if err := s.ApplyRules(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// An edit that has not been applied stays out of the kernel.
+	repo.rules = append(repo.rules, &models.Rule{ID: "b", Enabled: true})
+	if err := s.ReconcileSchedules(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.applies) != 1 {
+		t.Fatalf("reconcile without a transition loaded a ruleset")
+	}
+
+	// The window of s closes.
+	sched.EndDate = "2000-01-01"
+	verified := driver.verifies
+	if err := s.ReconcileSchedules(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.applies) != 2 {
+		t.Fatalf("transition loaded %d rulesets, want 2", len(driver.applies))
+	}
+	if got := driver.applies[1]; len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("transition loaded %v, want the last applied rule a", got)
+	}
+	if driver.verifies == verified {
+		t.Error("transition was not verified")
+	}
+	if len(history.entries) != 2 {
+		t.Errorf("history has %d entries, want a snapshot per load", len(history.entries))
+	}
+}
+
+func TestForgetAppliedStopsReconcile(t *testing.T) {
+	s, driver, _, _ := newTestFirewallService(&models.Rule{ID: "a", Enabled: true})
+	ctx := context.Background()
+	if err := s.ApplyRules(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// As after a rollback or panic.
+	s.forgetApplied()
+	if h := s.LoadedPayloadHash(ctx); h != "" {
+		t.Errorf("loaded hash %q kept", h)
+	}
+	if err := s.ReconcileSchedules(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.applies) != 1 {
+		t.Fatalf("reconcile after rollback re-applied the replaced rules")
+	}
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/network"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrLockout is returned when a change would cut off the management session
+// that requested it.
+var ErrLockout = errors.New("change would lock out the current management session")
+
+// ClientConn describes the TCP connection an API request arrived on, as the
+// kernel sees it: the peer address (not X-Forwarded-For) and the local
+// address and port the server accepted it on.
+type ClientConn struct {
+	ClientIP   string
+	ClientPort int
+	LocalIP    string
+	LocalPort  int
+	// Interface is the interface the connection arrives on, if known. The
+	// guard fills it in from LocalIP.
+	Interface string
+	// Override lets through a change the check would refuse; the override
+	// is audited.
+	Override bool
+}
+
+type clientConnKey struct{}
+
+// WithClientConn attaches the caller's connection to ctx so changes can be
+// checked against it.
+func WithClientConn(ctx context.Context, conn ClientConn) context.Context {
+	return context.WithValue(ctx, clientConnKey{}, conn)
+}
+
+func clientConnFrom(ctx context.Context) (ClientConn, bool) {
+	conn, ok := ctx.Value(clientConnKey{}).(ClientConn)
+	return conn, ok && conn.ClientIP != ""
+}
+
+// LockoutGuard keeps configuration changes from cutting off the API. It
+// owns the management allowlist, which is injected at the top of INPUT on
+// every apply, and checks rulesets and interface changes against the
+// connection of the request making them. Requests without a connection in
+// their context (the scheduler, background jobs) are not checked.
+type LockoutGuard struct {
+	net       network.Driver
+	audit     Auditor
+	allowlist []*models.Rule
+	log       *logrus.Logger
+}
+
+// NewLockoutGuard builds the allowlist from source CIDRs and the TCP ports
+// the API listens on. Each CIDR is allowed to every port.
+func NewLockoutGuard(netDriver network.Driver, cidrs, ports []string, audit Auditor, log *logrus.Logger) (*LockoutGuard, error) {
+	g := &LockoutGuard{net: netDriver, audit: audit, log: log}
+	for _, port := range ports {
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid management port: %s", port)
+		}
+	}
+	now := time.Now()
+	for i, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			if net.ParseIP(cidr) == nil {
+				return nil, fmt.Errorf("invalid management allowlist entry: %s", cidr)
+			}
+		}
+		for j, port := range ports {
+			g.allowlist = append(g.allowlist, &models.Rule{
+				ID:        fmt.Sprintf("mgmt-allowlist-%d-%d", i, j),
+				Chain:     models.ChainINPUT,
+				Protocol:  models.ProtocolTCP,
+				Src:       cidr,
+				DstPort:   port,
+				Action:    models.ActionACCEPT,
+				Enabled:   true,
+				Comment:   "fwmg management allowlist",
+				Position:  -1,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+		}
+	}
+	return g, nil
+}
+
+// Allowlist returns the rules that precede the user's INPUT rules.
+func (g *LockoutGuard) Allowlist() []*models.Rule {
+	if g == nil {
+		return nil
+	}
+	return g.allowlist
+}
+
+// CheckRules simulates the caller's next packet through the compiled INPUT
+// chain and refuses with ErrLockout if it would be dropped or rejected.
+// compiled holds scheduled rules whether or not they are in their window at
+// at; see firstMatch.
+func (g *LockoutGuard) CheckRules(ctx context.Context, compiled []*models.Rule, at time.Time) error {
+	if g == nil {
+		return nil
+	}
+	conn, ok := clientConnFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return g.enforce(ctx, conn, "ruleset", "", g.checkRules(conn, compiled, at))
+}
+
+func (g *LockoutGuard) checkRules(conn ClientConn, compiled []*models.Rule, at time.Time) error {
+	if iface := g.arrivalInterface(net.ParseIP(conn.LocalIP)); iface != nil {
+		conn.Interface = iface.Name
+	}
+	r := firstMatch(conn, compiled, at)
+	if r == nil || r.Action == models.ActionACCEPT {
+		// The INPUT policy rendered by the driver is ACCEPT.
+		return nil
+	}
+	return fmt.Errorf("%w: rule %s would %s traffic from %s to %s",
+		ErrLockout, r.ID, strings.ToLower(string(r.Action)), conn.ClientIP,
+		net.JoinHostPort(conn.LocalIP, strconv.Itoa(conn.LocalPort)))
+}
+
+// PanicAllowlist returns the allow rules for the panic lockdown: the
+// management allowlist, plus the caller's address on the given port if the
+// allowlist does not already admit it, so whoever pulls the brake can still
+// reconnect. An empty callerIP adds nothing.
+func (g *LockoutGuard) PanicAllowlist(callerIP string, port int) []*models.Rule {
+	allow := g.Allowlist()
+	if net.ParseIP(callerIP) == nil || port == 0 {
+		return allow
+	}
+	now := time.Now()
+	for _, r := range allow {
+		if addrMatches(r.Src, net.ParseIP(callerIP), true) && portMatches(r.DstPort, port) {
+			return allow
+		}
+	}
+	return append(append([]*models.Rule{}, allow...), &models.Rule{
+		ID:        "mgmt-panic-caller",
+		Chain:     models.ChainINPUT,
+		Protocol:  models.ProtocolTCP,
+		Src:       callerIP,
+		DstPort:   strconv.Itoa(port),
+		Action:    models.ActionACCEPT,
+		Enabled:   true,
+		Comment:   "fwmg panic caller",
+		Position:  -1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// firstMatch returns the first INPUT rule that decides the fate of the
+// caller's next packet (ACCEPT, DROP or REJECT), or nil if none does.
+// Scheduled DROP and REJECT rules count whether or not their window is open
+// at at: when it opens, the kernel time match or the rule scheduler loads
+// them without another check. A scheduled ACCEPT only counts inside its
+// window.
+func firstMatch(conn ClientConn, compiled []*models.Rule, at time.Time) *models.Rule {
+	client := net.ParseIP(conn.ClientIP)
+	local := net.ParseIP(conn.LocalIP)
+	if client == nil || local == nil {
+		return nil
+	}
+	for _, r := range compiled {
+		// Only certain matches count for ACCEPT; DROP and REJECT also stop
+		// on rules whose addresses cannot be evaluated here.
+		strict := r.Action == models.ActionACCEPT
+		active := r.Enabled
+		if strict {
+			active = ruleActiveAt(r, at)
+		}
+		if r.Chain != models.ChainINPUT || !active || !matchesConn(r, client, local, conn, strict) {
+			continue
+		}
+		switch r.Action {
+		case models.ActionACCEPT, models.ActionDROP, models.ActionREJECT:
+			return r
+		}
+		// LOG does not terminate; keep walking the chain.
+	}
+	return nil
+}
+
+// CheckInterfaceChange refuses disabling or re-addressing the interface the
+// caller's connection arrives on. ApplyConfig replaces the primary IPv4
+// address, so a session on that address must keep it.
+func (g *LockoutGuard) CheckInterfaceChange(ctx context.Context, id, name, ip string, enabled bool) error {
+	if g == nil {
+		return nil
+	}
+	conn, ok := clientConnFrom(ctx)
+	if !ok {
+		return nil
+	}
+	local := net.ParseIP(conn.LocalIP)
+	iface := g.arrivalInterface(local)
+	if iface == nil || iface.Name != name {
+		return nil
+	}
+	var err error
+	if !enabled {
+		err = fmt.Errorf("%w: the session arrives on %s, which would be disabled", ErrLockout, name)
+	} else if primary := net.ParseIP(iface.IP); ip != "" && primary != nil && primary.Equal(local) {
+		if newIP := net.ParseIP(ip); newIP == nil || !newIP.Equal(local) {
+			err = fmt.Errorf("%w: the session uses %s on %s, which would be removed", ErrLockout, conn.LocalIP, name)
+		}
+	}
+	return g.enforce(ctx, conn, "interface", id, err)
+}
+
+// CheckAddressRemoval refuses removing the address the caller's connection
+// arrives on.
+func (g *LockoutGuard) CheckAddressRemoval(ctx context.Context, id, name string, ip net.IP) error {
+	if g == nil {
+		return nil
+	}
+	conn, ok := clientConnFrom(ctx)
+	if !ok {
+		return nil
+	}
+	local := net.ParseIP(conn.LocalIP)
+	if local == nil || !local.Equal(ip) {
+		return nil
+	}
+	if iface := g.arrivalInterface(local); iface == nil || iface.Name != name {
+		return nil
+	}
+	err := fmt.Errorf("%w: the session uses %s on %s, which would be removed", ErrLockout, conn.LocalIP, name)
+	return g.enforce(ctx, conn, "interface", id, err)
+}
+
+// enforce returns a lockout error unless the caller overrode the check, in
+// which case the override is audited and the change allowed.
+func (g *LockoutGuard) enforce(ctx context.Context, conn ClientConn, resource, id string, err error) error {
+	if err == nil {
+		return nil
+	}
+	fields := logrus.Fields{
+		"client": conn.ClientIP,
+		"local":  net.JoinHostPort(conn.LocalIP, strconv.Itoa(conn.LocalPort)),
+	}
+	if iface := g.arrivalInterface(net.ParseIP(conn.LocalIP)); iface != nil {
+		fields["interface"] = iface.Name
+	}
+	if !conn.Override {
+		g.log.WithFields(fields).WithError(err).Warn("change refused to protect the management session")
+		return err
+	}
+	g.log.WithFields(fields).WithError(err).Warn("lockout check overridden")
+	g.audit.Record(ctx, "lockout.override", resource, id, nil, map[string]string{"reason": err.Error()})
+	return nil
+}
+
+// arrivalInterface returns the interface holding local among its IPv4 and
+// IPv6 addresses, or nil if none does (loopback is not listed by the network
+// driver).
+func (g *LockoutGuard) arrivalInterface(local net.IP) *network.Info {
+	if local == nil {
+		return nil
+	}
+	ifaces, err := g.net.GetInterfaces()
+	if err != nil {
+		g.log.WithError(err).Warn("could not list interfaces for lockout check")
+		return nil
+	}
+	for i := range ifaces {
+		if ifaces[i].HasIP(local) {
+			return &ifaces[i]
+		}
+	}
+	return nil
+}
+
+// matchesConn reports whether a TCP packet of conn matches r.
+func matchesConn(r *models.Rule, client, local net.IP, conn ClientConn, strict bool) bool {
+	if r.Protocol != models.ProtocolTCP && r.Protocol != models.ProtocolAll {
+		return false
+	}
+	return ifaceMatches(r.InInterface, conn.Interface, strict) &&
+		addrMatches(r.Src, client, strict) &&
+		addrMatches(r.Dst, local, strict) &&
+		portMatches(r.SrcPort, conn.ClientPort) &&
+		portMatches(r.DstPort, conn.LocalPort)
+}
+
+// addrMatches matches an iptables -s/-d value: empty, an address or a CIDR.
+// Anything else (a hostname resolved by iptables at load time) matches
+// unless strict is set.
+func addrMatches(spec string, ip net.IP, strict bool) bool {
+	if spec == "" {
+		return true
+	}
+	if _, n, err := net.ParseCIDR(spec); err == nil {
+		return n.Contains(ip)
+	}
+	if addr := net.ParseIP(spec); addr != nil {
+		return addr.Equal(ip)
+	}
+	return !strict
+}
+
+// ifaceMatches matches an iptables -i value against the interface name:
+// empty, a name, or a prefix ending in "+". An unknown interface matches
+// unless strict is set.
+func ifaceMatches(spec, name string, strict bool) bool {
+	if spec == "" {
+		return true
+	}
+	if name == "" {
+		return !strict
+	}
+	if prefix, ok := strings.CutSuffix(spec, "+"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return spec == name
+}
+
+// portMatches matches an iptables port value: "n" or "lo:hi". Values the
+// driver would drop as invalid render no port match, so they match any port.
+func portMatches(spec string, port int) bool {
+	lo, hi, found := strings.Cut(spec, ":")
+	from, err := strconv.Atoi(lo)
+	if err != nil {
+		return true
+	}
+	to := from
+	if found {
+		if to, err = strconv.Atoi(hi); err != nil {
+			return true
+		}
+	}
+	return port >= from && port <= to
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+func TestFirstMatchSchedules(t *testing.T) {
+	conn := ClientConn{ClientIP: "192.0.2.10", LocalIP: "192.0.2.1", LocalPort: 8080, Interface: "eth0"}
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) // a Monday
+
+	closed := func(mode models.ScheduleMode) *models.Schedule {
+		return &models.Schedule{Mode: mode, StartTime: "22:00", EndTime: "23:00"}
+	}
+	open := &models.Schedule{Mode: models.ScheduleModeScheduler, StartTime: "08:00", EndTime: "18:00"}
+	rule := func(id string, action models.Action, sched *models.Schedule) *models.Rule {
+		return &models.Rule{ID: id, Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "8080", Action: action, Enabled: true, Schedule: sched}
+	}
+
+	onIface := func(r *models.Rule, iface string) *models.Rule {
+		r.InInterface = iface
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		rules []*models.Rule
+		want  string
+	}{
+		{"unscheduled drop", []*models.Rule{rule("d", models.ActionDROP, nil)}, "d"},
+		{"scheduler drop outside window", []*models.Rule{rule("d", models.ActionDROP, closed(models.ScheduleModeScheduler))}, "d"},
+		{"kernel reject outside window", []*models.Rule{rule("r", models.ActionREJECT, closed(models.ScheduleModeKernel))}, "r"},
+		{"disabled drop", []*models.Rule{{ID: "d", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, Action: models.ActionDROP}}, ""},
+		{"accept inside window", []*models.Rule{rule("a", models.ActionACCEPT, open), rule("d", models.ActionDROP, nil)}, "a"},
+		{"accept outside window", []*models.Rule{rule("a", models.ActionACCEPT, closed(models.ScheduleModeScheduler)), rule("d", models.ActionDROP, nil)}, "d"},
+		{"other port", []*models.Rule{{ID: "d", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, DstPort: "22", Action: models.ActionDROP, Enabled: true}}, ""},
+		{"drop on arrival interface", []*models.Rule{onIface(rule("d", models.ActionDROP, nil), "eth0")}, "d"},
+		{"drop on interface prefix", []*models.Rule{onIface(rule("d", models.ActionDROP, nil), "eth+")}, "d"},
+		{"drop on other interface", []*models.Rule{onIface(rule("d", models.ActionDROP, nil), "wg0")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if r := firstMatch(conn, tt.rules, at); r != nil {
+				got = r.ID
+			}
+			if got != tt.want {
+				t.Errorf("firstMatch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstMatchUnknownInterface(t *testing.T) {
+	// Without the arrival interface, a rule bound to one may or may not
+	// apply: an ACCEPT is not relied on and a DROP is assumed to hit.
+	conn := ClientConn{ClientIP: "192.0.2.10", LocalIP: "192.0.2.1", LocalPort: 8080}
+	rules := []*models.Rule{
+		{ID: "a", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, InInterface: "eth0", Action: models.ActionACCEPT, Enabled: true},
+		{ID: "d", Chain: models.ChainINPUT, Protocol: models.ProtocolTCP, InInterface: "wg0", Action: models.ActionDROP, Enabled: true},
+	}
+	if r := firstMatch(conn, rules, time.Now()); r == nil || r.ID != "d" {
+		t.Errorf("firstMatch = %v, want d", r)
+	}
+}
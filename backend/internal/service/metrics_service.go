@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/firewall"
+	"github.com/firewall-manager/backend/internal/metrics"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// MetricsService renders the live counters and operational state for
+// Prometheus.
+type MetricsService interface {
+	Write(ctx context.Context, w io.Writer) error
+}
+
+type metricsService struct {
+	fw        FirewallService
+	rules     repository.RuleRepository
+	natRules  repository.NATRuleRepository
+	history   repository.HistoryRepository
+	collector *CounterCollector
+	log       *logrus.Logger
+}
+
+func NewMetricsService(
+	fw FirewallService,
+	rules repository.RuleRepository,
+	natRules repository.NATRuleRepository,
+	history repository.HistoryRepository,
+	collector *CounterCollector,
+	log *logrus.Logger,
+) MetricsService {
+	return &metricsService{
+		fw:        fw,
+		rules:     rules,
+		natRules:  natRules,
+		history:   history,
+		collector: collector,
+		log:       log,
+	}
+}
+
+// ruleMetric is the traffic of one stored rule in one table and chain.
+type ruleMetric struct {
+	id, table, chain, action string
+	packets, bytes           uint64
+}
+
+// Write renders every metric. Parts that cannot be read are left out and
+// logged, so one failing source does not break the whole scrape.
+func (s *metricsService) Write(ctx context.Context, out io.Writer) error {
+	w := metrics.NewWriter(out)
+	snap := s.collector.Snapshot()
+
+	w.Family("fwmg_counters_up", metrics.Gauge, "Whether the last counter collection from the source succeeded.")
+	w.Sample("fwmg_counters_up", metrics.Labels{"source", "rules"}, boolValue(snap.RulesErr == nil))
+	w.Sample("fwmg_counters_up", metrics.Labels{"source", "interfaces"}, boolValue(snap.LinksErr == nil))
+
+	if snap.RulesErr == nil {
+		s.writeRules(w, snap.Rules)
+	}
+	if snap.LinksErr == nil {
+		writeInterfaces(w, snap.Interfaces)
+	}
+
+	apply, rollback := s.fw.OperationStats(ctx)
+	w.Family("fwmg_operations_total", metrics.Counter, "Applies and rollbacks since startup, by result.")
+	for _, op := range []struct {
+		name  string
+		stats OperationStats
+	}{{"apply", apply}, {"rollback", rollback}} {
+		w.Sample("fwmg_operations_total", metrics.Labels{"operation", op.name, "result", "success"}, float64(op.stats.Succeeded))
+		w.Sample("fwmg_operations_total", metrics.Labels{"operation", op.name, "result", "failure"}, float64(op.stats.Failed))
+	}
+	w.Family("fwmg_operation_duration_seconds", metrics.Histogram, "Duration of applies and rollbacks since startup.")
+	w.Histogram("fwmg_operation_duration_seconds", metrics.Labels{"operation", "apply"}, apply.Durations)
+	w.Histogram("fwmg_operation_duration_seconds", metrics.Labels{"operation", "rollback"}, rollback.Durations)
+
+	w.Family("fwmg_last_apply_timestamp_seconds", metrics.Gauge, "Unix time of the last successful apply since startup, 0 if none.")
+	lastApply := 0.0
+	if !apply.LastSuccess.IsZero() {
+		lastApply = float64(apply.LastSuccess.UnixNano()) / 1e9
+	}
+	w.Sample("fwmg_last_apply_timestamp_seconds", nil, lastApply)
+
+	if drift, err := s.fw.Drift(ctx); err != nil {
+		s.log.WithError(err).Warn("drift check for metrics failed")
+	} else {
+		w.Family("fwmg_drift_status", metrics.Gauge, "Whether the kernel holds the payload of the last verified apply; 1 for the current state.")
+		for _, state := range []models.DriftState{models.DriftInSync, models.DriftDrifted, models.DriftUnknown} {
+			w.Sample("fwmg_drift_status", metrics.Labels{"state", string(state)}, boolValue(drift.State == state))
+		}
+	}
+
+	if n, err := s.history.Count(); err != nil {
+		s.log.WithError(err).Warn("history count for metrics failed")
+	} else {
+		w.Family("fwmg_history_entries", metrics.Gauge, "Number of entries in the apply history.")
+		w.Sample("fwmg_history_entries", nil, float64(n))
+	}
+
+	return w.Flush()
+}
+
+// writeRules writes the counters of stored rules, by the tag in their
+// comment, and of chain policies.
+func (s *metricsService) writeRules(w *metrics.Writer, counters []*models.Counter) {
+	type ruleInfo struct{ id, action string }
+	tags := make(map[string]ruleInfo)
+	if rules, err := s.rules.List(); err != nil {
+		s.log.WithError(err).Warn("list rules for metrics failed")
+	} else {
+		for _, r := range rules {
+			tags[firewall.RuleTag(r.ID)] = ruleInfo{r.ID, string(r.Action)}
+		}
+	}
+	if natRules, err := s.natRules.List(); err != nil {
+		s.log.WithError(err).Warn("list nat rules for metrics failed")
+	} else {
+		for _, r := range natRules {
+			tags[firewall.RuleTag(r.ID)] = ruleInfo{r.ID, strings.ToUpper(r.Type)}
+		}
+	}
+
+	byKey := make(map[string]*ruleMetric)
+	var keys []string
+	var policies []*models.Counter
+	for _, c := range counters {
+		if c.Rule == "policy" {
+			policies = append(policies, c)
+			continue
+		}
+		info, ok := tags[c.Tag]
+		if c.Tag == "" || !ok {
+			continue
+		}
+		key := info.id + "/" + c.Table + "/" + string(c.Chain)
+		m, ok := byKey[key]
+		if !ok {
+			m = &ruleMetric{id: info.id, table: c.Table, chain: string(c.Chain), action: info.action}
+			byKey[key] = m
+			keys = append(keys, key)
+		}
+		m.packets += c.Packets
+		m.bytes += c.Bytes
+	}
+	sort.Strings(keys)
+
+	w.Family("fwmg_rule_packets_total", metrics.Counter, "Packets matched by a rule since it was last loaded.")
+	for _, k := range keys {
+		m := byKey[k]
+		w.Sample("fwmg_rule_packets_total", m.labels(), float64(m.packets))
+	}
+	w.Family("fwmg_rule_bytes_total", metrics.Counter, "Bytes matched by a rule since it was last loaded.")
+	for _, k := range keys {
+		m := byKey[k]
+		w.Sample("fwmg_rule_bytes_total", m.labels(), float64(m.bytes))
+	}
+
+	w.Family("fwmg_chain_policy_packets_total", metrics.Counter, "Packets that reached the end of a built-in chain and took its policy.")
+	for _, c := range policies {
+		w.Sample("fwmg_chain_policy_packets_total", metrics.Labels{"table", c.Table, "chain", string(c.Chain)}, float64(c.Packets))
+	}
+	w.Family("fwmg_chain_policy_bytes_total", metrics.Counter, "Bytes that reached the end of a built-in chain and took its policy.")
+	for _, c := range policies {
+		w.Sample("fwmg_chain_policy_bytes_total", metrics.Labels{"table", c.Table, "chain", string(c.Chain)}, float64(c.Bytes))
+	}
+}
+
+func (m *ruleMetric) labels() metrics.Labels {
+	return metrics.Labels{"rule_id", m.id, "table", m.table, "chain", m.chain, "action", m.action}
+}
+
+// writeInterfaces writes the link statistics of every interface.
+func writeInterfaces(w *metrics.Writer, ifaces map[string]*models.InterfaceCounters) {
+	names := make([]string, 0, len(ifaces))
+	for name := range ifaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := []struct {
+		name, help string
+		value      func(c *models.InterfaceCounters) uint64
+	}{
+		{"fwmg_interface_receive_packets_total", "Packets received by an interface.", func(c *models.InterfaceCounters) uint64 { return c.In.Packets }},
+		{"fwmg_interface_receive_bytes_total", "Bytes received by an interface.", func(c *models.InterfaceCounters) uint64 { return c.In.Bytes }},
+		{"fwmg_interface_transmit_packets_total", "Packets sent by an interface.", func(c *models.InterfaceCounters) uint64 { return c.Out.Packets }},
+		{"fwmg_interface_transmit_bytes_total", "Bytes sent by an interface.", func(c *models.InterfaceCounters) uint64 { return c.Out.Bytes }},
+		{"fwmg_interface_dropped_packets_total", "Packets an interface dropped on receive and transmit.", func(c *models.InterfaceCounters) uint64 { return c.Drop.Packets }},
+	}
+	for _, f := range families {
+		w.Family(f.name, metrics.Counter, f.help)
+		for _, name := range names {
+			w.Sample(f.name, metrics.Labels{"interface", name}, float64(f.value(ifaces[name])))
+		}
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
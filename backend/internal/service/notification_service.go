@@ -0,0 +1,522 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/notify"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidNotificationSink is returned for sinks that fail validation.
+var ErrInvalidNotificationSink = errors.New("invalid notification sink")
+
+const (
+	defaultNotificationAttempts = 3
+	maxNotificationAttempts     = 10
+	notificationRetryDelay      = 5 * time.Second
+	maxNotificationRetryDelay   = 5 * time.Minute
+	// maxConcurrentDeliveries bounds the deliveries in flight; the rest
+	// wait their turn.
+	maxConcurrentDeliveries = 8
+	// notificationDeliveriesKept is how much of the delivery log is kept.
+	notificationDeliveriesKept = 10000
+	defaultDeliveryLimit       = 100
+	maxDeliveryLimit           = 1000
+	// maskedSecret stands in for secrets in responses. Sending it back
+	// keeps the stored value.
+	maskedSecret = "********"
+)
+
+// DefaultNotificationEvents are the event types a sink subscribes to when
+// none are given: failed applies and schedule re-applies, rollbacks, panic
+// lockdowns, failed jobs, drift and alerts.
+var DefaultNotificationEvents = []string{"apply.failed", "apply.schedule.failed", "rollback", "panic", "job.failed", "drift", "alert"}
+
+// NotificationSinkDTO is the input for creating or replacing a sink
+type NotificationSinkDTO struct {
+	Name    string                        `json:"name" binding:"required"`
+	Type    models.NotificationSinkType   `json:"type" binding:"required"`
+	Enabled *bool                         `json:"enabled"`
+	Events  []string                      `json:"events"`
+	Config  models.NotificationSinkConfig `json:"config"`
+}
+
+// NotificationService manages notification sinks and delivers the events
+// they subscribe to.
+type NotificationService interface {
+	ListSinks(ctx context.Context) ([]*models.NotificationSink, error)
+	GetSink(ctx context.Context, id string) (*models.NotificationSink, error)
+	CreateSink(ctx context.Context, dto NotificationSinkDTO) (*models.NotificationSink, error)
+	UpdateSink(ctx context.Context, id string, dto NotificationSinkDTO) (*models.NotificationSink, error)
+	DeleteSink(ctx context.Context, id string) error
+	// TestSink sends a "notification.test" event to the sink once, whether
+	// or not it is enabled or subscribed, and returns the delivery.
+	TestSink(ctx context.Context, id string) (*models.NotificationDelivery, error)
+
+	ListDeliveries(ctx context.Context, filter models.NotificationDeliveryFilter) ([]*models.NotificationDelivery, error)
+
+	Run(ctx context.Context)
+}
+
+type notificationService struct {
+	repo   repository.NotificationRepository
+	events *EventBus
+	audit  Auditor
+	log    *logrus.Logger
+
+	slots chan struct{} // one per delivery in flight
+}
+
+func NewNotificationService(
+	repo repository.NotificationRepository,
+	events *EventBus,
+	audit Auditor,
+	log *logrus.Logger,
+) NotificationService {
+	return &notificationService{
+		repo:   repo,
+		events: events,
+		audit:  audit,
+		log:    log,
+		slots:  make(chan struct{}, maxConcurrentDeliveries),
+	}
+}
+
+func (s *notificationService) ListSinks(_ context.Context) ([]*models.NotificationSink, error) {
+	sinks, err := s.repo.ListSinks()
+	if err != nil {
+		return nil, err
+	}
+	for i, sink := range sinks {
+		sinks[i] = maskSink(sink)
+	}
+	return sinks, nil
+}
+
+func (s *notificationService) GetSink(_ context.Context, id string) (*models.NotificationSink, error) {
+	sink, err := s.repo.GetSink(id)
+	if err != nil {
+		return nil, err
+	}
+	return maskSink(sink), nil
+}
+
+func (s *notificationService) CreateSink(ctx context.Context, dto NotificationSinkDTO) (*models.NotificationSink, error) {
+	now := time.Now().UTC()
+	sink := &models.NotificationSink{ID: uuid.New().String(), CreatedAt: now, UpdatedAt: now}
+	if err := applyNotificationSinkDTO(sink, dto); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSink(sink); err != nil {
+		return nil, err
+	}
+	masked := maskSink(sink)
+	s.audit.Record(ctx, "notification-sink.create", "notification-sink", sink.ID, nil, masked)
+	return masked, nil
+}
+
+func (s *notificationService) UpdateSink(ctx context.Context, id string, dto NotificationSinkDTO) (*models.NotificationSink, error) {
+	sink, err := s.repo.GetSink(id)
+	if err != nil {
+		return nil, err
+	}
+	before := maskSink(sink)
+	// A masked secret in the input means "unchanged".
+	if dto.Config.Secret == maskedSecret {
+		dto.Config.Secret = sink.Config.Secret
+	}
+	if dto.Config.Password == maskedSecret {
+		dto.Config.Password = sink.Config.Password
+	}
+	if err := applyNotificationSinkDTO(sink, dto); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSink(sink); err != nil {
+		return nil, err
+	}
+	masked := maskSink(sink)
+	s.audit.Record(ctx, "notification-sink.update", "notification-sink", sink.ID, before, masked)
+	return masked, nil
+}
+
+func (s *notificationService) DeleteSink(ctx context.Context, id string) error {
+	sink, err := s.repo.GetSink(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteSink(id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "notification-sink.delete", "notification-sink", id, maskSink(sink), nil)
+	return nil
+}
+
+// applyNotificationSinkDTO validates dto and copies it onto sink, keeping
+// only the settings of the sink's type.
+func applyNotificationSinkDTO(sink *models.NotificationSink, dto NotificationSinkDTO) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidNotificationSink, fmt.Sprintf(format, args...))
+	}
+	if strings.TrimSpace(dto.Name) == "" {
+		return invalid("name is required")
+	}
+
+	var events []string
+	for _, t := range dto.Events {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		for _, r := range t {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+				return invalid("invalid event type: %s", t)
+			}
+		}
+		if t == "counters" || strings.HasPrefix(t, "counters.") {
+			return invalid("counter samples are not delivered to sinks")
+		}
+		events = append(events, t)
+	}
+	if len(events) == 0 {
+		events = append(events, DefaultNotificationEvents...)
+	}
+
+	c := dto.Config
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = defaultNotificationAttempts
+	}
+	if c.MaxAttempts < 1 || c.MaxAttempts > maxNotificationAttempts {
+		return invalid("maxAttempts must be between 1 and %d", maxNotificationAttempts)
+	}
+	config := models.NotificationSinkConfig{MaxAttempts: c.MaxAttempts}
+	switch dto.Type {
+	case models.SinkWebhook:
+		config.URL, config.Secret = c.URL, c.Secret
+		config.CACert, config.InsecureSkipVerify = c.CACert, c.InsecureSkipVerify
+	case models.SinkSlack:
+		config.URL = c.URL
+		config.CACert, config.InsecureSkipVerify = c.CACert, c.InsecureSkipVerify
+	case models.SinkEmail:
+		config.Host, config.Port, config.Username, config.Password = c.Host, c.Port, c.Username, c.Password
+		config.From, config.To = c.From, c.To
+		config.CACert, config.InsecureSkipVerify = c.CACert, c.InsecureSkipVerify
+	case models.SinkSyslog:
+		config.Network, config.Address, config.Facility, config.AppName = c.Network, c.Address, c.Facility, c.AppName
+	default:
+		return invalid("type must be webhook, slack, email or syslog")
+	}
+
+	enabled := true
+	if dto.Enabled != nil {
+		enabled = *dto.Enabled
+	}
+	next := models.NotificationSink{Name: strings.TrimSpace(dto.Name), Type: dto.Type, Enabled: enabled, Events: events, Config: config}
+	if _, err := notify.New(&next); err != nil {
+		return invalid("%s", err.Error())
+	}
+	sink.Name, sink.Type, sink.Enabled, sink.Events, sink.Config = next.Name, next.Type, next.Enabled, next.Events, next.Config
+	return nil
+}
+
+// maskSink returns a copy of sink with its secrets masked.
+func maskSink(sink *models.NotificationSink) *models.NotificationSink {
+	masked := *sink
+	if masked.Config.Secret != "" {
+		masked.Config.Secret = maskedSecret
+	}
+	if masked.Config.Password != "" {
+		masked.Config.Password = maskedSecret
+	}
+	return &masked
+}
+
+func (s *notificationService) ListDeliveries(_ context.Context, filter models.NotificationDeliveryFilter) ([]*models.NotificationDelivery, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDeliveryLimit
+	}
+	if filter.Limit > maxDeliveryLimit {
+		filter.Limit = maxDeliveryLimit
+	}
+	deliveries, err := s.repo.ListDeliveries(filter)
+	if deliveries == nil && err == nil {
+		deliveries = []*models.NotificationDelivery{}
+	}
+	return deliveries, err
+}
+
+func (s *notificationService) TestSink(ctx context.Context, id string) (*models.NotificationDelivery, error) {
+	sink, err := s.repo.GetSink(id)
+	if err != nil {
+		return nil, err
+	}
+	e := &models.Event{
+		Type:       "notification.test",
+		Time:       time.Now().UTC(),
+		ResourceID: sink.ID,
+		Data:       map[string]string{"sink": sink.Name},
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		e.Actor = p.Name
+	}
+	d, err := s.start(sink, e)
+	if err != nil {
+		return nil, err
+	}
+	s.deliver(ctx, sink, d, e, 1)
+	return d, nil
+}
+
+// Run delivers every event of the event stream to the enabled sinks that
+// subscribe to it, until ctx is cancelled. Deliveries left pending by the
+// previous run are marked failed, and the delivery log is trimmed hourly.
+func (s *notificationService) Run(ctx context.Context) {
+	s.failInterrupted()
+	s.prune()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	var lastEvent uint64
+	subscribe := func() (<-chan *models.Event, context.CancelFunc) {
+		subCtx, cancel := context.WithCancel(ctx)
+		return s.events.Subscribe(subCtx, models.EventFilter{}, lastEvent), cancel
+	}
+	events, unsubscribe := subscribe()
+	defer func() { unsubscribe() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// Fell behind; pick up from the backlog.
+				unsubscribe()
+				events, unsubscribe = subscribe()
+				continue
+			}
+			lastEvent = e.ID
+			s.dispatch(ctx, e)
+		case <-ticker.C:
+			s.prune()
+		}
+	}
+}
+
+// dispatch starts a delivery of e to every sink that subscribes to it.
+func (s *notificationService) dispatch(ctx context.Context, e *models.Event) {
+	if e.Type == "counters.sample" {
+		return
+	}
+	sinks, err := s.repo.ListSinks()
+	if err != nil {
+		s.log.WithError(err).Error("failed to load notification sinks")
+		return
+	}
+	for _, sink := range sinks {
+		if !sink.Enabled || !(models.EventFilter{Types: sink.Events}).Matches(e) {
+			continue
+		}
+		d, err := s.start(sink, e)
+		if err != nil {
+			s.log.WithError(err).WithField("sink", sink.Name).Error("failed to record notification delivery")
+			continue
+		}
+		go func(sink *models.NotificationSink) {
+			select {
+			case s.slots <- struct{}{}:
+			case <-ctx.Done():
+				s.finish(d, models.DeliveryFailed, "interrupted by shutdown")
+				return
+			}
+			defer func() { <-s.slots }()
+			s.deliver(ctx, sink, d, e, sink.Config.MaxAttempts)
+		}(sink)
+	}
+}
+
+// start records a pending delivery.
+func (s *notificationService) start(sink *models.NotificationSink, e *models.Event) (*models.NotificationDelivery, error) {
+	summary, _ := describeEvent(e)
+	d := &models.NotificationDelivery{
+		ID:        uuid.New().String(),
+		SinkID:    sink.ID,
+		SinkName:  sink.Name,
+		EventID:   e.ID,
+		EventType: e.Type,
+		Summary:   summary,
+		Status:    models.DeliveryPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.CreateDelivery(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// deliver sends e to the sink, retrying with exponential backoff until it
+// succeeds, fails permanently or used up its attempts.
+func (s *notificationService) deliver(ctx context.Context, sink *models.NotificationSink, d *models.NotificationDelivery, e *models.Event, attempts int) {
+	target, err := notify.New(sink)
+	if err != nil {
+		s.finish(d, models.DeliveryFailed, err.Error())
+		return
+	}
+	msg := &notify.Message{DeliveryID: d.ID, Event: e}
+	msg.Summary, msg.Severity = describeEvent(e)
+
+	delay := notificationRetryDelay
+	for {
+		d.Attempts++
+		sendCtx, cancel := context.WithTimeout(ctx, notify.Timeout)
+		err := target.Send(sendCtx, msg)
+		cancel()
+		if err == nil {
+			s.finish(d, models.DeliveryDelivered, "")
+			return
+		}
+		entry := s.log.WithError(err).WithField("sink", sink.Name).WithField("event_type", e.Type).WithField("attempt", d.Attempts)
+		if notify.IsPermanent(err) || d.Attempts >= attempts {
+			entry.Warn("notification delivery failed")
+			s.finish(d, models.DeliveryFailed, err.Error())
+			return
+		}
+		entry.Debug("notification delivery failed, retrying")
+		d.Error = err.Error()
+		s.save(d)
+
+		select {
+		case <-ctx.Done():
+			s.finish(d, models.DeliveryFailed, "interrupted by shutdown")
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxNotificationRetryDelay {
+			delay = maxNotificationRetryDelay
+		}
+	}
+}
+
+func (s *notificationService) finish(d *models.NotificationDelivery, status models.NotificationDeliveryStatus, errMsg string) {
+	now := time.Now().UTC()
+	d.Status, d.Error, d.FinishedAt = status, errMsg, &now
+	s.save(d)
+}
+
+func (s *notificationService) save(d *models.NotificationDelivery) {
+	if err := s.repo.UpdateDelivery(d); err != nil {
+		s.log.WithError(err).WithField("delivery_id", d.ID).Error("failed to store notification delivery")
+	}
+}
+
+// failInterrupted marks the deliveries a shutdown cut short as failed.
+func (s *notificationService) failInterrupted() {
+	pending, err := s.repo.ListDeliveries(models.NotificationDeliveryFilter{Status: models.DeliveryPending, Limit: notificationDeliveriesKept})
+	if err != nil {
+		s.log.WithError(err).Error("failed to load pending notification deliveries")
+		return
+	}
+	for _, d := range pending {
+		s.finish(d, models.DeliveryFailed, "interrupted by shutdown")
+	}
+}
+
+func (s *notificationService) prune() {
+	n, err := s.repo.PruneDeliveries(notificationDeliveriesKept)
+	if err != nil {
+		s.log.WithError(err).Error("failed to prune notification deliveries")
+		return
+	}
+	if n > 0 {
+		s.log.WithField("deleted", n).Debug("pruned notification deliveries")
+	}
+}
+
+// describeEvent returns a one-line summary of e and how severe it is.
+func describeEvent(e *models.Event) (string, notify.Severity) {
+	by := ""
+	if e.Actor != "" {
+		by = " by " + e.Actor
+	}
+	failure := eventError(e.Data)
+
+	switch {
+	case e.Type == "apply":
+		return "Ruleset applied" + by, notify.SeverityNotice
+	case e.Type == "apply.failed":
+		return "Ruleset apply failed" + by + ": " + failure, notify.SeverityError
+	case e.Type == "apply.schedule":
+		return "Ruleset re-applied for a schedule transition", notify.SeverityInfo
+	case e.Type == "apply.schedule.failed":
+		return "Ruleset re-apply for a schedule transition failed: " + failure, notify.SeverityError
+	case e.Type == "rollback":
+		return fmt.Sprintf("Rolled back to snapshot %s%s", e.ResourceID, by), notify.SeverityNotice
+	case e.Type == "rollback.failed":
+		return fmt.Sprintf("Rollback to snapshot %s failed%s: %s", e.ResourceID, by, failure), notify.SeverityError
+	case e.Type == "panic":
+		if failure != "" {
+			return "Panic lockdown failed" + by + ": " + failure, notify.SeverityError
+		}
+		return "Panic lockdown engaged" + by, notify.SeverityWarning
+	case e.Type == "panic.release":
+		if failure != "" {
+			return "Panic lockdown release failed" + by + ": " + failure, notify.SeverityError
+		}
+		return "Panic lockdown released" + by, notify.SeverityNotice
+	case e.Type == "lockout.override":
+		return "Lockout guard overridden" + by, notify.SeverityWarning
+	case strings.HasPrefix(e.Type, "job."):
+		job, ok := e.Data.(*models.ApplyJob)
+		if !ok {
+			break
+		}
+		if job.Status == models.ApplyJobFailed {
+			return fmt.Sprintf("%s job %s failed: %s", job.Action, job.ID, job.Error), notify.SeverityError
+		}
+		return fmt.Sprintf("%s job %s %s", job.Action, job.ID, job.Status), notify.SeverityInfo
+	case e.Type == "drift.detected":
+		return "Live ruleset drifted from the last apply", notify.SeverityWarning
+	case e.Type == "drift.resolved":
+		return "Live ruleset matches the last apply again", notify.SeverityNotice
+	case strings.HasPrefix(e.Type, "alert."):
+		a, ok := e.Data.(*models.Alert)
+		if !ok {
+			break
+		}
+		summary := fmt.Sprintf("Alert %s %s: %s", a.RuleName, a.State, a.Message)
+		if a.State == models.AlertResolved {
+			return summary, notify.SeverityNotice
+		}
+		switch a.Severity {
+		case models.SeverityCritical:
+			return summary, notify.SeverityCritical
+		case models.SeverityWarning:
+			return summary, notify.SeverityWarning
+		}
+		return summary, notify.SeverityInfo
+	case e.Type == "notification.test":
+		return "Test notification" + by, notify.SeverityInfo
+	}
+
+	summary := e.Type
+	if e.ResourceID != "" {
+		summary += " " + e.ResourceID
+	}
+	return summary + by, notify.SeverityInfo
+}
+
+// eventError returns the error audited failures carry as their data.
+func eventError(data any) string {
+	if m, ok := data.(map[string]string); ok {
+		return m["error"]
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/oidc"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// pendingLoginTTL bounds how long a user may take at the identity provider.
+const pendingLoginTTL = 10 * time.Minute
+
+// ErrOIDCDisabled is returned when the OIDC login flow is not configured.
+var ErrOIDCDisabled = errors.New("oidc login is not configured")
+
+// pendingLogin is an authorization request waiting for its callback
+type pendingLogin struct {
+	req     *oidc.AuthRequest
+	expires time.Time
+}
+
+// authenticateJWT resolves a JWT bearer token from the identity provider.
+// The principal is not stored; its roles come from the token's groups.
+func (s *authService) authenticateJWT(ctx context.Context, raw string) (*auth.Principal, error) {
+	claims, err := s.cfg.OIDC.VerifyAccessToken(ctx, raw)
+	if err != nil {
+		s.log.WithError(err).Debug("jwt rejected")
+		return nil, ErrInvalidCredentials
+	}
+	bindings := auth.MappedBindings(s.cfg.GroupRoles, claims.Strings(s.cfg.GroupsClaim))
+	if len(bindings) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	return &auth.Principal{
+		ID:       claims.String("sub"),
+		Name:     s.claimUsername(claims),
+		Kind:     "oidc",
+		Bindings: bindings,
+	}, nil
+}
+
+// BeginOIDCLogin starts the authorization-code flow and returns the URL to
+// send the browser to.
+func (s *authService) BeginOIDCLogin(ctx context.Context) (string, error) {
+	if s.cfg.OIDC == nil || !s.cfg.OIDC.LoginEnabled() {
+		return "", ErrOIDCDisabled
+	}
+	req, err := s.cfg.OIDC.NewAuthRequest(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	s.pendingMu.Lock()
+	for state, p := range s.pending {
+		if now.After(p.expires) {
+			delete(s.pending, state)
+		}
+	}
+	s.pending[req.State] = pendingLogin{req: req, expires: now.Add(pendingLoginTTL)}
+	s.pendingMu.Unlock()
+
+	return req.URL, nil
+}
+
+// CompleteOIDCLogin handles the provider's callback. The user is created on
+// first login; their roles are replaced with the ones their groups map to on
+// every login, so group changes at the provider take effect.
+func (s *authService) CompleteOIDCLogin(ctx context.Context, state, code string) (*LoginResult, error) {
+	if s.cfg.OIDC == nil || !s.cfg.OIDC.LoginEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	s.pendingMu.Lock()
+	pending, ok := s.pending[state]
+	delete(s.pending, state)
+	s.pendingMu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		return nil, fmt.Errorf("unknown or expired login state")
+	}
+
+	claims, err := s.cfg.OIDC.Exchange(ctx, pending.req, code)
+	if err != nil {
+		return nil, err
+	}
+	bindings := auth.MappedBindings(s.cfg.GroupRoles, claims.Strings(s.cfg.GroupsClaim))
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("%w: no groups of %s map to a role", auth.ErrForbidden, s.claimUsername(claims))
+	}
+
+	user, err := s.syncOIDCUser(claims, bindings)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	result, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"username": user.Username,
+		"subject":  user.OIDCSubject,
+	}).Info("user logged in via oidc")
+	return result, nil
+}
+
+func (s *authService) syncOIDCUser(claims oidc.Claims, bindings []models.RoleBinding) (*models.User, error) {
+	subject := claims.String("sub")
+	user, err := s.users.GetByOIDCSubject(subject)
+	if err != nil {
+		now := time.Now()
+		user = &models.User{
+			ID:          uuid.New().String(),
+			Username:    s.claimUsername(claims),
+			OIDCSubject: subject,
+			Roles:       bindings,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := s.users.Create(user); err != nil {
+			return nil, fmt.Errorf("create oidc user %s: %w", user.Username, err)
+		}
+		return user, nil
+	}
+
+	user.Roles = bindings
+	if err := s.users.Update(user); err != nil {
+		return nil, fmt.Errorf("update oidc user: %w", err)
+	}
+	return user, nil
+}
+
+func (s *authService) claimUsername(claims oidc.Claims) string {
+	for _, name := range []string{s.cfg.UsernameClaim, "email", "sub"} {
+		if v := claims.String(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/metrics"
+)
+
+// operationDurationBuckets are the histogram bounds, in seconds, of apply
+// and rollback durations.
+var operationDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// OperationStats counts the applies or rollbacks run since startup.
+type OperationStats struct {
+	Succeeded   uint64
+	Failed      uint64
+	Durations   *metrics.Buckets // seconds
+	LastSuccess time.Time        // zero if none
+}
+
+func newOperationStats() OperationStats {
+	return OperationStats{Durations: metrics.NewBuckets(operationDurationBuckets...)}
+}
+
+func (o *OperationStats) record(start time.Time, err error) {
+	if o.Durations == nil {
+		*o = newOperationStats()
+	}
+	now := time.Now()
+	o.Durations.Observe(now.Sub(start).Seconds())
+	if err != nil {
+		o.Failed++
+		return
+	}
+	o.Succeeded++
+	o.LastSuccess = now.UTC()
+}
+
+func (o OperationStats) clone() OperationStats {
+	if o.Durations == nil {
+		return newOperationStats()
+	}
+	o.Durations = o.Durations.Clone()
+	return o
+}
+
+// recordOperation adds the outcome of an apply or rollback that started at
+// start to stats.
+func (s *firewallService) recordOperation(stats *OperationStats, start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.record(start, err)
+}
+
+// OperationStats returns the apply and rollback statistics.
+func (s *firewallService) OperationStats(_ context.Context) (apply, rollback OperationStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyStats.clone(), s.rollbackStats.clone()
+}
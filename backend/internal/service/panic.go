@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrPanicActive is returned for kernel changes while panic mode is on,
+	// and for a second panic.
+	ErrPanicActive = errors.New("panic mode is active")
+	// ErrPanicInactive is returned when releasing without an active panic.
+	ErrPanicInactive = errors.New("panic mode is not active")
+)
+
+// Panic snapshots the live ruleset and replaces it with the lockdown: drop
+// everything except loopback, established sessions, the management
+// allowlist and the caller. Applies, rollbacks and schedule transitions are
+// refused until ReleasePanic.
+func (s *firewallService) Panic(ctx context.Context, reason string) (*models.PanicState, error) {
+	if s.panics == nil {
+		return nil, fmt.Errorf("panic mode is not configured")
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	state, err := s.panics.Get()
+	if err != nil {
+		return nil, fmt.Errorf("load panic state: %w", err)
+	}
+	if state.Active {
+		return state, ErrPanicActive
+	}
+
+	now := time.Now().UTC()
+	state = &models.PanicState{Active: true, Reason: reason, ActivatedBy: "system", ActivatedAt: &now}
+	if p := auth.FromContext(ctx); p != nil {
+		state.ActivatedBy = p.Name
+	}
+	if conn, ok := clientConnFrom(ctx); ok {
+		state.CallerIP, state.CallerPort = conn.ClientIP, conn.LocalPort
+	}
+
+	snapshot, err := s.driver.Load()
+	if err != nil {
+		s.log.WithError(err).Warn("could not snapshot current ruleset before panic")
+	} else {
+		entry := &models.HistoryEntry{
+			ID:          uuid.New().String(),
+			Kind:        models.HistoryKindSnapshot,
+			Snapshot:    snapshot,
+			Description: fmt.Sprintf("snapshot before panic at %s", now.Format(time.RFC3339)),
+			AppliedAt:   now,
+		}
+		if err := s.history.Save(entry); err != nil {
+			return nil, fmt.Errorf("save snapshot before panic: %w", err)
+		}
+		state.SnapshotID = entry.ID
+	}
+
+	s.forgetApplied()
+	if err := s.driver.Lockdown(s.guard.PanicAllowlist(state.CallerIP, state.CallerPort)); err != nil {
+		s.audit.Record(ctx, "panic", "ruleset", "", nil, map[string]string{"error": err.Error()})
+		return nil, fmt.Errorf("apply lockdown: %w", err)
+	}
+	if err := s.panics.Save(state); err != nil {
+		// The kernel is locked down; say so even though it will not
+		// survive a restart.
+		s.log.WithError(err).Error("lockdown applied but panic state could not be saved")
+		return state, fmt.Errorf("lockdown applied but not persisted: %w", err)
+	}
+	s.audit.Record(ctx, "panic", "ruleset", state.SnapshotID, nil, state)
+
+	s.log.WithFields(logrus.Fields{"by": state.ActivatedBy, "reason": reason}).Warn("panic mode activated")
+	return state, nil
+}
+
+// ReleasePanic restores the snapshot taken when panic mode was entered, or
+// re-applies the stored rules if there is none.
+func (s *firewallService) ReleasePanic(ctx context.Context) (*models.PanicState, error) {
+	if s.panics == nil {
+		return nil, ErrPanicInactive
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	before, err := s.panics.Get()
+	if err != nil {
+		return nil, fmt.Errorf("load panic state: %w", err)
+	}
+	if !before.Active {
+		return before, ErrPanicInactive
+	}
+
+	if err := s.restoreFromPanic(ctx, before); err != nil {
+		s.audit.Record(ctx, "panic.release", "ruleset", before.SnapshotID, before, map[string]string{"error": err.Error()})
+		return nil, err
+	}
+
+	state := &models.PanicState{}
+	if err := s.panics.Save(state); err != nil {
+		return nil, fmt.Errorf("save panic state: %w", err)
+	}
+	s.audit.Record(ctx, "panic.release", "ruleset", before.SnapshotID, before, state)
+
+	s.log.WithField("snapshot_id", before.SnapshotID).Warn("panic mode released")
+	return state, nil
+}
+
+func (s *firewallService) restoreFromPanic(ctx context.Context, state *models.PanicState) error {
+	if state.SnapshotID != "" {
+		entry, err := s.history.Get(state.SnapshotID)
+		if err != nil {
+			return fmt.Errorf("load panic snapshot %s: %w", state.SnapshotID, err)
+		}
+		if err := restoreSnapshot(entry.Snapshot)(); err != nil {
+			return fmt.Errorf("restore panic snapshot: %w", err)
+		}
+		return nil
+	}
+
+	// No snapshot: rebuild from the database, lockout check included.
+	if _, err := s.applyRules(ctx); err != nil {
+		return fmt.Errorf("re-apply rules after panic: %w", err)
+	}
+	return nil
+}
+
+func (s *firewallService) GetPanicState(_ context.Context) (*models.PanicState, error) {
+	if s.panics == nil {
+		return &models.PanicState{}, nil
+	}
+	return s.panics.Get()
+}
+
+// RestorePanic re-applies the lockdown at startup if panic mode was active
+// when the server stopped; the kernel ruleset may have been reset since.
+func (s *firewallService) RestorePanic(ctx context.Context) error {
+	if s.panics == nil {
+		return nil
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	state, err := s.panics.Get()
+	if err != nil || !state.Active {
+		return err
+	}
+	if err := s.driver.Lockdown(s.guard.PanicAllowlist(state.CallerIP, state.CallerPort)); err != nil {
+		return fmt.Errorf("re-apply lockdown: %w", err)
+	}
+	s.log.WithField("since", state.ActivatedAt).Warn("panic mode still active, lockdown re-applied")
+	return nil
+}
+
+// refuseInPanic returns ErrPanicActive while the lockdown is in force.
+func (s *firewallService) refuseInPanic() error {
+	if s.panics == nil {
+		return nil
+	}
+	state, err := s.panics.Get()
+	if err != nil {
+		return fmt.Errorf("load panic state: %w", err)
+	}
+	if state.Active {
+		return ErrPanicActive
+	}
+	return nil
+}
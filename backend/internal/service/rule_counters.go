@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/firewall"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrRuleNotFound is returned for counters of an unknown rule.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// CounterTracker attributes the live kernel counters to stored filter and
+// NAT rules by the tag the driver embeds in each rule's comment. The kernel
+// keeps no last-hit time, so the tracker remembers each rule's packet count
+// between reads and notes when it grew.
+type CounterTracker struct {
+	collector *CounterCollector
+	log       *logrus.Logger
+
+	mu   sync.Mutex
+	seen map[string]ruleHits // by rule ID
+}
+
+type ruleHits struct {
+	packets uint64
+	lastHit *time.Time
+}
+
+func NewCounterTracker(collector *CounterCollector, log *logrus.Logger) *CounterTracker {
+	return &CounterTracker{collector: collector, log: log, seen: make(map[string]ruleHits)}
+}
+
+// Read returns the counters of the rules with the given IDs. Rules that are
+// not in the kernel (disabled, outside their schedule, never applied) read
+// as zero.
+func (t *CounterTracker) Read(ids []string) (map[string]*models.RuleCounters, error) {
+	counters, err := t.collector.Rules()
+	if err != nil {
+		return nil, err
+	}
+	byTag := make(map[string]*models.Counter)
+	for _, c := range counters {
+		if c.Tag == "" {
+			continue
+		}
+		sum, ok := byTag[c.Tag]
+		if !ok {
+			sum = &models.Counter{}
+			byTag[c.Tag] = sum
+		}
+		sum.Packets += c.Packets
+		sum.Bytes += c.Bytes
+	}
+
+	now := time.Now().UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[string]*models.RuleCounters, len(ids))
+	for _, id := range ids {
+		rc := &models.RuleCounters{Tag: firewall.RuleTag(id)}
+		if sum, ok := byTag[rc.Tag]; ok {
+			rc.Packets, rc.Bytes = sum.Packets, sum.Bytes
+		}
+
+		prev, ok := t.seen[id]
+		hits := ruleHits{packets: rc.Packets, lastHit: prev.lastHit}
+		// A lower count means the rule was reset by an apply; any packets
+		// since then are new hits.
+		if ok && (rc.Packets > prev.packets || (rc.Packets < prev.packets && rc.Packets > 0)) {
+			at := now
+			hits.lastHit = &at
+		}
+		t.seen[id] = hits
+		rc.LastHit = hits.lastHit
+		result[id] = rc
+	}
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RuleScheduler periodically re-applies the ruleset so that scheduler-mode
+// rules are added and removed as their time windows open and close.
+type RuleScheduler struct {
+	fw       FirewallService
+	interval time.Duration
+	log      *logrus.Logger
+}
+
+func NewRuleScheduler(fw FirewallService, interval time.Duration, log *logrus.Logger) *RuleScheduler {
+	return &RuleScheduler{fw: fw, interval: interval, log: log}
+}
+
+// Run checks schedules every interval until ctx is cancelled.
+func (r *RuleScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.fw.ReconcileSchedules(ctx); err != nil {
+			r.log.WithError(err).Error("schedule reconcile failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
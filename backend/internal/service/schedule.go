@@ -0,0 +1,207 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compiledSchedule is a validated, ready-to-evaluate form of models.Schedule.
+type compiledSchedule struct {
+	days  [7]bool // indexed by time.Weekday
+	start int     // minutes since midnight
+	end   int     // minutes since midnight; <= start means the window wraps
+	loc   *time.Location
+	from  time.Time // zero = unbounded
+	until time.Time // exclusive; zero = unbounded
+}
+
+// compileSchedule validates a schedule and prepares it for evaluation.
+func compileSchedule(s *models.Schedule) (*compiledSchedule, error) {
+	switch s.Mode {
+	case models.ScheduleModeKernel:
+		// The kernel time match only knows UTC and the kernel's own timezone.
+		if s.Timezone != "" && s.Timezone != "UTC" && s.Timezone != "Local" {
+			return nil, fmt.Errorf("kernel schedules support only UTC or Local timezone, use scheduler mode for %s", s.Timezone)
+		}
+	case models.ScheduleModeScheduler:
+	default:
+		return nil, fmt.Errorf("invalid schedule mode: %s", s.Mode)
+	}
+
+	c := &compiledSchedule{start: 0, end: 24 * 60}
+
+	if len(s.Days) == 0 {
+		for i := range c.days {
+			c.days[i] = true
+		}
+	}
+	for _, d := range s.Days {
+		wd, ok := scheduleDays[strings.ToLower(d)]
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule day: %s", d)
+		}
+		c.days[wd] = true
+	}
+
+	var err error
+	if s.StartTime != "" {
+		if c.start, err = parseClock(s.StartTime); err != nil {
+			return nil, err
+		}
+	}
+	if s.EndTime != "" {
+		if c.end, err = parseClock(s.EndTime); err != nil {
+			return nil, err
+		}
+	}
+	if c.start == c.end {
+		return nil, fmt.Errorf("schedule start and end time must differ")
+	}
+
+	switch s.Timezone {
+	case "", "UTC":
+		c.loc = time.UTC
+	case "Local":
+		c.loc = time.Local
+	default:
+		if c.loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid schedule timezone: %s", s.Timezone)
+		}
+	}
+
+	if s.StartDate != "" {
+		if c.from, err = time.ParseInLocation("2006-01-02", s.StartDate, c.loc); err != nil {
+			return nil, fmt.Errorf("invalid schedule start date: %s", s.StartDate)
+		}
+	}
+	if s.EndDate != "" {
+		end, err := time.ParseInLocation("2006-01-02", s.EndDate, c.loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule end date: %s", s.EndDate)
+		}
+		c.until = end.AddDate(0, 0, 1)
+	}
+	if !c.from.IsZero() && !c.until.IsZero() && !c.from.Before(c.until) {
+		return nil, fmt.Errorf("schedule start date must not be after end date")
+	}
+
+	return c, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time: %s", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// activeAt reports whether t falls inside one of the schedule's windows.
+func (c *compiledSchedule) activeAt(t time.Time) bool {
+	t = t.In(c.loc)
+	if !c.from.IsZero() && t.Before(c.from) {
+		return false
+	}
+	if !c.until.IsZero() && !t.Before(c.until) {
+		return false
+	}
+
+	mins := t.Hour()*60 + t.Minute()
+	if c.start < c.end {
+		return c.days[t.Weekday()] && mins >= c.start && mins < c.end
+	}
+
+	// Window runs past midnight: it belongs to the day it started on.
+	if mins >= c.start && c.days[t.Weekday()] {
+		return true
+	}
+	return mins < c.end && c.days[(t.Weekday()+6)%7]
+}
+
+// nextTransition returns the first instant after now at which the schedule
+// switches between active and inactive. ok is false if it never changes again.
+func (c *compiledSchedule) nextTransition(now time.Time) (next time.Time, ok bool) {
+	var candidates []time.Time
+	addDays := func(base time.Time) {
+		base = base.In(c.loc)
+		for i := -1; i <= 8; i++ {
+			// Wall-clock times, so that days with a DST change still open
+			// and close at the configured hour.
+			candidates = append(candidates,
+				time.Date(base.Year(), base.Month(), base.Day()+i, 0, c.start, 0, 0, c.loc),
+				time.Date(base.Year(), base.Month(), base.Day()+i, 0, c.end, 0, 0, c.loc))
+		}
+	}
+
+	addDays(now)
+	if !c.from.IsZero() && c.from.After(now) {
+		candidates = append(candidates, c.from)
+		addDays(c.from)
+	}
+	if !c.until.IsZero() {
+		candidates = append(candidates, c.until)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	current := c.activeAt(now)
+	for _, t := range candidates {
+		if t.After(now) && c.activeAt(t) != current {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ruleActiveAt reports whether an enabled rule's schedule (if any) is open at t.
+// Rules with an invalid stored schedule are treated as always active so that
+// a bad schedule never silently removes a rule.
+func ruleActiveAt(r *models.Rule, t time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.Schedule == nil {
+		return true
+	}
+	c, err := compileSchedule(r.Schedule)
+	if err != nil {
+		return true
+	}
+	return c.activeAt(t)
+}
+
+// scheduledRules returns the rules to hand to the driver at time t.
+// Scheduler-mode rules outside their window are left out; kernel-mode rules
+// are passed through because the time match is evaluated in the kernel.
+func scheduledRules(rules []*models.Rule, t time.Time) []*models.Rule {
+	out := make([]*models.Rule, 0, len(rules))
+	for _, r := range rules {
+		if r.Schedule != nil && r.Schedule.Mode == models.ScheduleModeScheduler && !ruleActiveAt(r, t) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// scheduleKey summarizes which scheduler-mode rules are in their window at t.
+// The ruleset only needs to be re-applied when this changes.
+func scheduleKey(rules []*models.Rule, t time.Time) string {
+	var sb strings.Builder
+	for _, r := range rules {
+		if r.Schedule == nil || r.Schedule.Mode != models.ScheduleModeScheduler {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s=%t;", r.ID, ruleActiveAt(r, t))
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // the Europe/Berlin cases must not depend on the host
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+var (
+	businessHours = &models.Schedule{Mode: models.ScheduleModeScheduler, Days: []string{"mon", "tue", "wed", "thu", "fri"}, StartTime: "08:00", EndTime: "18:00"}
+	weekendNights = &models.Schedule{Mode: models.ScheduleModeScheduler, Days: []string{"Fri", "Sat"}, StartTime: "22:00", EndTime: "06:00"}
+	berlinDays    = &models.Schedule{Mode: models.ScheduleModeScheduler, StartTime: "08:00", EndTime: "18:00", Timezone: "Europe/Berlin"}
+)
+
+// utc returns a time in October 2026; the 19th is a Monday.
+func utc(day, hour, min int) time.Time {
+	return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestCompileSchedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		sched models.Schedule
+		ok    bool
+	}{
+		{"empty scheduler", models.Schedule{Mode: models.ScheduleModeScheduler}, true},
+		{"kernel utc", models.Schedule{Mode: models.ScheduleModeKernel, Timezone: "UTC"}, true},
+		{"kernel local", models.Schedule{Mode: models.ScheduleModeKernel, Timezone: "Local"}, true},
+		{"kernel named zone", models.Schedule{Mode: models.ScheduleModeKernel, Timezone: "Europe/Berlin"}, false},
+		{"scheduler named zone", models.Schedule{Mode: models.ScheduleModeScheduler, Timezone: "Europe/Berlin"}, true},
+		{"unknown zone", models.Schedule{Mode: models.ScheduleModeScheduler, Timezone: "Mars/Olympus"}, false},
+		{"no mode", models.Schedule{}, false},
+		{"bad day", models.Schedule{Mode: models.ScheduleModeScheduler, Days: []string{"monday"}}, false},
+		{"bad time", models.Schedule{Mode: models.ScheduleModeScheduler, StartTime: "24:00"}, false},
+		{"empty window", models.Schedule{Mode: models.ScheduleModeScheduler, StartTime: "08:00", EndTime: "08:00"}, false},
+		{"midnight to end of day", models.Schedule{Mode: models.ScheduleModeScheduler, StartTime: "00:00"}, true},
+		{"bad date", models.Schedule{Mode: models.ScheduleModeScheduler, StartDate: "2026-02-30"}, false},
+		{"single day", models.Schedule{Mode: models.ScheduleModeScheduler, StartDate: "2026-10-20", EndDate: "2026-10-20"}, true},
+		{"dates reversed", models.Schedule{Mode: models.ScheduleModeScheduler, StartDate: "2026-10-21", EndDate: "2026-10-20"}, false},
+	}
+	for _, tt := range tests {
+		if _, err := compileSchedule(&tt.sched); (err == nil) != tt.ok {
+			t.Errorf("%s: compileSchedule = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestActiveAt(t *testing.T) {
+	wholeSunday := &models.Schedule{Mode: models.ScheduleModeScheduler, Days: []string{"sun"}}
+	twoDays := &models.Schedule{Mode: models.ScheduleModeScheduler, StartDate: "2026-10-20", EndDate: "2026-10-21"}
+	berlinFrom := &models.Schedule{Mode: models.ScheduleModeScheduler, StartDate: "2026-10-20", Timezone: "Europe/Berlin"}
+
+	tests := []struct {
+		name  string
+		sched *models.Schedule
+		at    time.Time
+		want  bool
+	}{
+		{"start is inclusive", businessHours, utc(19, 8, 0), true},
+		{"inside window", businessHours, utc(19, 17, 59), true},
+		{"end is exclusive", businessHours, utc(19, 18, 0), false},
+		{"before window", businessHours, utc(19, 7, 59), false},
+		{"day not listed", businessHours, utc(24, 12, 0), false},
+
+		{"night start", weekendNights, utc(23, 22, 0), true},
+		{"night after midnight", weekendNights, utc(24, 5, 59), true},
+		{"night ends", weekendNights, utc(24, 6, 0), false},
+		{"second night after midnight", weekendNights, utc(25, 5, 0), true},
+		{"morning after unlisted day", weekendNights, utc(23, 5, 0), false},
+		{"evening of unlisted day", weekendNights, utc(25, 22, 0), false},
+		{"monday morning", weekendNights, utc(26, 1, 0), false},
+
+		{"whole day start", wholeSunday, utc(25, 0, 0), true},
+		{"whole day end", wholeSunday, utc(25, 23, 59), true},
+		{"whole day over", wholeSunday, utc(26, 0, 0), false},
+
+		{"before start date", twoDays, utc(19, 23, 59), false},
+		{"start date", twoDays, utc(20, 0, 0), true},
+		{"end date is inclusive", twoDays, utc(21, 23, 59), true},
+		{"after end date", twoDays, utc(22, 0, 0), false},
+
+		{"zone summer time", berlinDays, utc(19, 6, 30), true},
+		{"zone summer evening", berlinDays, utc(19, 16, 30), false},
+		{"zone winter time", berlinDays, utc(26, 7, 30), true},
+		{"zone winter early", berlinDays, utc(26, 6, 30), false},
+		{"start date in zone", berlinFrom, utc(19, 22, 30), true},
+		{"before start date in zone", berlinFrom, utc(19, 21, 30), false},
+	}
+	for _, tt := range tests {
+		c, err := compileSchedule(tt.sched)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := c.activeAt(tt.at); got != tt.want {
+			t.Errorf("%s: activeAt(%s) = %v, want %v", tt.name, tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestNextTransition(t *testing.T) {
+	always := &models.Schedule{Mode: models.ScheduleModeScheduler}
+	until := &models.Schedule{Mode: models.ScheduleModeScheduler, EndDate: "2026-10-21"}
+	later := &models.Schedule{Mode: models.ScheduleModeScheduler, Days: businessHours.Days, StartTime: "08:00", EndTime: "18:00", StartDate: "2026-11-02"}
+	laterAllDay := &models.Schedule{Mode: models.ScheduleModeScheduler, StartDate: "2026-11-02"}
+
+	tests := []struct {
+		name  string
+		sched *models.Schedule
+		now   time.Time
+		want  time.Time // zero if the schedule never changes again
+	}{
+		{"closes today", businessHours, utc(19, 12, 0), utc(19, 18, 0)},
+		{"opens today", businessHours, utc(19, 6, 0), utc(19, 8, 0)},
+		{"at the opening", businessHours, utc(19, 8, 0), utc(19, 18, 0)},
+		{"over the weekend", businessHours, utc(23, 18, 0), utc(26, 8, 0)},
+		{"overnight closes", weekendNights, utc(24, 3, 0), utc(24, 6, 0)},
+		{"second night closes", weekendNights, utc(25, 3, 0), utc(25, 6, 0)},
+		{"next weekend", weekendNights, utc(25, 12, 0), utc(30, 22, 0)},
+		{"always active", always, utc(19, 12, 0), time.Time{}},
+		{"end date", until, utc(20, 12, 0), utc(22, 0, 0)},
+		{"after end date", until, utc(23, 12, 0), time.Time{}},
+		{"start date in the future", later, utc(19, 12, 0), time.Date(2026, 11, 2, 8, 0, 0, 0, time.UTC)},
+		{"whole days from a start date", laterAllDay, utc(19, 12, 0), time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)},
+		// Berlin leaves summer time at 01:00 UTC on 25 October 2026.
+		{"opens after the clock change", berlinDays, utc(24, 17, 0), utc(25, 7, 0)},
+		{"closes after the clock change", berlinDays, utc(25, 12, 0), utc(25, 17, 0)},
+	}
+	for _, tt := range tests {
+		c, err := compileSchedule(tt.sched)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		next, ok := c.nextTransition(tt.now)
+		if ok != !tt.want.IsZero() || !next.Equal(tt.want) {
+			t.Errorf("%s: nextTransition(%s) = %s, %v, want %s", tt.name, tt.now.Format(time.RFC3339), next.UTC().Format(time.RFC3339), ok, tt.want.Format(time.RFC3339))
+		}
+	}
+}
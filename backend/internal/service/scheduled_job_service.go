@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultJobGrace is how late a job may start before its catch-up
+	// policy applies, when the job does not set its own grace period.
+	defaultJobGrace = 5 * time.Minute
+	// jobPollInterval bounds how long the worker sleeps between checks.
+	jobPollInterval = 30 * time.Second
+)
+
+// CreateScheduledJobDTO is the input for queueing an apply or rollback
+type CreateScheduledJobDTO struct {
+	Action       models.ScheduledJobAction `json:"action" binding:"required"`
+	HistoryID    string                    `json:"historyId"`
+	Description  string                    `json:"description"`
+	RunAt        time.Time                 `json:"runAt" binding:"required"`
+	CatchUp      models.CatchUpPolicy      `json:"catchUp"`
+	GraceSeconds int                       `json:"graceSeconds"`
+}
+
+// PrincipalResolver checks a principal recorded earlier against the current
+// accounts and roles. AuthService implements it.
+type PrincipalResolver interface {
+	ResolvePrincipal(ctx context.Context, p *auth.Principal) (*auth.Principal, error)
+}
+
+// ScheduledJobService queues applies and rollbacks for a future time. When a
+// job is due it is handed to the ApplyJobService as its creator.
+type ScheduledJobService interface {
+	ListJobs(ctx context.Context) ([]*models.ScheduledJob, error)
+	GetJob(ctx context.Context, id string) (*models.ScheduledJob, error)
+	CreateJob(ctx context.Context, dto CreateScheduledJobDTO) (*models.ScheduledJob, error)
+	CancelJob(ctx context.Context, id string) (*models.ScheduledJob, error)
+	Run(ctx context.Context)
+}
+
+type scheduledJobService struct {
+	jobs       repository.ScheduledJobRepository
+	history    repository.HistoryRepository
+	applyJobs  ApplyJobService
+	principals PrincipalResolver
+	audit      Auditor
+	log        *logrus.Logger
+
+	mu   sync.Mutex    // serializes claiming and cancelling jobs
+	wake chan struct{} // nudges the worker when the queue changes
+}
+
+func NewScheduledJobService(
+	jobs repository.ScheduledJobRepository,
+	history repository.HistoryRepository,
+	applyJobs ApplyJobService,
+	principals PrincipalResolver,
+	audit Auditor,
+	log *logrus.Logger,
+) ScheduledJobService {
+	return &scheduledJobService{
+		jobs:       jobs,
+		history:    history,
+		applyJobs:  applyJobs,
+		principals: principals,
+		audit:      audit,
+		log:        log,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+func (s *scheduledJobService) ListJobs(_ context.Context) ([]*models.ScheduledJob, error) {
+	return s.jobs.List()
+}
+
+func (s *scheduledJobService) GetJob(_ context.Context, id string) (*models.ScheduledJob, error) {
+	return s.jobs.Get(id)
+}
+
+func (s *scheduledJobService) CreateJob(ctx context.Context, dto CreateScheduledJobDTO) (*models.ScheduledJob, error) {
+	if err := auth.Authorize(ctx, auth.PermApply, "", nil); err != nil {
+		return nil, err
+	}
+	switch dto.Action {
+	case models.ScheduledJobApply:
+		if dto.HistoryID != "" {
+			return nil, fmt.Errorf("historyId is only valid for rollback jobs")
+		}
+	case models.ScheduledJobRollback:
+		// A bad target would only surface when the job runs.
+		if err := s.checkRollbackTarget(dto.HistoryID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid job action: %s", dto.Action)
+	}
+
+	if dto.CatchUp == "" {
+		dto.CatchUp = models.CatchUpRun
+	}
+	if dto.CatchUp != models.CatchUpRun && dto.CatchUp != models.CatchUpSkip {
+		return nil, fmt.Errorf("invalid catch-up policy: %s", dto.CatchUp)
+	}
+	if dto.GraceSeconds < 0 {
+		return nil, fmt.Errorf("graceSeconds must not be negative")
+	}
+	if dto.GraceSeconds == 0 {
+		dto.GraceSeconds = int(defaultJobGrace / time.Second)
+	}
+
+	now := time.Now()
+	if !dto.RunAt.After(now) {
+		return nil, fmt.Errorf("runAt must be in the future")
+	}
+
+	job := &models.ScheduledJob{
+		ID:           uuid.New().String(),
+		Action:       dto.Action,
+		HistoryID:    dto.HistoryID,
+		Description:  dto.Description,
+		RunAt:        dto.RunAt.UTC(), // stored as text, so keep one zone for ordering
+		CatchUp:      dto.CatchUp,
+		GraceSeconds: dto.GraceSeconds,
+		Status:       models.ScheduledJobPending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	job.CreatedBy, job.Creator = jobCreator(ctx)
+	if err := s.jobs.Create(job); err != nil {
+		return nil, fmt.Errorf("create scheduled job: %w", err)
+	}
+	s.audit.Record(ctx, "scheduled-job.create", "scheduled-job", job.ID, nil, job)
+
+	s.log.WithFields(logrus.Fields{
+		"job_id": job.ID,
+		"action": job.Action,
+		"run_at": job.RunAt,
+	}).Info("job scheduled")
+	s.notify()
+	return job, nil
+}
+
+// jobCreator records the principal and connection of the request that
+// schedules a job.
+func jobCreator(ctx context.Context) (string, *models.JobCreator) {
+	name, c := "system", &models.JobCreator{Kind: "system"}
+	if p := auth.FromContext(ctx); p != nil {
+		name = p.Name
+		c.ID, c.Kind, c.Roles, c.Scopes = p.ID, p.Kind, p.Bindings, p.Scopes
+	}
+	if conn, ok := clientConnFrom(ctx); ok {
+		c.ClientIP, c.ClientPort = conn.ClientIP, conn.ClientPort
+		c.LocalIP, c.LocalPort = conn.LocalIP, conn.LocalPort
+		c.AllowLockout = conn.Override
+	}
+	return name, c
+}
+
+// checkRollbackTarget checks that historyID, or the latest snapshot if it
+// is empty, can be restored.
+func (s *scheduledJobService) checkRollbackTarget(historyID string) error {
+	var entry *models.HistoryEntry
+	var err error
+	if historyID == "" {
+		entry, err = s.history.Latest()
+	} else {
+		entry, err = s.history.Get(historyID)
+	}
+	if err != nil {
+		return fmt.Errorf("no snapshot to roll back to: %w", err)
+	}
+	if entry.Kind != models.HistoryKindSnapshot || entry.Snapshot == "" {
+		return fmt.Errorf("history entry %s is not a restorable snapshot", entry.ID)
+	}
+	return nil
+}
+
+func (s *scheduledJobService) CancelJob(ctx context.Context, id string) (*models.ScheduledJob, error) {
+	if err := auth.Authorize(ctx, auth.PermApply, "", nil); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.jobs.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.ScheduledJobPending {
+		return nil, fmt.Errorf("job is %s, only pending jobs can be cancelled", job.Status)
+	}
+
+	before := *job
+	now := time.Now()
+	job.Status = models.ScheduledJobCancelled
+	job.FinishedAt = &now
+	if err := s.jobs.Update(job); err != nil {
+		return nil, fmt.Errorf("cancel scheduled job: %w", err)
+	}
+	s.audit.Record(ctx, "scheduled-job.cancel", "scheduled-job", job.ID, before, job)
+
+	s.log.WithField("job_id", id).Info("scheduled job cancelled")
+	s.notify()
+	return job, nil
+}
+
+func (s *scheduledJobService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run executes due jobs until ctx is cancelled.
+func (s *scheduledJobService) Run(ctx context.Context) {
+	s.failInterrupted()
+
+	for {
+		wait := jobPollInterval
+		if next, ok := s.runDue(ctx); ok {
+			if d := time.Until(next); d < wait {
+				wait = d
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// failInterrupted marks jobs that were running when the server stopped.
+// Their outcome is unknown, so they are not retried.
+func (s *scheduledJobService) failInterrupted() {
+	running, err := s.jobs.ListByStatus(models.ScheduledJobRunning)
+	if err != nil {
+		s.log.WithError(err).Error("could not load running jobs")
+		return
+	}
+	for _, job := range running {
+		s.finish(job, models.ScheduledJobFailed, "interrupted by server shutdown")
+	}
+}
+
+// runDue executes every pending job whose time has come and returns the run
+// time of the next pending job, if any.
+func (s *scheduledJobService) runDue(ctx context.Context) (time.Time, bool) {
+	for ctx.Err() == nil {
+		job, next, ok := s.claimNext()
+		if job == nil {
+			return next, ok
+		}
+		s.execute(ctx, job)
+	}
+	return time.Time{}, false
+}
+
+// claimNext marks the earliest due job as running and returns it. If no job
+// is due it returns the run time of the next pending job instead. Due jobs
+// that missed their grace period under the skip policy are skipped here.
+func (s *scheduledJobService) claimNext() (*models.ScheduledJob, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, err := s.jobs.ListByStatus(models.ScheduledJobPending)
+	if err != nil {
+		s.log.WithError(err).Error("could not load pending jobs")
+		return nil, time.Time{}, false
+	}
+
+	now := time.Now()
+	for _, job := range pending {
+		if job.RunAt.After(now) {
+			return nil, job.RunAt, true
+		}
+
+		late := now.Sub(job.RunAt)
+		if late > time.Duration(job.GraceSeconds)*time.Second && job.CatchUp == models.CatchUpSkip {
+			s.finish(job, models.ScheduledJobSkipped, fmt.Sprintf("missed scheduled time by %s", late.Round(time.Second)))
+			continue
+		}
+
+		job.Status = models.ScheduledJobRunning
+		job.StartedAt = &now
+		if err := s.jobs.Update(job); err != nil {
+			s.log.WithError(err).WithField("job_id", job.ID).Error("could not claim scheduled job")
+			return nil, time.Time{}, false
+		}
+		return job, time.Time{}, false
+	}
+	return nil, time.Time{}, false
+}
+
+func (s *scheduledJobService) execute(ctx context.Context, job *models.ScheduledJob) {
+	s.log.WithFields(logrus.Fields{
+		"job_id":     job.ID,
+		"action":     job.Action,
+		"created_by": job.CreatedBy,
+	}).Info("running scheduled job")
+
+	runCtx, err := s.creatorContext(ctx, job)
+	if err != nil {
+		s.finish(job, models.ScheduledJobFailed, err.Error())
+		return
+	}
+
+	// Queue the run like an interactive apply, so it is serialized with
+	// other applies and can be followed through the apply job API.
+	var applyJob *models.ApplyJob
+	switch job.Action {
+	case models.ScheduledJobApply:
+		applyJob, err = s.applyJobs.SubmitApply(runCtx)
+	case models.ScheduledJobRollback:
+		applyJob, err = s.applyJobs.SubmitRollback(runCtx, job.HistoryID)
+	default:
+		err = fmt.Errorf("invalid job action: %s", job.Action)
+	}
+	if err != nil {
+		s.finish(job, models.ScheduledJobFailed, err.Error())
+		return
+	}
+
+	job.ApplyJobID = applyJob.ID
+	if err := s.jobs.Update(job); err != nil {
+		s.log.WithError(err).WithField("job_id", job.ID).Warn("could not record apply job of scheduled job")
+	}
+
+	updates, err := s.applyJobs.Watch(ctx, applyJob.ID)
+	if err != nil {
+		s.finish(job, models.ScheduledJobFailed, err.Error())
+		return
+	}
+	for applyJob = range updates {
+	}
+	switch applyJob.Status {
+	case models.ApplyJobSucceeded:
+		s.finish(job, models.ScheduledJobSucceeded, "")
+	case models.ApplyJobFailed:
+		s.finish(job, models.ScheduledJobFailed, applyJob.Error)
+	default:
+		// The server is stopping. The job stays running and is marked
+		// interrupted on the next start.
+	}
+}
+
+// creatorContext returns ctx carrying the principal and connection of the
+// job's creator. The creator is resolved again, so a job is refused once its
+// creator was disabled or revoked or lost the apply permission.
+func (s *scheduledJobService) creatorContext(ctx context.Context, job *models.ScheduledJob) (context.Context, error) {
+	c := job.Creator
+	if c == nil {
+		return nil, fmt.Errorf("job was scheduled without a recorded creator, schedule it again")
+	}
+	if c.ClientIP != "" {
+		ctx = WithClientConn(ctx, ClientConn{
+			ClientIP:   c.ClientIP,
+			ClientPort: c.ClientPort,
+			LocalIP:    c.LocalIP,
+			LocalPort:  c.LocalPort,
+			Override:   c.AllowLockout,
+		})
+	}
+	if c.Kind == "system" {
+		return ctx, nil
+	}
+
+	p, err := s.principals.ResolvePrincipal(ctx, &auth.Principal{
+		ID:       c.ID,
+		Name:     job.CreatedBy,
+		Kind:     c.Kind,
+		Bindings: c.Roles,
+		Scopes:   c.Scopes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creator %s is no longer valid", job.CreatedBy)
+	}
+	if !p.Can(auth.PermApply) {
+		return nil, fmt.Errorf("creator %s no longer holds the apply permission: %w", job.CreatedBy, auth.ErrForbidden)
+	}
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+// finish stores the final job status and records the outcome in history.
+func (s *scheduledJobService) finish(job *models.ScheduledJob, status models.ScheduledJobStatus, result string) {
+	now := time.Now()
+	job.Status = status
+	job.Result = result
+	job.FinishedAt = &now
+	if err := s.jobs.Update(job); err != nil {
+		s.log.WithError(err).WithField("job_id", job.ID).Error("could not update scheduled job")
+	}
+
+	description := fmt.Sprintf("scheduled %s job %s %s", job.Action, job.ID, status)
+	if result != "" {
+		description += ": " + result
+	}
+	entry := &models.HistoryEntry{
+		ID:          uuid.New().String(),
+		Kind:        models.HistoryKindEvent,
+		Description: description,
+		AppliedAt:   now,
+	}
+	if err := s.history.Save(entry); err != nil {
+		s.log.WithError(err).WithField("job_id", job.ID).Warn("could not save history entry")
+	}
+
+	logEntry := s.log.WithFields(logrus.Fields{"job_id": job.ID, "status": status})
+	if status == models.ScheduledJobFailed {
+		logEntry.WithField("result", result).Error("scheduled job finished")
+	} else {
+		logEntry.Info("scheduled job finished")
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeScheduledJobRepo struct{ jobs []*models.ScheduledJob }
+
+func (r *fakeScheduledJobRepo) List() ([]*models.ScheduledJob, error) { return r.jobs, nil }
+func (r *fakeScheduledJobRepo) Get(id string) (*models.ScheduledJob, error) {
+	for _, job := range r.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, errors.New("scheduled job not found: " + id)
+}
+func (r *fakeScheduledJobRepo) ListByStatus(status models.ScheduledJobStatus) ([]*models.ScheduledJob, error) {
+	var jobs []*models.ScheduledJob
+	for _, job := range r.jobs {
+		if job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+func (r *fakeScheduledJobRepo) Create(job *models.ScheduledJob) error {
+	r.jobs = append(r.jobs, job)
+	return nil
+}
+func (r *fakeScheduledJobRepo) Update(job *models.ScheduledJob) error { return nil }
+
+func TestCreateJobValidation(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	history := &fakeHistoryRepo{entries: []*models.HistoryEntry{
+		{ID: "empty", Kind: models.HistoryKindSnapshot},
+		{ID: "snap", Kind: models.HistoryKindSnapshot, Snapshot: "*filter\nCOMMIT\n"},
+		{ID: "event", Kind: models.HistoryKindEvent, Description: "scheduled apply job succeeded"},
+	}}
+	s := NewScheduledJobService(&fakeScheduledJobRepo{}, history, nil, nil, nopAuditor{}, log)
+	applier := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:     "ops",
+		Bindings: []models.RoleBinding{{Role: models.RoleApplier}},
+	})
+	editor := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:     "dev",
+		Bindings: []models.RoleBinding{{Role: models.RoleRuleEditor}},
+	})
+	runAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		dto  CreateScheduledJobDTO
+		err  string // "" for success
+	}{
+		{"apply", applier, CreateScheduledJobDTO{Action: models.ScheduledJobApply, RunAt: runAt}, ""},
+		{"rollback to a snapshot", applier, CreateScheduledJobDTO{Action: models.ScheduledJobRollback, HistoryID: "snap", RunAt: runAt}, ""},
+		{"rollback to the latest snapshot", applier, CreateScheduledJobDTO{Action: models.ScheduledJobRollback, RunAt: runAt}, ""},
+		{"without apply permission", editor, CreateScheduledJobDTO{Action: models.ScheduledJobApply, RunAt: runAt}, "forbidden"},
+		{"unknown history entry", applier, CreateScheduledJobDTO{Action: models.ScheduledJobRollback, HistoryID: "gone", RunAt: runAt}, "not found"},
+		{"event entry", applier, CreateScheduledJobDTO{Action: models.ScheduledJobRollback, HistoryID: "event", RunAt: runAt}, "not a restorable snapshot"},
+		{"snapshot without rules", applier, CreateScheduledJobDTO{Action: models.ScheduledJobRollback, HistoryID: "empty", RunAt: runAt}, "not a restorable snapshot"},
+		{"apply with history entry", applier, CreateScheduledJobDTO{Action: models.ScheduledJobApply, HistoryID: "snap", RunAt: runAt}, "only valid for rollback"},
+		{"in the past", applier, CreateScheduledJobDTO{Action: models.ScheduledJobApply, RunAt: time.Now().Add(-time.Minute)}, "in the future"},
+	}
+	for _, tt := range tests {
+		_, err := s.CreateJob(tt.ctx, tt.dto)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	// Without any snapshot, a rollback to the latest one has no target.
+	s = NewScheduledJobService(&fakeScheduledJobRepo{}, &fakeHistoryRepo{}, nil, nil, nopAuditor{}, log)
+	if _, err := s.CreateJob(applier, CreateScheduledJobDTO{Action: models.ScheduledJobRollback, RunAt: runAt}); err == nil {
+		t.Error("rollback job accepted without any snapshot")
+	}
+}
+
+// fakeResolver returns the current principal of each known creator ID.
+type fakeResolver map[string]*auth.Principal
+
+func (r fakeResolver) ResolvePrincipal(_ context.Context, p *auth.Principal) (*auth.Principal, error) {
+	if current, ok := r[p.ID]; ok {
+		return current, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// actorAuditor records the principal and connection each action ran with.
+type actorAuditor struct{ actors []string }
+
+func (a *actorAuditor) Record(ctx context.Context, action, _, _ string, _, _ any) {
+	actor := "system"
+	if p := auth.FromContext(ctx); p != nil {
+		actor = p.Name
+	}
+	conn, _ := clientConnFrom(ctx)
+	a.actors = append(a.actors, action+" by "+actor+" from "+conn.ClientIP)
+}
+
+func TestScheduledJobRunsAsCreator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fw, driver, _, history := newTestFirewallService()
+	audit := &actorAuditor{}
+	fw.audit = audit
+	applyJobs := NewApplyJobService(fw, NewEventBus(fw.log), fw.log)
+	go applyJobs.Run(ctx)
+
+	ops := &auth.Principal{ID: "u1", Name: "ops", Kind: "user", Bindings: []models.RoleBinding{{Role: models.RoleApplier}}}
+	resolver := fakeResolver{"u1": ops}
+	repo := &fakeScheduledJobRepo{}
+	s := NewScheduledJobService(repo, history, applyJobs, resolver, nopAuditor{}, fw.log).(*scheduledJobService)
+
+	schedule := func() *models.ScheduledJob {
+		t.Helper()
+		reqCtx := WithClientConn(auth.WithPrincipal(ctx, ops), ClientConn{ClientIP: "192.0.2.10", ClientPort: 50000, LocalIP: "192.0.2.1", LocalPort: 8080})
+		job, err := s.CreateJob(reqCtx, CreateScheduledJobDTO{Action: models.ScheduledJobApply, RunAt: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job.RunAt = time.Now().Add(-time.Second)
+		return job
+	}
+
+	job := schedule()
+	if job.CreatedBy != "ops" || job.Creator == nil || job.Creator.ID != "u1" || job.Creator.ClientIP != "192.0.2.10" {
+		t.Fatalf("creator not recorded: %q %+v", job.CreatedBy, job.Creator)
+	}
+	s.runDue(ctx)
+	if job.Status != models.ScheduledJobSucceeded || len(driver.applies) != 1 {
+		t.Fatalf("job %s (%s), %d applies", job.Status, job.Result, len(driver.applies))
+	}
+	applyJob, err := applyJobs.GetJob(ctx, job.ApplyJobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applyJob.RequestedBy != "ops" || applyJob.Status != models.ApplyJobSucceeded {
+		t.Errorf("apply job requested by %s, %s", applyJob.RequestedBy, applyJob.Status)
+	}
+	if len(audit.actors) != 1 || audit.actors[0] != "apply by ops from 192.0.2.10" {
+		t.Errorf("audited as %q", audit.actors)
+	}
+
+	// The creator's roles are checked again when the job runs.
+	tests := []struct {
+		name    string
+		current *auth.Principal
+		result  string
+	}{
+		{"apply permission revoked", &auth.Principal{ID: "u1", Name: "ops", Kind: "user", Bindings: []models.RoleBinding{{Role: models.RoleViewer}}}, "no longer holds the apply permission"},
+		{"account disabled", nil, "no longer valid"},
+	}
+	for _, tt := range tests {
+		job := schedule()
+		delete(resolver, "u1")
+		if tt.current != nil {
+			resolver["u1"] = tt.current
+		}
+		s.runDue(ctx)
+		if job.Status != models.ScheduledJobFailed || !strings.Contains(job.Result, tt.result) {
+			t.Errorf("%s: job %s (%s), want failed with %q", tt.name, job.Status, job.Result, tt.result)
+		}
+		resolver["u1"] = ops
+	}
+
+	legacy := schedule()
+	legacy.Creator = nil
+	s.runDue(ctx)
+	if legacy.Status != models.ScheduledJobFailed {
+		t.Errorf("job without creator %s", legacy.Status)
+	}
+	if len(driver.applies) != 1 {
+		t.Errorf("refused jobs applied: %d applies", len(driver.applies))
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/conntrack"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidTrafficQuery is returned for top-talker queries with an unknown
+// grouping or direction or an empty window.
+var ErrInvalidTrafficQuery = errors.New("invalid traffic query")
+
+const (
+	defaultTalkerLimit = 10
+	maxTalkerLimit     = 1000
+	// maxTrafficHosts and maxTrafficPeers cap the series one sample writes;
+	// the traffic of the smaller talkers is summed under "other".
+	maxTrafficHosts = 1000
+	maxTrafficPeers = 200
+	trafficOther    = "other"
+)
+
+// DefaultTrafficLocalNets are the networks counted as local hosts when none
+// are configured: the private and link-local ranges. This host's own
+// addresses are always local.
+var DefaultTrafficLocalNets = []string{
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "169.254.0.0/16",
+	"fc00::/7", "fe80::/10",
+}
+
+// TrafficService attributes the bytes and packets of tracked connections to
+// local hosts and remote peers, and ranks them.
+type TrafficService interface {
+	Run(ctx context.Context)
+	Top(ctx context.Context, q models.TrafficQuery) ([]*models.Talker, error)
+	// History returns the rx and tx series of a host or peer.
+	History(ctx context.Context, kind, addr string, from, to time.Time, step time.Duration) ([]*models.CounterSeries, error)
+}
+
+type trafficService struct {
+	driver    conntrack.Driver
+	samples   repository.CounterSampleRepository
+	history   CounterHistoryService
+	localNets []*net.IPNet
+	interval  time.Duration
+	log       *logrus.Logger
+
+	// Touched only by Run.
+	prev   map[connKey]connCounts
+	prevAt time.Time
+}
+
+// connKey identifies a tracked connection across dumps. The kernel reuses
+// IDs, so the original tuple is part of the key.
+type connKey struct {
+	id               uint32
+	proto, src, dst  string
+	srcPort, dstPort int
+}
+
+type connCounts struct {
+	orig, reply models.CounterStats
+}
+
+// trafficSums is what one address sent and received in a sample.
+type trafficSums struct {
+	rx, tx models.CounterStats
+}
+
+func NewTrafficService(
+	driver conntrack.Driver,
+	samples repository.CounterSampleRepository,
+	history CounterHistoryService,
+	localNets []string,
+	interval time.Duration,
+	log *logrus.Logger,
+) (TrafficService, error) {
+	s := &trafficService{driver: driver, samples: samples, history: history, interval: interval, log: log}
+	if len(localNets) == 0 {
+		localNets = DefaultTrafficLocalNets
+	}
+	for _, cidr := range localNets {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local network %q: %w", cidr, err)
+		}
+		s.localNets = append(s.localNets, n)
+	}
+	return s, nil
+}
+
+// Run samples the conntrack counters every interval until ctx is cancelled.
+// A zero interval disables sampling. The samples are stored with the
+// counter time series, which downsamples and expires them.
+func (s *trafficService) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	if b, err := os.ReadFile("/proc/sys/net/netfilter/nf_conntrack_acct"); err == nil && strings.TrimSpace(string(b)) == "0" {
+		s.log.Warn("conntrack accounting is off, traffic is not counted; set net.netfilter.nf_conntrack_acct=1")
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sample(); err != nil {
+			s.log.WithError(err).Error("traffic sampling failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample stores how much traffic every host and peer saw since the previous
+// sample. A connection first seen now started after the previous dump, so
+// all of its counters are new; the first sample after a start only records
+// the current values. What a connection transfers after one dump is lost if
+// it ends before the next.
+func (s *trafficService) sample() error {
+	conns, err := s.driver.List()
+	if err != nil {
+		return fmt.Errorf("list connections: %w", err)
+	}
+	at := time.Now().UTC()
+	local := localAddrs()
+
+	next := make(map[connKey]connCounts, len(conns))
+	hosts := make(map[string]*trafficSums)
+	peers := make(map[string]*trafficSums)
+	for _, c := range conns {
+		key := connKey{
+			id: c.ID, proto: c.Protocol,
+			src: c.Original.Src, dst: c.Original.Dst,
+			srcPort: c.Original.SrcPort, dstPort: c.Original.DstPort,
+		}
+		cur := connCounts{
+			orig:  models.CounterStats{Packets: c.Original.Packets, Bytes: c.Original.Bytes},
+			reply: models.CounterStats{Packets: c.Reply.Packets, Bytes: c.Reply.Bytes},
+		}
+		next[key] = cur
+		if s.prev == nil {
+			continue
+		}
+		old := s.prev[key]
+		sent := models.CounterStats{
+			Packets: counterDelta(old.orig.Packets, cur.orig.Packets),
+			Bytes:   counterDelta(old.orig.Bytes, cur.orig.Bytes),
+		}
+		received := models.CounterStats{
+			Packets: counterDelta(old.reply.Packets, cur.reply.Packets),
+			Bytes:   counterDelta(old.reply.Bytes, cur.reply.Bytes),
+		}
+		if sent.Bytes == 0 && received.Bytes == 0 && sent.Packets == 0 && received.Packets == 0 {
+			continue
+		}
+
+		// The initiator sent the original direction; the responder is where
+		// the reply comes from, after destination NAT.
+		host, peer := c.Original.Src, c.Reply.Src
+		if !s.isLocal(host, local) && s.isLocal(peer, local) {
+			host, peer = peer, host
+			sent, received = received, sent
+		}
+		addTraffic(hosts, host, sent, received)
+		addTraffic(peers, peer, sent, received)
+	}
+
+	prevAt := s.prevAt
+	first := s.prev == nil
+	s.prev, s.prevAt = next, at
+	if first {
+		return nil
+	}
+	span := int64(math.Round(at.Sub(prevAt).Seconds()))
+	if span <= 0 {
+		return nil
+	}
+
+	var samples []*models.CounterSample
+	samples = appendTraffic(samples, "host/", capTalkers(hosts, maxTrafficHosts), at, span)
+	samples = appendTraffic(samples, "peer/", capTalkers(peers, maxTrafficPeers), at, span)
+	if len(samples) == 0 {
+		return nil
+	}
+	return s.samples.Insert(samples)
+}
+
+// isLocal reports whether addr is this host or inside a local network.
+func (s *trafficService) isLocal(addr string, local map[string]bool) bool {
+	if local[addr] {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range s.localNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func addTraffic(sums map[string]*trafficSums, addr string, sent, received models.CounterStats) {
+	t := sums[addr]
+	if t == nil {
+		t = &trafficSums{}
+		sums[addr] = t
+	}
+	t.tx.Packets += sent.Packets
+	t.tx.Bytes += sent.Bytes
+	t.rx.Packets += received.Packets
+	t.rx.Bytes += received.Bytes
+}
+
+// capTalkers keeps the max addresses with the most bytes and sums the rest
+// under "other".
+func capTalkers(sums map[string]*trafficSums, max int) map[string]*trafficSums {
+	if len(sums) <= max {
+		return sums
+	}
+	addrs := make([]string, 0, len(sums))
+	for addr := range sums {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		a, b := sums[addrs[i]], sums[addrs[j]]
+		return a.rx.Bytes+a.tx.Bytes > b.rx.Bytes+b.tx.Bytes
+	})
+	capped := make(map[string]*trafficSums, max+1)
+	for _, addr := range addrs[:max] {
+		capped[addr] = sums[addr]
+	}
+	for _, addr := range addrs[max:] {
+		t := sums[addr]
+		addTraffic(capped, trafficOther, t.tx, t.rx)
+	}
+	return capped
+}
+
+func appendTraffic(samples []*models.CounterSample, prefix string, sums map[string]*trafficSums, at time.Time, span int64) []*models.CounterSample {
+	for addr, t := range sums {
+		samples = append(samples,
+			&models.CounterSample{Series: prefix + addr + "/rx", At: at, Span: span, Packets: t.rx.Packets, Bytes: t.rx.Bytes},
+			&models.CounterSample{Series: prefix + addr + "/tx", At: at, Span: span, Packets: t.tx.Packets, Bytes: t.tx.Bytes},
+		)
+	}
+	return samples
+}
+
+// Top ranks the hosts or peers by their traffic in the window.
+func (s *trafficService) Top(_ context.Context, q models.TrafficQuery) ([]*models.Talker, error) {
+	if q.By != "host" && q.By != "peer" {
+		return nil, fmt.Errorf("%w: by must be host or peer", ErrInvalidTrafficQuery)
+	}
+	if q.Direction != "rx" && q.Direction != "tx" && q.Direction != "total" {
+		return nil, fmt.Errorf("%w: direction must be rx, tx or total", ErrInvalidTrafficQuery)
+	}
+	if !q.To.After(q.From) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidTrafficQuery)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultTalkerLimit
+	}
+	if q.Limit > maxTalkerLimit {
+		q.Limit = maxTalkerLimit
+	}
+
+	prefix := q.By + "/"
+	totals, err := s.samples.Totals(prefix, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]*models.Talker)
+	talkers := make([]*models.Talker, 0)
+	for _, sum := range totals {
+		rest := strings.TrimPrefix(sum.Series, prefix)
+		i := strings.LastIndexByte(rest, '/')
+		if i < 0 {
+			continue
+		}
+		addr, dir := rest[:i], rest[i+1:]
+		t := index[addr]
+		if t == nil {
+			t = &models.Talker{Address: addr}
+			index[addr] = t
+			talkers = append(talkers, t)
+		}
+		switch dir {
+		case "rx":
+			t.RxBytes += sum.Bytes
+			t.RxPackets += sum.Packets
+		case "tx":
+			t.TxBytes += sum.Bytes
+			t.TxPackets += sum.Packets
+		}
+	}
+
+	window := q.To.Sub(q.From).Seconds()
+	for _, t := range talkers {
+		t.Bytes = t.RxBytes + t.TxBytes
+		t.BPS = float64(t.Bytes) * 8 / window
+	}
+	rank := func(t *models.Talker) uint64 {
+		switch q.Direction {
+		case "rx":
+			return t.RxBytes
+		case "tx":
+			return t.TxBytes
+		}
+		return t.Bytes
+	}
+	sort.SliceStable(talkers, func(i, j int) bool {
+		a, b := rank(talkers[i]), rank(talkers[j])
+		if a != b {
+			return a > b
+		}
+		return talkers[i].Address < talkers[j].Address
+	})
+	if len(talkers) > q.Limit {
+		talkers = talkers[:q.Limit]
+	}
+	return talkers, nil
+}
+
+func (s *trafficService) History(ctx context.Context, kind, addr string, from, to time.Time, step time.Duration) ([]*models.CounterSeries, error) {
+	prefix := kind + "/" + addr + "/"
+	return s.history.Query(ctx, models.CounterQuery{
+		Series: []string{prefix + "rx", prefix + "tx"},
+		From:   from,
+		To:     to,
+		Step:   step,
+	})
+}
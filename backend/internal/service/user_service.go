@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/firewall-manager/backend/internal/auth"
+	"github.com/firewall-manager/backend/internal/models"
+	"github.com/firewall-manager/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+// CreateUserDTO is the input for creating a user
+type CreateUserDTO struct {
+	Username string               `json:"username" binding:"required"`
+	Password string               `json:"password" binding:"required"`
+	Disabled bool                 `json:"disabled"`
+	Roles    []models.RoleBinding `json:"roles"`
+}
+
+// UpdateUserDTO is the input for updating a user. An empty password keeps
+// the current one.
+type UpdateUserDTO struct {
+	Password string               `json:"password"`
+	Disabled bool                 `json:"disabled"`
+	Roles    []models.RoleBinding `json:"roles"`
+}
+
+// UserService manages local user accounts
+type UserService interface {
+	ListUsers(ctx context.Context) ([]*models.User, error)
+	CreateUser(ctx context.Context, dto CreateUserDTO) (*models.User, error)
+	UpdateUser(ctx context.Context, id string, dto UpdateUserDTO) (*models.User, error)
+	DeleteUser(ctx context.Context, id string) error
+	EnsureAdmin(ctx context.Context, username, password string) error
+}
+
+type userService struct {
+	users repository.UserRepository
+	audit Auditor
+	log   *logrus.Logger
+}
+
+func NewUserService(users repository.UserRepository, audit Auditor, log *logrus.Logger) UserService {
+	return &userService{users: users, audit: audit, log: log}
+}
+
+func (s *userService) ListUsers(_ context.Context) ([]*models.User, error) {
+	return s.users.List()
+}
+
+func (s *userService) CreateUser(ctx context.Context, dto CreateUserDTO) (*models.User, error) {
+	if !validName(dto.Username) {
+		return nil, fmt.Errorf("invalid username: %s", dto.Username)
+	}
+	if err := validateRoles(dto.Roles); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(dto.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Username:     dto.Username,
+		PasswordHash: hash,
+		Disabled:     dto.Disabled,
+		Roles:        dto.Roles,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if user.Roles == nil {
+		user.Roles = []models.RoleBinding{}
+	}
+	if err := s.users.Create(user); err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+	s.audit.Record(ctx, "user.create", "user", user.ID, nil, user)
+
+	s.log.WithField("username", user.Username).Info("user created")
+	return user, nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, id string, dto UpdateUserDTO) (*models.User, error) {
+	if err := validateRoles(dto.Roles); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *user
+	if dto.Password != "" {
+		if user.PasswordHash, err = hashPassword(dto.Password); err != nil {
+			return nil, err
+		}
+	}
+	user.Disabled = dto.Disabled
+	user.Roles = dto.Roles
+	if user.Roles == nil {
+		user.Roles = []models.RoleBinding{}
+	}
+
+	if err := s.users.Update(user); err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	s.audit.Record(ctx, "user.update", "user", user.ID, before, user)
+
+	s.log.WithField("username", user.Username).Info("user updated")
+	return user, nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id string) error {
+	if p := auth.FromContext(ctx); p != nil && p.Kind == "user" && p.ID == id {
+		return fmt.Errorf("cannot delete your own account")
+	}
+	before, err := s.users.Get(id)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	if err := s.users.Delete(id); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	s.audit.Record(ctx, "user.delete", "user", id, before, nil)
+	s.log.WithField("user_id", id).Info("user deleted")
+	return nil
+}
+
+// EnsureAdmin creates a global admin account if no users exist yet. It lets
+// a fresh installation be bootstrapped from configuration.
+func (s *userService) EnsureAdmin(ctx context.Context, username, password string) error {
+	n, err := s.users.Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err = s.CreateUser(ctx, CreateUserDTO{
+		Username: username,
+		Password: password,
+		Roles:    []models.RoleBinding{{Role: models.RoleAdmin}},
+	})
+	return err
+}
+
+func validateRoles(roles []models.RoleBinding) error {
+	for _, b := range roles {
+		if err := auth.ValidateBinding(b); err != nil {
+			return err
+		}
+		if b.Tag != "" && !validName(b.Tag) {
+			return fmt.Errorf("invalid tag: %s", b.Tag)
+		}
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// validName reports whether s is a usable username or tag: 1-64 characters
+// from [a-zA-Z0-9._-].
+func validName(s string) bool {
+	if s == "" || len(s) > 64 {
+		return false
+	}
+	for _, c := range s {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/firewall-manager/backend/internal/models"
+)
+
+// ErrVerificationFailed is returned when the live state read back after an
+// apply does not match what was rendered. The apply has been rolled back.
+var ErrVerificationFailed = errors.New("post-apply verification failed")
+
+// verifyApply checks the kernel against what applyRules just loaded and, on
+// a mismatch or if the state cannot be read back, restores the pre-apply
+// snapshot. The report is returned even when the check fails so it can be
+// kept with the history entry.
+func (s *firewallService) verifyApply(ctx context.Context, compiled []*models.Rule, natRules []*models.NATRule, cfg *models.FirewallConfig, snapshot string) (*models.VerificationReport, error) {
+	report, err := s.driver.Verify(compiled, natRules, cfg)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	} else if !report.OK {
+		m := report.Mismatches[0]
+		where := strings.TrimSpace(m.Table + " " + m.Chain)
+		if m.Position > 0 {
+			where += fmt.Sprintf(" #%d", m.Position)
+		}
+		err = fmt.Errorf("%w: %d mismatches, first in %s: expected %q, found %q",
+			ErrVerificationFailed, len(report.Mismatches), where, m.Expected, m.Actual)
+	}
+	if err == nil {
+		logStep(ctx, "verified %d rules, %d policies, %d sysctls", report.Rules, report.Policies, report.Sysctls)
+		return report, nil
+	}
+
+	s.log.WithError(err).Error("live state does not match applied ruleset")
+	logStep(ctx, "verification failed: %v", err)
+	if snapshot == "" {
+		return report, fmt.Errorf("%w; not rolled back: no snapshot was taken", err)
+	}
+	if rbErr := restoreSnapshot(snapshot)(); rbErr != nil {
+		return report, fmt.Errorf("%w; rollback failed: %v", err, rbErr)
+	}
+	logStep(ctx, "pre-apply snapshot restored")
+	return report, fmt.Errorf("%w; rolled back", err)
+}
@@ -0,0 +1,39 @@
+package tlsutil
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// ClientCertificate returns the verified client certificate of r, or nil.
+// Certificates that were sent but not verified against the client CA are
+// ignored.
+func ClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// Identities lists the names a certificate can be mapped by, most specific
+// first: "cn:<common name>", then "dns:", "email:", "uri:" and "ip:" for
+// each subject alternative name.
+func Identities(cert *x509.Certificate) []string {
+	var ids []string
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, "cn:"+cert.Subject.CommonName)
+	}
+	for _, n := range cert.DNSNames {
+		ids = append(ids, "dns:"+n)
+	}
+	for _, n := range cert.EmailAddresses {
+		ids = append(ids, "email:"+n)
+	}
+	for _, u := range cert.URIs {
+		ids = append(ids, "uri:"+u.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		ids = append(ids, "ip:"+ip.String())
+	}
+	return ids
+}
@@ -0,0 +1,175 @@
+// Package tlsutil serves TLS from certificate files that can be replaced at
+// runtime, optionally verifying client certificates.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ParseClientAuth maps a configured client auth mode to its tls constant:
+// "none", "optional" (verify a certificate if one is sent) or "require".
+func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(mode) {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("invalid client auth mode: %s", mode)
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader holds the current certificate and client CA pool. Reload replaces
+// them atomically; a failed reload keeps the previous ones in service.
+type Reloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+	log        *logrus.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]fileStamp
+}
+
+// NewReloader loads the files once. caFile may be empty when client
+// certificates are not used.
+func NewReloader(certFile, keyFile, caFile string, clientAuth tls.ClientAuthType, log *logrus.Logger) (*Reloader, error) {
+	if clientAuth != tls.NoClientCert && caFile == "" {
+		return nil, fmt.Errorf("client certificate verification needs a CA file")
+	}
+	r := &Reloader{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		caFile:     caFile,
+		clientAuth: clientAuth,
+		log:        log,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate, key and CA bundle from disk.
+func (r *Reloader) Reload() error {
+	stamps := r.currentStamps()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		cert.Leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA file %s contains no certificates", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.stamps = stamps
+	r.mu.Unlock()
+
+	fields := logrus.Fields{"cert": r.certFile}
+	if cert.Leaf != nil {
+		fields["subject"] = cert.Leaf.Subject.String()
+		fields["not_after"] = cert.Leaf.NotAfter
+	}
+	r.log.WithFields(fields).Info("tls certificate loaded")
+	return nil
+}
+
+// Watch reloads whenever one of the files changes, checking every interval,
+// until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.RLock()
+			changed := !sameStamps(r.stamps, r.currentStamps())
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				// Files are often replaced one at a time; retry next tick.
+				r.log.WithError(err).Warn("tls reload failed, keeping previous certificate")
+			}
+		}
+	}
+}
+
+// TLSConfig returns a server config that always uses the current material.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+func (r *Reloader) currentStamps() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		if fi, err := os.Stat(f); err == nil {
+			stamps[f] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return stamps
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -1,14 +1,35 @@
+import { useState, useEffect } from 'react'
 import { Routes, Route } from 'react-router-dom'
 import { Sidebar } from './components/Sidebar'
 import { Dashboard } from './pages/Dashboard'
 import { RulesPage } from './pages/RulesPage'
 import { InterfacesPage } from './pages/InterfacesPage'
 import { Settings } from './pages/Settings'
+import { LoginPage } from './pages/LoginPage'
+import { api, hasSession, onUnauthorized } from './services/api'
 
 export default function App() {
+  const [loggedIn, setLoggedIn] = useState(hasSession())
+
+  useEffect(() => {
+    onUnauthorized(() => setLoggedIn(false))
+  }, [])
+
+  if (!loggedIn) {
+    return <LoginPage onLogin={() => setLoggedIn(true)} />
+  }
+
+  const handleLogout = async () => {
+    try {
+      await api.logout()
+    } finally {
+      setLoggedIn(false)
+    }
+  }
+
   return (
     <div className="flex min-h-screen bg-gray-50 dark:bg-gray-900">
-      <Sidebar />
+      <Sidebar onLogout={handleLogout} />
       <main className="flex-1 ml-64">
         <Routes>
           <Route path="/" element={<Dashboard />} />
@@ -19,4 +40,4 @@ export default function App() {
       </main>
     </div>
   )
-}
\ No newline at end of file
+}
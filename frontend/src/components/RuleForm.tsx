@@ -1,5 +1,5 @@
 import { useState } from 'react'
-import type { Rule, CreateRulePayload, Chain, Protocol, Action } from '../types'
+import type { Rule, CreateRulePayload, Chain, Protocol, Action, LogOptions } from '../types'
 
 const CHAINS: Chain[] = ['INPUT', 'OUTPUT', 'FORWARD']
 const PROTOCOLS: Protocol[] = ['tcp', 'udp', 'icmp', 'all']
@@ -39,6 +39,10 @@ export function RuleForm({ initial, onSubmit, onCancel, submitLabel = 'Save' }:
     setForm((prev) => ({ ...prev, [key]: value }))
   }
 
+  function setLog<K extends keyof LogOptions>(key: K, value: LogOptions[K]) {
+    setForm((prev) => ({ ...prev, log: { ...prev.log, [key]: value || undefined } }))
+  }
+
   async function handleSubmit(e: React.FormEvent) {
     e.preventDefault()
     setBusy(true)
@@ -123,6 +127,29 @@ export function RuleForm({ initial, onSubmit, onCancel, submitLabel = 'Save' }:
         </div>
       </div>
 
+      <div className="grid grid-cols-2 gap-4">
+        <div>
+          <label className="label">In Interface</label>
+          <input
+            className="input"
+            placeholder="any"
+            disabled={form.chain === 'OUTPUT'}
+            value={form.inInterface ?? ''}
+            onChange={(e) => set('inInterface', e.target.value)}
+          />
+        </div>
+        <div>
+          <label className="label">Out Interface</label>
+          <input
+            className="input"
+            placeholder="any"
+            disabled={form.chain === 'INPUT'}
+            value={form.outInterface ?? ''}
+            onChange={(e) => set('outInterface', e.target.value)}
+          />
+        </div>
+      </div>
+
       <div className="grid grid-cols-2 gap-4">
         <div>
           <label className="label">Action</label>
@@ -158,6 +185,45 @@ export function RuleForm({ initial, onSubmit, onCancel, submitLabel = 'Save' }:
         />
       </div>
 
+      {form.action !== 'LOG' && (
+        <div className="flex items-center gap-2">
+          <input
+            id="log"
+            type="checkbox"
+            className="h-4 w-4 rounded border-gray-300 text-blue-600 focus:ring-blue-500"
+            checked={form.log !== undefined}
+            onChange={(e) => set('log', e.target.checked ? {} : undefined)}
+          />
+          <label htmlFor="log" className="text-sm text-gray-700 dark:text-gray-300">
+            Log matching packets before the verdict
+          </label>
+        </div>
+      )}
+
+      {(form.action === 'LOG' || form.log !== undefined) && (
+        <div className="grid grid-cols-2 gap-4">
+          <div>
+            <label className="label">Log Prefix</label>
+            <input
+              className="input"
+              placeholder="Defaults to the rule tag"
+              maxLength={28}
+              value={form.log?.prefix ?? ''}
+              onChange={(e) => setLog('prefix', e.target.value)}
+            />
+          </div>
+          <div>
+            <label className="label">Log Rate Limit</label>
+            <input
+              className="input"
+              placeholder="e.g. 5/minute"
+              value={form.log?.limit ?? ''}
+              onChange={(e) => setLog('limit', e.target.value)}
+            />
+          </div>
+        </div>
+      )}
+
       <div className="flex items-center gap-2">
         <input
           id="enabled"
@@ -1,5 +1,5 @@
 import { NavLink } from 'react-router-dom'
-import { Shield, LayoutDashboard, List, Settings, Network } from 'lucide-react'
+import { Shield, LayoutDashboard, List, Settings, Network, LogOut } from 'lucide-react'
 
 const links = [
   { to: '/', icon: LayoutDashboard, label: 'Dashboard' },
@@ -8,7 +8,11 @@ const links = [
   { to: '/settings', icon: Settings, label: 'Settings' },
 ]
 
-export function Sidebar() {
+interface SidebarProps {
+  onLogout: () => void
+}
+
+export function Sidebar({ onLogout }: SidebarProps) {
   return (
     <aside className="w-64 min-h-screen bg-gray-900 text-gray-100 flex flex-col">
       <div className="flex items-center gap-3 px-6 py-5 border-b border-gray-700">
@@ -39,7 +43,14 @@ export function Sidebar() {
         ))}
       </nav>
 
-      <div className="px-6 py-4 border-t border-gray-700">
+      <div className="px-6 py-4 border-t border-gray-700 space-y-3">
+        <button
+          onClick={onLogout}
+          className="flex items-center gap-2 text-sm text-gray-400 hover:text-white transition-colors"
+        >
+          <LogOut size={16} />
+          Sign out
+        </button>
         <p className="text-xs text-gray-500">Kernel: Linux iptables</p>
       </div>
     </aside>
@@ -1,6 +1,6 @@
-import { useState, useEffect, useCallback } from 'react'
-import type { Counter } from '../types'
-import { api } from '../services/api'
+import { useState, useEffect, useCallback, useRef } from 'react'
+import type { Counter, CounterSampleEvent } from '../types'
+import { api, subscribeEvents } from '../services/api'
 
 export function useCounters(refreshInterval = 5000) {
   const [counters, setCounters] = useState<Counter[]>([])
@@ -20,10 +20,35 @@ export function useCounters(refreshInterval = 5000) {
     }
   }, [])
 
+  // Counter samples arrive on the event stream; poll only while it is down.
+  const streaming = useRef(false)
+
   useEffect(() => {
     fetch()
-    const timer = setInterval(fetch, refreshInterval)
-    return () => clearInterval(timer)
+    const unsubscribe = subscribeEvents(
+      ['counters.sample', 'job.succeeded'],
+      (event) => {
+        streaming.current = true
+        const sample = event.data as CounterSampleEvent | undefined
+        if (event.type === 'counters.sample' && sample?.rules) {
+          setCounters(sample.rules)
+          setError(null)
+        } else {
+          // An apply or rollback reset the counters.
+          fetch()
+        }
+      },
+      () => {
+        streaming.current = false
+      }
+    )
+    const timer = setInterval(() => {
+      if (!streaming.current) fetch()
+    }, refreshInterval)
+    return () => {
+      unsubscribe()
+      clearInterval(timer)
+    }
   }, [fetch, refreshInterval])
 
   return { counters, loading, error, refresh: fetch }
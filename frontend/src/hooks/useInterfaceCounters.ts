@@ -1,5 +1,5 @@
 import { useState, useEffect, useRef } from 'react'
-import { api } from '../services/api'
+import { api, subscribeEvents } from '../services/api'
 import type { Interface, InterfaceCounters } from '../types'
 
 export interface ChartDataPoint {
@@ -90,9 +90,27 @@ export function useInterfaceCounters() {
     }
 
     fetchCounters()
-    const interval = setInterval(fetchCounters, 5000) // Refresh every 5 seconds
+    // Refresh on every counter sample; poll every 5 seconds while the event
+    // stream is down.
+    let streaming = false
+    const unsubscribe = subscribeEvents(
+      ['counters.sample'],
+      () => {
+        streaming = true
+        fetchCounters()
+      },
+      () => {
+        streaming = false
+      }
+    )
+    const interval = setInterval(() => {
+      if (!streaming) fetchCounters()
+    }, 5000)
 
-    return () => clearInterval(interval)
+    return () => {
+      unsubscribe()
+      clearInterval(interval)
+    }
   }, [selectedInterface])
 
   return {
@@ -1,7 +1,7 @@
-import { useState, useCallback } from 'react'
+import { useState, useCallback, useEffect } from 'react'
 import toast from 'react-hot-toast'
 import type { Rule, CreateRulePayload, UpdateRulePayload } from '../types'
-import { api } from '../services/api'
+import { api, subscribeEvents } from '../services/api'
 
 export function useRules() {
   const [rules, setRules] = useState<Rule[]>([])
@@ -20,6 +20,21 @@ export function useRules() {
     }
   }, [])
 
+  // Changes made by other operators arrive on the event stream.
+  useEffect(() => {
+    return subscribeEvents(['rule'], (event) => {
+      const rule = event.data as Rule | undefined
+      if (!rule || !event.resourceId) return
+      if (event.type === 'rule.delete') {
+        setRules((prev) => prev.filter((r) => r.id !== event.resourceId))
+        return
+      }
+      setRules((prev) =>
+        [...prev.filter((r) => r.id !== rule.id), rule].sort((a, b) => a.position - b.position)
+      )
+    })
+  }, [])
+
   const createRule = useCallback(async (payload: CreateRulePayload) => {
     const rule = await api.createRule(payload)
     setRules((prev) => [...prev, rule].sort((a, b) => a.position - b.position))
@@ -43,7 +58,14 @@ export function useRules() {
   const applyRules = useCallback(async () => {
     setApplying(true)
     try {
-      await api.applyRules()
+      try {
+        await api.applyRules()
+      } catch (err) {
+        // The server refuses rulesets that would drop this session.
+        const message = (err as Error).message
+        if (!message.includes('lock out') || !window.confirm(`${message}\n\nApply anyway?`)) throw err
+        await api.applyRules(true)
+      }
       toast.success('Ruleset applied to kernel ✓')
     } catch (err) {
       toast.error(`Apply failed: ${(err as Error).message}`)
@@ -3,12 +3,14 @@ import { Trash2, Plus, Edit2, Network, RefreshCw } from 'lucide-react'
 import toast from 'react-hot-toast'
 import { api } from '../services/api'
 import { Modal } from '../components/Modal'
-import type { NetworkInterface, Zone, PolicyType } from '../types'
+import type { NetworkInterface, Zone, PolicyType, InterfaceAddress } from '../types'
 
 interface ExtendedNetworkInterface extends NetworkInterface {
   ip?: string
   mask?: string
   gateway?: string
+  gateway6?: string
+  addresses?: InterfaceAddress[]
 }
 
 export function InterfacesPage() {
@@ -312,6 +314,19 @@ export function InterfacesPage() {
                             GW: {extendedIface.gateway}
                           </div>
                         )}
+                        {extendedIface.addresses
+                          ?.filter((a) => a.ip !== extendedIface.ip)
+                          .map((a) => (
+                            <div key={`${a.ip}/${a.prefixLen}`} className="text-xs text-gray-500 font-mono mt-0.5">
+                              {a.ip}/{a.prefixLen}
+                              {a.scope !== 'global' && ` (${a.scope})`}
+                            </div>
+                          ))}
+                        {extendedIface.gateway6 && (
+                          <div className="text-xs text-gray-500 font-mono mt-0.5">
+                            GW6: {extendedIface.gateway6}
+                          </div>
+                        )}
                         {iface.notes && (
                           <p className="text-xs text-gray-500 mt-1">{iface.notes}</p>
                         )}
@@ -0,0 +1,73 @@
+import { useState } from 'react'
+import { Shield, LogIn } from 'lucide-react'
+import toast from 'react-hot-toast'
+import { api, OIDC_LOGIN_URL } from '../services/api'
+
+interface LoginPageProps {
+  onLogin: () => void
+}
+
+export function LoginPage({ onLogin }: LoginPageProps) {
+  const [username, setUsername] = useState('')
+  const [password, setPassword] = useState('')
+  const [submitting, setSubmitting] = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setSubmitting(true)
+    try {
+      await api.login(username, password)
+      onLogin()
+    } catch (err) {
+      toast.error((err as Error).message)
+    } finally {
+      setSubmitting(false)
+    }
+  }
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-gray-50 dark:bg-gray-900">
+      <div className="card w-full max-w-sm p-6 space-y-5">
+        <div className="flex items-center gap-3">
+          <Shield className="text-blue-400" size={26} />
+          <div>
+            <div className="font-bold text-gray-900 dark:text-gray-100 leading-tight">Firewall</div>
+            <div className="text-xs text-gray-500">Manager</div>
+          </div>
+        </div>
+
+        <form onSubmit={handleSubmit} className="space-y-4">
+          <div>
+            <label className="label">Username</label>
+            <input
+              className="input"
+              autoComplete="username"
+              value={username}
+              onChange={(e) => setUsername(e.target.value)}
+              required
+            />
+          </div>
+          <div>
+            <label className="label">Password</label>
+            <input
+              type="password"
+              className="input"
+              autoComplete="current-password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              required
+            />
+          </div>
+          <button type="submit" className="btn-primary w-full flex items-center justify-center gap-2" disabled={submitting}>
+            <LogIn size={16} />
+            {submitting ? 'Signing in...' : 'Sign in'}
+          </button>
+        </form>
+
+        <a href={OIDC_LOGIN_URL} className="btn-secondary w-full flex items-center justify-center">
+          Sign in with SSO
+        </a>
+      </div>
+    </div>
+  )
+}
@@ -74,6 +74,12 @@ function SortableRow({ rule, onEdit, onDelete, onToggle }: SortableRowProps) {
           />
         </button>
       </td>
+      <td
+        className="px-4 py-3 font-mono text-xs text-gray-500"
+        title={rule.lastHit ? `Last hit ${new Date(rule.lastHit).toLocaleString()}` : 'No hits seen yet'}
+      >
+        {(rule.packets ?? 0).toLocaleString()}
+      </td>
       <td className="px-4 py-3 text-sm text-gray-500 max-w-[160px] truncate">{rule.comment}</td>
       <td className="px-4 py-3">
         <div className="flex items-center gap-2">
@@ -210,6 +216,7 @@ export function RulesPage() {
                   <th className="px-4 py-3">Port</th>
                   <th className="px-4 py-3">Action</th>
                   <th className="px-4 py-3">Enabled</th>
+                  <th className="px-4 py-3">Hits</th>
                   <th className="px-4 py-3">Comment</th>
                   <th className="px-4 py-3" />
                 </tr>
@@ -223,7 +230,7 @@ export function RulesPage() {
                     {filtered.length === 0 ? (
                       <tr>
                         <td
-                          colSpan={11}
+                          colSpan={12}
                           className="px-4 py-16 text-center text-gray-400 text-sm"
                         >
                           {filter ? 'No rules match your filter.' : 'No rules yet. Add one to get started.'}
@@ -4,6 +4,8 @@ import type {
   CreateRulePayload,
   UpdateRulePayload,
   Counter,
+  RuleCounters,
+  ApplyJob,
   FirewallConfig,
   NetworkInterface,
   Zone,
@@ -16,22 +18,91 @@ import type {
   UpdateZonePayload,
   Interface,
   InterfaceCounters,
+  CounterSeries,
+  CounterHistoryQuery,
+  FirewallEvent,
+  LogEntry,
+  LogQuery,
+  Connection,
+  ConnectionQuery,
+  Talker,
+  TrafficTopQuery,
+  AlertRule,
+  AlertRulePayload,
+  Alert,
+  AlertQuery,
+  AlertSilence,
+  CreateAlertSilencePayload,
+  NotificationSink,
+  NotificationSinkPayload,
+  NotificationDelivery,
+  NotificationDeliveryQuery,
+  InterfaceAddress,
+  LoginResult,
 } from '../types'
 
-const API_KEY = import.meta.env.VITE_API_KEY ?? 'dev-insecure-key-change-in-production'
+const SESSION_KEY = 'fwmg.session'
+
+// After an OIDC login the server redirects here with the session token in
+// the URL fragment. Keep it for this tab and remove it from the address bar.
+function captureSession(): string | null {
+  const params = new URLSearchParams(window.location.hash.slice(1))
+  const session = params.get('session')
+  if (session) {
+    sessionStorage.setItem(SESSION_KEY, session)
+    window.history.replaceState(null, '', window.location.pathname + window.location.search)
+  }
+  return sessionStorage.getItem(SESSION_KEY)
+}
+
+let credential = captureSession()
+let unauthorizedHandler: (() => void) | null = null
+
+function setSession(token: string | null) {
+  credential = token
+  if (token) sessionStorage.setItem(SESSION_KEY, token)
+  else sessionStorage.removeItem(SESSION_KEY)
+}
+
+// hasSession reports whether the UI holds a session token. Without one it
+// shows the login page.
+export function hasSession(): boolean {
+  return credential !== null
+}
+
+// onUnauthorized registers fn to be called when the server rejects the
+// session, after it has been dropped.
+export function onUnauthorized(fn: () => void) {
+  unauthorizedHandler = fn
+}
+
+// OIDC_LOGIN_URL starts the single sign-on flow; the server redirects back
+// with a session.
+export const OIDC_LOGIN_URL = '/api/auth/oidc/login'
+
+const JOB_POLL_MS = 500
+const EVENT_RETRY_MS = 3000
 
 const client = axios.create({
   baseURL: '/api',
   headers: {
     'Content-Type': 'application/json',
-    Authorization: `Bearer ${API_KEY}`,
   },
   timeout: 15_000,
 })
 
+client.interceptors.request.use((config) => {
+  if (credential) config.headers.Authorization = `Bearer ${credential}`
+  return config
+})
+
 client.interceptors.response.use(
   (res) => res,
   (err) => {
+    if (err.response?.status === 401 && credential) {
+      setSession(null)
+      unauthorizedHandler?.()
+    }
     const message: string =
       err.response?.data?.error ?? err.message ?? 'Unknown error'
     return Promise.reject(new Error(message))
@@ -39,6 +110,21 @@ client.interceptors.response.use(
 )
 
 export const api = {
+  // Session
+  login: async (username: string, password: string): Promise<LoginResult> => {
+    const res = await client.post<LoginResult>('/auth/login', { username, password })
+    setSession(res.data.token)
+    return res.data
+  },
+
+  logout: async (): Promise<void> => {
+    try {
+      await client.post('/auth/logout')
+    } finally {
+      setSession(null)
+    }
+  },
+
   // Rules
   getRules: async (): Promise<Rule[]> => {
     const res = await client.get<{ rules: Rule[] }>('/rules')
@@ -59,13 +145,24 @@ export const api = {
     await client.delete(`/rules/${id}`)
   },
 
-  // Firewall control
-  applyRules: async (): Promise<void> => {
-    await client.post('/apply')
+  // Firewall control. Applies and rollbacks run as server-side jobs; these
+  // wait for the job and reject with its error if it fails.
+  applyRules: async (allowLockout = false, flushConntrack?: boolean): Promise<ApplyJob> => {
+    const params: Record<string, boolean> = {}
+    if (allowLockout) params.allowLockout = true
+    if (flushConntrack !== undefined) params.flushConntrack = flushConntrack
+    const res = await client.post<{ job: ApplyJob }>('/apply', undefined, { params })
+    return waitForJob(res.data.job)
+  },
+
+  rollback: async (): Promise<ApplyJob> => {
+    const res = await client.post<{ job: ApplyJob }>('/rollback')
+    return waitForJob(res.data.job)
   },
 
-  rollback: async (): Promise<void> => {
-    await client.post('/rollback')
+  getJob: async (id: string): Promise<ApplyJob> => {
+    const res = await client.get<{ job: ApplyJob }>(`/jobs/${id}`)
+    return res.data.job
   },
 
   getCounters: async (): Promise<Counter[]> => {
@@ -73,6 +170,16 @@ export const api = {
     return res.data.counters ?? []
   },
 
+  getRuleCounters: async (id: string): Promise<RuleCounters> => {
+    const res = await client.get<{ counters: RuleCounters }>(`/rules/${id}/counters`)
+    return res.data.counters
+  },
+
+  getNATRuleCounters: async (id: string): Promise<RuleCounters> => {
+    const res = await client.get<{ counters: RuleCounters }>(`/nat-rules/${id}/counters`)
+    return res.data.counters
+  },
+
   // Configuration
   getConfig: async (): Promise<FirewallConfig> => {
     const res = await client.get<{ config: FirewallConfig }>('/config')
@@ -107,6 +214,15 @@ export const api = {
     await client.delete(`/interfaces/${id}`)
   },
 
+  addInterfaceAddress: async (id: string, address: string): Promise<InterfaceAddress[]> => {
+    const res = await client.post<{ addresses: InterfaceAddress[] }>(`/interfaces/${id}/addresses`, { address })
+    return res.data.addresses ?? []
+  },
+
+  removeInterfaceAddress: async (id: string, address: string): Promise<void> => {
+    await client.delete(`/interfaces/${id}/addresses`, { params: { address } })
+  },
+
   // Zones
   getZones: async (): Promise<Zone[]> => {
     const res = await client.get<{ zones: Zone[] }>('/zones')
@@ -166,4 +282,245 @@ export const api = {
     const res = await client.get<{ counters: InterfaceCounters }>(`/counters/aggregate`)
     return res.data.counters
   },
-}
\ No newline at end of file
+
+  getCounterSeries: async (): Promise<string[]> => {
+    const res = await client.get<{ series: string[] }>('/counters/series')
+    return res.data.series ?? []
+  },
+
+  getCounterHistory: async (query: CounterHistoryQuery): Promise<CounterSeries[]> => {
+    const params = new URLSearchParams()
+    query.series.forEach((s) => params.append('series', s))
+    if (query.from) params.set('from', query.from)
+    if (query.to) params.set('to', query.to)
+    if (query.step) params.set('step', query.step)
+    const res = await client.get<{ series: CounterSeries[] }>('/counters/history', { params })
+    return res.data.series ?? []
+  },
+
+  // Traffic accounting
+  getTopTalkers: async (query: TrafficTopQuery = {}): Promise<Talker[]> => {
+    const res = await client.get<{ talkers: Talker[] }>('/traffic/top', { params: query })
+    return res.data.talkers ?? []
+  },
+
+  getTrafficHistory: async (
+    kind: 'host' | 'peer',
+    address: string,
+    query: Omit<CounterHistoryQuery, 'series'> = {},
+  ): Promise<CounterSeries[]> => {
+    const res = await client.get<{ series: CounterSeries[] }>(
+      `/traffic/${kind}s/${encodeURIComponent(address)}`,
+      { params: query },
+    )
+    return res.data.series ?? []
+  },
+
+  // Alerts
+  getAlerts: async (query: AlertQuery = {}): Promise<Alert[]> => {
+    const res = await client.get<{ alerts: Alert[] }>('/alerts', { params: query })
+    return res.data.alerts ?? []
+  },
+
+  getAlertRules: async (): Promise<AlertRule[]> => {
+    const res = await client.get<{ rules: AlertRule[] }>('/alerts/rules')
+    return res.data.rules ?? []
+  },
+
+  createAlertRule: async (payload: AlertRulePayload): Promise<AlertRule> => {
+    const res = await client.post<{ rule: AlertRule }>('/alerts/rules', payload)
+    return res.data.rule
+  },
+
+  updateAlertRule: async (id: string, payload: AlertRulePayload): Promise<AlertRule> => {
+    const res = await client.put<{ rule: AlertRule }>(`/alerts/rules/${id}`, payload)
+    return res.data.rule
+  },
+
+  deleteAlertRule: async (id: string): Promise<void> => {
+    await client.delete(`/alerts/rules/${id}`)
+  },
+
+  getAlertSilences: async (): Promise<AlertSilence[]> => {
+    const res = await client.get<{ silences: AlertSilence[] }>('/alerts/silences')
+    return res.data.silences ?? []
+  },
+
+  createAlertSilence: async (payload: CreateAlertSilencePayload): Promise<AlertSilence> => {
+    const res = await client.post<{ silence: AlertSilence }>('/alerts/silences', payload)
+    return res.data.silence
+  },
+
+  deleteAlertSilence: async (id: string): Promise<void> => {
+    await client.delete(`/alerts/silences/${id}`)
+  },
+
+  // Notifications
+  getNotificationSinks: async (): Promise<NotificationSink[]> => {
+    const res = await client.get<{ sinks: NotificationSink[] }>('/notifications/sinks')
+    return res.data.sinks ?? []
+  },
+
+  createNotificationSink: async (payload: NotificationSinkPayload): Promise<NotificationSink> => {
+    const res = await client.post<{ sink: NotificationSink }>('/notifications/sinks', payload)
+    return res.data.sink
+  },
+
+  updateNotificationSink: async (id: string, payload: NotificationSinkPayload): Promise<NotificationSink> => {
+    const res = await client.put<{ sink: NotificationSink }>(`/notifications/sinks/${id}`, payload)
+    return res.data.sink
+  },
+
+  deleteNotificationSink: async (id: string): Promise<void> => {
+    await client.delete(`/notifications/sinks/${id}`)
+  },
+
+  // Resolves with the delivery whether or not it succeeded; check its status.
+  testNotificationSink: async (id: string): Promise<NotificationDelivery> => {
+    const res = await client.post<{ delivery: NotificationDelivery }>(`/notifications/sinks/${id}/test`, null, {
+      validateStatus: (status) => status === 200 || status === 502,
+    })
+    return res.data.delivery
+  },
+
+  getNotificationDeliveries: async (query: NotificationDeliveryQuery = {}): Promise<NotificationDelivery[]> => {
+    const res = await client.get<{ deliveries: NotificationDelivery[] }>('/notifications/deliveries', { params: query })
+    return res.data.deliveries ?? []
+  },
+
+  // Firewall log
+  getLogs: async (query: LogQuery = {}): Promise<LogEntry[]> => {
+    const res = await client.get<{ entries: LogEntry[] }>('/logs', { params: logParams(query) })
+    return res.data.entries ?? []
+  },
+
+  // Connection tracking
+  getConnections: async (
+    query: ConnectionQuery = {},
+  ): Promise<{ connections: Connection[]; total: number }> => {
+    const res = await client.get<{ connections: Connection[]; total: number }>('/conntrack', {
+      params: logParams(query),
+    })
+    return { connections: res.data.connections ?? [], total: res.data.total }
+  },
+
+  deleteConnection: async (id: number): Promise<void> => {
+    await client.delete(`/conntrack/${id}`)
+  },
+
+  deleteConnections: async (query: ConnectionQuery): Promise<number> => {
+    const res = await client.delete<{ deleted: number }>('/conntrack', { params: logParams(query) })
+    return res.data.deleted
+  },
+}
+
+// subscribeEvents follows /api/events and calls onEvent for every event of
+// the given types ("rule" matches every rule event). It reconnects with the
+// last event ID after errors; onError reports each failure so callers can
+// fall back to polling. Returns a function that unsubscribes.
+export function subscribeEvents(
+  types: string[],
+  onEvent: (event: FirewallEvent) => void,
+  onError?: (err: Error) => void
+): () => void {
+  let lastId = 0
+  return followStream(
+    () => {
+      const params = new URLSearchParams()
+      if (types.length > 0) params.set('type', types.join(','))
+      if (lastId > 0) params.set('after', String(lastId))
+      return `/api/events?${params}`
+    },
+    (data) => {
+      const event = data as FirewallEvent
+      lastId = event.id
+      onEvent(event)
+    },
+    onError
+  )
+}
+
+// tailLogs follows the firewall log entries matching filter as they are
+// stored. Returns a function that stops it.
+export function tailLogs(
+  filter: LogQuery,
+  onEntry: (entry: LogEntry) => void,
+  onError?: (err: Error) => void
+): () => void {
+  const params = logParams(filter)
+  return followStream(() => `/api/logs/tail?${params}`, (data) => onEntry(data as LogEntry), onError)
+}
+
+// followStream reads the server-sent events of url() and passes the JSON of
+// each to onData, reconnecting after errors. EventSource cannot send the
+// Authorization header, so the stream is read with fetch.
+function followStream(
+  url: () => string,
+  onData: (data: unknown) => void,
+  onError?: (err: Error) => void
+): () => void {
+  const controller = new AbortController()
+
+  async function connect() {
+    const res = await fetch(url(), {
+      headers: credential ? { Authorization: `Bearer ${credential}` } : {},
+      signal: controller.signal,
+    })
+    if (res.status === 401 && credential) {
+      setSession(null)
+      unauthorizedHandler?.()
+    }
+    if (!res.ok || !res.body) throw new Error(`stream: HTTP ${res.status}`)
+
+    const reader = res.body.pipeThrough(new TextDecoderStream()).getReader()
+    let buffer = ''
+    for (;;) {
+      const { value, done } = await reader.read()
+      if (done) return
+      buffer += value
+      let end: number
+      while ((end = buffer.indexOf('\n\n')) >= 0) {
+        const frame = buffer.slice(0, end)
+        buffer = buffer.slice(end + 2)
+        const data = frame
+          .split('\n')
+          .filter((line) => line.startsWith('data:'))
+          .map((line) => line.slice(line.startsWith('data: ') ? 6 : 5))
+          .join('\n')
+        if (!data) continue // keepalive
+        onData(JSON.parse(data))
+      }
+    }
+  }
+
+  ;(async () => {
+    while (!controller.signal.aborted) {
+      try {
+        await connect()
+      } catch (err) {
+        if (controller.signal.aborted) return
+        onError?.(err as Error)
+      }
+      await new Promise((resolve) => setTimeout(resolve, EVENT_RETRY_MS))
+    }
+  })()
+
+  return () => controller.abort()
+}
+
+function logParams(filter: LogQuery | ConnectionQuery): URLSearchParams {
+  const params = new URLSearchParams()
+  Object.entries(filter).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') params.set(key, String(value))
+  })
+  return params
+}
+
+async function waitForJob(job: ApplyJob): Promise<ApplyJob> {
+  while (job.status === 'queued' || job.status === 'running') {
+    await new Promise((resolve) => setTimeout(resolve, JOB_POLL_MS))
+    job = await api.getJob(job.id)
+  }
+  if (job.status === 'failed') throw new Error(job.error ?? 'job failed')
+  return job
+}
@@ -4,6 +4,28 @@ export type Action = 'ACCEPT' | 'DROP' | 'REJECT' | 'LOG'
 export type NATType = 'SNAT' | 'DNAT'
 export type PolicyType = 'ACCEPT' | 'DROP' | 'REJECT'
 
+export type ScheduleMode = 'kernel' | 'scheduler'
+
+export interface Schedule {
+  mode: ScheduleMode
+  days?: string[]
+  startTime?: string
+  endTime?: string
+  timezone?: string
+  startDate?: string
+  endDate?: string
+}
+
+export type LogLevel = 'emerg' | 'alert' | 'crit' | 'error' | 'warning' | 'notice' | 'info' | 'debug'
+
+export interface LogOptions {
+  prefix?: string
+  level?: LogLevel
+  limit?: string
+  burst?: number
+  nflogGroup?: number
+}
+
 export interface Rule {
   id: string
   chain: Chain
@@ -12,12 +34,29 @@ export interface Rule {
   dst: string
   srcPort: string
   dstPort: string
+  inInterface?: string
+  outInterface?: string
   action: Action
   enabled: boolean
   comment: string
   position: number
+  schedule?: Schedule
+  log?: LogOptions
+  active?: boolean
+  nextTransition?: string
   createdAt: string
   updatedAt: string
+  tag?: string
+  packets?: number
+  bytes?: number
+  lastHit?: string
+}
+
+export interface RuleCounters {
+  tag: string
+  packets: number
+  bytes: number
+  lastHit?: string
 }
 
 export interface CreateRulePayload {
@@ -27,17 +66,39 @@ export interface CreateRulePayload {
   dst: string
   srcPort: string
   dstPort: string
+  inInterface?: string
+  outInterface?: string
   action: Action
   enabled: boolean
   comment: string
   position: number
+  schedule?: Schedule
+  log?: LogOptions
 }
 
 export type UpdateRulePayload = CreateRulePayload
 
+export type ApplyJobStatus = 'queued' | 'running' | 'succeeded' | 'failed'
+
+export interface ApplyJob {
+  id: string
+  action: 'apply' | 'rollback'
+  historyId?: string
+  status: ApplyJobStatus
+  error?: string
+  requestedBy: string
+  steps: { at: string; message: string }[]
+  queuedAt: string
+  startedAt?: string
+  finishedAt?: string
+  durationMs?: number
+}
+
 export interface Counter {
+  table?: string
   chain: Chain
   rule: string
+  tag?: string
   packets: number
   bytes: number
 }
@@ -89,6 +150,10 @@ export interface NATRule {
   position: number
   createdAt: string
   updatedAt: string
+  tag?: string
+  packets?: number
+  bytes?: number
+  lastHit?: string
 }
 
 export interface CreateNATRulePayload {
@@ -185,3 +250,294 @@ export interface InterfaceCounters {
   drop: CounterStats;
 
 }
+
+export interface CounterPoint {
+  time: string
+  packets: number
+  bytes: number
+  pps: number
+  bps: number
+}
+
+export interface CounterSeries {
+  series: string
+  step: number
+  points: CounterPoint[]
+}
+
+export interface CounterHistoryQuery {
+  series: string[]
+  from?: string
+  to?: string
+  step?: string
+}
+
+export interface FirewallEvent<T = unknown> {
+  id: number
+  type: string
+  time: string
+  actor?: string
+  resourceId?: string
+  data?: T
+}
+
+export interface CounterSampleEvent {
+  time: string
+  rules?: Counter[]
+  interfaces?: Record<string, InterfaceCounters>
+}
+
+export interface LogEntry {
+  id: number
+  time: string
+  source: 'kmsg' | 'journald' | 'file' | 'nflog'
+  ruleId?: string
+  prefix: string
+  in?: string
+  out?: string
+  src?: string
+  dst?: string
+  proto?: string
+  srcPort?: number
+  dstPort?: number
+  length?: number
+  message: string
+}
+
+export interface LogQuery {
+  ruleId?: string
+  prefix?: string
+  in?: string
+  out?: string
+  src?: string
+  dst?: string
+  proto?: string
+  port?: number
+  q?: string
+  from?: string
+  to?: string
+  limit?: number
+  before?: number
+}
+
+export interface ConnectionTuple {
+  src: string
+  dst: string
+  srcPort?: number
+  dstPort?: number
+  packets: number
+  bytes: number
+}
+
+export interface Connection {
+  id: number
+  family: 'ipv4' | 'ipv6'
+  protocol: string
+  state?: string
+  flags?: string[]
+  original: ConnectionTuple
+  reply: ConnectionTuple
+  snat?: string
+  dnat?: string
+  timeout: number
+  mark?: number
+  zone?: number
+}
+
+export interface ConnectionQuery {
+  family?: 'ipv4' | 'ipv6'
+  proto?: string
+  state?: string
+  addr?: string
+  src?: string
+  dst?: string
+  port?: number
+  limit?: number
+}
+
+export interface Talker {
+  address: string
+  rxBytes: number
+  txBytes: number
+  rxPackets: number
+  txPackets: number
+  bytes: number
+  bps: number
+}
+
+export interface TrafficTopQuery {
+  by?: 'host' | 'peer'
+  direction?: 'rx' | 'tx' | 'total'
+  from?: string
+  to?: string
+  limit?: number
+}
+
+export type AlertKind = 'threshold' | 'event'
+export type AlertMetric = 'pps' | 'bps' | 'packets' | 'bytes'
+export type AlertSeverity = 'info' | 'warning' | 'critical'
+export type AlertState = 'pending' | 'firing' | 'resolved'
+
+export interface AlertRule {
+  id: string
+  name: string
+  description: string
+  kind: AlertKind
+  severity: AlertSeverity
+  enabled: boolean
+  series?: string
+  metric?: AlertMetric
+  operator?: '>' | '>=' | '<' | '<='
+  value?: number
+  windowSeconds?: number
+  forSeconds?: number
+  eventType?: string
+  resourceId?: string
+  resolveEvent?: string
+  resolveAfterSeconds?: number
+  createdAt: string
+  updatedAt: string
+}
+
+export type AlertRulePayload = Omit<AlertRule, 'id' | 'createdAt' | 'updatedAt' | 'enabled' | 'description'> & {
+  description?: string
+  enabled?: boolean
+}
+
+export interface Alert {
+  id: string
+  ruleId: string
+  ruleName: string
+  severity: AlertSeverity
+  state: AlertState
+  message: string
+  value: number
+  count: number
+  silenced: boolean
+  startedAt: string
+  firedAt?: string
+  resolvedAt?: string
+  lastSeenAt: string
+}
+
+export interface AlertQuery {
+  ruleId?: string
+  state?: AlertState
+  from?: string
+  to?: string
+  limit?: number
+}
+
+export interface AlertSilence {
+  id: string
+  ruleId?: string
+  startsAt: string
+  endsAt: string
+  comment: string
+  createdBy: string
+  createdAt: string
+}
+
+export interface CreateAlertSilencePayload {
+  ruleId?: string
+  startsAt?: string
+  endsAt: string
+  comment?: string
+}
+
+export type NotificationSinkType = 'webhook' | 'slack' | 'email' | 'syslog'
+
+// Secret and password come back as "********"; sending that value keeps the stored one.
+export interface NotificationSinkConfig {
+  maxAttempts?: number
+  url?: string
+  secret?: string
+  host?: string
+  port?: number
+  username?: string
+  password?: string
+  from?: string
+  to?: string[]
+  caCert?: string
+  insecureSkipVerify?: boolean
+  network?: 'udp' | 'tcp'
+  address?: string
+  facility?: string
+  appName?: string
+}
+
+export interface NotificationSink {
+  id: string
+  name: string
+  type: NotificationSinkType
+  enabled: boolean
+  events: string[]
+  config: NotificationSinkConfig
+  createdAt: string
+  updatedAt: string
+}
+
+export interface NotificationSinkPayload {
+  name: string
+  type: NotificationSinkType
+  enabled?: boolean
+  events?: string[]
+  config: NotificationSinkConfig
+}
+
+export type NotificationDeliveryStatus = 'pending' | 'delivered' | 'failed'
+
+export interface NotificationDelivery {
+  id: string
+  sinkId: string
+  sinkName: string
+  eventId: number
+  eventType: string
+  summary: string
+  status: NotificationDeliveryStatus
+  attempts: number
+  error?: string
+  createdAt: string
+  finishedAt?: string
+}
+
+export interface NotificationDeliveryQuery {
+  sinkId?: string
+  status?: NotificationDeliveryStatus
+  eventType?: string
+  limit?: number
+}
+
+export interface InterfaceAddress {
+  ip: string
+  prefixLen: number
+  family: 'ipv4' | 'ipv6'
+  scope: 'global' | 'site' | 'link' | 'host'
+  flags?: string[]
+  validLifetime?: number
+  preferredLifetime?: number
+}
+
+export type Role = 'viewer' | 'rule-editor' | 'applier' | 'admin'
+
+export interface RoleBinding {
+  role: Role
+  zone?: string
+  tag?: string
+}
+
+export interface User {
+  id: string
+  username: string
+  oidcSubject?: string
+  disabled: boolean
+  roles: RoleBinding[]
+  createdAt: string
+  updatedAt: string
+}
+
+export interface LoginResult {
+  token: string
+  expiresAt: string
+  user: User
+}